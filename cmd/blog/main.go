@@ -14,14 +14,20 @@ import (
 	"blog/config"
 	"blog/internal/entity"
 	"blog/internal/http"
+	"blog/internal/http/router"
 	"blog/internal/repository/postgres"
+	"blog/internal/usecase"
+	"blog/internal/usecase/repo"
 	"blog/pkg/geoip"
 	"blog/pkg/log"
 	"blog/pkg/util"
 )
 
 var (
-	createAdmin = flag.Bool("create-admin", false, "Create admin account")
+	createAdmin    = flag.Bool("create-admin", false, "Create admin account")
+	migrateStorage = flag.String("migrate-storage", "", "Move media files to this storage backend (local | s3) and exit")
+	deleteSource   = flag.Bool("delete-source", false, "With -migrate-storage, delete files from the old backend once moved")
+	resetTwoFactor = flag.String("reset-2fa", "", "Turn off two-factor authentication for the account with this email and exit")
 )
 
 func main() {
@@ -49,6 +55,23 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *resetTwoFactor != "" {
+		if err := resetAccountTwoFactor(*resetTwoFactor); err != nil {
+			log.Errorf("Failed to reset two-factor authentication: %v", err)
+			os.Exit(1)
+		}
+		log.Infof("Two-factor authentication reset for %s", *resetTwoFactor)
+		os.Exit(0)
+	}
+
+	if *migrateStorage != "" {
+		if err := migrateMediaStorage(*migrateStorage, *deleteSource); err != nil {
+			log.Errorf("Failed to migrate media storage: %v", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	srv := http.NewServer()
 	srv.Run()
 
@@ -111,14 +134,17 @@ func createAdminAccount() error {
 		return fmt.Errorf("password encryption failed: %w", err)
 	}
 
+	now := time.Now()
 	admin := &entity.User{
-		Username:   username,
-		Email:      email,
-		Password:   hashedPassword,
-		Provider:   "email",
-		ProviderID: email,
-		Role:       "admin",
-		Bio:        "Administrator",
+		Username:           username,
+		Email:              email,
+		Password:           hashedPassword,
+		Provider:           "email",
+		ProviderID:         email,
+		Role:               "admin",
+		Bio:                "Administrator",
+		EmailNotifications: true,
+		EmailVerifiedAt:    &now,
 	}
 
 	if err := db.Create(admin).Error; err != nil {
@@ -134,6 +160,44 @@ func createAdminAccount() error {
 	return nil
 }
 
+// migrateMediaStorage moves all media files to the target backend. Both
+// backends must be configured under storage; switch storage.driver to the
+// target before or after so new uploads go there too.
+func migrateMediaStorage(target string, deleteSource bool) error {
+	dbRepo, err := postgres.New()
+	if err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		dbRepo.DbWClose()
+		dbRepo.DbRClose()
+	}()
+
+	stores, driver := router.NewStorages()
+	db := dbRepo.GetDbW()
+	mediaUseCase := usecase.NewMediaUseCase(repo.NewMediaRepo(db), repo.NewMediaFolderRepo(db), stores, driver)
+	moved, err := mediaUseCase.MigrateStorage(context.Background(), target, config.Conf.App.SiteURL, deleteSource)
+	log.Infof("Moved %d media to %s storage", moved, target)
+	return err
+}
+
+// resetAccountTwoFactor turns off two-factor authentication for the account
+// with the email, for when no other admin can reset it.
+func resetAccountTwoFactor(email string) error {
+	dbRepo, err := postgres.New()
+	if err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer func() {
+		dbRepo.DbWClose()
+		dbRepo.DbRClose()
+	}()
+
+	db := dbRepo.GetDbW()
+	authUseCase := usecase.NewAuthUseCase(repo.NewUserRepo(db), nil, nil, repo.NewRecoveryCodeRepo(db), nil, nil, nil)
+	return authUseCase.ResetTwoFactorByEmail(context.Background(), email)
+}
+
 func promptInput(reader *bufio.Reader, label, def string) string {
 	fmt.Print(label)
 	text, _ := reader.ReadString('\n')
@@ -28,10 +28,23 @@ type config struct {
 	LogLevelAddr    string `mapstructure:"log_level_addr"`
 	LogLevelPattern string `mapstructure:"log_level_pattern"`
 
-	App      AppConfig
-	Http     HttpConfig
-	Postgres PostgresConfig
-	Mysql    MysqlConfig
+	App       AppConfig
+	Http      HttpConfig
+	Postgres  PostgresConfig
+	Mysql     MysqlConfig
+	Comment   CommentConfig
+	Mail      MailConfig
+	OAuth     OAuthConfig
+	TwoFactor TwoFactorConfig `mapstructure:"two_factor"`
+	Security  SecurityConfig
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Redis     RedisConfig
+	Cache     CacheConfig
+	Analytics AnalyticsConfig
+	Privacy   PrivacyConfig
+	Media     MediaConfig
+	Storage   StorageConfig
+	Scheduler SchedulerConfig
 }
 
 type AppConfig struct {
@@ -44,6 +57,191 @@ type AppConfig struct {
 	GeoIPDBPath        string `mapstructure:"geoip_db_path"`
 }
 
+// CommentConfig controls the comment moderation policy and spam filter.
+// With the hold options at their zero value every non-spam comment is approved immediately.
+type CommentConfig struct {
+	HoldFirstTime        bool     `mapstructure:"hold_first_time"`        // Hold comments from users with no approved comment yet
+	AutoApproveAfter     int      `mapstructure:"auto_approve_after"`     // Hold until the user has N approved comments (0 = disabled)
+	SpamThreshold        float64  `mapstructure:"spam_threshold"`         // Score at which a comment is marked spam (default: 0.9)
+	SpamMaxLinks         int      `mapstructure:"spam_max_links"`         // Links allowed before a comment looks spammy (default: 2)
+	SpamBlocklist        []string `mapstructure:"spam_blocklist"`         // Case-insensitive words/phrases that mark a comment as spam
+	RequireVerifiedEmail bool     `mapstructure:"require_verified_email"` // Reject comments from non-admins without a verified email
+}
+
+// MailConfig selects how outgoing email is delivered.
+type MailConfig struct {
+	Driver   string // smtp | file | log (default: log)
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // Sender, e.g. "Voocel Journal <noreply@voocel.com>"
+	Dir      string // Output directory for the file driver
+}
+
+// OAuthConfig configures third-party login. A provider is enabled when its client ID is set.
+type OAuthConfig struct {
+	FrontendCallback string `mapstructure:"frontend_callback"` // SPA path that receives the tokens (default: /oauth/callback)
+	Github           OAuthProviderConfig
+	Google           OAuthProviderConfig
+}
+
+// OAuthProviderConfig holds one provider's client credentials and endpoints.
+// Endpoints default to the real provider and can point at a mock server for testing.
+type OAuthProviderConfig struct {
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	AuthURL      string   `mapstructure:"auth_url"`
+	TokenURL     string   `mapstructure:"token_url"`
+	UserInfoURL  string   `mapstructure:"userinfo_url"`
+	EmailsURL    string   `mapstructure:"emails_url"`   // GitHub only
+	RedirectURL  string   `mapstructure:"redirect_url"` // Default: site_url + /api/v1/auth/oauth/<provider>/callback
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// TwoFactorConfig configures TOTP two-factor authentication.
+type TwoFactorConfig struct {
+	RequireAdmin bool   `mapstructure:"require_admin"` // Admins must enroll before using admin endpoints
+	Issuer       string `mapstructure:"issuer"`        // Account label shown in authenticator apps (default: Voocel Journal)
+}
+
+// SecurityConfig holds keys for data encrypted at rest. Unlike
+// app.jwt_secret, which may be rotated, they must stay stable.
+type SecurityConfig struct {
+	TOTPKey string `mapstructure:"totp_key"` // Encrypts TOTP secrets (default: derived from app.jwt_secret)
+}
+
+// RateLimitConfig configures request throttling and login lockout.
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string                    // memory | redis (default: memory)
+	Groups  map[string]RateLimitGroup // Keyed by route group: login, register, account, comment, like, analytics
+	Lockout LoginLockoutConfig
+}
+
+// RateLimitGroup is a token bucket: Requests per Period on average, bursts up to Burst.
+type RateLimitGroup struct {
+	Requests int
+	Period   time.Duration
+	Burst    int    // Default: Requests
+	Key      string // ip | user (default: user, falling back to ip for anonymous requests)
+}
+
+// LoginLockoutConfig locks an account after repeated failed logins. The lock
+// starts at Base and doubles with every further failure, up to Max.
+type LoginLockoutConfig struct {
+	Threshold  int           // Failed logins before the first lock (0 = disabled)
+	Base       time.Duration // First lock duration (default: 1m)
+	Max        time.Duration // Longest lock (default: 1h)
+	ResetAfter time.Duration `mapstructure:"reset_after"` // Forget failures after this long without one
+}
+
+// RedisConfig is the Redis (or compatible server) connection.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// CacheConfig configures the read cache for public posts, categories and tags.
+// Writes invalidate the affected entries; TTL bounds staleness of view counts.
+type CacheConfig struct {
+	Driver string        // memory | redis | none (default: memory)
+	TTL    time.Duration // Default: 5m
+	Size   int           // Entries kept by the memory driver (default: 10000)
+}
+
+// AnalyticsConfig configures how page views and visits are buffered before
+// they are written in batches.
+type AnalyticsConfig struct {
+	FlushInterval  time.Duration `mapstructure:"flush_interval"`  // Default: 5s
+	BatchSize      int           `mapstructure:"batch_size"`      // Flush early once this many visits are queued (default: 500)
+	MaxQueue       int           `mapstructure:"max_queue"`       // Visits buffered before new ones are dropped (default: 10000)
+	RollupInterval time.Duration `mapstructure:"rollup_interval"` // How often daily rollups are rebuilt (default: 10m)
+	Bots           BotDetectionConfig
+}
+
+// BotDetectionConfig classifies crawlers and other automated clients. Their
+// visits are logged as bot traffic and they do not count as post views or likes.
+type BotDetectionConfig struct {
+	Enabled  bool          // Default: true
+	Requests int           // Tracked requests per IP within Period above which the client counts as a bot (default: 60; 0 = no rate check)
+	Period   time.Duration // Default: 1m
+}
+
+// PrivacyConfig controls how client IPs are stored and how long raw records
+// keep them.
+type PrivacyConfig struct {
+	IPMode    string `mapstructure:"ip_mode"` // full | truncate | hash (default: full)
+	Salt      string // Key for IP hashes in likes and events (default: derived from app.jwt_secret)
+	HonorDNT  bool   `mapstructure:"honor_dnt"` // Do not log visits sent with DNT: 1 or Sec-GPC: 1
+	Retention RetentionConfig
+}
+
+// RetentionConfig limits how long raw records keep personal data. 0 keeps it forever.
+type RetentionConfig struct {
+	AnalyticsDays int    `mapstructure:"analytics_days"` // Raw visits; daily rollups are kept (minimum: 2)
+	Action        string // What happens to old raw visits: delete | anonymize (default: delete)
+	EventDays     int    `mapstructure:"event_days"` // Clear IP and user agent of system events after this many days
+	LikeDays      int    `mapstructure:"like_days"`  // Clear IP and user agent of likes after this many days
+}
+
+// MediaConfig controls how uploaded images are processed. Metadata such as
+// EXIF GPS positions is always stripped.
+type MediaConfig struct {
+	Widths   []int // Widths of the resized variants made of post images (default: 480, 960, 1600)
+	Quality  int   // JPEG and WebP quality of variants, 1-100 (default: 82)
+	WebP     bool  // Also make WebP variants, kept when smaller (default: true)
+	MaxWidth int   `mapstructure:"max_width"` // Downscale wider post images on upload (0 = keep the original size)
+	GC       MediaGCConfig
+}
+
+// MediaGCConfig configures the reconciliation of stored files against the
+// media table and post references. Quarantined files are moved under
+// quarantine/ in the same storage, from where they can be restored by hand.
+type MediaGCConfig struct {
+	Enabled  bool          // Run every Interval; the admin API can always run it (default: false)
+	Interval time.Duration // Default: 24h
+	Action   string        // report | quarantine | delete (default: report)
+	MinAge   time.Duration `mapstructure:"min_age"` // Leave files and media younger than this alone (default: 24h)
+	Unused   bool          // Also act on media no post or avatar uses; otherwise they are only reported
+}
+
+// StorageConfig selects where media files are stored. Existing media can be
+// moved between backends with the -migrate-storage flag.
+type StorageConfig struct {
+	Driver        string        // local | s3 (default: local)
+	PresignExpiry time.Duration `mapstructure:"presign_expiry"` // Lifetime of presigned URLs (default: 15m)
+	QuotaMB       int64         `mapstructure:"quota_mb"`       // Storage budget shown on the dashboard (0 = none)
+	Local         LocalStorageConfig
+	S3            S3StorageConfig
+}
+
+// SchedulerConfig controls the worker that turns publish_at and unpublish_at
+// into events: it records post.published once a post goes live and expires
+// posts past unpublish_at, then runs the hooks for each event.
+type SchedulerConfig struct {
+	Interval    time.Duration // How often publish times are checked (default: 30s)
+	SitemapPing []string      `mapstructure:"sitemap_ping"` // Endpoints pinged with ?sitemap=<site_url>/sitemap.xml when posts go live or expire
+}
+
+// LocalStorageConfig stores media under a directory served by this server.
+type LocalStorageConfig struct {
+	Dir     string // Default: static
+	BaseURL string `mapstructure:"base_url"` // Path Dir is served under (default: /static)
+}
+
+// S3StorageConfig stores media in an S3-compatible bucket (AWS S3, MinIO, R2).
+type S3StorageConfig struct {
+	Endpoint  string // Default: AWS for Region
+	Region    string // Default: us-east-1
+	Bucket    string
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"` // Address the bucket as endpoint/bucket, as MinIO expects
+	PublicURL string `mapstructure:"public_url"` // Base URL objects are served from, e.g. a CDN (default: the bucket URL)
+}
+
 type HttpConfig struct {
 	Addr           string
 	AllowedOrigins []string `mapstructure:"allowed_origins"` // CORS allowlist, e.g. ["http://localhost:5173"]
@@ -108,6 +306,91 @@ func LoadConfig(paths ...string) {
 	viper.SetDefault("app.site_url", "https://voocel.com")
 	viper.SetDefault("app.frontend_dist_path", "/app/frontend")
 
+	// Comment spam filter defaults
+	viper.SetDefault("comment.spam_threshold", 0.9)
+	viper.SetDefault("comment.spam_max_links", 2)
+
+	// Mail defaults: log only, nothing leaves the machine until SMTP is configured.
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.port", 587)
+	viper.SetDefault("mail.from", "Voocel Journal <noreply@voocel.com>")
+
+	// OAuth provider endpoint defaults
+	viper.SetDefault("oauth.frontend_callback", "/oauth/callback")
+	viper.SetDefault("oauth.github.auth_url", "https://github.com/login/oauth/authorize")
+	viper.SetDefault("oauth.github.token_url", "https://github.com/login/oauth/access_token")
+	viper.SetDefault("oauth.github.userinfo_url", "https://api.github.com/user")
+	viper.SetDefault("oauth.github.emails_url", "https://api.github.com/user/emails")
+	viper.SetDefault("oauth.github.scopes", []string{"read:user", "user:email"})
+	viper.SetDefault("oauth.google.auth_url", "https://accounts.google.com/o/oauth2/v2/auth")
+	viper.SetDefault("oauth.google.token_url", "https://oauth2.googleapis.com/token")
+	viper.SetDefault("oauth.google.userinfo_url", "https://openidconnect.googleapis.com/v1/userinfo")
+	viper.SetDefault("oauth.google.scopes", []string{"openid", "email", "profile"})
+
+	// Two-factor authentication
+	viper.SetDefault("two_factor.issuer", "Voocel Journal")
+
+	// Rate limiting
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.store", "memory")
+	for group, limit := range map[string][3]any{
+		"login":     {10, "1m", 5},
+		"register":  {5, "1h", 3},
+		"account":   {5, "15m", 3},
+		"comment":   {10, "10m", 5},
+		"like":      {30, "1m", 10},
+		"analytics": {120, "1m", 30},
+	} {
+		viper.SetDefault("rate_limit.groups."+group+".requests", limit[0])
+		viper.SetDefault("rate_limit.groups."+group+".period", limit[1])
+		viper.SetDefault("rate_limit.groups."+group+".burst", limit[2])
+	}
+	for _, group := range []string{"login", "register", "account", "like", "analytics"} {
+		viper.SetDefault("rate_limit.groups."+group+".key", "ip")
+	}
+	viper.SetDefault("rate_limit.lockout.threshold", 5)
+	viper.SetDefault("rate_limit.lockout.base", "1m")
+	viper.SetDefault("rate_limit.lockout.max", "1h")
+	viper.SetDefault("rate_limit.lockout.reset_after", "24h")
+	viper.SetDefault("redis.addr", "localhost:6379")
+
+	// Read cache
+	viper.SetDefault("cache.driver", "memory")
+	viper.SetDefault("cache.ttl", "5m")
+	viper.SetDefault("cache.size", 10000)
+
+	// Analytics buffering
+	viper.SetDefault("analytics.flush_interval", "5s")
+	viper.SetDefault("analytics.batch_size", 500)
+	viper.SetDefault("analytics.max_queue", 10000)
+	viper.SetDefault("analytics.rollup_interval", "10m")
+	viper.SetDefault("analytics.bots.enabled", true)
+	viper.SetDefault("analytics.bots.requests", 60)
+	viper.SetDefault("analytics.bots.period", "1m")
+
+	// Privacy
+	viper.SetDefault("privacy.ip_mode", "full")
+	viper.SetDefault("privacy.honor_dnt", true)
+	viper.SetDefault("privacy.retention.action", "delete")
+
+	// Media
+	viper.SetDefault("media.widths", []int{480, 960, 1600})
+	viper.SetDefault("media.quality", 82)
+	viper.SetDefault("media.webp", true)
+	viper.SetDefault("media.gc.interval", "24h")
+	viper.SetDefault("media.gc.action", "report")
+	viper.SetDefault("media.gc.min_age", "24h")
+
+	// Storage
+	viper.SetDefault("storage.driver", "local")
+	viper.SetDefault("storage.presign_expiry", "15m")
+	viper.SetDefault("storage.local.dir", "static")
+	viper.SetDefault("storage.local.base_url", "/static")
+	viper.SetDefault("storage.s3.region", "us-east-1")
+
+	// Scheduler
+	viper.SetDefault("scheduler.interval", "30s")
+
 	// Read config.yaml (required)
 	viper.SetConfigName("config")
 	if err := viper.ReadInConfig(); err != nil {
@@ -5,32 +5,79 @@ import (
 )
 
 type Analytics struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	PagePath  string    `gorm:"type:varchar(500);not null;index" json:"pagePath"`
-	PostID    *int64    `gorm:"index" json:"postId,omitempty"`
-	PostTitle string    `gorm:"type:varchar(255)" json:"postTitle,omitempty"`
-	IP        string    `gorm:"type:varchar(45);index" json:"ip"`
-	Location  string    `gorm:"type:varchar(200)" json:"location"`
-	Timestamp int64     `gorm:"type:bigint;not null;index" json:"timestamp"`
-	UserAgent string    `gorm:"type:text" json:"userAgent"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	PagePath     string    `gorm:"type:varchar(500);not null;index" json:"pagePath"`
+	PostID       *int64    `gorm:"index" json:"postId,omitempty"`
+	PostTitle    string    `gorm:"type:varchar(255)" json:"postTitle,omitempty"`
+	IP           string    `gorm:"type:varchar(45);index" json:"ip"`
+	Location     string    `gorm:"type:varchar(200)" json:"location"`
+	Country      string    `gorm:"type:varchar(2)" json:"country,omitempty"`        // ISO 3166-1 alpha-2; empty when unknown
+	VisitorID    string    `gorm:"type:varchar(64)" json:"-"`                       // Anonymous per-day visitor key for unique counts
+	Referrer     string    `gorm:"type:varchar(500)" json:"referrer,omitempty"`     // Full referring URL
+	ReferrerHost string    `gorm:"type:varchar(255)" json:"referrerHost,omitempty"` // Lowercased host without www.; empty for direct and internal traffic
+	UTMSource    string    `gorm:"type:varchar(100)" json:"utmSource,omitempty"`
+	UTMMedium    string    `gorm:"type:varchar(100)" json:"utmMedium,omitempty"`
+	UTMCampaign  string    `gorm:"type:varchar(100)" json:"utmCampaign,omitempty"`
+	UTMTerm      string    `gorm:"type:varchar(100)" json:"utmTerm,omitempty"`
+	UTMContent   string    `gorm:"type:varchar(100)" json:"utmContent,omitempty"`
+	Timestamp    int64     `gorm:"type:bigint;not null;index" json:"timestamp"`
+	UserAgent    string    `gorm:"type:text" json:"userAgent"`
+	Bot          bool      `gorm:"not null;default:false;index" json:"bot"`
+	BotReason    string    `gorm:"type:varchar(20)" json:"botReason,omitempty"` // user_agent | headless | rate
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"-"`
+}
+
+// AnalyticsSalt is the random salt of one UTC day for hashing visitor IPs
+// in privacy mode. It is deleted the day after, so hashes made with it can
+// no longer be linked to an address.
+type AnalyticsSalt struct {
+	Day  string `gorm:"type:varchar(10);primaryKey" json:"-"`
+	Salt string `gorm:"type:varchar(64);not null" json:"-"`
 }
 
 type AnalyticsResponse struct {
-	ID        int64  `json:"id"`
-	PagePath  string `json:"pagePath"`
-	PostID    *int64 `json:"postId,omitempty"`
-	PostTitle string `json:"postTitle,omitempty"`
-	IP        string `json:"ip"`
-	Location  string `json:"location"`
-	Timestamp int64  `json:"timestamp"`
-	UserAgent string `json:"userAgent"`
+	ID          int64  `json:"id"`
+	PagePath    string `json:"pagePath"`
+	PostID      *int64 `json:"postId,omitempty"`
+	PostTitle   string `json:"postTitle,omitempty"`
+	IP          string `json:"ip"`
+	Location    string `json:"location"`
+	Country     string `json:"country,omitempty"`
+	Referrer    string `json:"referrer,omitempty"`
+	UTMSource   string `json:"utmSource,omitempty"`
+	UTMMedium   string `json:"utmMedium,omitempty"`
+	UTMCampaign string `json:"utmCampaign,omitempty"`
+	Timestamp   int64  `json:"timestamp"`
+	UserAgent   string `json:"userAgent"`
+	Bot         bool   `json:"bot"`
+	BotReason   string `json:"botReason,omitempty"`
 }
 
+// LogVisitRequest is reported by the frontend on navigation. UTM parameters
+// may be sent explicitly or left in the query string of PagePath.
 type LogVisitRequest struct {
-	PagePath  string `json:"pagePath" binding:"required"`
-	PostID    int64  `json:"postId,omitempty"`
-	PostTitle string `json:"postTitle,omitempty"`
+	PagePath    string `json:"pagePath" binding:"required"`
+	PostID      int64  `json:"postId,omitempty"`
+	PostTitle   string `json:"postTitle,omitempty"`
+	Referrer    string `json:"referrer,omitempty"` // document.referrer
+	UTMSource   string `json:"utmSource,omitempty"`
+	UTMMedium   string `json:"utmMedium,omitempty"`
+	UTMCampaign string `json:"utmCampaign,omitempty"`
+	UTMTerm     string `json:"utmTerm,omitempty"`
+	UTMContent  string `json:"utmContent,omitempty"`
+}
+
+// ViewRecorderStats describes the in-memory buffer of views and visits
+// waiting to be written.
+type ViewRecorderStats struct {
+	QueuedVisits int        `json:"queuedVisits"`
+	QueueLimit   int        `json:"queueLimit"`
+	PendingPosts int        `json:"pendingPosts"` // Posts with unwritten view counts
+	PendingViews int64      `json:"pendingViews"`
+	Dropped      int64      `json:"dropped"`     // Visits dropped because the queue was full
+	Flushed      int64      `json:"flushed"`     // Visits written since startup
+	FlushErrors  int64      `json:"flushErrors"` // Failed batch writes since startup
+	LastFlushAt  *time.Time `json:"lastFlushAt,omitempty"`
 }
 
 type DashboardOverviewResponse struct {
@@ -47,10 +94,16 @@ type DashboardCounts struct {
 }
 
 type DashboardSystemStatus struct {
-	StorageUsage int `json:"storageUsage"` // Storage usage percentage 0-100
-	AIQuota      int `json:"aiQuota"`      // AI token usage percentage 0-100
+	StorageUsage      int   `json:"storageUsage"`      // Storage usage percentage of the quota 0-100 (0 without a quota)
+	StorageUsedBytes  int64 `json:"storageUsedBytes"`  // Media files and their variants
+	StorageQuotaBytes int64 `json:"storageQuotaBytes"` // 0 = no quota
+	AIQuota           int   `json:"aiQuota"`           // AI token usage percentage 0-100
 }
 
 func (Analytics) TableName() string {
 	return "analytics"
 }
+
+func (AnalyticsSalt) TableName() string {
+	return "analytics_salts"
+}
@@ -0,0 +1,136 @@
+package entity
+
+// Daily analytics rollups, rebuilt from raw analytics rows by a background
+// job. Day is the UTC date as YYYY-MM-DD. Visitors counts distinct visitors
+// within that day; summed over a range it counts visitor-days. Views and
+// Visitors are human traffic; BotViews and BotVisitors are bot traffic.
+
+// AnalyticsDaily is the site-wide traffic of one day.
+type AnalyticsDaily struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	Day         string `gorm:"type:varchar(10);not null;uniqueIndex" json:"day"`
+	Views       int64  `gorm:"not null;default:0" json:"views"`
+	Visitors    int64  `gorm:"not null;default:0" json:"visitors"`
+	BotViews    int64  `gorm:"not null;default:0" json:"botViews"`
+	BotVisitors int64  `gorm:"not null;default:0" json:"botVisitors"`
+}
+
+// AnalyticsDailyPath is the traffic of one page path on one day.
+type AnalyticsDailyPath struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	Day         string `gorm:"type:varchar(10);not null;uniqueIndex:idx_analytics_daily_path" json:"day"`
+	Path        string `gorm:"type:varchar(500);not null;uniqueIndex:idx_analytics_daily_path" json:"path"`
+	Views       int64  `gorm:"not null;default:0" json:"views"`
+	Visitors    int64  `gorm:"not null;default:0" json:"visitors"`
+	BotViews    int64  `gorm:"not null;default:0" json:"botViews"`
+	BotVisitors int64  `gorm:"not null;default:0" json:"botVisitors"`
+}
+
+// AnalyticsDailyPost is the traffic of one post on one day.
+type AnalyticsDailyPost struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	Day         string `gorm:"type:varchar(10);not null;uniqueIndex:idx_analytics_daily_post" json:"day"`
+	PostID      int64  `gorm:"not null;uniqueIndex:idx_analytics_daily_post;index" json:"postId"`
+	Views       int64  `gorm:"not null;default:0" json:"views"`
+	Visitors    int64  `gorm:"not null;default:0" json:"visitors"`
+	BotViews    int64  `gorm:"not null;default:0" json:"botViews"`
+	BotVisitors int64  `gorm:"not null;default:0" json:"botVisitors"`
+}
+
+// AnalyticsDailyCountry is the traffic from one country on one day. Country
+// is an ISO 3166-1 alpha-2 code, ZZ when unknown.
+type AnalyticsDailyCountry struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	Day         string `gorm:"type:varchar(10);not null;uniqueIndex:idx_analytics_daily_country" json:"day"`
+	Country     string `gorm:"type:varchar(2);not null;uniqueIndex:idx_analytics_daily_country" json:"country"`
+	Views       int64  `gorm:"not null;default:0" json:"views"`
+	Visitors    int64  `gorm:"not null;default:0" json:"visitors"`
+	BotViews    int64  `gorm:"not null;default:0" json:"botViews"`
+	BotVisitors int64  `gorm:"not null;default:0" json:"botVisitors"`
+}
+
+// AnalyticsDailyReferrer is the traffic referred by one external host on one day.
+type AnalyticsDailyReferrer struct {
+	ID          int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	Day         string `gorm:"type:varchar(10);not null;uniqueIndex:idx_analytics_daily_referrer" json:"day"`
+	Host        string `gorm:"type:varchar(255);not null;uniqueIndex:idx_analytics_daily_referrer" json:"host"`
+	Views       int64  `gorm:"not null;default:0" json:"views"`
+	Visitors    int64  `gorm:"not null;default:0" json:"visitors"`
+	BotViews    int64  `gorm:"not null;default:0" json:"botViews"`
+	BotVisitors int64  `gorm:"not null;default:0" json:"botVisitors"`
+}
+
+// AnalyticsRollup is the full set of rollups for one day.
+type AnalyticsRollup struct {
+	Total     AnalyticsDaily
+	Paths     []AnalyticsDailyPath
+	Posts     []AnalyticsDailyPost
+	Countries []AnalyticsDailyCountry
+	Referrers []AnalyticsDailyReferrer
+}
+
+// UnknownCountry is the rollup key for visits without a known country.
+const UnknownCountry = "ZZ"
+
+// Traffic selects which visits a report counts.
+type Traffic string
+
+const (
+	TrafficHuman Traffic = "human"
+	TrafficBot   Traffic = "bot"
+	TrafficAll   Traffic = "all"
+)
+
+// AnalyticsPoint is one day of a time series.
+type AnalyticsPoint struct {
+	Day      string `json:"day"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+}
+
+// AnalyticsTimeseriesResponse covers every day of the range, zero-filled.
+type AnalyticsTimeseriesResponse struct {
+	StartDate string           `json:"startDate"`
+	EndDate   string           `json:"endDate"`
+	Traffic   Traffic          `json:"traffic"`
+	Views     int64            `json:"views"`
+	Visitors  int64            `json:"visitors"` // Visitor-days
+	Points    []AnalyticsPoint `json:"points"`
+}
+
+// AnalyticsRankItem is one entry of a top-N list; Name is the path, host or
+// country code.
+type AnalyticsRankItem struct {
+	Name     string `json:"name"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+}
+
+// AnalyticsTopPost is one entry of the top posts list.
+type AnalyticsTopPost struct {
+	PostID   int64  `json:"postId"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+}
+
+func (AnalyticsDaily) TableName() string {
+	return "analytics_daily"
+}
+
+func (AnalyticsDailyPath) TableName() string {
+	return "analytics_daily_paths"
+}
+
+func (AnalyticsDailyPost) TableName() string {
+	return "analytics_daily_posts"
+}
+
+func (AnalyticsDailyCountry) TableName() string {
+	return "analytics_daily_countries"
+}
+
+func (AnalyticsDailyReferrer) TableName() string {
+	return "analytics_daily_referrers"
+}
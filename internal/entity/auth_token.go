@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// Auth token purposes.
+const (
+	AuthTokenPasswordReset = "password_reset"
+	AuthTokenEmailVerify   = "email_verify"
+	AuthTokenTwoFactor     = "two_factor" // Login challenge between the password and TOTP steps
+)
+
+// AuthToken is a single-use, expiring token emailed or handed to a user. Only
+// the SHA-256 hash of the token is stored, so a database leak cannot be used to
+// reset passwords.
+type AuthToken struct {
+	ID        int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int64      `gorm:"not null;index" json:"userId"`
+	Purpose   string     `gorm:"type:varchar(20);not null" json:"purpose"` // password_reset | email_verify | two_factor
+	TokenHash string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	Attempts  int        `gorm:"not null;default:0" json:"-"` // Failed attempts to complete the token's step
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+func (AuthToken) TableName() string {
+	return "auth_tokens"
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
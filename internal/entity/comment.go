@@ -2,16 +2,37 @@ package entity
 
 import "time"
 
+// Comment moderation states. Only approved comments are visible publicly.
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusSpam     = "spam"
+	CommentStatusRejected = "rejected"
+)
+
+// IsValidCommentStatus reports whether s is one of the moderation states.
+func IsValidCommentStatus(s string) bool {
+	switch s {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusSpam, CommentStatusRejected:
+		return true
+	}
+	return false
+}
+
 // Comment represents a top-level comment or a single-level reply (no deep nesting).
 type Comment struct {
-	ID        int64      `gorm:"primaryKey;autoIncrement" json:"id"`
-	PostID    int64      `gorm:"not null;index" json:"postId"`
-	UserID    int64      `gorm:"not null;index" json:"userId"`
-	ParentID  *int64     `gorm:"index" json:"parentId,omitempty"`
-	Content   string     `gorm:"type:text;not null" json:"content"`
-	CreatedAt time.Time  `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
-	DeletedAt *time.Time `gorm:"index" json:"-"`
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	PostID      int64      `gorm:"not null;index" json:"postId"`
+	UserID      int64      `gorm:"not null;index" json:"userId"`
+	ParentID    *int64     `gorm:"index" json:"parentId,omitempty"`
+	Content     string     `gorm:"type:text;not null" json:"content"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'approved';index" json:"status"` // pending | approved | spam | rejected
+	SpamScore   float64    `gorm:"not null;default:0" json:"spamScore"`                              // Spam filter score at submission (0..1)
+	SpamReasons string     `gorm:"type:varchar(500)" json:"spamReasons"`                             // "; "-separated signals behind SpamScore
+	TrainedAs   string     `gorm:"type:varchar(10)" json:"-"`                                        // Label the classifier last learned from this comment (spam | ham)
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt   *time.Time `gorm:"index" json:"-"`
 }
 
 type CreateCommentRequest struct {
@@ -28,6 +49,7 @@ type CommentResponse struct {
 	ID          int64             `json:"id"`
 	ParentID    *int64            `json:"parentId,omitempty"`
 	Content     string            `json:"content"`
+	Status      string            `json:"status,omitempty"` // Only set on create, so the author knows it awaits moderation
 	CreatedAt   time.Time         `json:"createdAt"`
 	User        CommentUser       `json:"user"`
 	ReplyToUser *CommentUser      `json:"replyToUser,omitempty"`
@@ -36,13 +58,31 @@ type CommentResponse struct {
 
 // AdminCommentResponse is used by admin moderation endpoints.
 type AdminCommentResponse struct {
-	ID        int64       `json:"id"`
-	ParentID  *int64      `json:"parentId"`
-	Content   string      `json:"content"`
-	CreatedAt time.Time   `json:"createdAt"`
-	PostID    int64       `json:"postId"`
-	PostTitle string      `json:"postTitle"`
-	User      CommentUser `json:"user"`
+	ID          int64       `json:"id"`
+	ParentID    *int64      `json:"parentId"`
+	Content     string      `json:"content"`
+	Status      string      `json:"status"`
+	SpamScore   float64     `json:"spamScore"`
+	SpamReasons []string    `json:"spamReasons,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	PostID      int64       `json:"postId"`
+	PostTitle   string      `json:"postTitle"`
+	User        CommentUser `json:"user"`
+}
+
+type PaginatedAdminCommentsResponse struct {
+	Data       []AdminCommentResponse `json:"data"`
+	Pagination Pagination             `json:"pagination"`
+}
+
+// ModerateCommentsRequest sets the moderation status of several comments at once.
+type ModerateCommentsRequest struct {
+	IDs    []int64 `json:"ids" binding:"required,min=1,max=200"`
+	Status string  `json:"status" binding:"required"` // approved | rejected | spam | pending
+}
+
+type ModerateCommentsResponse struct {
+	Updated int64 `json:"updated"`
 }
 
 type PaginatedCommentsResponse struct {
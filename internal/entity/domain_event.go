@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// Domain event types.
+const (
+	EventPostPublished   = "post.published"   // A post went live: published and its publish time reached
+	EventPostUnpublished = "post.unpublished" // A post expired at its unpublish time
+)
+
+// Domain event processing states.
+const (
+	EventStatusPending    = "pending"
+	EventStatusProcessing = "processing" // Claimed by an instance until next_attempt_at; reclaimed if it lapses
+	EventStatusDone       = "done"
+	EventStatusFailed     = "failed" // A hook still failed after the maximum number of attempts
+)
+
+// DomainEvent records a change in a post's lifecycle so its hooks run even
+// across restarts. The unique index on type, post and time makes recording
+// idempotent; post.published is further limited to one per post by
+// Post.PublishedEventAt.
+type DomainEvent struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Type          string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_domain_event" json:"type"`
+	PostID        int64      `gorm:"not null;uniqueIndex:idx_domain_event" json:"postId"`
+	OccurredAt    time.Time  `gorm:"not null;uniqueIndex:idx_domain_event" json:"occurredAt"` // When the change took effect, e.g. the publish time
+	Status        string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_domain_event_due" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:varchar(500)" json:"lastError,omitempty"`
+	DoneHooks     string     `gorm:"type:varchar(500)" json:"doneHooks,omitempty"` // Comma-separated hooks that succeeded; retries skip them
+	NextAttemptAt time.Time  `gorm:"index:idx_domain_event_due" json:"nextAttemptAt"`
+	ProcessedAt   *time.Time `json:"processedAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// SchedulerCursor is a persisted high-water mark of the scheduler: publish
+// times up to CheckedUntil have been looked at. It lets the scheduler catch
+// up after downtime of any length without announcing older posts.
+type SchedulerCursor struct {
+	Name         string    `gorm:"type:varchar(50);primaryKey"`
+	CheckedUntil time.Time `gorm:"not null"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
+
+func (DomainEvent) TableName() string {
+	return "domain_events"
+}
+
+func (SchedulerCursor) TableName() string {
+	return "scheduler_cursors"
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// Mail outbox states.
+const (
+	MailStatusPending = "pending"
+	MailStatusSending = "sending" // Claimed by a worker until next_attempt_at; reclaimed if it lapses
+	MailStatusSent    = "sent"
+	MailStatusFailed  = "failed" // Gave up after the maximum number of attempts
+)
+
+// MailOutbox is a queued outgoing email. Messages are rendered when queued and
+// delivered by a background worker, so sending survives restarts and SMTP outages.
+type MailOutbox struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind          string     `gorm:"type:varchar(30);not null" json:"kind"` // comment_reply | post_comment
+	To            string     `gorm:"column:recipient;type:varchar(255);not null" json:"to"`
+	Subject       string     `gorm:"type:varchar(255);not null" json:"subject"`
+	TextBody      string     `gorm:"type:text" json:"-"`
+	HTMLBody      string     `gorm:"column:html_body;type:text" json:"-"`
+	Headers       string     `gorm:"type:text" json:"-"` // JSON-encoded extra headers
+	Status        string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_mail_outbox_due" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:varchar(500)" json:"lastError,omitempty"`
+	NextAttemptAt time.Time  `gorm:"index:idx_mail_outbox_due" json:"nextAttemptAt"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (MailOutbox) TableName() string {
+	return "mail_outbox"
+}
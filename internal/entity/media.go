@@ -9,24 +9,191 @@ type Media struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
 	URL       string    `gorm:"type:varchar(500);not null" json:"url"`  // Absolute URL
 	Name      string    `gorm:"type:varchar(255);not null" json:"name"` // Original filename
-	Type      string    `gorm:"type:varchar(20);not null" json:"type"`  // image | video | document
-	Size      int64     `gorm:"type:bigint" json:"size,omitempty"`      // File size in bytes
+	FolderID  *int64    `gorm:"index" json:"folderId"`                  // Nil = library root
+	Alt       string    `gorm:"type:varchar(500)" json:"alt"`           // Alternative text for images
+	Caption   string    `gorm:"type:text" json:"caption"`
+	Type      string    `gorm:"type:varchar(20);not null" json:"type"` // image | video | document
+	Size      int64     `gorm:"type:bigint" json:"size,omitempty"`     // File size in bytes
 	MimeType  string    `gorm:"type:varchar(100)" json:"mimeType,omitempty"`
-	Path      string    `gorm:"type:varchar(500)" json:"path,omitempty"` // Server file path
-	Date      string    `gorm:"type:varchar(30);not null" json:"date"`   // ISO Date
+	Storage   string    `gorm:"type:varchar(20)" json:"-"`                 // Backend holding the files: local | s3 (empty = local)
+	Path      string    `gorm:"type:varchar(500)" json:"path,omitempty"`   // Storage key; static/<key> on older local rows
+	Date      string    `gorm:"type:varchar(30);not null" json:"date"`     // ISO Date
+	Width     int       `gorm:"not null;default:0" json:"width,omitempty"` // Images only, as displayed
+	Height    int       `gorm:"not null;default:0" json:"height,omitempty"`
+	BlurHash  string    `gorm:"type:varchar(64)" json:"blurhash,omitempty"` // Placeholder, see blurha.sh
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"-"`
+
+	Variants []MediaVariant `gorm:"foreignKey:MediaID" json:"variants,omitempty"`
+}
+
+// MediaVariant is a resized or WebP copy of an image, generated on upload.
+type MediaVariant struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"-"`
+	MediaID   int64     `gorm:"not null;index" json:"-"`
+	URL       string    `gorm:"type:varchar(500);not null" json:"url"`
+	Width     int       `gorm:"not null" json:"width"`
+	Height    int       `gorm:"not null" json:"height"`
+	MimeType  string    `gorm:"type:varchar(100);not null" json:"mimeType"`
+	Size      int64     `gorm:"type:bigint" json:"size"`
+	Path      string    `gorm:"type:varchar(500)" json:"-"` // Storage key, in the media's backend
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
 }
 
+// MediaFolder groups media files in the library. Folders are flat.
+type MediaFolder struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"-"`
+}
+
+// MediaReference records that a post uses a media file, found by scanning
+// the post's content and cover for the URLs of the file and its variants.
+type MediaReference struct {
+	ID      int64  `gorm:"primaryKey;autoIncrement"`
+	MediaID int64  `gorm:"not null;uniqueIndex:idx_media_ref"`
+	PostID  int64  `gorm:"not null;uniqueIndex:idx_media_ref;index"`
+	Field   string `gorm:"type:varchar(20);not null;uniqueIndex:idx_media_ref"` // content | cover
+}
+
+// Fields of a post a media file can be referenced from.
+const (
+	MediaRefContent = "content"
+	MediaRefCover   = "cover"
+)
+
 // MediaResponse media file response
 type MediaResponse struct {
-	ID   int64  `json:"id"`
-	URL  string `json:"url"`
-	Name string `json:"name"`
-	Type string `json:"type"`
-	Date string `json:"date"`
+	ID         int64                  `json:"id"`
+	URL        string                 `json:"url"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	MimeType   string                 `json:"mimeType"`
+	Size       int64                  `json:"size"`
+	Date       string                 `json:"date"`
+	FolderID   *int64                 `json:"folderId"`
+	Alt        string                 `json:"alt"`
+	Caption    string                 `json:"caption"`
+	UsageCount int64                  `json:"usageCount"` // Posts using the file
+	Width      int                    `json:"width,omitempty"`
+	Height     int                    `json:"height,omitempty"`
+	BlurHash   string                 `json:"blurhash,omitempty"`
+	Variants   []MediaVariantResponse `json:"variants,omitempty"` // For srcset, narrowest first
+}
+
+type PaginatedMediaResponse struct {
+	Data       []MediaResponse `json:"data"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+// UpdateMediaRequest edits a media file's metadata. Nil fields are left
+// unchanged; a FolderID of 0 moves the file to the library root.
+type UpdateMediaRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Alt      *string `json:"alt" binding:"omitempty,max=500"`
+	Caption  *string `json:"caption" binding:"omitempty,max=2000"`
+	FolderID *int64  `json:"folderId"`
+}
+
+// MediaUsage is a post that uses a media file.
+type MediaUsage struct {
+	PostID int64  `json:"postId"`
+	Title  string `json:"title"`
+	Slug   string `json:"slug"`
+	Status string `json:"status"`
+	Field  string `json:"field"` // content | cover
+}
+
+// MediaInUseResponse is returned when deleting a file that posts still use.
+type MediaInUseResponse struct {
+	Error string       `json:"error"`
+	Usage []MediaUsage `json:"usage"`
+}
+
+type MediaFolderRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type MediaFolderResponse struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	MediaCount int64     `json:"mediaCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// MediaVariantResponse is one srcset candidate of an image.
+type MediaVariantResponse struct {
+	URL      string `json:"url"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+}
+
+// MediaURLResponse is a time-limited URL of a media file.
+type MediaURLResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// MediaGCReport is the result of reconciling stored files with media records.
+type MediaGCReport struct {
+	Action       string          `json:"action"` // report | quarantine | delete
+	StartedAt    time.Time       `json:"startedAt"`
+	FinishedAt   time.Time       `json:"finishedAt"`
+	OrphanFiles  []OrphanFile    `json:"orphanFiles"`  // Stored files no media record points to
+	OrphanBytes  int64           `json:"orphanBytes"`  // Size of the orphan files
+	MissingFiles []MissingFile   `json:"missingFiles"` // Media records whose file is gone
+	UnusedMedia  []MediaResponse `json:"unusedMedia"`  // Media no post or avatar uses
+	Quarantined  int             `json:"quarantined"`  // Files moved under quarantine/
+	Deleted      int             `json:"deleted"`      // Files and media deleted
+	Errors       []string        `json:"errors,omitempty"`
+
+	// Bytes stored without a media record once the run is over, counted
+	// towards the storage usage until the next run.
+	RemainingOrphanBytes int64 `json:"remainingOrphanBytes"` // Orphan files left in place
+	QuarantineBytes      int64 `json:"quarantineBytes"`      // Files under quarantine/
+}
+
+type OrphanFile struct {
+	Storage string    `json:"storage"`
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Linked  bool      `json:"linked"` // A post or avatar links to it, so it is kept
+}
+
+type MissingFile struct {
+	MediaID int64  `json:"mediaId"`
+	Storage string `json:"storage"`
+	Key     string `json:"key"`
+}
+
+// StorageUsageResponse is the space taken by media files and their variants,
+// plus the files without a record found by the last reconciliation.
+type StorageUsageResponse struct {
+	TotalBytes      int64            `json:"totalBytes"`
+	QuotaBytes      int64            `json:"quotaBytes"` // 0 = no quota
+	Percent         int              `json:"percent"`    // Of the quota, 0-100
+	ByType          map[string]int64 `json:"byType"`     // image | video | document
+	Files           int64            `json:"files"`
+	OrphanBytes     int64            `json:"orphanBytes"`     // Files without a record
+	QuarantineBytes int64            `json:"quarantineBytes"` // Files under quarantine/
 }
 
 func (Media) TableName() string {
 	return "media"
 }
+
+func (MediaVariant) TableName() string {
+	return "media_variants"
+}
+
+func (MediaFolder) TableName() string {
+	return "media_folders"
+}
+
+func (MediaReference) TableName() string {
+	return "media_references"
+}
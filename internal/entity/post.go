@@ -10,10 +10,18 @@ type Post struct {
 	Title      string    `gorm:"type:varchar(255);not null" json:"title"`
 	Excerpt    string    `gorm:"type:varchar(500);not null" json:"excerpt"`
 	Content    string    `gorm:"type:text;not null" json:"content"` // Markdown
-	Author     string    `gorm:"type:varchar(100);not null" json:"author"`
+	Author     string    `gorm:"type:varchar(100);not null" json:"author"` // Byline: the author's username when last assigned
+	AuthorID   int64     `gorm:"not null;default:0;index" json:"authorId"` // User who owns the post; 0 for posts older than author accounts
 	// PublishAt is the scheduled publish time (supports second-level scheduling).
 	// Public APIs only return posts where status=published and publish_at <= now().
 	PublishAt  time.Time `gorm:"not null;index" json:"publishAt"`
+	// UnpublishAt, when set, expires a time-limited post: the scheduler moves
+	// it back to draft once the time passes.
+	UnpublishAt *time.Time `gorm:"index" json:"unpublishAt,omitempty"`
+	// PublishedEventAt is when the post's post.published event was recorded.
+	// It is set once, on the first publication, and never cleared, so editing
+	// the publish time of a live post does not announce it again.
+	PublishedEventAt *time.Time `gorm:"index" json:"-"`
 	CategoryID int64     `gorm:"not null;index" json:"categoryId"`
 	Cover      string    `gorm:"type:varchar(500);not null" json:"cover"`
 	Views      int       `gorm:"type:int;default:0" json:"views"`
@@ -36,7 +44,9 @@ type PostResponse struct {
 	Excerpt    string    `json:"excerpt"`
 	Content    string    `json:"content"`
 	Author     string    `json:"author"`
+	AuthorID   int64     `json:"authorId"`
 	PublishAt  time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt,omitempty"`
 	CategoryID int64     `json:"categoryId"`
 	Category   string    `json:"category"` // Category name
 	ReadTime   string    `json:"readTime"`
@@ -44,6 +54,7 @@ type PostResponse struct {
 	Tags       []string  `json:"tags"` // Tag names
 	Views      int       `json:"views"`
 	Status     string    `json:"status"`
+	Series     *PostSeriesBlock `json:"series,omitempty"` // Set when the post is part of a series
 }
 
 type CreatePostRequest struct {
@@ -55,21 +66,26 @@ type CreatePostRequest struct {
 	Tags       []int64  `json:"tags"` // Tag IDs
 	Cover      string   `json:"cover" binding:"required"`
 	Status     string   `json:"status"` // published | draft, default: draft
+	AuthorID   int64    `json:"authorId"` // Optional: assign to another author (editors only); default the creator
 	// PublishAt should be RFC3339 (e.g. 2025-12-14T16:30:00+08:00).
 	// If omitted, defaults to server current time.
 	PublishAt string `json:"publishAt"`
+	// UnpublishAt optionally expires the post at that time (RFC3339).
+	UnpublishAt string `json:"unpublishAt"`
 }
 
 type UpdatePostRequest struct {
 	Title      string  `json:"title,omitempty"`
 	Slug       string  `json:"slug,omitempty"`
-	Excerpt    string  `json:"excerpt,omitempty"`
+	Excerpt    *string `json:"excerpt,omitempty"` // "" clears it, omitted keeps it
 	Content    string  `json:"content,omitempty"`
 	CategoryID int64   `json:"categoryId,omitempty"`
 	Tags       []int64 `json:"tags,omitempty"`
-	Cover      string  `json:"cover,omitempty"`
+	Cover      *string `json:"cover,omitempty"` // "" clears it, omitted keeps it
 	Status     string  `json:"status,omitempty"`
 	PublishAt  string  `json:"publishAt,omitempty"` // RFC3339
+	AuthorID   int64   `json:"authorId,omitempty"`  // Reassign the post (editors only)
+	UnpublishAt *string `json:"unpublishAt,omitempty"` // RFC3339; "" clears it, omitted keeps it
 }
 
 type PaginatedPostsResponse struct {
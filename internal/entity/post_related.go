@@ -0,0 +1,14 @@
+package entity
+
+// PostRelated is a precomputed similarity score from a post to another one.
+// Rows are rebuilt in the background whenever posts change.
+type PostRelated struct {
+	ID        int64   `gorm:"primaryKey;autoIncrement" json:"id"`
+	PostID    int64   `gorm:"not null;uniqueIndex:idx_post_related" json:"postId"`
+	RelatedID int64   `gorm:"not null;uniqueIndex:idx_post_related" json:"relatedId"`
+	Score     float64 `gorm:"not null" json:"score"`
+}
+
+func (PostRelated) TableName() string {
+	return "post_related"
+}
@@ -0,0 +1,64 @@
+package entity
+
+import "time"
+
+// PostRevision is an immutable snapshot of a post written on every create,
+// update and restore, so earlier versions can be compared and brought back.
+type PostRevision struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	PostID     int64     `gorm:"not null;uniqueIndex:idx_post_revision" json:"postId"`
+	Revision   int       `gorm:"not null;uniqueIndex:idx_post_revision" json:"revision"` // 1-based sequence per post
+	Title      string    `gorm:"type:varchar(255);not null" json:"title"`
+	Slug       string    `gorm:"type:varchar(255);not null" json:"slug"`
+	Excerpt    string    `gorm:"type:varchar(500)" json:"excerpt"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	Cover      string    `gorm:"type:varchar(500)" json:"cover"`
+	CategoryID int64     `json:"categoryId"`
+	TagIDs     string    `gorm:"type:varchar(500)" json:"-"` // Comma-separated tag IDs
+	Status     string    `gorm:"type:varchar(20)" json:"status"`
+	PublishAt  time.Time `json:"publishAt"`
+	EditorID   int64     `gorm:"index" json:"editorId"`                   // Admin user who made the change
+	EditorName string    `gorm:"type:varchar(50)" json:"editorName"`      // Username at the time of the change
+	Action     string    `gorm:"type:varchar(20);not null" json:"action"` // create | update | restore
+	// RestoredFrom is the revision number that was restored (action=restore only).
+	RestoredFrom int       `json:"restoredFrom,omitempty"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// PostRevisionSummary is a revision list item without the (potentially large) content.
+type PostRevisionSummary struct {
+	ID           int64     `json:"id"`
+	Revision     int       `json:"revision"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	EditorID     int64     `json:"editorId"`
+	EditorName   string    `json:"editorName"`
+	Action       string    `json:"action"`
+	RestoredFrom int       `json:"restoredFrom,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// PostRevisionResponse is a full revision including content and tag IDs.
+type PostRevisionResponse struct {
+	PostRevision
+	Tags []int64 `json:"tags"`
+}
+
+// PostRevisionDiffResponse carries a unified diff between two revisions of a post.
+type PostRevisionDiffResponse struct {
+	PostID int64  `json:"postId"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Diff   string `json:"diff"`
+}
+
+// Editor identifies the authenticated user performing a content change.
+type Editor struct {
+	ID       int64
+	Username string
+	Role     string
+}
+
+func (PostRevision) TableName() string {
+	return "post_revisions"
+}
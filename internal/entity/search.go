@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+// PostSearchHit is a post matched by full-text search together with its relevance score.
+type PostSearchHit struct {
+	Post Post
+	Rank float64
+}
+
+// SearchResult is a single ranked hit returned by the public search API.
+// Title and Snippet are HTML-escaped with matched terms wrapped in <mark>.
+type SearchResult struct {
+	ID        int64     `json:"id"`
+	Slug      string    `json:"slug"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Cover     string    `json:"cover"`
+	Category  string    `json:"category"`
+	Tags      []string  `json:"tags"`
+	PublishAt time.Time `json:"publishAt"`
+	ReadTime  string    `json:"readTime"`
+	Score     float64   `json:"score"`
+}
+
+type PaginatedSearchResponse struct {
+	Query      string         `json:"query"`
+	Data       []SearchResult `json:"data"`
+	Pagination Pagination     `json:"pagination"`
+}
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"time"
+)
+
+// Series groups posts into an ordered sequence, such as a multi-part tutorial.
+type Series struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title       string    `gorm:"type:varchar(255);not null" json:"title"`
+	Slug        string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"slug"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (Series) TableName() string {
+	return "series"
+}
+
+// SeriesPost places a post in a series. A post belongs to at most one series.
+type SeriesPost struct {
+	ID       int64 `gorm:"primaryKey;autoIncrement" json:"id"`
+	SeriesID int64 `gorm:"not null;index" json:"seriesId"`
+	PostID   int64 `gorm:"not null;uniqueIndex" json:"postId"`
+	Position int   `gorm:"not null;default:0" json:"position"` // 1-based order within the series
+}
+
+func (SeriesPost) TableName() string {
+	return "series_posts"
+}
+
+// SeriesRequest creates or updates a series. PostIDs lists the posts in
+// reading order; on update a nil list keeps the current posts.
+type SeriesRequest struct {
+	Title       string  `json:"title" binding:"required"`
+	Slug        string  `json:"slug"` // Optional: if empty, generated from title
+	Description string  `json:"description"`
+	PostIDs     []int64 `json:"postIds"`
+}
+
+// SeriesPostItem is a post within a series listing.
+type SeriesPostItem struct {
+	ID        int64     `json:"id"`
+	Slug      string    `json:"slug"`
+	Title     string    `json:"title"`
+	Excerpt   string    `json:"excerpt"`
+	Cover     string    `json:"cover"`
+	PublishAt time.Time `json:"publishAt"`
+	Part      int       `json:"part"`
+	Status    string    `json:"status,omitempty"` // Admin only
+}
+
+type SeriesResponse struct {
+	ID          int64            `json:"id"`
+	Title       string           `json:"title"`
+	Slug        string           `json:"slug"`
+	Description string           `json:"description"`
+	PostCount   int              `json:"postCount"`
+	Posts       []SeriesPostItem `json:"posts,omitempty"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+// SeriesLink points to a neighbouring part of a series.
+type SeriesLink struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+// PostSeriesBlock tells a reader where a post sits in its series. Only live
+// posts are counted.
+type PostSeriesBlock struct {
+	ID    int64       `json:"id"`
+	Title string      `json:"title"`
+	Slug  string      `json:"slug"`
+	Part  int         `json:"part"`  // N in "part N of M"
+	Total int         `json:"total"` // M
+	Prev  *SeriesLink `json:"prev,omitempty"`
+	Next  *SeriesLink `json:"next,omitempty"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// Session is one issued refresh token. A login starts a family (FamilyID);
+// every refresh rotates the token, marking the old row rotated and adding a
+// new row to the same family. Presenting a rotated token again means it was
+// stolen or replayed, and the whole family is revoked.
+type Session struct {
+	ID        string     `gorm:"type:varchar(36);primaryKey" json:"-"`      // Refresh token jti
+	FamilyID  string     `gorm:"type:varchar(36);not null;index" json:"id"` // Stable session ID, carried as sid in tokens
+	UserID    int64      `gorm:"not null;index" json:"-"`                   // Owner
+	IP        string     `gorm:"type:varchar(45)" json:"ip"`                // Client IP at issue time
+	UserAgent string     `gorm:"type:varchar(255)" json:"userAgent"`        // Client user agent at issue time
+	StartedAt time.Time  `gorm:"not null" json:"startedAt"`                 // When the family was created (login)
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expiresAt"`           // Refresh token expiry
+	RotatedAt *time.Time `json:"-"`                                         // Set once exchanged for a newer token
+	RevokedAt *time.Time `json:"-"`                                         // Set when the family is revoked
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"lastSeenAt"`          // Issue time, i.e. the last refresh
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// ClientInfo identifies the device a session is issued to.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// SessionResponse is returned by the session list endpoint.
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	StartedAt  time.Time `json:"startedAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"` // The session making the request
+}
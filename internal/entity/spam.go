@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// Spam training labels.
+const (
+	SpamLabelSpam = "spam"
+	SpamLabelHam  = "ham"
+)
+
+// SpamToken holds how many spam and ham training comments contained a token.
+type SpamToken struct {
+	Token     string    `gorm:"primaryKey;type:varchar(64)" json:"token"`
+	SpamCount int64     `gorm:"not null;default:0" json:"spamCount"`
+	HamCount  int64     `gorm:"not null;default:0" json:"hamCount"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (SpamToken) TableName() string {
+	return "spam_tokens"
+}
+
+// SpamCorpus holds the number of training comments per label.
+type SpamCorpus struct {
+	Label     string    `gorm:"primaryKey;type:varchar(10)" json:"label"` // spam | ham
+	Documents int64     `gorm:"not null;default:0" json:"documents"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (SpamCorpus) TableName() string {
+	return "spam_corpus"
+}
+
+// SpamVerdict is the outcome of a spam check.
+type SpamVerdict struct {
+	Score   float64  // 0 (clean) .. 1 (certainly spam)
+	Reasons []string // Human-readable signals that raised the score
+}
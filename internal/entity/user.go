@@ -6,20 +6,37 @@ import (
 	"gorm.io/gorm"
 )
 
+// User roles. Authors write their own drafts, editors publish and manage
+// content, admins also manage users and settings.
+const (
+	RoleAdmin   = "admin"
+	RoleEditor  = "editor"
+	RoleAuthor  = "author"
+	RoleVisitor = "visitor"
+)
+
 type User struct {
-	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
-	Username     string    `gorm:"type:varchar(50);not null" json:"username"`                // Nickname, non-unique
-	Email        string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"email"`      // Unique identifier
-	Password     string    `gorm:"type:varchar(255)" json:"-"`                               // Optional, can be empty for OAuth users
-	Status       string    `gorm:"type:varchar(20);not null;default:'active'" json:"status"` // active | banned
-	Role         string    `gorm:"type:varchar(20);not null;default:'visitor'" json:"role"`  // admin | visitor
-	TokenVersion int       `gorm:"type:int;not null;default:1" json:"-"`                     // Token revocation version
-	Avatar       string    `gorm:"type:varchar(500)" json:"avatar,omitempty"`
-	Bio          string    `gorm:"type:text" json:"bio,omitempty"`
-	Provider     string    `gorm:"type:varchar(20);not null;default:'email';uniqueIndex:idx_provider_user" json:"provider"` // email | google | github | apple
-	ProviderID   string    `gorm:"type:varchar(255);uniqueIndex:idx_provider_user" json:"-"`                                // Third-party platform user ID, unique with provider
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+	ID                 int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username           string     `gorm:"type:varchar(50);not null" json:"username"`                // Nickname, non-unique
+	Email              string     `gorm:"type:varchar(100);uniqueIndex;not null" json:"email"`      // Unique identifier
+	Password           string     `gorm:"type:varchar(255)" json:"-"`                               // Optional, can be empty for OAuth users
+	Status             string     `gorm:"type:varchar(20);not null;default:'active'" json:"status"` // active | banned
+	Role               string     `gorm:"type:varchar(20);not null;default:'visitor'" json:"role"`  // admin | editor | author | visitor
+	TokenVersion       int        `gorm:"type:int;not null;default:1" json:"-"`                     // Token revocation version
+	Avatar             string     `gorm:"type:varchar(500)" json:"avatar,omitempty"`
+	Bio                string     `gorm:"type:text" json:"bio,omitempty"`
+	Provider           string     `gorm:"type:varchar(20);not null;default:'email';uniqueIndex:idx_provider_user" json:"provider"` // email | google | github | apple
+	ProviderID         string     `gorm:"type:varchar(255);uniqueIndex:idx_provider_user" json:"-"`                                // Third-party platform user ID, unique with provider
+	EmailNotifications bool       `gorm:"not null;default:true" json:"emailNotifications"`                                         // Receive reply/new-comment emails
+	EmailVerifiedAt    *time.Time `json:"emailVerifiedAt,omitempty"`                                                               // Set once the user proves ownership of Email
+	TOTPSecret         string     `gorm:"column:totp_secret;type:varchar(128)" json:"-"`                                           // Encrypted base32 TOTP secret; set during enrollment
+	TOTPEnabledAt      *time.Time `gorm:"column:totp_enabled_at" json:"-"`                                                         // Set once enrollment is confirmed
+	TOTPLastStep       int64      `gorm:"column:totp_last_step;not null;default:0" json:"-"`                                       // Last accepted time step, rejects code replay
+	FailedLogins       int        `gorm:"not null;default:0" json:"-"`                                                             // Consecutive failed logins
+	LastFailedLoginAt  *time.Time `json:"-"`                                                                                       // Used to forget old failures
+	LockedUntil        *time.Time `json:"-"`                                                                                       // Login refused until then
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
 }
 
 func (User) TableName() string {
@@ -35,10 +52,13 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 }
 
 type UserResponse struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	Avatar   string `json:"avatar,omitempty"`
+	Username           string `json:"username"`
+	Email              string `json:"email"`
+	Role               string `json:"role"`
+	Avatar             string `json:"avatar,omitempty"`
+	EmailNotifications bool   `json:"emailNotifications"`
+	EmailVerified      bool   `json:"emailVerified"`
+	TwoFactorEnabled   bool   `json:"twoFactorEnabled"`
 }
 
 // AdminUserResponse is returned in admin user listing/status endpoints.
@@ -53,6 +73,27 @@ type AdminUserResponse struct {
 	JoinedAt string `json:"joinedAt,omitempty"`
 }
 
+// UpdateUserRoleRequest changes a user's role from the admin panel.
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// AuthorResponse is the public profile shown on an author page.
+type AuthorResponse struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	Bio       string `json:"bio,omitempty"`
+	Avatar    string `json:"avatar,omitempty"`
+	PostCount int64  `json:"postCount"`
+}
+
+// AuthorPageResponse is an author's profile with a page of their published posts.
+type AuthorPageResponse struct {
+	Author     AuthorResponse `json:"author"`
+	Data       []PostResponse `json:"data"`
+	Pagination Pagination     `json:"pagination"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
@@ -82,7 +123,8 @@ type RefreshTokenResponse struct {
 }
 
 type UpdateProfileRequest struct {
-	Username string `json:"username,omitempty"`
-	Bio      string `json:"bio,omitempty"`
-	Avatar   string `json:"avatar,omitempty"`
+	Username           string `json:"username,omitempty"`
+	Bio                string `json:"bio,omitempty"`
+	Avatar             string `json:"avatar,omitempty"`
+	EmailNotifications *bool  `json:"emailNotifications,omitempty"`
 }
@@ -0,0 +1,145 @@
+package entity
+
+import "time"
+
+// Webhook event types an endpoint can subscribe to. post.published is the
+// domain event of the same name.
+const (
+	WebhookEventPostCreated    = "post.created"
+	WebhookEventPostUpdated    = "post.updated"
+	WebhookEventPostPublished  = EventPostPublished
+	WebhookEventPostDeleted    = "post.deleted"
+	WebhookEventCommentCreated = "comment.created"
+	WebhookEventUserRegistered = "user.registered"
+	WebhookEventPing           = "ping" // Sent by "send test event" only
+)
+
+// WebhookEvents lists the events a webhook can subscribe to, in display order.
+var WebhookEvents = []string{
+	WebhookEventPostCreated,
+	WebhookEventPostUpdated,
+	WebhookEventPostPublished,
+	WebhookEventPostDeleted,
+	WebhookEventCommentCreated,
+	WebhookEventUserRegistered,
+}
+
+// Webhook delivery states.
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySending = "sending" // Claimed by an instance until next_attempt_at; reclaimed if it lapses
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed" // Gave up after the maximum number of attempts
+)
+
+// Webhook is an admin-managed HTTP endpoint that receives signed JSON
+// payloads when content changes.
+type Webhook struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	URL       string    `gorm:"type:varchar(500);not null" json:"url"`
+	Secret    string    `gorm:"type:varchar(255);not null" json:"-"`      // HMAC-SHA256 key for the signature header
+	Events    string    `gorm:"type:varchar(255);not null" json:"events"` // Comma-separated subscribed event types
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// WebhookDelivery is one payload queued for one webhook, and its delivery log.
+type WebhookDelivery struct {
+	ID             int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	WebhookID      int64      `gorm:"not null;index" json:"webhookId"`
+	EventID        string     `gorm:"type:varchar(36);not null;index" json:"eventId"` // Shared by the deliveries of one event
+	Event          string     `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"type:varchar(20);not null;default:'pending';index:idx_webhook_delivery_due" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index:idx_webhook_delivery_due" json:"nextAttemptAt"`
+	ResponseStatus int        `gorm:"not null;default:0" json:"responseStatus"` // HTTP status of the last attempt; 0 if none was received
+	ResponseBody   string     `gorm:"type:varchar(1000)" json:"responseBody,omitempty"`
+	LastError      string     `gorm:"type:varchar(500)" json:"lastError,omitempty"`
+	DurationMs     int64      `gorm:"not null;default:0" json:"durationMs"` // Duration of the last attempt
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime;index" json:"createdAt"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookRequest creates or updates a webhook. On create an empty secret is
+// generated; on update an empty secret keeps the current one.
+type WebhookRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events" binding:"required"`
+	Active *bool    `json:"active"` // Defaults to true on create
+}
+
+type WebhookResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the secret was set or generated
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type PaginatedWebhookDeliveryResponse struct {
+	Data       []WebhookDelivery `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook.
+type WebhookPayload struct {
+	ID        string    `json:"id"` // Event ID, the same for every webhook
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// WebhookPost describes a post in webhook payloads.
+type WebhookPost struct {
+	ID          int64      `json:"id"`
+	Slug        string     `json:"slug"`
+	Title       string     `json:"title"`
+	Excerpt     string     `json:"excerpt"`
+	Status      string     `json:"status"`
+	Author      string     `json:"author"`
+	AuthorID    int64      `json:"authorId"`
+	CategoryID  int64      `json:"categoryId"`
+	PublishAt   time.Time  `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt,omitempty"`
+	URL         string     `json:"url"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// WebhookComment describes a comment in webhook payloads.
+type WebhookComment struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"postId"`
+	PostSlug  string    `json:"postSlug"`
+	PostTitle string    `json:"postTitle"`
+	ParentID  *int64    `json:"parentId,omitempty"`
+	Author    string    `json:"author"`
+	Content   string    `json:"content"`
+	Status    string    `json:"status"` // Always approved; comments are sent once visible
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookUser describes a user in webhook payloads. Email addresses are
+// left out on purpose.
+type WebhookUser struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Provider  string    `json:"provider"` // email | google | github | apple
+	CreatedAt time.Time `json:"createdAt"`
+}
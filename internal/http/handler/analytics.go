@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"blog/config"
 	"blog/internal/entity"
 	"blog/internal/usecase"
+	"blog/pkg/privacy"
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -25,10 +29,16 @@ func (h *AnalyticsHandler) LogVisit(c *gin.Context) {
 		return
 	}
 
+	// Opted-out visitors get the same response, just without a record.
+	if doNotTrack(c) {
+		c.Status(http.StatusOK)
+		return
+	}
+
 	ip := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	if err := h.analyticsUseCase.LogVisit(c.Request.Context(), req, ip, userAgent); err != nil {
+	if err := h.analyticsUseCase.LogVisit(c.Request.Context(), req, ip, userAgent, botReason(c)); err != nil {
 		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
@@ -36,21 +46,91 @@ func (h *AnalyticsHandler) LogVisit(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
-// GetLogs - GET /analytics/logs
+// GetLogs - GET /analytics/logs?startDate=&endDate=&traffic=&limit=
 func (h *AnalyticsHandler) GetLogs(c *gin.Context) {
 	startDate := c.Query("startDate")
 	endDate := c.Query("endDate")
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 
-	logs, err := h.analyticsUseCase.GetLogs(c.Request.Context(), startDate, endDate, limit)
+	logs, err := h.analyticsUseCase.GetLogs(c.Request.Context(), startDate, endDate, c.Query("traffic"), limit)
 	if err != nil {
-		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		h.respondReportError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, logs)
 }
 
+// GetRecorderStats - GET /analytics/recorder
+func (h *AnalyticsHandler) GetRecorderStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.analyticsUseCase.RecorderStats())
+}
+
+// GetTimeseries - GET /analytics/timeseries?startDate=&endDate=&postId=&traffic=
+func (h *AnalyticsHandler) GetTimeseries(c *gin.Context) {
+	var postID int64
+	if v := c.Query("postId"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			JSONError(c, http.StatusBadRequest, "Invalid post ID", err)
+			return
+		}
+		postID = id
+	}
+
+	series, err := h.analyticsUseCase.Timeseries(c.Request.Context(), c.Query("startDate"), c.Query("endDate"), postID, c.Query("traffic"))
+	if err != nil {
+		h.respondReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// GetTopPosts - GET /analytics/top-posts?startDate=&endDate=&traffic=&limit=
+func (h *AnalyticsHandler) GetTopPosts(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, err := h.analyticsUseCase.TopPosts(c.Request.Context(), c.Query("startDate"), c.Query("endDate"), c.Query("traffic"), limit)
+	if err != nil {
+		h.respondReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// GetTopPages - GET /analytics/top-pages?startDate=&endDate=&traffic=&limit=
+func (h *AnalyticsHandler) GetTopPages(c *gin.Context) {
+	h.respondRanking(c, h.analyticsUseCase.TopPages)
+}
+
+// GetTopReferrers - GET /analytics/referrers?startDate=&endDate=&traffic=&limit=
+func (h *AnalyticsHandler) GetTopReferrers(c *gin.Context) {
+	h.respondRanking(c, h.analyticsUseCase.TopReferrers)
+}
+
+// GetTopLocations - GET /analytics/locations?startDate=&endDate=&traffic=&limit=
+func (h *AnalyticsHandler) GetTopLocations(c *gin.Context) {
+	h.respondRanking(c, h.analyticsUseCase.TopLocations)
+}
+
+// Reports count human traffic; traffic=bot or traffic=all selects otherwise.
+func (h *AnalyticsHandler) respondRanking(c *gin.Context, rank func(ctx context.Context, startDate, endDate, traffic string, limit int) ([]entity.AnalyticsRankItem, error)) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, err := rank(c.Request.Context(), c.Query("startDate"), c.Query("endDate"), c.Query("traffic"), limit)
+	if err != nil {
+		h.respondReportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *AnalyticsHandler) respondReportError(c *gin.Context, err error) {
+	if errors.Is(err, usecase.ErrInvalidArgument) {
+		JSONError(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+}
+
 // GetDashboardOverview - GET /analytics/dashboard-overview
 func (h *AnalyticsHandler) GetDashboardOverview(c *gin.Context) {
 	overview, err := h.analyticsUseCase.GetDashboardOverview(c.Request.Context())
@@ -61,3 +141,15 @@ func (h *AnalyticsHandler) GetDashboardOverview(c *gin.Context) {
 
 	c.JSON(http.StatusOK, overview)
 }
+
+// doNotTrack reports whether the request opts out of analytics via DNT or
+// Global Privacy Control and privacy.honor_dnt is on.
+func doNotTrack(c *gin.Context) bool {
+	return config.GetConf().Privacy.HonorDNT && privacy.DoNotTrack(c.Request.Header)
+}
+
+// botReason returns why DetectBots classified the client as a bot, or "" for
+// people and routes without the middleware.
+func botReason(c *gin.Context) string {
+	return c.GetString("bot_reason")
+}
@@ -4,7 +4,11 @@ import (
 	"blog/internal/entity"
 	"blog/internal/usecase"
 	"blog/pkg/log"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,16 +42,27 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		log.Pair("ip", c.ClientIP()),
 	)
 
-	resp, err := h.authUseCase.Login(c.Request.Context(), req)
+	resp, challenge, err := h.authUseCase.Login(c.Request.Context(), req, clientInfo(c))
 	if err != nil {
 		log.Errorw("Login failed",
 			log.Pair("email", req.Email),
 			log.Pair("error", err.Error()),
 			log.Pair("ip", c.ClientIP()),
 		)
+		if respondAccountLocked(c, err) {
+			return
+		}
 		JSONError(c, http.StatusUnauthorized, err.Error(), err)
 		return
 	}
+	if challenge != nil {
+		log.Infow("Login requires second factor",
+			log.Pair("email", req.Email),
+			log.Pair("ip", c.ClientIP()),
+		)
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
 
 	log.Infow("Login success",
 		log.Pair("email", req.Email),
@@ -76,7 +91,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		log.Pair("ip", c.ClientIP()),
 	)
 
-	resp, err := h.authUseCase.Register(c.Request.Context(), req)
+	resp, err := h.authUseCase.Register(c.Request.Context(), req, clientInfo(c))
 	if err != nil {
 		log.Errorw("Register failed",
 			log.Pair("email", req.Email),
@@ -146,7 +161,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		log.Pair("ip", c.ClientIP()),
 	)
 
-	resp, err := h.authUseCase.RefreshToken(c.Request.Context(), req)
+	resp, err := h.authUseCase.RefreshToken(c.Request.Context(), req, clientInfo(c))
 	if err != nil {
 		log.Errorw("RefreshToken failed",
 			log.Pair("error", err.Error()),
@@ -162,3 +177,26 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// clientInfo describes the requesting device for session tracking.
+func clientInfo(c *gin.Context) entity.ClientInfo {
+	return entity.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondAccountLocked answers 429 with Retry-After if err is a login lockout.
+func respondAccountLocked(c *gin.Context, err error) bool {
+	var locked *usecase.AccountLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	retryAfter := int(math.Ceil(time.Until(locked.Until).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	JSONError(c, http.StatusTooManyRequests, err.Error(), err)
+	return true
+}
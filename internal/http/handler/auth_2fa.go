@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"blog/pkg/log"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor - POST /auth/login/2fa
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req entity.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	resp, err := h.authUseCase.LoginTwoFactor(c.Request.Context(), req, clientInfo(c))
+	if err != nil {
+		log.Errorw("Two-factor login failed",
+			log.Pair("error", err.Error()),
+			log.Pair("ip", c.ClientIP()),
+		)
+		if respondAccountLocked(c, err) {
+			return
+		}
+		JSONError(c, http.StatusUnauthorized, err.Error(), err)
+		return
+	}
+
+	log.Infow("Login success",
+		log.Pair("username", resp.User.Username),
+		log.Pair("ip", c.ClientIP()),
+	)
+	c.JSON(http.StatusOK, resp)
+}
+
+// TwoFactorStatus - GET /auth/2fa
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	resp, err := h.authUseCase.TwoFactorStatus(c.Request.Context(), userID)
+	if err != nil {
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetupTwoFactor - POST /auth/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	resp, err := h.authUseCase.SetupTwoFactor(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ConfirmTwoFactor - POST /auth/2fa/confirm
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req entity.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	resp, err := h.authUseCase.ConfirmTwoFactor(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DisableTwoFactor - POST /auth/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req entity.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	if err := h.authUseCase.DisableTwoFactor(c.Request.Context(), userID, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes - POST /auth/2fa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req entity.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	resp, err := h.authUseCase.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ResetTwoFactorAdmin - DELETE /admin/users/:id/2fa
+func (h *AuthHandler) ResetTwoFactorAdmin(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid user id", nil)
+		return
+	}
+	if err := h.authUseCase.ResetTwoFactor(c.Request.Context(), c.GetInt64("user_id"), id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// currentUserID returns the authenticated user's ID, writing the error
+// response itself when there is none.
+func currentUserID(c *gin.Context) (int64, bool) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return 0, false
+	}
+	userID, ok := userIDVal.(int64)
+	if !ok {
+		JSONError(c, http.StatusInternalServerError, "Invalid user ID type", nil)
+		return 0, false
+	}
+	return userID, true
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidTwoFactorCode),
+		errors.Is(err, usecase.ErrInvalidArgument):
+		JSONError(c, http.StatusBadRequest, err.Error(), err)
+	case errors.Is(err, usecase.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, usecase.ErrTwoFactorNotEnabled),
+		errors.Is(err, usecase.ErrTwoFactorNotStarted),
+		errors.Is(err, usecase.ErrTOTPSecretUnreadable):
+		JSONError(c, http.StatusConflict, err.Error(), err)
+	case errors.Is(err, usecase.ErrTwoFactorRequired):
+		JSONError(c, http.StatusForbidden, err.Error(), err)
+	default:
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"blog/config"
+	"blog/internal/usecase"
+	"blog/pkg/log"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oauthFlowCookie     = "oauth_flow"
+	oauthFlowCookiePath = "/api/v1/auth/oauth"
+	oauthFlowCookieTTL  = 600 // seconds, matches the flow token lifetime
+)
+
+// OAuthProviders - GET /auth/oauth/providers
+func (h *AuthHandler) OAuthProviders(c *gin.Context) {
+	providers := h.authUseCase.OAuthProviders()
+	if providers == nil {
+		providers = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+// OAuthStart - GET /auth/oauth/:provider
+// Redirects the browser to the provider's consent page.
+func (h *AuthHandler) OAuthStart(c *gin.Context) {
+	authURL, flowToken, err := h.authUseCase.StartOAuth(c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrOAuthProviderDisabled) {
+			JSONError(c, http.StatusNotFound, "OAuth provider not available", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	// SameSite=Lax so the cookie comes back on the provider's top-level redirect.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthFlowCookie, flowToken, oauthFlowCookieTTL, oauthFlowCookiePath, "", secureCookies(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback - GET /auth/oauth/:provider/callback
+// Completes the login and redirects to the frontend with the tokens in the
+// URL fragment, which is never sent to any server.
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	provider := c.Param("provider")
+	flowToken, _ := c.Cookie(oauthFlowCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthFlowCookie, "", -1, oauthFlowCookiePath, "", secureCookies(c), true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		redirectOAuthResult(c, url.Values{"error": {providerErr}})
+		return
+	}
+
+	resp, challenge, err := h.authUseCase.CompleteOAuth(c.Request.Context(), provider, c.Query("code"), c.Query("state"), flowToken, clientInfo(c))
+	if err != nil {
+		log.Errorw("OAuth login failed",
+			log.Pair("provider", provider),
+			log.Pair("error", err.Error()),
+			log.Pair("ip", c.ClientIP()),
+		)
+		_ = c.Error(err)
+		msg := "oauth_failed"
+		switch {
+		case errors.Is(err, usecase.ErrOAuthInvalidState):
+			msg = "invalid_state"
+		case errors.Is(err, usecase.ErrOAuthEmailUnverified):
+			msg = "email_unverified"
+		case errors.Is(err, usecase.ErrOAuthProviderDisabled):
+			msg = "provider_disabled"
+		}
+		redirectOAuthResult(c, url.Values{"error": {msg}})
+		return
+	}
+
+	if challenge != nil {
+		// The SPA finishes the login on its 2FA screen.
+		redirectOAuthResult(c, url.Values{
+			"challenge_token": {challenge.ChallengeToken},
+			"expires_in":      {strconv.FormatInt(challenge.ExpiresIn, 10)},
+		})
+		return
+	}
+
+	log.Infow("OAuth login success",
+		log.Pair("provider", provider),
+		log.Pair("username", resp.User.Username),
+		log.Pair("ip", c.ClientIP()),
+	)
+	redirectOAuthResult(c, url.Values{
+		"access_token":  {resp.AccessToken},
+		"refresh_token": {resp.RefreshToken},
+		"expires_in":    {strconv.FormatInt(resp.ExpiresIn, 10)},
+	})
+}
+
+func redirectOAuthResult(c *gin.Context, fragment url.Values) {
+	conf := config.GetConf()
+	target := strings.TrimRight(conf.App.SiteURL, "/") + conf.OAuth.FrontendCallback
+	c.Redirect(http.StatusFound, target+"#"+fragment.Encode())
+}
+
+// secureCookies reports whether cookies should carry the Secure flag.
+func secureCookies(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" || config.GetConf().Mode == "release"
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"blog/pkg/log"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword - POST /auth/forgot-password
+// Always answers 202 so the response does not reveal whether the email is registered.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req entity.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	if err := h.authUseCase.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		log.Errorw("Forgot password failed",
+			log.Pair("error", err.Error()),
+			log.Pair("ip", c.ClientIP()),
+		)
+		_ = c.Error(err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword - POST /auth/reset-password
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req entity.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	if err := h.authUseCase.ResetPassword(c.Request.Context(), req); err != nil {
+		if errors.Is(err, usecase.ErrInvalidAuthToken) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmail - POST /auth/verify-email
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req entity.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	if err := h.authUseCase.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		if errors.Is(err, usecase.ErrInvalidAuthToken) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerification - POST /auth/verify-email/resend
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, ok := userIDVal.(int64)
+	if !ok {
+		JSONError(c, http.StatusInternalServerError, "Invalid user ID type", nil)
+		return
+	}
+
+	if err := h.authUseCase.SendVerificationEmail(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, usecase.ErrEmailAlreadyVerified) {
+			JSONError(c, http.StatusConflict, err.Error(), err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"blog/internal/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions - GET /users/sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, ok := userIDVal.(int64)
+	if !ok {
+		JSONError(c, http.StatusInternalServerError, "Invalid user ID type", nil)
+		return
+	}
+
+	sessions, err := h.authUseCase.ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession - DELETE /users/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, ok := userIDVal.(int64)
+	if !ok {
+		JSONError(c, http.StatusInternalServerError, "Invalid user ID type", nil)
+		return
+	}
+
+	if err := h.authUseCase.RevokeSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, usecase.ErrSessionNotFound) {
+			JSONError(c, http.StatusNotFound, "Session not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
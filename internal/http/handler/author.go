@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAuthors - GET /authors (Public API)
+func (h *PostHandler) ListAuthors(c *gin.Context) {
+	authors, err := h.postUseCase.ListAuthors(c.Request.Context())
+	if err != nil {
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, authors)
+}
+
+// GetAuthor - GET /authors/:id?page=&limit= (Public API)
+func (h *PostHandler) GetAuthor(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		JSONError(c, http.StatusBadRequest, "Invalid author id", nil)
+		return
+	}
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	limit = clampLimit(limit, 100)
+
+	result, err := h.postUseCase.GetAuthorPage(c.Request.Context(), id, page, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Author not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
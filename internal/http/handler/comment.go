@@ -4,6 +4,7 @@ import (
 	"blog/internal/entity"
 	"blog/internal/usecase"
 	"blog/pkg/util"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -88,6 +89,10 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 
 	comment, err := h.commentUseCase.Create(c.Request.Context(), post.ID, userID, req)
 	if err != nil {
+		if errors.Is(err, usecase.ErrEmailNotVerified) {
+			JSONError(c, http.StatusForbidden, err.Error(), err)
+			return
+		}
 		JSONError(c, http.StatusBadRequest, err.Error(), err)
 		return
 	}
@@ -105,6 +110,43 @@ func (h *CommentHandler) ListAllCommentsAdmin(c *gin.Context) {
 	c.JSON(http.StatusOK, comments)
 }
 
+// ListCommentQueueAdmin - GET /admin/comments/queue?status=pending&page=1&limit=20
+func (h *CommentHandler) ListCommentQueueAdmin(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	resp, err := h.commentUseCase.ListQueue(c.Request.Context(), c.Query("status"), page, limit)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidArgument) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ModerateCommentsAdmin - PATCH /admin/comments/status
+func (h *CommentHandler) ModerateCommentsAdmin(c *gin.Context) {
+	var req entity.ModerateCommentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	resp, err := h.commentUseCase.Moderate(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidArgument) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // DeleteCommentAdmin - DELETE /admin/comments/:id
 func (h *CommentHandler) DeleteCommentAdmin(c *gin.Context) {
 	idStr := c.Param("id")
@@ -0,0 +1,432 @@
+package handler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"blog/config"
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"blog/pkg/markdown"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	feedTitle       = "Voocel Journal"
+	feedDescription = "Voocel's personal blog exploring technology, design, and life."
+	feedLimit       = 20
+)
+
+// RSS 2.0 structures
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XmlnsAtom string     `xml:"xmlns:atom,attr"`
+	XmlnsCont string     `xml:"xmlns:content,attr"`
+	XmlnsDC   string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator,omitempty"` // RSS <author> must be an email address
+	Categories  []string `xml:"category,omitempty"`
+	Description string   `xml:"description"`
+	Content     cdata    `xml:"content:encoded"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// Atom structures
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category,omitempty"`
+	Summary    string         `xml:"summary"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// feedScope describes which subset of posts a feed covers.
+type feedScope struct {
+	title   string
+	link    string // HTML page the feed belongs to
+	self    string // Feed URL itself
+	filters map[string]any
+}
+
+// feedEntry is the format-independent representation of one post in a feed.
+type feedEntry struct {
+	post entity.Post
+	tags []string
+}
+
+// FeedHandler serves RSS 2.0 and Atom feeds of published posts.
+type FeedHandler struct {
+	postRepo     usecase.PostRepo
+	categoryRepo usecase.CategoryRepo
+	tagRepo      usecase.TagRepo
+}
+
+func NewFeedHandler(postRepo usecase.PostRepo, categoryRepo usecase.CategoryRepo, tagRepo usecase.TagRepo) *FeedHandler {
+	return &FeedHandler{
+		postRepo:     postRepo,
+		categoryRepo: categoryRepo,
+		tagRepo:      tagRepo,
+	}
+}
+
+// RSS handles GET /feed.xml
+func (h *FeedHandler) RSS(c *gin.Context) {
+	siteURL := siteBaseURL()
+	h.serveRSS(c, feedScope{
+		title: feedTitle,
+		link:  siteURL,
+		self:  siteURL + "/feed.xml",
+	})
+}
+
+// Atom handles GET /atom.xml
+func (h *FeedHandler) Atom(c *gin.Context) {
+	siteURL := siteBaseURL()
+	h.serveAtom(c, feedScope{
+		title: feedTitle,
+		link:  siteURL,
+		self:  siteURL + "/atom.xml",
+	})
+}
+
+// CategoryRSS handles GET /category/:slug/feed.xml
+func (h *FeedHandler) CategoryRSS(c *gin.Context) {
+	if scope, ok := h.categoryScope(c, "feed.xml"); ok {
+		h.serveRSS(c, scope)
+	}
+}
+
+// CategoryAtom handles GET /category/:slug/atom.xml
+func (h *FeedHandler) CategoryAtom(c *gin.Context) {
+	if scope, ok := h.categoryScope(c, "atom.xml"); ok {
+		h.serveAtom(c, scope)
+	}
+}
+
+// TagRSS handles GET /tag/:name/feed.xml
+func (h *FeedHandler) TagRSS(c *gin.Context) {
+	if scope, ok := h.tagScope(c, "feed.xml"); ok {
+		h.serveRSS(c, scope)
+	}
+}
+
+// TagAtom handles GET /tag/:name/atom.xml
+func (h *FeedHandler) TagAtom(c *gin.Context) {
+	if scope, ok := h.tagScope(c, "atom.xml"); ok {
+		h.serveAtom(c, scope)
+	}
+}
+
+func (h *FeedHandler) categoryScope(c *gin.Context, file string) (feedScope, bool) {
+	category, err := h.categoryRepo.GetBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		c.String(http.StatusNotFound, "category not found")
+		return feedScope{}, false
+	}
+	siteURL := siteBaseURL()
+	return feedScope{
+		title:   category.Name + " | " + feedTitle,
+		link:    siteURL + "/posts?category=" + fmt.Sprint(category.ID),
+		self:    siteURL + "/category/" + category.Slug + "/" + file,
+		filters: map[string]any{"categoryId": category.ID},
+	}, true
+}
+
+func (h *FeedHandler) tagScope(c *gin.Context, file string) (feedScope, bool) {
+	tag, err := h.tagRepo.GetByName(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.String(http.StatusNotFound, "tag not found")
+		return feedScope{}, false
+	}
+	siteURL := siteBaseURL()
+	return feedScope{
+		title:   "#" + tag.Name + " | " + feedTitle,
+		link:    siteURL + "/posts",
+		self:    siteURL + "/tag/" + url.PathEscape(tag.Name) + "/" + file,
+		filters: map[string]any{"tagId": tag.ID},
+	}, true
+}
+
+// loadEntries returns the latest live posts for the scope together with the
+// most recent modification time, which drives ETag / Last-Modified.
+func (h *FeedHandler) loadEntries(c *gin.Context, scope feedScope) ([]feedEntry, time.Time, error) {
+	ctx := c.Request.Context()
+
+	// Same visibility rule as the public post list: published and publish_at <= now.
+	filters := map[string]any{
+		"status":          "published",
+		"beforePublishAt": time.Now(),
+	}
+	for k, v := range scope.filters {
+		filters[k] = v
+	}
+
+	posts, _, err := h.postRepo.List(ctx, filters, 1, feedLimit)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	postIDs := make([]int64, 0, len(posts))
+	for i := range posts {
+		postIDs = append(postIDs, posts[i].ID)
+	}
+	tagIDsByPostID, err := h.postRepo.GetTagIDsByPostIDs(ctx, postIDs)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	tagIDSet := make(map[int64]struct{})
+	for _, ids := range tagIDsByPostID {
+		for _, id := range ids {
+			tagIDSet[id] = struct{}{}
+		}
+	}
+	tagNameByID := make(map[int64]string, len(tagIDSet))
+	if len(tagIDSet) > 0 {
+		tagIDs := make([]int64, 0, len(tagIDSet))
+		for id := range tagIDSet {
+			tagIDs = append(tagIDs, id)
+		}
+		tags, err := h.tagRepo.GetByIDs(ctx, tagIDs)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for i := range tags {
+			tagNameByID[tags[i].ID] = tags[i].Name
+		}
+	}
+
+	var lastModified time.Time
+	entries := make([]feedEntry, 0, len(posts))
+	for i := range posts {
+		entry := feedEntry{post: posts[i]}
+		for _, id := range tagIDsByPostID[posts[i].ID] {
+			if name := tagNameByID[id]; name != "" {
+				entry.tags = append(entry.tags, name)
+			}
+		}
+		if m := postModifiedAt(&posts[i]); m.After(lastModified) {
+			lastModified = m
+		}
+		entries = append(entries, entry)
+	}
+	return entries, lastModified, nil
+}
+
+func (h *FeedHandler) serveRSS(c *gin.Context, scope feedScope) {
+	entries, lastModified, err := h.loadEntries(c, scope)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to build feed")
+		return
+	}
+	if notModified(c, scope.self, len(entries), lastModified) {
+		return
+	}
+
+	siteURL := siteBaseURL()
+	feed := rssFeed{
+		Version:   "2.0",
+		XmlnsAtom: "http://www.w3.org/2005/Atom",
+		XmlnsCont: "http://purl.org/rss/1.0/modules/content/",
+		XmlnsDC:   "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       scope.title,
+			Link:        scope.link,
+			Description: feedDescription,
+			Language:    "zh-CN",
+			AtomLink:    rssLink{Href: scope.self, Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]rssItem, 0, len(entries)),
+		},
+	}
+	if !lastModified.IsZero() {
+		feed.Channel.LastBuildDate = lastModified.Format(time.RFC1123Z)
+	}
+
+	for _, e := range entries {
+		link := siteURL + "/post/" + e.post.Slug
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       e.post.Title,
+			Link:        link,
+			GUID:        rssGUID{Value: link, IsPermaLink: true},
+			PubDate:     e.post.PublishAt.Format(time.RFC1123Z),
+			Creator:     e.post.Author,
+			Categories:  e.tags,
+			Description: e.post.Excerpt,
+			Content:     cdata{Value: markdown.ToHTML(e.post.Content)},
+		})
+	}
+
+	writeFeed(c, "application/rss+xml; charset=utf-8", feed)
+}
+
+func (h *FeedHandler) serveAtom(c *gin.Context, scope feedScope) {
+	entries, lastModified, err := h.loadEntries(c, scope)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to build feed")
+		return
+	}
+	if notModified(c, scope.self, len(entries), lastModified) {
+		return
+	}
+
+	updated := lastModified
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	siteURL := siteBaseURL()
+	feed := atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		Title:   scope.title,
+		ID:      scope.self,
+		Updated: updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: scope.link, Rel: "alternate", Type: "text/html"},
+			{Href: scope.self, Rel: "self", Type: "application/atom+xml"},
+		},
+		Entries: make([]atomEntry, 0, len(entries)),
+	}
+
+	for _, e := range entries {
+		link := siteURL + "/post/" + e.post.Slug
+		entry := atomEntry{
+			Title:     e.post.Title,
+			ID:        link,
+			Link:      atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published: e.post.PublishAt.Format(time.RFC3339),
+			Updated:   postModifiedAt(&e.post).Format(time.RFC3339),
+			Author:    atomAuthor{Name: e.post.Author},
+			Summary:   e.post.Excerpt,
+			Content:   atomContent{Type: "html", Value: markdown.ToHTML(e.post.Content)},
+		}
+		for _, t := range e.tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: t})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	writeFeed(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+// postModifiedAt returns the time a post last changed from a reader's perspective.
+// A scheduled post becomes visible at PublishAt, which may be later than UpdatedAt.
+func postModifiedAt(post *entity.Post) time.Time {
+	if post.PublishAt.After(post.UpdatedAt) {
+		return post.PublishAt
+	}
+	return post.UpdatedAt
+}
+
+// notModified sets validators and answers conditional requests with 304.
+// The ETag covers the feed identity, entry count and latest modification so that
+// deletions and newly scheduled posts going live also invalidate it.
+func notModified(c *gin.Context, self string, count int, lastModified time.Time) bool {
+	sum := fnv.New32a()
+	_, _ = sum.Write([]byte(self))
+	etag := fmt.Sprintf(`W/"%x-%d-%d"`, sum.Sum32(), count, lastModified.UnixNano())
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=300")
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			if strings.TrimSpace(candidate) == etag || strings.TrimSpace(candidate) == "*" {
+				c.Status(http.StatusNotModified)
+				return true
+			}
+		}
+		return false
+	}
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func writeFeed(c *gin.Context, contentType string, v any) {
+	out, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "failed to build feed")
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), out...))
+}
+
+func siteBaseURL() string {
+	return strings.TrimRight(config.GetConf().App.SiteURL, "/")
+}
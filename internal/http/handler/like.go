@@ -44,7 +44,7 @@ func (h *LikeHandler) Like(c *gin.Context) {
 	ip := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	count, err := h.likeUseCase.Like(c.Request.Context(), slug, ip, userAgent)
+	count, err := h.likeUseCase.Like(c.Request.Context(), slug, ip, userAgent, botReason(c) != "")
 	if err != nil {
 		JSONError(c, http.StatusInternalServerError, "Failed to like", err)
 		return
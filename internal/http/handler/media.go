@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"blog/internal/entity"
 	"blog/internal/usecase"
 	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -74,9 +76,48 @@ func (h *MediaHandler) UploadAvatar(c *gin.Context) {
 	c.JSON(http.StatusCreated, media)
 }
 
-// ListFiles - GET /files
+// ListFiles - GET /files?page=1&limit=50&q=&type=image&folderId=3|root&from=2025-01-01&to=2025-01-31&unused=true
 func (h *MediaHandler) ListFiles(c *gin.Context) {
-	files, err := h.mediaUseCase.List(c.Request.Context())
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	filters := make(map[string]interface{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		filters["query"] = q
+	}
+	if mediaType := c.Query("type"); mediaType != "" {
+		filters["type"] = mediaType
+	}
+	if folder := c.Query("folderId"); folder == "root" {
+		filters["inRoot"] = true
+	} else if folder != "" {
+		folderID, err := strconv.ParseInt(folder, 10, 64)
+		if err != nil {
+			JSONError(c, http.StatusBadRequest, "Invalid folderId", nil)
+			return
+		}
+		filters["folderId"] = folderID
+	}
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			JSONError(c, http.StatusBadRequest, "Invalid "+param+" date, expected YYYY-MM-DD", nil)
+			return
+		}
+		if param == "to" {
+			t = t.AddDate(0, 0, 1) // Inclusive
+		}
+		filters[param] = t
+	}
+	if c.Query("unused") == "true" {
+		filters["unused"] = true
+	}
+
+	files, err := h.mediaUseCase.List(c.Request.Context(), filters, page, limit)
 	if err != nil {
 		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
 		return
@@ -85,6 +126,67 @@ func (h *MediaHandler) ListFiles(c *gin.Context) {
 	c.JSON(http.StatusOK, files)
 }
 
+// UpdateFile - PATCH /files/:id (Name, alt text, caption, folder)
+func (h *MediaHandler) UpdateFile(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid file id", nil)
+		return
+	}
+
+	var req entity.UpdateMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	media, err := h.mediaUseCase.Update(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// GetFileUsage - GET /files/:id/usage (Posts using the file)
+func (h *MediaHandler) GetFileUsage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid file id", nil)
+		return
+	}
+
+	usage, err := h.mediaUseCase.Usage(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetFileURL - GET /files/:id/url (Time-limited URL, for private buckets)
+func (h *MediaHandler) GetFileURL(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid file id", nil)
+		return
+	}
+
+	url, err := h.mediaUseCase.SignedURL(c.Request.Context(), id, buildBaseURL(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "File not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, url)
+}
+
 // DeleteFile - DELETE /files/:id
 func (h *MediaHandler) DeleteFile(c *gin.Context) {
 	idStr := c.Param("id")
@@ -94,14 +196,135 @@ func (h *MediaHandler) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	if err := h.mediaUseCase.Delete(c.Request.Context(), id); err != nil {
+	force := c.Query("force") == "true"
+	if err := h.mediaUseCase.Delete(c.Request.Context(), id, force); err != nil {
+		if errors.Is(err, usecase.ErrMediaInUse) {
+			// Tell the admin where the file is used; ?force=true deletes anyway.
+			usage, usageErr := h.mediaUseCase.Usage(c.Request.Context(), id)
+			if usageErr != nil {
+				JSONError(c, http.StatusInternalServerError, "Internal server error", usageErr)
+				return
+			}
+			c.JSON(http.StatusConflict, entity.MediaInUseResponse{
+				Error: "File is used by posts; delete with force=true to remove it anyway",
+				Usage: usage,
+			})
+			return
+		}
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListFolders - GET /media/folders
+func (h *MediaHandler) ListFolders(c *gin.Context) {
+	folders, err := h.mediaUseCase.ListFolders(c.Request.Context())
+	if err != nil {
 		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
+	c.JSON(http.StatusOK, folders)
+}
+
+// CreateFolder - POST /media/folders
+func (h *MediaHandler) CreateFolder(c *gin.Context) {
+	var req entity.MediaFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	folder, err := h.mediaUseCase.CreateFolder(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, folder)
+}
+
+// RenameFolder - PUT /media/folders/:id
+func (h *MediaHandler) RenameFolder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid folder id", nil)
+		return
+	}
+	var req entity.MediaFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	folder, err := h.mediaUseCase.RenameFolder(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder - DELETE /media/folders/:id (Its files move to the root)
+func (h *MediaHandler) DeleteFolder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid folder id", nil)
+		return
+	}
 
+	if err := h.mediaUseCase.DeleteFolder(c.Request.Context(), id); err != nil {
+		h.handleError(c, err)
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
 
+// GetStorageUsage - GET /media/usage (Bytes by media type against the quota)
+func (h *MediaHandler) GetStorageUsage(c *gin.Context) {
+	usage, err := h.mediaUseCase.StorageUsage(c.Request.Context())
+	if err != nil {
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetGCReport - GET /media/gc (Latest reconciliation report)
+func (h *MediaHandler) GetGCReport(c *gin.Context) {
+	report := h.mediaUseCase.LastGCReport()
+	if report == nil {
+		JSONError(c, http.StatusNotFound, "No reconciliation has run yet", nil)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RunGC - POST /media/gc?action=report|quarantine|delete
+func (h *MediaHandler) RunGC(c *gin.Context) {
+	report, err := h.mediaUseCase.ReconcileStorage(c.Request.Context(), c.DefaultQuery("action", usecase.GCActionReport))
+	if err != nil {
+		if errors.Is(err, usecase.ErrGCRunning) {
+			JSONError(c, http.StatusConflict, err.Error(), err)
+			return
+		}
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *MediaHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidArgument):
+		JSONError(c, http.StatusBadRequest, err.Error(), err)
+	case strings.Contains(err.Error(), "not found"):
+		JSONError(c, http.StatusNotFound, err.Error(), err)
+	default:
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
+
 const (
 	maxAvatarUploadBytes int64 = 2 * 1024 * 1024
 	maxPostUploadBytes   int64 = 50 * 1024 * 1024
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"blog/config"
+	"blog/internal/usecase"
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationUseCase *usecase.NotificationUseCase
+}
+
+func NewNotificationHandler(notificationUseCase *usecase.NotificationUseCase) *NotificationHandler {
+	return &NotificationHandler{notificationUseCase: notificationUseCase}
+}
+
+// unsubscribePage asks for confirmation, so that mail scanners prefetching
+// the link do not unsubscribe the user.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Unsubscribe</title>
+</head>
+<body style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.6;color:#222;max-width:480px;margin:64px auto;padding:0 16px">
+<h1 style="font-size:20px">Stop notification emails?</h1>
+<p>You will no longer receive emails about replies and comments. You can turn them back on in your settings.</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="confirm" value="page">
+<button type="submit" style="padding:10px 18px;background:#222;color:#fff;border:none;border-radius:6px;cursor:pointer">Unsubscribe</button>
+</form>
+</body>
+</html>`))
+
+// UnsubscribePage - GET /notifications/unsubscribe?u=<user id>&sig=<signature>
+// The link in the email body. It only shows a confirmation form; invalid
+// links redirect to the settings page.
+func (h *NotificationHandler) UnsubscribePage(c *gin.Context) {
+	userID, _ := strconv.ParseInt(c.Query("u"), 10, 64)
+	if err := h.notificationUseCase.VerifyUnsubscribe(userID, c.Query("sig")); err != nil {
+		redirectToSettings(c, "invalid")
+		return
+	}
+
+	var page bytes.Buffer
+	if err := unsubscribePage.Execute(&page, gin.H{"Action": c.Request.URL.RequestURI()}); err != nil {
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// Unsubscribe - POST /notifications/unsubscribe?u=<user id>&sig=<signature>
+// Sent by the confirmation page, which is redirected to the settings page,
+// and as the RFC 8058 one-click request by mail clients.
+func (h *NotificationHandler) Unsubscribe(c *gin.Context) {
+	userID, _ := strconv.ParseInt(c.Query("u"), 10, 64)
+	err := h.notificationUseCase.Unsubscribe(c.Request.Context(), userID, c.Query("sig"))
+
+	if c.PostForm("confirm") == "page" {
+		result := "ok"
+		if err != nil {
+			result = "invalid"
+			if !errors.Is(err, usecase.ErrInvalidUnsubscribeLink) {
+				result = "error"
+				_ = c.Error(err)
+			}
+		}
+		redirectToSettings(c, result)
+		return
+	}
+
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidUnsubscribeLink) {
+			JSONError(c, http.StatusBadRequest, "Invalid unsubscribe link", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func redirectToSettings(c *gin.Context, result string) {
+	siteURL := strings.TrimRight(config.GetConf().App.SiteURL, "/")
+	c.Redirect(http.StatusSeeOther, siteURL+"/settings?unsubscribed="+result)
+}
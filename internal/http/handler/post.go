@@ -50,8 +50,27 @@ func (h *PostHandler) ListPublishedPosts(c *gin.Context) {
 	}
 
 	// Log homepage visit (only for first page without filters)
-	if page <= 1 && category == "" && search == "" {
-		go h.postUseCase.LogHomeVisit(c.ClientIP(), c.GetHeader("User-Agent"))
+	if page <= 1 && category == "" && search == "" && !doNotTrack(c) {
+		h.postUseCase.LogHomeVisit(c.ClientIP(), c.GetHeader("User-Agent"), botReason(c))
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SearchPosts - GET /search?q= (Public API)
+func (h *PostHandler) SearchPosts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	limit = clampLimit(limit, 50)
+
+	result, err := h.postUseCase.Search(c.Request.Context(), c.Query("q"), page, limit)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidArgument) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -79,6 +98,12 @@ func (h *PostHandler) ListAllPosts(c *gin.Context) {
 	if search != "" {
 		filters["search"] = search
 	}
+	// Authors only see their own posts; editors may filter by author.
+	if editor, ok := editorFromContext(c); ok && !usecase.HasPermission(editor.Role, usecase.PermPostsEditAny) {
+		filters["authorId"] = editor.ID
+	} else if authorID, err := strconv.ParseInt(c.Query("author"), 10, 64); err == nil && authorID != 0 {
+		filters["authorId"] = authorID
+	}
 
 	result, err := h.postUseCase.List(c.Request.Context(), filters, page, limit)
 	if err != nil {
@@ -99,7 +124,7 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 	ip := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
-	post, err := h.postUseCase.GetBySlugWithAnalytics(c.Request.Context(), slug, ip, userAgent)
+	post, err := h.postUseCase.GetBySlugWithAnalytics(c.Request.Context(), slug, ip, userAgent, !doNotTrack(c), botReason(c))
 	if err != nil {
 		JSONError(c, http.StatusNotFound, "Post not found", err)
 		return
@@ -110,8 +135,10 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		return
 	}
 
-	// Check if publish date has arrived (scheduled publishing)
-	if post.PublishAt.After(time.Now()) {
+	// Check if publish date has arrived (scheduled publishing) and the post
+	// has not expired yet; the scheduler moves expired posts to draft shortly.
+	now := time.Now()
+	if post.PublishAt.After(now) || (post.UnpublishAt != nil && !post.UnpublishAt.After(now)) {
 		JSONError(c, http.StatusNotFound, "Post not found", nil)
 		return
 	}
@@ -119,6 +146,31 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 	c.JSON(http.StatusOK, post)
 }
 
+// GetRelatedPosts - GET /posts/:slug/related?limit= (Public API)
+func (h *PostHandler) GetRelatedPosts(c *gin.Context) {
+	slug := c.Param("slug")
+	if !util.IsValidSlug(slug) {
+		JSONError(c, http.StatusBadRequest, "Invalid post slug", nil)
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 5
+	}
+	limit = clampLimit(limit, 20)
+
+	posts, err := h.postUseCase.Related(c.Request.Context(), slug, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Post not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, posts)
+}
+
 // GetPostAdmin - GET /admin/posts/:id (Admin API)
 func (h *PostHandler) GetPostAdmin(c *gin.Context) {
 	idStr := c.Param("id")
@@ -127,6 +179,9 @@ func (h *PostHandler) GetPostAdmin(c *gin.Context) {
 		JSONError(c, http.StatusBadRequest, "Invalid post id", nil)
 		return
 	}
+	if !h.checkPostAccess(c, id) {
+		return
+	}
 
 	post, err := h.postUseCase.GetByID(c.Request.Context(), id)
 	if err != nil {
@@ -139,8 +194,8 @@ func (h *PostHandler) GetPostAdmin(c *gin.Context) {
 
 // CreatePost - POST /posts
 func (h *PostHandler) CreatePost(c *gin.Context) {
-	username, exists := c.Get("username")
-	if !exists {
+	editor, ok := editorFromContext(c)
+	if !ok {
 		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
 		return
 	}
@@ -151,11 +206,15 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.postUseCase.Create(c.Request.Context(), req, username.(string)); err != nil {
+	if err := h.postUseCase.Create(c.Request.Context(), req, editor); err != nil {
 		if errors.Is(err, usecase.ErrInvalidArgument) {
 			JSONError(c, http.StatusBadRequest, err.Error(), err)
 			return
 		}
+		if errors.Is(err, usecase.ErrForbidden) {
+			JSONError(c, http.StatusForbidden, err.Error(), err)
+			return
+		}
 		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
@@ -172,17 +231,31 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
+	editor, ok := editorFromContext(c)
+	if !ok {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
 	var req entity.UpdatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		JSONError(c, http.StatusBadRequest, "Invalid request", err)
 		return
 	}
 
-	if err := h.postUseCase.Update(c.Request.Context(), id, req); err != nil {
+	if err := h.postUseCase.Update(c.Request.Context(), id, req, editor); err != nil {
 		if errors.Is(err, usecase.ErrInvalidArgument) {
 			JSONError(c, http.StatusBadRequest, err.Error(), err)
 			return
 		}
+		if errors.Is(err, usecase.ErrForbidden) {
+			JSONError(c, http.StatusForbidden, err.Error(), err)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Post not found", err)
+			return
+		}
 		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
@@ -204,7 +277,21 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.postUseCase.Delete(c.Request.Context(), id); err != nil {
+	editor, ok := editorFromContext(c)
+	if !ok {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	if err := h.postUseCase.Delete(c.Request.Context(), id, editor); err != nil {
+		if errors.Is(err, usecase.ErrForbidden) {
+			JSONError(c, http.StatusForbidden, err.Error(), err)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Post not found", err)
+			return
+		}
 		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
 		return
 	}
@@ -212,6 +299,43 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// editorFromContext returns the authenticated user set by JWTAuth.
+func editorFromContext(c *gin.Context) (entity.Editor, bool) {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return entity.Editor{}, false
+	}
+	id, ok := userID.(int64)
+	if !ok {
+		return entity.Editor{}, false
+	}
+	username, _ := c.Get("username")
+	name, _ := username.(string)
+	return entity.Editor{ID: id, Username: name, Role: c.GetString("role")}, true
+}
+
+// checkPostAccess writes an error response and returns false unless the
+// signed-in user may see the post in the admin panel.
+func (h *PostHandler) checkPostAccess(c *gin.Context, id int64) bool {
+	editor, ok := editorFromContext(c)
+	if !ok {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return false
+	}
+	if err := h.postUseCase.CheckPostAccess(c.Request.Context(), id, editor); err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrForbidden):
+			JSONError(c, http.StatusForbidden, err.Error(), err)
+		case strings.Contains(err.Error(), "not found"):
+			JSONError(c, http.StatusNotFound, "Post not found", err)
+		default:
+			JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		}
+		return false
+	}
+	return true
+}
+
 func clampLimit(limit int, max int) int {
 	if limit > max {
 		return max
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"blog/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPostRevisions - GET /admin/posts/:id/revisions
+func (h *PostHandler) ListPostRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid post id", nil)
+		return
+	}
+	if !h.checkPostAccess(c, id) {
+		return
+	}
+
+	revisions, err := h.postUseCase.ListRevisions(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Post not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetPostRevision - GET /admin/posts/:id/revisions/:rev
+func (h *PostHandler) GetPostRevision(c *gin.Context) {
+	id, rev, ok := parseRevisionParams(c)
+	if !ok || !h.checkPostAccess(c, id) {
+		return
+	}
+
+	revision, err := h.postUseCase.GetRevision(c.Request.Context(), id, rev)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Revision not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffPostRevisions - GET /admin/posts/:id/revisions/diff?from=1&to=2
+func (h *PostHandler) DiffPostRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid post id", nil)
+		return
+	}
+	from, err1 := strconv.Atoi(c.Query("from"))
+	to, err2 := strconv.Atoi(c.Query("to"))
+	if err1 != nil || err2 != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid revision numbers", nil)
+		return
+	}
+	if !h.checkPostAccess(c, id) {
+		return
+	}
+
+	result, err := h.postUseCase.DiffRevisions(c.Request.Context(), id, from, to)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidArgument) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Revision not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RestorePostRevision - POST /admin/posts/:id/revisions/:rev/restore
+// (?status=true also restores the revision's status and publish time)
+func (h *PostHandler) RestorePostRevision(c *gin.Context) {
+	id, rev, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+	editor, ok := editorFromContext(c)
+	if !ok {
+		JSONError(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	withStatus := c.Query("status") == "true"
+	if err := h.postUseCase.RestoreRevision(c.Request.Context(), id, rev, withStatus, editor); err != nil {
+		if errors.Is(err, usecase.ErrInvalidArgument) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if errors.Is(err, usecase.ErrForbidden) {
+			JSONError(c, http.StatusForbidden, err.Error(), err)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "Revision not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+
+	post, err := h.postUseCase.GetByID(c.Request.Context(), id)
+	if err != nil {
+		JSONError(c, http.StatusNotFound, "Post not found", err)
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+func parseRevisionParams(c *gin.Context) (int64, int, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid post id", nil)
+		return 0, 0, false
+	}
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev <= 0 {
+		JSONError(c, http.StatusBadRequest, "Invalid revision number", nil)
+		return 0, 0, false
+	}
+	return id, rev, true
+}
@@ -183,6 +183,8 @@ func (h *SEOHandler) servePage(c *gin.Context, meta pageMeta) {
 		inject.WriteString(`<meta property="og:url" content="` + html.EscapeString(meta.URL) + `"/>` + "\n")
 		inject.WriteString(`<link rel="canonical" href="` + html.EscapeString(meta.URL) + `"/>` + "\n")
 	}
+	inject.WriteString(`<link rel="alternate" type="application/rss+xml" title="Voocel Journal" href="` + html.EscapeString(h.siteURL) + `/feed.xml"/>` + "\n")
+	inject.WriteString(`<link rel="alternate" type="application/atom+xml" title="Voocel Journal" href="` + html.EscapeString(h.siteURL) + `/atom.xml"/>` + "\n")
 	inject.WriteString(`<meta name="twitter:card" content="summary_large_image"/>` + "\n")
 	inject.WriteString(`<meta name="twitter:title" content="` + html.EscapeString(meta.Title) + `"/>` + "\n")
 	inject.WriteString(`<meta name="twitter:description" content="` + html.EscapeString(meta.Description) + `"/>` + "\n")
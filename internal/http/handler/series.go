@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"blog/pkg/util"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SeriesHandler struct {
+	seriesUseCase *usecase.SeriesUseCase
+}
+
+func NewSeriesHandler(seriesUseCase *usecase.SeriesUseCase) *SeriesHandler {
+	return &SeriesHandler{seriesUseCase: seriesUseCase}
+}
+
+// GetSeries - GET /series/:slug (Public API)
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	slug := c.Param("slug")
+	if !util.IsValidSlug(slug) {
+		JSONError(c, http.StatusBadRequest, "Invalid series slug", nil)
+		return
+	}
+
+	series, err := h.seriesUseCase.GetBySlug(c.Request.Context(), slug)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// ListSeriesAdmin - GET /admin/series
+func (h *SeriesHandler) ListSeriesAdmin(c *gin.Context) {
+	series, err := h.seriesUseCase.List(c.Request.Context())
+	if err != nil {
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// GetSeriesAdmin - GET /admin/series/:id
+func (h *SeriesHandler) GetSeriesAdmin(c *gin.Context) {
+	id, ok := parseSeriesID(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.seriesUseCase.Get(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// CreateSeries - POST /admin/series
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var req entity.SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	series, err := h.seriesUseCase.Create(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, series)
+}
+
+// UpdateSeries - PUT /admin/series/:id
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	id, ok := parseSeriesID(c)
+	if !ok {
+		return
+	}
+	var req entity.SeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	series, err := h.seriesUseCase.Update(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// DeleteSeries - DELETE /admin/series/:id
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	id, ok := parseSeriesID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.seriesUseCase.Delete(c.Request.Context(), id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseSeriesID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid series id", nil)
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *SeriesHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidArgument):
+		JSONError(c, http.StatusBadRequest, err.Error(), err)
+	case strings.Contains(err.Error(), "not found"):
+		JSONError(c, http.StatusNotFound, "Series not found", err)
+	default:
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+	}
+}
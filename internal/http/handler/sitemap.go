@@ -26,11 +26,12 @@ type sitemapURL struct {
 }
 
 type SitemapHandler struct {
-	postRepo usecase.PostRepo
+	postRepo   usecase.PostRepo
+	seriesRepo usecase.SeriesRepo
 }
 
-func NewSitemapHandler(postRepo usecase.PostRepo) *SitemapHandler {
-	return &SitemapHandler{postRepo: postRepo}
+func NewSitemapHandler(postRepo usecase.PostRepo, seriesRepo usecase.SeriesRepo) *SitemapHandler {
+	return &SitemapHandler{postRepo: postRepo, seriesRepo: seriesRepo}
 }
 
 func (h *SitemapHandler) GenerateSitemap(c *gin.Context) {
@@ -74,6 +75,19 @@ func (h *SitemapHandler) GenerateSitemap(c *gin.Context) {
 		}
 	}
 
+	// Series with at least one live post
+	series, err := h.seriesRepo.ListLive(ctx, time.Now())
+	if err == nil {
+		for _, s := range series {
+			sitemap.URLs = append(sitemap.URLs, sitemapURL{
+				Loc:        siteURL + "/series/" + s.Slug,
+				Lastmod:    s.UpdatedAt.Format(time.RFC3339),
+				Changefreq: "weekly",
+				Priority:   0.7,
+			})
+		}
+	}
+
 	c.Header("Content-Type", "application/xml; charset=utf-8")
 	c.XML(http.StatusOK, sitemap)
 }
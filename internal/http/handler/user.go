@@ -3,6 +3,7 @@ package handler
 import (
 	"blog/internal/entity"
 	"blog/internal/usecase"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -84,3 +85,32 @@ func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, updated)
 }
+
+// UpdateUserRole - PATCH /admin/users/:id/role
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid user id", nil)
+		return
+	}
+	var req entity.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+	actorID := c.GetInt64("user_id")
+	updated, err := h.userUseCase.UpdateRole(c.Request.Context(), actorID, id, strings.ToLower(strings.TrimSpace(req.Role)))
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidArgument) {
+			JSONError(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			JSONError(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		JSONError(c, http.StatusInternalServerError, "Internal server error", err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
@@ -1,17 +1,31 @@
 package middleware
 
 import (
+	"blog/internal/usecase"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AdminOnly() gin.HandlerFunc {
+// RequirePermission lets the request through only when the role set by
+// JWTAuth grants perm (see usecase.HasPermission for the matrix).
+func RequirePermission(perm usecase.Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
+		role := c.GetString("role")
+		if !usecase.HasPermission(role, perm) {
 			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Admin access required",
+				"error":      "Permission denied",
+				"permission": perm,
+			})
+			c.Abort()
+			return
+		}
+		// When the policy requires 2FA, admins can still sign in and enroll
+		// under /auth/2fa, but admin endpoints stay closed until they do.
+		if usecase.TwoFactorRequired(role) && !c.GetBool("two_factor") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Two-factor authentication required",
+				"code":  "two_factor_required",
 			})
 			c.Abort()
 			return
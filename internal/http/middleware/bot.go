@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"blog/config"
+	"blog/pkg/botdetect"
+	"blog/pkg/log"
+	"blog/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DetectBots classifies the client of routes that count views, visits or
+// likes and stores the reason under "bot_reason" ("" for people). It never
+// rejects a request: bots are still served, just not counted. Clients that
+// pass the user agent and header checks count as bots while they exceed
+// analytics.bots.requests per period.
+func DetectBots(store ratelimit.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := config.GetConf().Analytics.Bots
+		if !conf.Enabled {
+			c.Next()
+			return
+		}
+
+		reason := botdetect.Classify(c.Request.UserAgent(), c.Request.Header)
+		limit := ratelimit.Limit{Requests: conf.Requests, Period: conf.Period}
+		if reason == botdetect.Human && limit.Valid() {
+			res, err := store.Take(c.Request.Context(), "bots:ip:"+c.ClientIP(), limit)
+			if err != nil {
+				log.Warnw("Bot rate store failed", log.Pair("error", err.Error()))
+			} else if !res.Allowed {
+				reason = botdetect.Rate
+			}
+		}
+
+		c.Set("bot_reason", string(reason))
+		c.Next()
+	}
+}
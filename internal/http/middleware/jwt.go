@@ -10,7 +10,7 @@ import (
 )
 
 // JWTAuth validates JWT access tokens
-func JWTAuth(userRepo usecase.UserRepo) gin.HandlerFunc {
+func JWTAuth(userRepo usecase.UserRepo, sessionRepo usecase.SessionRepo) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -70,10 +70,27 @@ func JWTAuth(userRepo usecase.UserRepo) gin.HandlerFunc {
 			return
 		}
 
+		// Signing out a session must also cut off its outstanding access tokens.
+		if claims.SessionID != "" {
+			revoked, err := sessionRepo.IsRevoked(c.Request.Context(), claims.SessionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token revoked"})
+				c.Abort()
+				return
+			}
+		}
+
 		// Use DB values so role/status changes take effect immediately.
 		c.Set("user_id", user.ID)
 		c.Set("username", user.Username)
 		c.Set("role", user.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("two_factor", user.TOTPEnabledAt != nil)
 
 		c.Next()
 	}
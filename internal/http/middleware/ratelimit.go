@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"blog/pkg/log"
+	"blog/pkg/ratelimit"
+	"blog/pkg/util"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tripEventLimit bounds security events to one per client and group per
+// minute, so a flood of rejected requests does not flood the event table.
+var tripEventLimit = ratelimit.Limit{Requests: 1, Period: time.Minute, Burst: 1}
+
+// RateLimit throttles a route group with the token bucket configured under
+// rate_limit.groups.<group>. Limits are read per request, so config reloads
+// apply immediately; a group without a valid config is not limited.
+// Place it after JWTAuth to key by user.
+func RateLimit(store ratelimit.Store, eventRepo usecase.SystemEventRepo, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := config.GetConf().RateLimit
+		groupConf, ok := conf.Groups[group]
+		limit := ratelimit.Limit{Requests: groupConf.Requests, Period: groupConf.Period, Burst: groupConf.Burst}
+		if !conf.Enabled || !ok || !limit.Valid() {
+			c.Next()
+			return
+		}
+
+		key := group + ":" + rateLimitKey(c, groupConf.Key)
+		res, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			// Fail open: an unavailable store must not take the site down.
+			log.Warnw("Rate limit store failed", log.Pair("group", group), log.Pair("error", err.Error()))
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, limit, res)
+		if res.Allowed {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please slow down"})
+		c.Abort()
+
+		if first, err := store.Take(c.Request.Context(), "tripped:"+key, tripEventLimit); err == nil && first.Allowed {
+			recordRateLimitEvent(c, eventRepo, group, key)
+		}
+	}
+}
+
+// rateLimitKey identifies the client: "ip" always uses the client IP; "user"
+// (the default) uses the user when authenticated and the IP otherwise.
+func rateLimitKey(c *gin.Context, keyBy string) string {
+	switch keyBy {
+	case "ip":
+		return "ip:" + c.ClientIP()
+	default:
+		if userID, ok := c.Get("user_id"); ok {
+			return fmt.Sprintf("user:%v", userID)
+		}
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// setRateLimitHeaders writes the IETF RateLimit header fields
+// (draft-ietf-httpapi-ratelimit-headers).
+func setRateLimitHeaders(c *gin.Context, limit ratelimit.Limit, res ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, ceilSeconds(limit.Period), res.Limit))
+}
+
+func recordRateLimitEvent(c *gin.Context, eventRepo usecase.SystemEventRepo, group, key string) {
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	requestID, _ := c.Get("request_id")
+
+	log.Warnw("Rate limit exceeded",
+		log.Pair("group", group),
+		log.Pair("key", key),
+		log.Pair("path", c.Request.URL.Path),
+	)
+	event := &entity.SystemEvent{
+		RequestID:     getStringValue(requestID),
+		EventType:     entity.EventTypeSecurity,
+		EventCategory: entity.CategorySecurityAttempt,
+		Severity:      entity.SeverityWarning,
+		UserID:        getInt64Value(userID),
+		Username:      getStringValue(username),
+		Action:        "RATE_LIMITED",
+		Resource:      group,
+		Method:        c.Request.Method,
+		Path:          c.Request.URL.Path,
+		IP:            c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		Status:        http.StatusTooManyRequests,
+		Message:       fmt.Sprintf("Rate limit %q exceeded by %s", group, key),
+	}
+
+	util.SafeGo(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := eventRepo.Create(ctx, event); err != nil {
+			log.Errorw("Record security event failed", log.Pair("error", err.Error()))
+		}
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -2,12 +2,20 @@ package router
 
 import (
 	"path/filepath"
+	"time"
 
 	"blog/config"
+	"blog/internal/entity"
 	"blog/internal/http/handler"
 	"blog/internal/usecase"
 	"blog/internal/usecase/repo"
+	"blog/pkg/cache"
+	"blog/pkg/log"
+	"blog/pkg/mailer"
+	"blog/pkg/ratelimit"
+	"blog/pkg/storage"
 
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
@@ -15,41 +23,67 @@ import (
 // This pattern provides centralized dependency management and makes testing easier
 type Container struct {
 	// Repositories
-	UserRepo        usecase.UserRepo
-	PostRepo        usecase.PostRepo
-	CategoryRepo    usecase.CategoryRepo
-	TagRepo         usecase.TagRepo
-	MediaRepo       usecase.MediaRepo
-	AnalyticsRepo   usecase.AnalyticsRepo
-	SystemEventRepo usecase.SystemEventRepo
-	CommentRepo     usecase.CommentRepo
-	LikeRepo        usecase.LikeRepo
+	UserRepo         usecase.UserRepo
+	PostRepo         usecase.PostRepo
+	RevisionRepo     usecase.PostRevisionRepo
+	SeriesRepo       usecase.SeriesRepo
+	PostRelatedRepo  usecase.PostRelatedRepo
+	CategoryRepo     usecase.CategoryRepo
+	TagRepo          usecase.TagRepo
+	MediaRepo        usecase.MediaRepo
+	MediaFolderRepo  usecase.MediaFolderRepo
+	AnalyticsRepo    usecase.AnalyticsRepo
+	SystemEventRepo  usecase.SystemEventRepo
+	CommentRepo      usecase.CommentRepo
+	LikeRepo         usecase.LikeRepo
+	SpamRepo         usecase.SpamRepo
+	MailOutboxRepo   usecase.MailOutboxRepo
+	DomainEventRepo  usecase.DomainEventRepo
+	WebhookRepo      usecase.WebhookRepo
+	AuthTokenRepo    usecase.AuthTokenRepo
+	SessionRepo      usecase.SessionRepo
+	RecoveryCodeRepo usecase.RecoveryCodeRepo
+
+	// Infrastructure
+	RateLimitStore ratelimit.Store
+	Cache          cache.Cache
 
 	// UseCases
-	AuthUseCase        *usecase.AuthUseCase
-	UserUseCase        *usecase.UserUseCase
-	PostUseCase        *usecase.PostUseCase
-	CategoryUseCase    *usecase.CategoryUseCase
-	TagUseCase         *usecase.TagUseCase
-	MediaUseCase       *usecase.MediaUseCase
-	AnalyticsUseCase   *usecase.AnalyticsUseCase
-	SystemEventUseCase *usecase.SystemEventUseCase
-	CommentUseCase     *usecase.CommentUseCase
-	LikeUseCase        *usecase.LikeUseCase
+	ViewRecorder        *usecase.ViewRecorder
+	RelatedPosts        *usecase.RelatedPosts
+	AuthUseCase         *usecase.AuthUseCase
+	UserUseCase         *usecase.UserUseCase
+	PostUseCase         *usecase.PostUseCase
+	SeriesUseCase       *usecase.SeriesUseCase
+	CategoryUseCase     *usecase.CategoryUseCase
+	TagUseCase          *usecase.TagUseCase
+	MediaUseCase        *usecase.MediaUseCase
+	AnalyticsUseCase    *usecase.AnalyticsUseCase
+	RetentionUseCase    *usecase.RetentionUseCase
+	SystemEventUseCase  *usecase.SystemEventUseCase
+	CommentUseCase      *usecase.CommentUseCase
+	LikeUseCase         *usecase.LikeUseCase
+	NotificationUseCase *usecase.NotificationUseCase
+	Scheduler           *usecase.Scheduler
+	WebhookUseCase      *usecase.WebhookUseCase
 
 	// Handlers
-	AuthHandler        *handler.AuthHandler
-	UserHandler        *handler.UserHandler
-	PostHandler        *handler.PostHandler
-	CategoryHandler    *handler.CategoryHandler
-	TagHandler         *handler.TagHandler
-	MediaHandler       *handler.MediaHandler
-	AnalyticsHandler   *handler.AnalyticsHandler
-	SystemEventHandler *handler.SystemEventHandler
-	CommentHandler     *handler.CommentHandler
-	LikeHandler        *handler.LikeHandler
-	SitemapHandler     *handler.SitemapHandler
-	SEOHandler         *handler.SEOHandler
+	AuthHandler         *handler.AuthHandler
+	UserHandler         *handler.UserHandler
+	PostHandler         *handler.PostHandler
+	SeriesHandler       *handler.SeriesHandler
+	CategoryHandler     *handler.CategoryHandler
+	TagHandler          *handler.TagHandler
+	MediaHandler        *handler.MediaHandler
+	AnalyticsHandler    *handler.AnalyticsHandler
+	SystemEventHandler  *handler.SystemEventHandler
+	CommentHandler      *handler.CommentHandler
+	LikeHandler         *handler.LikeHandler
+	NotificationHandler *handler.NotificationHandler
+	WebhookHandler      *handler.WebhookHandler
+	SitemapHandler      *handler.SitemapHandler
+	FeedHandler         *handler.FeedHandler
+	SEOHandler          *handler.SEOHandler
 }
 
 // NewContainer creates and initializes all application dependencies
@@ -60,30 +94,67 @@ func NewContainer(db *gorm.DB) *Container {
 	// Initialize Repositories
 	c.UserRepo = repo.NewUserRepo(db)
 	c.PostRepo = repo.NewPostRepo(db)
+	c.RevisionRepo = repo.NewPostRevisionRepo(db)
+	c.SeriesRepo = repo.NewSeriesRepo(db)
+	c.PostRelatedRepo = repo.NewPostRelatedRepo(db)
 	c.CategoryRepo = repo.NewCategoryRepo(db)
 	c.TagRepo = repo.NewTagRepo(db)
 	c.MediaRepo = repo.NewMediaRepo(db)
+	c.MediaFolderRepo = repo.NewMediaFolderRepo(db)
 	c.AnalyticsRepo = repo.NewAnalyticsRepo(db)
-	c.SystemEventRepo = repo.NewSystemEventRepo(db)
+	c.SystemEventRepo = usecase.WithAnonymizedIPs(repo.NewSystemEventRepo(db))
 	c.CommentRepo = repo.NewCommentRepo(db)
 	c.LikeRepo = repo.NewLikeRepo(db)
+	c.SpamRepo = repo.NewSpamRepo(db)
+	c.MailOutboxRepo = repo.NewMailOutboxRepo(db)
+	c.DomainEventRepo = repo.NewDomainEventRepo(db)
+	c.WebhookRepo = repo.NewWebhookRepo(db)
+	c.AuthTokenRepo = repo.NewAuthTokenRepo(db)
+	c.SessionRepo = repo.NewSessionRepo(db)
+	c.RecoveryCodeRepo = repo.NewRecoveryCodeRepo(db)
+
+	c.RateLimitStore = newRateLimitStore()
+	c.Cache = newCache()
 
 	// Initialize UseCases
-	c.AuthUseCase = usecase.NewAuthUseCase(c.UserRepo)
+	c.ViewRecorder = usecase.NewViewRecorder(c.PostRepo, c.AnalyticsRepo)
+	c.RelatedPosts = usecase.NewRelatedPosts(c.PostRepo, c.PostRelatedRepo, c.Cache)
+	c.NotificationUseCase = usecase.NewNotificationUseCase(c.UserRepo, c.PostRepo, c.CommentRepo, c.MailOutboxRepo, newMailer())
+	c.WebhookUseCase = usecase.NewWebhookUseCase(c.WebhookRepo)
+	c.AuthUseCase = usecase.NewAuthUseCase(c.UserRepo, c.AuthTokenRepo, c.SessionRepo, c.RecoveryCodeRepo, c.SystemEventRepo, c.NotificationUseCase, c.WebhookUseCase)
 	c.UserUseCase = usecase.NewUserUseCase(c.UserRepo)
-	c.PostUseCase = usecase.NewPostUseCase(c.PostRepo, c.CategoryRepo, c.TagRepo, c.AnalyticsRepo)
-	c.CategoryUseCase = usecase.NewCategoryUseCase(c.CategoryRepo)
-	c.TagUseCase = usecase.NewTagUseCase(c.TagRepo)
-	c.MediaUseCase = usecase.NewMediaUseCase(c.MediaRepo)
-	c.AnalyticsUseCase = usecase.NewAnalyticsUseCase(c.AnalyticsRepo, c.PostRepo, c.CategoryRepo, c.TagRepo, c.MediaRepo)
+	c.PostUseCase = usecase.NewPostUseCase(c.PostRepo, c.CategoryRepo, c.TagRepo, c.RevisionRepo, c.UserRepo, c.SeriesRepo, c.ViewRecorder, c.RelatedPosts, c.WebhookUseCase, c.Cache)
+	c.SeriesUseCase = usecase.NewSeriesUseCase(c.SeriesRepo, c.PostRepo, c.Cache)
+	c.CategoryUseCase = usecase.NewCategoryUseCase(c.CategoryRepo, c.Cache)
+	c.TagUseCase = usecase.NewTagUseCase(c.TagRepo, c.Cache)
+	stores, driver := NewStorages()
+	c.MediaUseCase = usecase.NewMediaUseCase(c.MediaRepo, c.MediaFolderRepo, stores, driver)
+	c.AnalyticsUseCase = usecase.NewAnalyticsUseCase(c.AnalyticsRepo, c.PostRepo, c.CategoryRepo, c.TagRepo, c.MediaRepo, c.MediaUseCase, c.ViewRecorder)
+	c.RetentionUseCase = usecase.NewRetentionUseCase(c.AnalyticsRepo, c.LikeRepo, c.SystemEventRepo)
 	c.SystemEventUseCase = usecase.NewSystemEventUseCase(c.SystemEventRepo)
-	c.CommentUseCase = usecase.NewCommentUseCase(c.CommentRepo, c.PostRepo, c.UserRepo)
+	commentConf := config.GetConf().Comment
+	bayes := usecase.NewBayesSpamClassifier(c.SpamRepo)
+	spamFilter := usecase.NewSpamFilter(
+		usecase.NewLinkSpamChecker(commentConf.SpamMaxLinks),
+		usecase.NewBlocklistSpamChecker(commentConf.SpamBlocklist),
+		usecase.NewDuplicateSpamChecker(c.CommentRepo, 24*time.Hour),
+		bayes,
+	)
+	c.CommentUseCase = usecase.NewCommentUseCase(c.CommentRepo, c.PostRepo, c.UserRepo, spamFilter, bayes, c.NotificationUseCase, c.WebhookUseCase)
 	c.LikeUseCase = usecase.NewLikeUseCase(c.LikeRepo)
+	c.Scheduler = usecase.NewScheduler(c.PostRepo, c.DomainEventRepo)
+	for _, event := range []string{entity.EventPostPublished, entity.EventPostUnpublished} {
+		c.Scheduler.On(event, "cache", c.PostUseCase.HandleLifecycleEvent)
+		c.Scheduler.On(event, "sitemap_ping", usecase.PingSitemaps)
+	}
+	c.Scheduler.On(entity.EventPostPublished, "notify_author", c.NotificationUseCase.PostPublished)
+	c.Scheduler.On(entity.EventPostPublished, "webhooks", c.WebhookUseCase.PostPublished)
 
 	// Initialize Handlers
 	c.AuthHandler = handler.NewAuthHandler(c.AuthUseCase, c.UserUseCase)
 	c.UserHandler = handler.NewUserHandler(c.UserUseCase)
 	c.PostHandler = handler.NewPostHandler(c.PostUseCase)
+	c.SeriesHandler = handler.NewSeriesHandler(c.SeriesUseCase)
 	c.CategoryHandler = handler.NewCategoryHandler(c.CategoryUseCase)
 	c.TagHandler = handler.NewTagHandler(c.TagUseCase)
 	c.MediaHandler = handler.NewMediaHandler(c.MediaUseCase)
@@ -91,7 +162,10 @@ func NewContainer(db *gorm.DB) *Container {
 	c.SystemEventHandler = handler.NewSystemEventHandler(c.SystemEventUseCase)
 	c.CommentHandler = handler.NewCommentHandler(c.CommentUseCase, c.PostUseCase)
 	c.LikeHandler = handler.NewLikeHandler(c.LikeUseCase)
-	c.SitemapHandler = handler.NewSitemapHandler(c.PostRepo)
+	c.NotificationHandler = handler.NewNotificationHandler(c.NotificationUseCase)
+	c.WebhookHandler = handler.NewWebhookHandler(c.WebhookUseCase)
+	c.SitemapHandler = handler.NewSitemapHandler(c.PostRepo, c.SeriesRepo)
+	c.FeedHandler = handler.NewFeedHandler(c.PostRepo, c.CategoryRepo, c.TagRepo)
 	c.SEOHandler = handler.NewSEOHandler(
 		c.PostUseCase,
 		filepath.Join(config.GetConf().App.FrontendDistPath, "index.html"),
@@ -99,3 +173,93 @@ func NewContainer(db *gorm.DB) *Container {
 
 	return c
 }
+
+// newMailer builds the configured mailer, falling back to logging mail when
+// the configuration is invalid so the app still starts.
+func newMailer() mailer.Mailer {
+	conf := config.GetConf().Mail
+	m, err := mailer.New(mailer.Config{
+		Driver:   conf.Driver,
+		Host:     conf.Host,
+		Port:     conf.Port,
+		Username: conf.Username,
+		Password: conf.Password,
+		From:     conf.From,
+		Dir:      conf.Dir,
+	})
+	if err != nil {
+		log.Errorw("Invalid mail configuration, falling back to log mailer", log.Pair("error", err.Error()))
+		return mailer.NewFileMailer("", conf.From)
+	}
+	return m
+}
+
+// NewStorages builds the local store and, when a bucket is configured, the S3
+// store, keyed by driver name, and returns which of them takes new uploads.
+// Media keep being served from the store they were uploaded to. An invalid
+// S3 configuration falls back to local storage so the app still starts.
+func NewStorages() (map[string]storage.Storage, string) {
+	conf := config.GetConf().Storage
+	stores := map[string]storage.Storage{
+		"local": storage.NewLocal(storage.LocalConfig{Dir: conf.Local.Dir, BaseURL: conf.Local.BaseURL}),
+	}
+	if conf.Driver == "s3" || conf.S3.Bucket != "" {
+		s3, err := storage.NewS3(storage.S3Config{
+			Endpoint:  conf.S3.Endpoint,
+			Region:    conf.S3.Region,
+			Bucket:    conf.S3.Bucket,
+			AccessKey: conf.S3.AccessKey,
+			SecretKey: conf.S3.SecretKey,
+			PathStyle: conf.S3.PathStyle,
+			PublicURL: conf.S3.PublicURL,
+		})
+		if err != nil {
+			log.Errorw("Invalid S3 storage configuration", log.Pair("error", err.Error()))
+		} else {
+			stores["s3"] = s3
+		}
+	}
+
+	driver := conf.Driver
+	if driver == "" {
+		driver = "local"
+	}
+	if _, ok := stores[driver]; !ok {
+		log.Errorw("Storage driver unavailable, falling back to local storage", log.Pair("driver", driver))
+		driver = "local"
+	}
+	return stores, driver
+}
+
+// newRateLimitStore returns the configured rate limit store. The Redis store
+// shares limits across instances; the memory store is per instance.
+func newRateLimitStore() ratelimit.Store {
+	conf := config.GetConf()
+	if conf.RateLimit.Store == "redis" {
+		return ratelimit.NewRedisStore(newRedisClient(), "ratelimit:")
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// newCache returns the configured read cache. The Redis cache is shared across
+// instances, so an edit on one is seen by all; the memory cache is per instance.
+func newCache() cache.Cache {
+	conf := config.GetConf().Cache
+	switch conf.Driver {
+	case "none":
+		return cache.Nop{}
+	case "redis":
+		return cache.NewRedis(newRedisClient(), "cache:")
+	default:
+		return cache.NewLRU(conf.Size)
+	}
+}
+
+func newRedisClient() *redis.Client {
+	conf := config.GetConf().Redis
+	return redis.NewClient(&redis.Options{
+		Addr:     conf.Addr,
+		Password: conf.Password,
+		DB:       conf.DB,
+	})
+}
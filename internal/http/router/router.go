@@ -3,6 +3,7 @@ package router
 import (
 	"blog/internal/http/handler"
 	"blog/internal/http/middleware"
+	"blog/internal/usecase"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +14,21 @@ func SetupRoutes(r *gin.Engine, c *Container) {
 	r.GET("/sitemap.xml", c.SitemapHandler.GenerateSitemap)
 	r.GET("/robots.txt", c.SitemapHandler.RobotsTxt)
 
+	// Feeds
+	r.GET("/feed.xml", c.FeedHandler.RSS)
+	r.GET("/atom.xml", c.FeedHandler.Atom)
+	r.GET("/category/:slug/feed.xml", c.FeedHandler.CategoryRSS)
+	r.GET("/category/:slug/atom.xml", c.FeedHandler.CategoryAtom)
+	r.GET("/tag/:name/feed.xml", c.FeedHandler.TagRSS)
+	r.GET("/tag/:name/atom.xml", c.FeedHandler.TagAtom)
+
 	// Page routes — backend injects meta tags into index.html for SEO
 	r.GET("/", c.SEOHandler.ServeHome)
 	r.GET("/posts", c.SEOHandler.ServePosts)
 	r.GET("/post/:slug", c.SEOHandler.ServePost)
 	r.GET("/about", c.SEOHandler.ServeAbout)
+	r.GET("/author/:id", c.SEOHandler.ServeFallback)
+	r.GET("/series/:slug", c.SEOHandler.ServeFallback)
 	r.GET("/clock", c.SEOHandler.ServeFallback)
 	r.GET("/settings", c.SEOHandler.ServeFallback)
 
@@ -28,27 +39,74 @@ func SetupRoutes(r *gin.Engine, c *Container) {
 		// Setup route modules
 		setupAuthRoutes(v1, c)
 		setupUserRoutes(v1, c)
+		setupNotificationRoutes(v1, c)
 		setupPublicRoutes(v1, c)
 		setupAdminRoutes(v1, c)
 	}
 }
 
+// detectBots marks bot traffic on routes that count views, visits or likes.
+func detectBots(c *Container) gin.HandlerFunc {
+	return middleware.DetectBots(c.RateLimitStore)
+}
+
+// rateLimit throttles a route with the limits configured for group.
+func rateLimit(c *Container, group string) gin.HandlerFunc {
+	return middleware.RateLimit(c.RateLimitStore, c.SystemEventRepo, group)
+}
+
 func setupAuthRoutes(v1 *gin.RouterGroup, c *Container) {
 	auth := v1.Group("/auth")
 	{
-		auth.POST("/login", c.AuthHandler.Login)
-		auth.POST("/register", c.AuthHandler.Register)
+		auth.POST("/login", rateLimit(c, "login"), c.AuthHandler.Login)
+		auth.POST("/login/2fa", rateLimit(c, "login"), c.AuthHandler.LoginTwoFactor)
+		auth.POST("/register", rateLimit(c, "register"), c.AuthHandler.Register)
 		auth.POST("/refresh", c.AuthHandler.RefreshToken)
-		auth.GET("/me", middleware.JWTAuth(c.UserRepo), c.AuthHandler.GetCurrentUser)
+		auth.GET("/me", middleware.JWTAuth(c.UserRepo, c.SessionRepo), c.AuthHandler.GetCurrentUser)
+
+		// Account recovery and email verification
+		auth.POST("/forgot-password", rateLimit(c, "account"), c.AuthHandler.ForgotPassword)
+		auth.POST("/reset-password", rateLimit(c, "account"), c.AuthHandler.ResetPassword)
+		auth.POST("/verify-email", rateLimit(c, "account"), c.AuthHandler.VerifyEmail)
+		auth.POST("/verify-email/resend", middleware.JWTAuth(c.UserRepo, c.SessionRepo), rateLimit(c, "account"), c.AuthHandler.ResendVerification)
+
+		// Two-factor authentication (TOTP) enrollment
+		twoFactor := auth.Group("/2fa")
+		twoFactor.Use(middleware.JWTAuth(c.UserRepo, c.SessionRepo))
+		{
+			twoFactor.GET("", c.AuthHandler.TwoFactorStatus)
+			twoFactor.POST("/setup", c.AuthHandler.SetupTwoFactor)
+			twoFactor.POST("/confirm", c.AuthHandler.ConfirmTwoFactor)
+			twoFactor.POST("/disable", c.AuthHandler.DisableTwoFactor)
+			twoFactor.POST("/recovery-codes", c.AuthHandler.RegenerateRecoveryCodes)
+		}
+
+		// OAuth (authorization code + PKCE)
+		auth.GET("/oauth/providers", c.AuthHandler.OAuthProviders)
+		auth.GET("/oauth/:provider", c.AuthHandler.OAuthStart)
+		auth.GET("/oauth/:provider/callback", c.AuthHandler.OAuthCallback)
+	}
+}
+
+func setupNotificationRoutes(v1 *gin.RouterGroup, c *Container) {
+	notifications := v1.Group("/notifications")
+	{
+		// Authenticated by the signature in the link, not a session.
+		notifications.GET("/unsubscribe", c.NotificationHandler.UnsubscribePage)
+		notifications.POST("/unsubscribe", c.NotificationHandler.Unsubscribe)
 	}
 }
 
 func setupUserRoutes(v1 *gin.RouterGroup, c *Container) {
 	users := v1.Group("/users")
-	users.Use(middleware.JWTAuth(c.UserRepo))
+	users.Use(middleware.JWTAuth(c.UserRepo, c.SessionRepo))
 	{
 		users.PUT("/profile", c.UserHandler.UpdateProfile)
 		users.POST("/avatar", c.MediaHandler.UploadAvatar)
+
+		// Signed-in devices
+		users.GET("/sessions", c.AuthHandler.ListSessions)
+		users.DELETE("/sessions/:id", c.AuthHandler.RevokeSession)
 	}
 }
 
@@ -56,16 +114,30 @@ func setupPublicRoutes(v1 *gin.RouterGroup, c *Container) {
 	// Blog Posts - Public
 	posts := v1.Group("/posts")
 	{
-		posts.GET("", c.PostHandler.ListPublishedPosts)
-		posts.GET("/:slug", c.PostHandler.GetPost)
+		posts.GET("", detectBots(c), c.PostHandler.ListPublishedPosts)
+		posts.GET("/:slug", detectBots(c), c.PostHandler.GetPost)
 		posts.GET("/:slug/comments", c.CommentHandler.ListComments)
+		posts.GET("/:slug/related", c.PostHandler.GetRelatedPosts)
+	}
+
+	// Full-text search - Public
+	v1.GET("/search", c.PostHandler.SearchPosts)
+
+	// Series - Public
+	v1.GET("/series/:slug", c.SeriesHandler.GetSeries)
+
+	// Author pages - Public
+	authors := v1.Group("/authors")
+	{
+		authors.GET("", c.PostHandler.ListAuthors)
+		authors.GET("/:id", c.PostHandler.GetAuthor)
 	}
 
 	// Comments - Authenticated create
 	authComments := v1.Group("/posts")
-	authComments.Use(middleware.JWTAuth(c.UserRepo))
+	authComments.Use(middleware.JWTAuth(c.UserRepo, c.SessionRepo))
 	{
-		authComments.POST("/:slug/comments", c.CommentHandler.CreateComment)
+		authComments.POST("/:slug/comments", rateLimit(c, "comment"), c.CommentHandler.CreateComment)
 	}
 
 	// Taxonomy - Public Read
@@ -81,35 +153,47 @@ func setupPublicRoutes(v1 *gin.RouterGroup, c *Container) {
 
 	// Likes - Public
 	v1.GET("/likes", c.LikeHandler.GetLikes)
-	v1.POST("/likes", c.LikeHandler.Like)
+	v1.POST("/likes", rateLimit(c, "like"), detectBots(c), c.LikeHandler.Like)
 
 	// Analytics - Public tracking
-	v1.POST("/analytics/visit", c.AnalyticsHandler.LogVisit)
+	v1.POST("/analytics/visit", rateLimit(c, "analytics"), detectBots(c), c.AnalyticsHandler.LogVisit)
 }
 
 func setupAdminRoutes(v1 *gin.RouterGroup, c *Container) {
 	admin := v1.Group("/admin")
-	admin.Use(middleware.JWTAuth(c.UserRepo), middleware.AdminOnly())
+	admin.Use(middleware.JWTAuth(c.UserRepo, c.SessionRepo), middleware.RequirePermission(usecase.PermAdminAccess))
 	{
 		setupAdminPostRoutes(admin, c)
 		setupAdminTaxonomyRoutes(admin, c)
 		setupAdminMediaRoutes(admin, c)
 		setupAdminAnalyticsRoutes(admin, c)
 		setupAdminEventRoutes(admin, c)
+		setupAdminWebhookRoutes(admin, c)
 		setupAdminUserRoutes(admin, c)
 		setupAdminCommentRoutes(admin, c)
 	}
 }
 
+// Post routes only need posts.write; PostUseCase limits authors to their
+// own drafts.
 func setupAdminPostRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermPostsWrite))
 	admin.GET("/posts", c.PostHandler.ListAllPosts)
 	admin.GET("/posts/:id", c.PostHandler.GetPostAdmin)
 	admin.POST("/posts", c.PostHandler.CreatePost)
 	admin.PUT("/posts/:id", c.PostHandler.UpdatePost)
 	admin.DELETE("/posts/:id", c.PostHandler.DeletePost)
+
+	// Revision history
+	admin.GET("/posts/:id/revisions", c.PostHandler.ListPostRevisions)
+	admin.GET("/posts/:id/revisions/diff", c.PostHandler.DiffPostRevisions)
+	admin.GET("/posts/:id/revisions/:rev", c.PostHandler.GetPostRevision)
+	admin.POST("/posts/:id/revisions/:rev/restore", c.PostHandler.RestorePostRevision)
 }
 
 func setupAdminTaxonomyRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermTaxonomyManage))
+
 	// Categories
 	admin.POST("/categories", c.CategoryHandler.CreateCategory)
 	admin.DELETE("/categories/:id", c.CategoryHandler.DeleteCategory)
@@ -117,30 +201,84 @@ func setupAdminTaxonomyRoutes(admin *gin.RouterGroup, c *Container) {
 	// Tags
 	admin.POST("/tags", c.TagHandler.CreateTag)
 	admin.DELETE("/tags/:id", c.TagHandler.DeleteTag)
+
+	// Series
+	admin.GET("/series", c.SeriesHandler.ListSeriesAdmin)
+	admin.GET("/series/:id", c.SeriesHandler.GetSeriesAdmin)
+	admin.POST("/series", c.SeriesHandler.CreateSeries)
+	admin.PUT("/series/:id", c.SeriesHandler.UpdateSeries)
+	admin.DELETE("/series/:id", c.SeriesHandler.DeleteSeries)
 }
 
 func setupAdminMediaRoutes(admin *gin.RouterGroup, c *Container) {
-	admin.POST("/upload", c.MediaHandler.UploadFile)
-	admin.GET("/files", c.MediaHandler.ListFiles)
-	admin.DELETE("/files/:id", c.MediaHandler.DeleteFile)
+	upload := middleware.RequirePermission(usecase.PermMediaUpload)
+	manage := middleware.RequirePermission(usecase.PermMediaManage)
+	settings := middleware.RequirePermission(usecase.PermSettingsManage)
+
+	admin.POST("/upload", upload, c.MediaHandler.UploadFile)
+	admin.GET("/files", upload, c.MediaHandler.ListFiles)
+	admin.GET("/files/:id/url", upload, c.MediaHandler.GetFileURL)
+	admin.GET("/files/:id/usage", manage, c.MediaHandler.GetFileUsage)
+	admin.PATCH("/files/:id", manage, c.MediaHandler.UpdateFile)
+	admin.DELETE("/files/:id", manage, c.MediaHandler.DeleteFile)
+
+	// Folders
+	admin.GET("/media/folders", upload, c.MediaHandler.ListFolders)
+	admin.POST("/media/folders", manage, c.MediaHandler.CreateFolder)
+	admin.PUT("/media/folders/:id", manage, c.MediaHandler.RenameFolder)
+	admin.DELETE("/media/folders/:id", manage, c.MediaHandler.DeleteFolder)
+
+	// Storage usage and reconciliation
+	admin.GET("/media/usage", settings, c.MediaHandler.GetStorageUsage)
+	admin.GET("/media/gc", settings, c.MediaHandler.GetGCReport)
+	admin.POST("/media/gc", settings, c.MediaHandler.RunGC)
 }
 
 func setupAdminUserRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermUsersManage))
 	admin.GET("/users", c.UserHandler.ListUsersAdmin)
 	admin.PATCH("/users/:id/status", c.UserHandler.UpdateUserStatus)
+	admin.PATCH("/users/:id/role", c.UserHandler.UpdateUserRole)
+	admin.DELETE("/users/:id/2fa", c.AuthHandler.ResetTwoFactorAdmin)
 }
 
 func setupAdminCommentRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermCommentsModerate))
 	admin.GET("/comments", c.CommentHandler.ListAllCommentsAdmin)
+	admin.GET("/comments/queue", c.CommentHandler.ListCommentQueueAdmin)
+	admin.PATCH("/comments/status", c.CommentHandler.ModerateCommentsAdmin)
 	admin.DELETE("/comments/:id", c.CommentHandler.DeleteCommentAdmin)
 }
 
 func setupAdminAnalyticsRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermAnalyticsView))
 	admin.GET("/analytics/logs", c.AnalyticsHandler.GetLogs)
 	admin.GET("/analytics/dashboard-overview", c.AnalyticsHandler.GetDashboardOverview)
+	admin.GET("/analytics/recorder", c.AnalyticsHandler.GetRecorderStats)
+
+	// Traffic reports from the daily rollups
+	admin.GET("/analytics/timeseries", c.AnalyticsHandler.GetTimeseries)
+	admin.GET("/analytics/top-posts", c.AnalyticsHandler.GetTopPosts)
+	admin.GET("/analytics/top-pages", c.AnalyticsHandler.GetTopPages)
+	admin.GET("/analytics/referrers", c.AnalyticsHandler.GetTopReferrers)
+	admin.GET("/analytics/locations", c.AnalyticsHandler.GetTopLocations)
+}
+
+func setupAdminWebhookRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermSettingsManage))
+
+	admin.GET("/webhooks", c.WebhookHandler.ListWebhooks)
+	admin.POST("/webhooks", c.WebhookHandler.CreateWebhook)
+	admin.GET("/webhooks/:id", c.WebhookHandler.GetWebhook)
+	admin.PUT("/webhooks/:id", c.WebhookHandler.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", c.WebhookHandler.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", c.WebhookHandler.ListDeliveries)
+	admin.POST("/webhooks/:id/test", c.WebhookHandler.SendTestEvent)
 }
 
 func setupAdminEventRoutes(admin *gin.RouterGroup, c *Container) {
+	admin = admin.Group("", middleware.RequirePermission(usecase.PermSettingsManage))
+
 	// General event queries
 	admin.GET("/events", c.SystemEventHandler.ListEvents)
 	admin.GET("/events/user/:id", c.SystemEventHandler.GetUserEvents)
@@ -5,22 +5,34 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"blog/config"
 	"blog/internal/http/middleware"
 	"blog/internal/http/router"
 	"blog/internal/repository/postgres"
+	"blog/pkg/log"
+	"blog/pkg/util"
 
 	"github.com/gin-gonic/gin"
 )
 
+// workerDrainTimeout bounds how long Stop waits for background workers to
+// flush queued work, independently of the HTTP shutdown deadline.
+const workerDrainTimeout = 10 * time.Second
+
 type Server struct {
 	srv    http.Server
 	dbRepo postgres.Repo
+	// stopWorkers cancels background workers; workers is done once they have exited.
+	stopWorkers  context.CancelFunc
+	workers      sync.WaitGroup
+	drainTimeout time.Duration
 }
 
 func NewServer() *Server {
-	return &Server{}
+	return &Server{drainTimeout: workerDrainTimeout}
 }
 
 func (s *Server) Run() {
@@ -53,10 +65,51 @@ func (s *Server) Run() {
 
 	// Static file service
 	g.StaticFS("/static", gin.Dir("static", false))
+	if local := config.GetConf().Storage.Local; local.BaseURL != "" && local.BaseURL != "/static" {
+		g.StaticFS(local.BaseURL, gin.Dir(local.Dir, false))
+	}
 	g.StaticFile("/favicon.ico", "./static/favicon.ico")
 
 	router.SetupRoutes(g, container)
 
+	// Index posts created before full-text search existed.
+	util.SafeGo(func() {
+		if err := container.PostRepo.RebuildSearchIndex(context.Background()); err != nil {
+			log.Warnw("Rebuild search index failed", log.Pair("error", err.Error()))
+		}
+	})
+	// Link posts written before author accounts to their author.
+	util.SafeGo(func() {
+		if err := container.PostRepo.BackfillAuthorIDs(context.Background()); err != nil {
+			log.Warnw("Backfill post authors failed", log.Pair("error", err.Error()))
+		}
+	})
+	// Encrypt TOTP secrets stored before they were encrypted at rest.
+	util.SafeGo(func() {
+		if err := container.AuthUseCase.EncryptTOTPSecrets(context.Background()); err != nil {
+			log.Warnw("Encrypt TOTP secrets failed", log.Pair("error", err.Error()))
+		}
+	})
+	// Index media usage of posts written before it was tracked.
+	util.SafeGo(func() {
+		if err := container.MediaUseCase.RebuildReferences(context.Background()); err != nil {
+			log.Warnw("Rebuild media references failed", log.Pair("error", err.Error()))
+		}
+	})
+
+	workerCtx, cancel := context.WithCancel(context.Background())
+	s.stopWorkers = cancel
+	s.goWorker(func() { container.NotificationUseCase.RunOutbox(workerCtx) })
+	s.goWorker(func() { container.WebhookUseCase.RunDeliveries(workerCtx) })
+	s.goWorker(func() { container.AuthUseCase.RunSessionPruner(workerCtx) })
+	// Stop drains buffered views after the HTTP server has stopped taking requests.
+	s.goWorker(func() { container.ViewRecorder.Run(workerCtx) })
+	s.goWorker(func() { container.RelatedPosts.Run(workerCtx) })
+	s.goWorker(func() { container.Scheduler.Run(workerCtx) })
+	s.goWorker(func() { container.AnalyticsUseCase.RunRollups(workerCtx) })
+	s.goWorker(func() { container.RetentionUseCase.RunRetention(workerCtx) })
+	s.goWorker(func() { container.MediaUseCase.RunGC(workerCtx) })
+
 	s.srv = http.Server{
 		Addr:    config.Conf.Http.Addr,
 		Handler: g,
@@ -69,14 +122,52 @@ func (s *Server) Run() {
 	}()
 }
 
+// goWorker runs a long-lived background worker that Stop waits for.
+func (s *Server) goWorker(fn func()) {
+	s.workers.Add(1)
+	util.SafeGo(func() {
+		defer s.workers.Done()
+		fn()
+	})
+}
+
 func (s *Server) Stop(ctx context.Context) error {
-	if err := s.srv.Shutdown(ctx); err != nil {
-		return err
+	shutdownErr := s.srv.Shutdown(ctx)
+
+	// Workers get their own deadline: connections that outlived ctx must
+	// not cut short the flush of queued views and visits.
+	if !s.drainWorkers() {
+		// Workers still running may be writing; leave the connections to
+		// the process exit rather than fail their queries.
+		log.Warnw("Background workers did not stop in time, leaving database connections open")
+		return shutdownErr
 	}
 	// Close DB connections
 	if s.dbRepo != nil {
 		_ = s.dbRepo.DbRClose()
 		_ = s.dbRepo.DbWClose()
 	}
-	return nil
+	return shutdownErr
+}
+
+// drainWorkers cancels the background workers and waits up to drainTimeout
+// for them to exit. It reports whether they all did.
+func (s *Server) drainWorkers() bool {
+	if s.stopWorkers == nil {
+		return true
+	}
+	s.stopWorkers()
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(s.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
 }
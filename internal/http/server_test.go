@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"blog/internal/repository/postgres"
+	"blog/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "http-test")
+	if err != nil {
+		panic(err)
+	}
+	log.Init("error", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// closeRecorder records when the database connections are closed.
+type closeRecorder struct {
+	postgres.Repo
+	closed atomic.Bool
+}
+
+func (r *closeRecorder) DbRClose() error { r.closed.Store(true); return nil }
+func (r *closeRecorder) DbWClose() error { r.closed.Store(true); return nil }
+
+// newStoppableServer returns a server whose worker exits flushDelay after it
+// is cancelled and notes whether the database was still open then.
+func newStoppableServer(drainTimeout, flushDelay time.Duration) (*Server, *closeRecorder, *atomic.Bool) {
+	db := &closeRecorder{}
+	s := &Server{dbRepo: db, drainTimeout: drainTimeout}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopWorkers = cancel
+
+	flushedWithDB := &atomic.Bool{}
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		<-ctx.Done()
+		time.Sleep(flushDelay)
+		flushedWithDB.Store(!db.closed.Load())
+	}()
+	return s, db, flushedWithDB
+}
+
+func TestStopDrainsWorkersPastShutdownDeadline(t *testing.T) {
+	s, db, flushedWithDB := newStoppableServer(time.Second, 50*time.Millisecond)
+
+	// The HTTP deadline has already passed; the drain must not inherit it.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !flushedWithDB.Load() {
+		t.Error("worker flushed after the database was closed, or not at all")
+	}
+	if !db.closed.Load() {
+		t.Error("database left open after workers finished")
+	}
+}
+
+func TestStopKeepsDatabaseOpenForStuckWorkers(t *testing.T) {
+	s, db, _ := newStoppableServer(20*time.Millisecond, time.Hour)
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if db.closed.Load() {
+		t.Error("database closed while a worker was still running")
+	}
+}
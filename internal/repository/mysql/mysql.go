@@ -121,12 +121,35 @@ func dbConnect(user, pass, addr, dbName string) (*gorm.DB, error) {
 			&entity.User{},
 			&entity.Post{},
 			&entity.PostTag{},
+			&entity.PostRevision{},
+			&entity.PostRelated{},
+			&entity.Series{},
+			&entity.SeriesPost{},
 			&entity.Category{},
 			&entity.Tag{},
 			&entity.Media{},
+			&entity.MediaVariant{},
+			&entity.MediaFolder{},
+			&entity.MediaReference{},
 			&entity.Analytics{},
 			&entity.SystemEvent{},
 			&entity.Comment{},
+			&entity.SpamToken{},
+			&entity.SpamCorpus{},
+			&entity.MailOutbox{},
+			&entity.DomainEvent{},
+			&entity.SchedulerCursor{},
+			&entity.Webhook{},
+			&entity.WebhookDelivery{},
+			&entity.AuthToken{},
+			&entity.Session{},
+			&entity.RecoveryCode{},
+			&entity.AnalyticsDaily{},
+			&entity.AnalyticsDailyPath{},
+			&entity.AnalyticsDailyPost{},
+			&entity.AnalyticsDailyCountry{},
+			&entity.AnalyticsDailyReferrer{},
+			&entity.AnalyticsSalt{},
 			&entity.Like{},
 		)
 		if err != nil {
@@ -122,22 +122,61 @@ func dbConnect(user, pass, addr, dbName, sslMode string) (*gorm.DB, error) {
 			&entity.User{},
 			&entity.Post{},
 			&entity.PostTag{},
+			&entity.PostRevision{},
+			&entity.PostRelated{},
+			&entity.Series{},
+			&entity.SeriesPost{},
 			&entity.Category{},
 			&entity.Tag{},
 			&entity.Media{},
+			&entity.MediaVariant{},
+			&entity.MediaFolder{},
+			&entity.MediaReference{},
 			&entity.Analytics{},
 			&entity.SystemEvent{},
 			&entity.Comment{},
+			&entity.SpamToken{},
+			&entity.SpamCorpus{},
+			&entity.MailOutbox{},
+			&entity.DomainEvent{},
+			&entity.SchedulerCursor{},
+			&entity.Webhook{},
+			&entity.WebhookDelivery{},
+			&entity.AuthToken{},
+			&entity.Session{},
+			&entity.RecoveryCode{},
+			&entity.AnalyticsDaily{},
+			&entity.AnalyticsDailyPath{},
+			&entity.AnalyticsDailyPost{},
+			&entity.AnalyticsDailyCountry{},
+			&entity.AnalyticsDailyReferrer{},
+			&entity.AnalyticsSalt{},
 			&entity.Like{},
 		)
 		if err != nil {
 			return nil, err
 		}
+		if err = migrateSearchIndex(db); err != nil {
+			return nil, err
+		}
 	}
 
 	return db, nil
 }
 
+// migrateSearchIndex adds the weighted full-text column used by post search.
+// The column is maintained by the post repository rather than generated,
+// because CJK text is tokenized in Go before being indexed.
+func migrateSearchIndex(db *gorm.DB) error {
+	if err := db.Exec(`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector`).Error; err != nil {
+		return err
+	}
+	if err := db.Exec(`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_index_version smallint NOT NULL DEFAULT 0`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`).Error
+}
+
 // parseAddress parses address string, separates host and port
 func parseAddress(addr string) (host, port string) {
 	if len(addr) == 0 {
@@ -1,10 +1,12 @@
 package usecase
 
 import (
+	"blog/config"
 	"blog/internal/entity"
-	"blog/pkg/geoip"
 	"blog/pkg/log"
 	"context"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -14,45 +16,114 @@ type AnalyticsUseCase struct {
 	categoryRepo  CategoryRepo
 	tagRepo       TagRepo
 	mediaRepo     MediaRepo
+	media         *MediaUseCase
+	views         *ViewRecorder
 }
 
-func NewAnalyticsUseCase(analyticsRepo AnalyticsRepo, postRepo PostRepo, categoryRepo CategoryRepo, tagRepo TagRepo, mediaRepo MediaRepo) *AnalyticsUseCase {
+func NewAnalyticsUseCase(analyticsRepo AnalyticsRepo, postRepo PostRepo, categoryRepo CategoryRepo, tagRepo TagRepo, mediaRepo MediaRepo, media *MediaUseCase, views *ViewRecorder) *AnalyticsUseCase {
 	return &AnalyticsUseCase{
 		analyticsRepo: analyticsRepo,
 		postRepo:      postRepo,
 		categoryRepo:  categoryRepo,
 		tagRepo:       tagRepo,
 		mediaRepo:     mediaRepo,
+		media:         media,
+		views:         views,
 	}
 }
 
-func (uc *AnalyticsUseCase) LogVisit(ctx context.Context, req entity.LogVisitRequest, ip, userAgent string) error {
-	location := geoip.Lookup(ip)
-
+// LogVisit queues a visit reported by the frontend; it is written with the
+// next batch. The query string is dropped from the page path so visits group
+// by page; UTM parameters found there are kept unless sent explicitly.
+// botReason is set when the client was classified as a bot; the visit is
+// still kept, marked as such.
+func (uc *AnalyticsUseCase) LogVisit(ctx context.Context, req entity.LogVisitRequest, ip, userAgent, botReason string) error {
 	var postID *int64
 	if req.PostID != 0 {
 		postID = &req.PostID
 	}
 
-	log := &entity.Analytics{
-		PagePath:  req.PagePath,
-		PostID:    postID,
-		PostTitle: req.PostTitle,
-		IP:        ip,
-		Location:  location,
-		Timestamp: time.Now().Unix(),
-		UserAgent: userAgent,
+	pagePath, query := splitPagePath(req.PagePath)
+	utm := func(explicit, param string) string {
+		if explicit == "" {
+			explicit = query.Get(param)
+		}
+		return truncateRunes(strings.TrimSpace(explicit), 100)
 	}
 
-	return uc.analyticsRepo.Create(ctx, log)
+	uc.views.RecordVisit(entity.Analytics{
+		PagePath:     truncateRunes(pagePath, 500),
+		PostID:       postID,
+		PostTitle:    truncateRunes(req.PostTitle, 255),
+		IP:           ip,
+		Referrer:     truncateRunes(req.Referrer, 500),
+		ReferrerHost: referrerHost(req.Referrer),
+		UTMSource:    utm(req.UTMSource, "utm_source"),
+		UTMMedium:    utm(req.UTMMedium, "utm_medium"),
+		UTMCampaign:  utm(req.UTMCampaign, "utm_campaign"),
+		UTMTerm:      utm(req.UTMTerm, "utm_term"),
+		UTMContent:   utm(req.UTMContent, "utm_content"),
+		Timestamp:    time.Now().Unix(),
+		UserAgent:    userAgent,
+		Bot:          botReason != "",
+		BotReason:    botReason,
+	})
+	return nil
+}
+
+// splitPagePath separates a reported path from its query string and fragment.
+func splitPagePath(raw string) (string, url.Values) {
+	path, rawQuery, _ := strings.Cut(raw, "?")
+	path, _, _ = strings.Cut(path, "#")
+	rawQuery, _, _ = strings.Cut(rawQuery, "#")
+	query, _ := url.ParseQuery(rawQuery)
+	if path == "" {
+		path = "/"
+	}
+	return path, query
 }
 
-func (uc *AnalyticsUseCase) GetLogs(ctx context.Context, startDate, endDate string, limit int) ([]entity.AnalyticsResponse, error) {
+// referrerHost returns the lowercased host of an external referrer without
+// "www.", or "" for direct visits, non-web referrers and the site itself.
+func referrerHost(referrer string) string {
+	u, err := url.Parse(strings.TrimSpace(referrer))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return ""
+	}
+	if site, err := url.Parse(config.GetConf().App.SiteURL); err == nil && normalizeHost(site.Hostname()) == host {
+		return ""
+	}
+	return truncateRunes(host, 255)
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// RecorderStats reports the state of the view and visit buffer.
+func (uc *AnalyticsUseCase) RecorderStats() entity.ViewRecorderStats {
+	return uc.views.Stats()
+}
+
+// GetLogs returns raw visits, newest first. Unlike the reports it includes
+// bot traffic unless filtered.
+func (uc *AnalyticsUseCase) GetLogs(ctx context.Context, startDate, endDate, traffic string, limit int) ([]entity.AnalyticsResponse, error) {
 	if limit == 0 {
 		limit = 100
 	}
+	filter := entity.TrafficAll
+	if traffic != "" {
+		var err error
+		if filter, err = parseTraffic(traffic); err != nil {
+			return nil, err
+		}
+	}
 
-	logs, err := uc.analyticsRepo.GetLogs(ctx, startDate, endDate, limit)
+	logs, err := uc.analyticsRepo.GetLogs(ctx, startDate, endDate, filter, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -60,14 +131,21 @@ func (uc *AnalyticsUseCase) GetLogs(ctx context.Context, startDate, endDate stri
 	responses := make([]entity.AnalyticsResponse, len(logs))
 	for i, log := range logs {
 		responses[i] = entity.AnalyticsResponse{
-			ID:        log.ID,
-			PagePath:  log.PagePath,
-			PostID:    log.PostID,
-			PostTitle: log.PostTitle,
-			IP:        log.IP,
-			Location:  log.Location,
-			Timestamp: log.Timestamp,
-			UserAgent: log.UserAgent,
+			ID:          log.ID,
+			PagePath:    log.PagePath,
+			PostID:      log.PostID,
+			PostTitle:   log.PostTitle,
+			IP:          log.IP,
+			Location:    log.Location,
+			Country:     log.Country,
+			Referrer:    log.Referrer,
+			UTMSource:   log.UTMSource,
+			UTMMedium:   log.UTMMedium,
+			UTMCampaign: log.UTMCampaign,
+			Timestamp:   log.Timestamp,
+			UserAgent:   log.UserAgent,
+			Bot:         log.Bot,
+			BotReason:   log.BotReason,
 		}
 	}
 
@@ -131,8 +209,14 @@ func (uc *AnalyticsUseCase) GetDashboardOverview(ctx context.Context) (*entity.D
 	}
 
 	systemStatus := entity.DashboardSystemStatus{
-		StorageUsage: 45, // todo
-		AIQuota:      60, // todo
+		AIQuota: 60, // todo
+	}
+	if usage, err := uc.media.StorageUsage(ctx); err != nil {
+		log.Warnf("failed to get storage usage: %v", err)
+	} else {
+		systemStatus.StorageUsage = usage.Percent
+		systemStatus.StorageUsedBytes = usage.TotalBytes
+		systemStatus.StorageQuotaBytes = usage.QuotaBytes
 	}
 
 	return &entity.DashboardOverviewResponse{
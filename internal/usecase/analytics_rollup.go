@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/privacy"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	rollupDayLayout = "2006-01-02"
+	// defaultAnalyticsRangeDays is the range reports cover without dates.
+	defaultAnalyticsRangeDays = 30
+	maxAnalyticsRangeDays     = 366
+	maxAnalyticsTopLimit      = 100
+)
+
+// RunRollups rebuilds the daily rollups from raw analytics rows every
+// analytics.rollup_interval until ctx is cancelled. Each run rebuilds the
+// last rolled-up day onwards, so rows flushed after midnight still count for
+// the day they happened; the first run backfills from the oldest raw row.
+func (uc *AnalyticsUseCase) RunRollups(ctx context.Context) {
+	interval := config.GetConf().Analytics.RollupInterval
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := uc.rollup(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Warnw("Analytics rollup failed", log.Pair("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (uc *AnalyticsUseCase) rollup(ctx context.Context, now time.Time) error {
+	today := utcDay(now)
+
+	var start time.Time
+	latest, err := uc.analyticsRepo.LatestRollupDay(ctx)
+	if err != nil {
+		return err
+	}
+	if latest != "" {
+		day, err := time.Parse(rollupDayLayout, latest)
+		if err != nil {
+			return err
+		}
+		start = day.AddDate(0, 0, -1)
+	} else {
+		earliest, err := uc.analyticsRepo.EarliestTimestamp(ctx)
+		if err != nil || earliest == 0 {
+			return err
+		}
+		start = utcDay(time.Unix(earliest, 0))
+	}
+
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next := day.AddDate(0, 0, 1)
+		if err := uc.analyticsRepo.RollupDay(ctx, day.Format(rollupDayLayout), day.Unix(), next.Unix()); err != nil {
+			return fmt.Errorf("rollup %s: %w", day.Format(rollupDayLayout), err)
+		}
+	}
+	return nil
+}
+
+// Timeseries returns daily page views and visitors over the range, site-wide
+// or for one post.
+func (uc *AnalyticsUseCase) Timeseries(ctx context.Context, startDate, endDate string, postID int64, traffic string) (*entity.AnalyticsTimeseriesResponse, error) {
+	start, end, err := parseAnalyticsRange(startDate, endDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseTraffic(traffic)
+	if err != nil {
+		return nil, err
+	}
+	startDay, endDay := start.Format(rollupDayLayout), end.Format(rollupDayLayout)
+
+	rows, err := uc.analyticsRepo.DailySeries(ctx, startDay, endDay, postID, filter)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]entity.AnalyticsPoint, len(rows))
+	for _, row := range rows {
+		byDay[row.Day] = row
+	}
+
+	resp := &entity.AnalyticsTimeseriesResponse{StartDate: startDay, EndDate: endDay, Traffic: filter}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(rollupDayLayout)
+		point := byDay[key]
+		point.Day = key
+		resp.Points = append(resp.Points, point)
+		resp.Views += point.Views
+		resp.Visitors += point.Visitors
+	}
+	return resp, nil
+}
+
+// TopPosts ranks posts by page views over the range.
+func (uc *AnalyticsUseCase) TopPosts(ctx context.Context, startDate, endDate, traffic string, limit int) ([]entity.AnalyticsTopPost, error) {
+	start, end, err := parseAnalyticsRange(startDate, endDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseTraffic(traffic)
+	if err != nil {
+		return nil, err
+	}
+	items, err := uc.analyticsRepo.TopPosts(ctx, start.Format(rollupDayLayout), end.Format(rollupDayLayout), filter, clampTopLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return []entity.AnalyticsTopPost{}, nil
+	}
+
+	ids := make([]int64, len(items))
+	for i := range items {
+		ids[i] = items[i].PostID
+	}
+	posts, err := uc.postRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]entity.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	// Deleted posts keep their history but have no title or slug.
+	for i := range items {
+		if p, ok := byID[items[i].PostID]; ok {
+			items[i].Title = p.Title
+			items[i].Slug = p.Slug
+		}
+	}
+	return items, nil
+}
+
+// TopPages ranks page paths by page views over the range.
+func (uc *AnalyticsUseCase) TopPages(ctx context.Context, startDate, endDate, traffic string, limit int) ([]entity.AnalyticsRankItem, error) {
+	return uc.topRanked(startDate, endDate, traffic, limit, func(start, end string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+		return uc.analyticsRepo.TopPaths(ctx, start, end, traffic, limit)
+	})
+}
+
+// TopReferrers ranks external referring hosts by page views over the range.
+func (uc *AnalyticsUseCase) TopReferrers(ctx context.Context, startDate, endDate, traffic string, limit int) ([]entity.AnalyticsRankItem, error) {
+	return uc.topRanked(startDate, endDate, traffic, limit, func(start, end string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+		return uc.analyticsRepo.TopReferrers(ctx, start, end, traffic, limit)
+	})
+}
+
+// TopLocations ranks countries by page views over the range.
+func (uc *AnalyticsUseCase) TopLocations(ctx context.Context, startDate, endDate, traffic string, limit int) ([]entity.AnalyticsRankItem, error) {
+	return uc.topRanked(startDate, endDate, traffic, limit, func(start, end string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+		return uc.analyticsRepo.TopCountries(ctx, start, end, traffic, limit)
+	})
+}
+
+func (uc *AnalyticsUseCase) topRanked(startDate, endDate, traffic string, limit int, query func(start, end string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error)) ([]entity.AnalyticsRankItem, error) {
+	start, end, err := parseAnalyticsRange(startDate, endDate, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseTraffic(traffic)
+	if err != nil {
+		return nil, err
+	}
+	items, err := query(start.Format(rollupDayLayout), end.Format(rollupDayLayout), filter, clampTopLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []entity.AnalyticsRankItem{}
+	}
+	return items, nil
+}
+
+// parseAnalyticsRange parses an inclusive YYYY-MM-DD range. It defaults to
+// the last 30 days up to today (UTC).
+func parseAnalyticsRange(startDate, endDate string, now time.Time) (time.Time, time.Time, error) {
+	end := utcDay(now)
+	if endDate != "" {
+		t, err := time.Parse(rollupDayLayout, endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid endDate: expected YYYY-MM-DD", ErrInvalidArgument)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -(defaultAnalyticsRangeDays - 1))
+	if startDate != "" {
+		t, err := time.Parse(rollupDayLayout, startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid startDate: expected YYYY-MM-DD", ErrInvalidArgument)
+		}
+		start = t
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: startDate is after endDate", ErrInvalidArgument)
+	}
+	if end.Sub(start) >= maxAnalyticsRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: range exceeds %d days", ErrInvalidArgument, maxAnalyticsRangeDays)
+	}
+	return start, end, nil
+}
+
+// parseTraffic parses the traffic filter of a report; reports count human
+// traffic unless asked otherwise.
+func parseTraffic(traffic string) (entity.Traffic, error) {
+	switch t := entity.Traffic(traffic); t {
+	case "":
+		return entity.TrafficHuman, nil
+	case entity.TrafficHuman, entity.TrafficBot, entity.TrafficAll:
+		return t, nil
+	default:
+		return "", fmt.Errorf("%w: invalid traffic: expected human, bot or all", ErrInvalidArgument)
+	}
+}
+
+func clampTopLimit(limit int) int {
+	if limit <= 0 {
+		return 10
+	}
+	if limit > maxAnalyticsTopLimit {
+		return maxAnalyticsTopLimit
+	}
+	return limit
+}
+
+// utcDay returns midnight UTC of the day t falls on.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// visitorID is the key unique visitors are counted by: a hash of the day,
+// IP and user agent, so the same client counts once per day and cannot be
+// followed across days. With a salt (privacy mode) it is keyed by the day's
+// salt instead, and cannot be reversed once the salt is deleted.
+func visitorID(visit *entity.Analytics, salt []byte) string {
+	if salt != nil {
+		return privacy.Hash(salt, visit.IP+"|"+visit.UserAgent)
+	}
+	day := time.Unix(visit.Timestamp, 0).UTC().Format(rollupDayLayout)
+	sum := sha256.Sum256([]byte(day + "|" + visit.IP + "|" + visit.UserAgent))
+	return hex.EncodeToString(sum[:16])
+}
@@ -3,6 +3,7 @@ package usecase
 import (
 	"blog/internal/entity"
 	"blog/pkg/jwt"
+	"blog/pkg/log"
 	"blog/pkg/util"
 	"context"
 	"errors"
@@ -10,30 +11,60 @@ import (
 )
 
 type AuthUseCase struct {
-	userRepo UserRepo
+	userRepo         UserRepo
+	authTokenRepo    AuthTokenRepo
+	sessionRepo      SessionRepo
+	recoveryCodeRepo RecoveryCodeRepo
+	eventRepo        SystemEventRepo
+	notifier         *NotificationUseCase
+	webhooks         *WebhookUseCase
 }
 
-func NewAuthUseCase(userRepo UserRepo) *AuthUseCase {
-	return &AuthUseCase{userRepo: userRepo}
+func NewAuthUseCase(userRepo UserRepo, authTokenRepo AuthTokenRepo, sessionRepo SessionRepo, recoveryCodeRepo RecoveryCodeRepo, eventRepo SystemEventRepo, notifier *NotificationUseCase, webhooks *WebhookUseCase) *AuthUseCase {
+	return &AuthUseCase{
+		userRepo:         userRepo,
+		authTokenRepo:    authTokenRepo,
+		sessionRepo:      sessionRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		eventRepo:        eventRepo,
+		notifier:         notifier,
+		webhooks:         webhooks,
+	}
 }
 
-// Login authenticates user and returns access/refresh tokens
-func (uc *AuthUseCase) Login(ctx context.Context, req entity.LoginRequest) (*entity.LoginResponse, error) {
+// Login authenticates user and returns access/refresh tokens. When the
+// account has 2FA enabled it returns a challenge instead, to be completed
+// with LoginTwoFactor.
+func (uc *AuthUseCase) Login(ctx context.Context, req entity.LoginRequest, client entity.ClientInfo) (*entity.LoginResponse, *entity.TwoFactorChallengeResponse, error) {
 	user, err := uc.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, nil, errors.New("invalid email or password")
+	}
+
+	// Account state is only revealed once the password is known to be
+	// right, so responses do not tell which addresses are registered.
+	// Failures during a lock still count and extend it.
+	if !util.CheckPasswordHash(req.Password, user.Password) {
+		uc.recordLoginFailure(ctx, user, client)
+		return nil, nil, errors.New("invalid email or password")
+	}
+	if err := checkLockout(user); err != nil {
+		return nil, nil, err
 	}
 	if user.Status == "banned" {
-		return nil, errors.New("user is banned")
+		return nil, nil, errors.New("user is banned")
 	}
 
-	if !util.CheckPasswordHash(req.Password, user.Password) {
-		return nil, errors.New("invalid email or password")
+	// With 2FA the failure count is cleared only once the second step passes.
+	if user.TOTPEnabledAt != nil {
+		challenge, err := uc.challengeTwoFactor(ctx, user)
+		return nil, challenge, err
 	}
+	uc.clearLoginFailures(ctx, user)
 
-	tokenPair, err := jwt.GenerateTokenPair(user)
+	tokenPair, err := uc.startSession(ctx, user, client)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	return &entity.LoginResponse{
@@ -41,12 +72,15 @@ func (uc *AuthUseCase) Login(ctx context.Context, req entity.LoginRequest) (*ent
 		RefreshToken: tokenPair.RefreshToken,
 		ExpiresIn:    tokenPair.ExpiresIn,
 		User: entity.UserResponse{
-			Username: user.Username,
-			Email:    user.Email,
-			Role:     user.Role,
-			Avatar:   user.Avatar,
+			Username:           user.Username,
+			Email:              user.Email,
+			Role:               user.Role,
+			Avatar:             user.Avatar,
+			EmailNotifications: user.EmailNotifications,
+			EmailVerified:      user.EmailVerifiedAt != nil,
+			TwoFactorEnabled:   user.TOTPEnabledAt != nil,
 		},
-	}, nil
+	}, nil, nil
 }
 
 // GetCurrentUser retrieves current user info
@@ -57,15 +91,18 @@ func (uc *AuthUseCase) GetCurrentUser(ctx context.Context, userID int64) (*entit
 	}
 
 	return &entity.UserResponse{
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		Avatar:   user.Avatar,
+		Username:           user.Username,
+		Email:              user.Email,
+		Role:               user.Role,
+		Avatar:             user.Avatar,
+		EmailNotifications: user.EmailNotifications,
+		EmailVerified:      user.EmailVerifiedAt != nil,
+		TwoFactorEnabled:   user.TOTPEnabledAt != nil,
 	}, nil
 }
 
 // Register creates new user account and returns tokens
-func (uc *AuthUseCase) Register(ctx context.Context, req entity.RegisterRequest) (*entity.LoginResponse, error) {
+func (uc *AuthUseCase) Register(ctx context.Context, req entity.RegisterRequest, client entity.ClientInfo) (*entity.LoginResponse, error) {
 	existUser, _ := uc.userRepo.GetByEmail(ctx, req.Email)
 	if existUser != nil {
 		return nil, errors.New("email already exists")
@@ -95,17 +132,23 @@ func (uc *AuthUseCase) Register(ctx context.Context, req entity.RegisterRequest)
 	}
 
 	user := &entity.User{
-		Username: username,
-		Email:    req.Email,
-		Password: hashedPassword,
-		Role:     "visitor", // Default role
+		Username:           username,
+		Email:              req.Email,
+		Password:           hashedPassword,
+		Role:               "visitor", // Default role
+		EmailNotifications: true,
 	}
 
 	if err := uc.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	uc.webhooks.UserRegistered(ctx, user)
+
+	if err := uc.SendVerificationEmail(ctx, user.ID); err != nil {
+		log.Errorw("Send verification email failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+	}
 
-	tokenPair, err := jwt.GenerateTokenPair(user)
+	tokenPair, err := uc.startSession(ctx, user, client)
 	if err != nil {
 		return nil, err
 	}
@@ -115,19 +158,23 @@ func (uc *AuthUseCase) Register(ctx context.Context, req entity.RegisterRequest)
 		RefreshToken: tokenPair.RefreshToken,
 		ExpiresIn:    tokenPair.ExpiresIn,
 		User: entity.UserResponse{
-			Username: user.Username,
-			Email:    user.Email,
-			Role:     user.Role,
-			Avatar:   user.Avatar,
+			Username:           user.Username,
+			Email:              user.Email,
+			Role:               user.Role,
+			Avatar:             user.Avatar,
+			EmailNotifications: user.EmailNotifications,
+			EmailVerified:      user.EmailVerifiedAt != nil,
+			TwoFactorEnabled:   user.TOTPEnabledAt != nil,
 		},
 	}, nil
 }
 
-// RefreshToken generates new access token using refresh token
-func (uc *AuthUseCase) RefreshToken(ctx context.Context, req entity.RefreshTokenRequest) (*entity.RefreshTokenResponse, error) {
+// RefreshToken rotates the refresh token: the presented token is retired and
+// a new pair is issued for the same session.
+func (uc *AuthUseCase) RefreshToken(ctx context.Context, req entity.RefreshTokenRequest, client entity.ClientInfo) (*entity.RefreshTokenResponse, error) {
 	claims, err := jwt.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
-		return nil, errors.New("invalid or expired refresh token")
+		return nil, ErrInvalidRefreshToken
 	}
 
 	// Get user from database to ensure user still exists and get latest info
@@ -150,8 +197,7 @@ func (uc *AuthUseCase) RefreshToken(ctx context.Context, req entity.RefreshToken
 		return nil, errors.New("token revoked")
 	}
 
-	// Generate new token pair
-	tokenPair, err := jwt.GenerateTokenPair(user)
+	tokenPair, err := uc.rotateSession(ctx, user, claims, client)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,509 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/totp"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts wrong codes burn the challenge; the user has to
+	// enter the password again.
+	twoFactorMaxAttempts = 5
+	// totpSkew accepts codes from one step before and after the current one.
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	// recoveryCodeAlphabet omits 0/O and 1/I; 16 characters give 80 bits.
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	recoveryCodeLength   = 16
+	// totpSecretPrefix marks an encrypted secret; secrets stored before
+	// encryption are plain base32, which never contains a colon.
+	totpSecretPrefix = "v1:"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted     = errors.New("two-factor setup has not been started")
+	ErrTwoFactorRequired       = errors.New("two-factor authentication is required for this account")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	// ErrTOTPSecretUnreadable means the stored secret was encrypted with
+	// another security.totp_key. Recovery codes still work, and an admin can
+	// reset two-factor for the account.
+	ErrTOTPSecretUnreadable = errors.New("two-factor secret cannot be decrypted with the configured security.totp_key; use a recovery code or ask an administrator to reset two-factor authentication")
+)
+
+// TwoFactorRequired reports whether the policy requires 2FA for the role.
+func TwoFactorRequired(role string) bool {
+	return role == entity.RoleAdmin && config.GetConf().TwoFactor.RequireAdmin
+}
+
+// challengeTwoFactor issues the short-lived token that LoginTwoFactor
+// exchanges, together with a valid code, for the token pair.
+func (uc *AuthUseCase) challengeTwoFactor(ctx context.Context, user *entity.User) (*entity.TwoFactorChallengeResponse, error) {
+	if err := uc.authTokenRepo.InvalidateUser(ctx, user.ID, entity.AuthTokenTwoFactor); err != nil {
+		return nil, err
+	}
+	token, err := uc.issueAuthToken(ctx, user.ID, entity.AuthTokenTwoFactor, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// LoginTwoFactor completes a login with the challenge token and a TOTP or
+// recovery code.
+func (uc *AuthUseCase) LoginTwoFactor(ctx context.Context, req entity.TwoFactorLoginRequest, client entity.ClientInfo) (*entity.LoginResponse, error) {
+	stored, err := uc.authTokenRepo.GetByHash(ctx, entity.AuthTokenTwoFactor, hashAuthToken(req.ChallengeToken))
+	if err != nil {
+		return nil, ErrInvalidAuthToken
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) || stored.Attempts >= twoFactorMaxAttempts {
+		return nil, ErrInvalidAuthToken
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil || user.TOTPEnabledAt == nil {
+		return nil, ErrInvalidAuthToken
+	}
+	if user.Status == "banned" {
+		return nil, errors.New("user is banned")
+	}
+	if err := checkLockout(user); err != nil {
+		return nil, err
+	}
+
+	ok, err := uc.checkSecondFactor(ctx, user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := uc.authTokenRepo.RecordFailure(ctx, stored.ID); err != nil {
+			log.Warnw("Record 2FA failure failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+		}
+		uc.recordLoginFailure(ctx, user, client)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	consumed, err := uc.authTokenRepo.Consume(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidAuthToken
+	}
+
+	uc.clearLoginFailures(ctx, user)
+
+	tokenPair, err := uc.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		ExpiresIn:    tokenPair.ExpiresIn,
+		User: entity.UserResponse{
+			Username:           user.Username,
+			Email:              user.Email,
+			Role:               user.Role,
+			Avatar:             user.Avatar,
+			EmailNotifications: user.EmailNotifications,
+			EmailVerified:      user.EmailVerifiedAt != nil,
+			TwoFactorEnabled:   true,
+		},
+	}, nil
+}
+
+// SetupTwoFactor starts enrollment with a new secret. It only takes effect
+// once ConfirmTwoFactor sees a code generated from it.
+func (uc *AuthUseCase) SetupTwoFactor(ctx context.Context, userID int64) (*entity.TwoFactorSetupResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealTOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = sealed
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return &entity.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, config.GetConf().TwoFactor.Issuer, user.Email),
+	}, nil
+}
+
+// ConfirmTwoFactor enables 2FA after verifying a code from the pending secret
+// and returns the first set of recovery codes.
+func (uc *AuthUseCase) ConfirmTwoFactor(ctx context.Context, userID int64, code string) (*entity.RecoveryCodesResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotStarted
+	}
+
+	secret, _, err := openTOTPSecret(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, normalizeTOTPCode(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	user.TOTPLastStep = step
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	log.Infow("Two-factor authentication enabled", log.Pair("user_id", user.ID))
+	return uc.generateRecoveryCodes(ctx, user.ID)
+}
+
+// DisableTwoFactor turns 2FA off after verifying a current code. Accounts the
+// policy requires 2FA for cannot disable it.
+func (uc *AuthUseCase) DisableTwoFactor(ctx context.Context, userID int64, code string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	if TwoFactorRequired(user.Role) {
+		return ErrTwoFactorRequired
+	}
+
+	ok, err := uc.checkSecondFactor(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	if err := uc.recoveryCodeRepo.DeleteByUser(ctx, user.ID); err != nil {
+		log.Warnw("Delete recovery codes failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+	}
+
+	log.Infow("Two-factor authentication disabled", log.Pair("user_id", user.ID))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a
+// current code.
+func (uc *AuthUseCase) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) (*entity.RecoveryCodesResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	ok, err := uc.checkSecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	return uc.generateRecoveryCodes(ctx, user.ID)
+}
+
+// TwoFactorStatus reports whether 2FA is enabled and how many recovery codes are left.
+func (uc *AuthUseCase) TwoFactorStatus(ctx context.Context, userID int64) (*entity.TwoFactorStatusResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &entity.TwoFactorStatusResponse{
+		Enabled:  user.TOTPEnabledAt != nil,
+		Required: TwoFactorRequired(user.Role),
+	}
+	if resp.Enabled {
+		resp.RecoveryCodesLeft, err = uc.recoveryCodeRepo.CountUnused(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// checkSecondFactor accepts a TOTP code (once per time step) or an unused
+// recovery code.
+func (uc *AuthUseCase) checkSecondFactor(ctx context.Context, user *entity.User, code string) (bool, error) {
+	if c := normalizeTOTPCode(code); len(c) == totp.Digits {
+		secret, _, err := openTOTPSecret(user.TOTPSecret)
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret, c, time.Now(), totpSkew)
+		if !ok {
+			return false, nil
+		}
+		// Each code works once, even within its validity window.
+		return uc.userRepo.AdvanceTOTPStep(ctx, user.ID, step)
+	}
+
+	ok, err := uc.recoveryCodeRepo.Consume(ctx, user.ID, hashAuthToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		log.Infow("Recovery code used", log.Pair("user_id", user.ID))
+	}
+	return ok, nil
+}
+
+// ResetTwoFactor turns 2FA off for another user without a code, for
+// accounts whose authenticator is lost or whose secret cannot be decrypted.
+// The user can enroll again at the next login.
+func (uc *AuthUseCase) ResetTwoFactor(ctx context.Context, actorID, userID int64) error {
+	if actorID == userID {
+		return fmt.Errorf("%w: disable your own two-factor authentication with a code", ErrInvalidArgument)
+	}
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := uc.clearTwoFactor(ctx, user); err != nil {
+		return err
+	}
+
+	log.Infow("Two-factor authentication reset by admin",
+		log.Pair("user_id", user.ID),
+		log.Pair("actor_id", actorID),
+	)
+	return nil
+}
+
+// ResetTwoFactorByEmail turns off two-factor authentication for the account
+// with the email. It backs the -reset-2fa command, the way back in for a
+// sole admin whose secret can no longer be read.
+func (uc *AuthUseCase) ResetTwoFactorByEmail(ctx context.Context, email string) error {
+	user, err := uc.userRepo.GetByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return err
+	}
+	if err := uc.clearTwoFactor(ctx, user); err != nil {
+		return err
+	}
+
+	log.Infow("Two-factor authentication reset from the command line",
+		log.Pair("user_id", user.ID),
+	)
+	return nil
+}
+
+// clearTwoFactor removes the TOTP secret and recovery codes of user.
+func (uc *AuthUseCase) clearTwoFactor(ctx context.Context, user *entity.User) error {
+	if user.TOTPSecret == "" && user.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	return uc.recoveryCodeRepo.DeleteByUser(ctx, user.ID)
+}
+
+// EncryptTOTPSecrets brings stored TOTP secrets under the current key: it
+// encrypts those stored in plain text before secrets were encrypted at rest,
+// and re-encrypts those sealed with the key derived from the JWT secret once
+// security.totp_key is set. Secrets no key opens are reported, as their
+// users need a recovery code or an admin reset.
+func (uc *AuthUseCase) EncryptTOTPSecrets(ctx context.Context) error {
+	users, err := uc.userRepo.ListWithTOTPSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 && config.GetConf().Security.TOTPKey == "" {
+		log.Warnw("security.totp_key is not set: TOTP secrets are encrypted with a key derived from app.jwt_secret, so rotating it breaks two-factor login")
+	}
+
+	n := 0
+	var unreadable []int64
+	for _, user := range users {
+		secret, current, err := openTOTPSecret(user.TOTPSecret)
+		if errors.Is(err, ErrTOTPSecretUnreadable) {
+			unreadable = append(unreadable, user.ID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if current {
+			continue
+		}
+		sealed, err := sealTOTPSecret(secret)
+		if err != nil {
+			return err
+		}
+		// A concurrent enrollment or disable wins over the migration.
+		ok, err := uc.userRepo.ReplaceTOTPSecret(ctx, user.ID, user.TOTPSecret, sealed)
+		if err != nil {
+			return err
+		}
+		if ok {
+			n++
+		}
+	}
+	if n > 0 {
+		log.Infow("Encrypted stored TOTP secrets", log.Pair("count", n))
+	}
+	if len(unreadable) > 0 {
+		log.Errorw("TOTP secrets cannot be decrypted with security.totp_key; reset two-factor for these users with DELETE /admin/users/:id/2fa",
+			log.Pair("user_ids", unreadable),
+		)
+	}
+	return nil
+}
+
+// totpSecretKeys returns the AES-256 keys for TOTP secrets, the one to seal
+// with first. Without security.totp_key the key is derived from the JWT
+// secret; with it, the derived key is still tried so that secrets sealed
+// before it was set can be opened and re-sealed.
+func totpSecretKeys() [][]byte {
+	conf := config.GetConf()
+	legacy := sha256.Sum256([]byte("totp-secret:" + conf.App.JwtSecret))
+	if conf.Security.TOTPKey == "" {
+		return [][]byte{legacy[:]}
+	}
+	key := sha256.Sum256([]byte("totp-secret:" + conf.Security.TOTPKey))
+	return [][]byte{key[:], legacy[:]}
+}
+
+// sealTOTPSecret encrypts a base32 secret with AES-GCM for storage.
+func sealTOTPSecret(secret string) (string, error) {
+	gcm, err := totpSecretCipher(totpSecretKeys()[0])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return totpSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openTOTPSecret returns the base32 secret of a stored value, and whether it
+// is sealed with the current key. Values without the prefix predate
+// encryption and are returned as they are.
+func openTOTPSecret(stored string) (secret string, current bool, err error) {
+	encoded, ok := strings.CutPrefix(stored, totpSecretPrefix)
+	if !ok {
+		return stored, false, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false, errors.New("invalid stored totp secret")
+	}
+	for i, key := range totpSecretKeys() {
+		gcm, err := totpSecretCipher(key)
+		if err != nil {
+			return "", false, err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return "", false, errors.New("invalid stored totp secret")
+		}
+		nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		if plain, err := gcm.Open(nil, nonce, ciphertext, nil); err == nil {
+			return string(plain), i == 0, nil
+		}
+	}
+	return "", false, ErrTOTPSecretUnreadable
+}
+
+func totpSecretCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (uc *AuthUseCase) generateRecoveryCodes(ctx context.Context, userID int64) (*entity.RecoveryCodesResponse, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hashAuthToken(normalizeRecoveryCode(code))
+		codes[i] = code
+	}
+	if err := uc.recoveryCodeRepo.Replace(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &entity.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// newRecoveryCode returns a code formatted as XXXX-XXXX-XXXX-XXXX.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, v := range b {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		// 256 is a multiple of the 32-character alphabet, so there is no bias.
+		sb.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+func normalizeTOTPCode(code string) string {
+	return strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,373 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/totp"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "usecase-test")
+	if err != nil {
+		panic(err)
+	}
+	log.Init("error", dir)
+	config.Conf.App.JwtSecret = "test-secret"
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeUserRepo keeps users in memory. Methods the tests do not reach are
+// left to the embedded nil interface.
+type fakeUserRepo struct {
+	UserRepo
+	users map[int64]*entity.User
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id int64) (*entity.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	c := *u
+	return &c, nil
+}
+
+func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*entity.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			c := *u
+			return &c, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *entity.User) error {
+	c := *user
+	r.users[user.ID] = &c
+	return nil
+}
+
+func (r *fakeUserRepo) AdvanceTOTPStep(_ context.Context, id int64, step int64) (bool, error) {
+	u := r.users[id]
+	if step <= u.TOTPLastStep {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+	return true, nil
+}
+
+func (r *fakeUserRepo) ListWithTOTPSecret(context.Context) ([]entity.User, error) {
+	var users []entity.User
+	for _, u := range r.users {
+		if u.TOTPSecret != "" {
+			users = append(users, entity.User{ID: u.ID, TOTPSecret: u.TOTPSecret})
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) ReplaceTOTPSecret(_ context.Context, id int64, old, secret string) (bool, error) {
+	u := r.users[id]
+	if u.TOTPSecret != old {
+		return false, nil
+	}
+	u.TOTPSecret = secret
+	return true, nil
+}
+
+type fakeAuthTokenRepo struct {
+	AuthTokenRepo
+	tokens []*entity.AuthToken
+}
+
+func (r *fakeAuthTokenRepo) Create(_ context.Context, token *entity.AuthToken) error {
+	token.ID = int64(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *fakeAuthTokenRepo) GetByHash(_ context.Context, purpose, tokenHash string) (*entity.AuthToken, error) {
+	for _, t := range r.tokens {
+		if t.Purpose == purpose && t.TokenHash == tokenHash {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, errors.New("token not found")
+}
+
+func (r *fakeAuthTokenRepo) Consume(_ context.Context, id int64) (bool, error) {
+	t := r.tokens[id-1]
+	if t.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeAuthTokenRepo) InvalidateUser(_ context.Context, userID int64, purpose string) error {
+	now := time.Now()
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeAuthTokenRepo) RecordFailure(_ context.Context, id int64) error {
+	r.tokens[id-1].Attempts++
+	return nil
+}
+
+type fakeRecoveryCodeRepo struct {
+	RecoveryCodeRepo
+	unused map[string]bool
+}
+
+func (r *fakeRecoveryCodeRepo) Replace(_ context.Context, _ int64, codeHashes []string) error {
+	r.unused = make(map[string]bool)
+	for _, h := range codeHashes {
+		r.unused[h] = true
+	}
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepo) Consume(_ context.Context, _ int64, codeHash string) (bool, error) {
+	if !r.unused[codeHash] {
+		return false, nil
+	}
+	delete(r.unused, codeHash)
+	return true, nil
+}
+
+func (r *fakeRecoveryCodeRepo) DeleteByUser(context.Context, int64) error {
+	r.unused = nil
+	return nil
+}
+
+// newTwoFactorTest returns an AuthUseCase with one user (ID 1) enrolled in
+// 2FA, and the user's base32 secret.
+func newTwoFactorTest(t *testing.T) (*AuthUseCase, *fakeUserRepo, string) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealTOTPSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabled := time.Now()
+	users := &fakeUserRepo{users: map[int64]*entity.User{
+		1: {ID: 1, Email: "a@example.com", Role: entity.RoleVisitor, TOTPSecret: sealed, TOTPEnabledAt: &enabled},
+	}}
+	uc := NewAuthUseCase(users, &fakeAuthTokenRepo{}, nil, &fakeRecoveryCodeRepo{}, nil, nil, nil)
+	return uc, users, secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestCheckSecondFactorRejectsReplayedStep(t *testing.T) {
+	uc, users, secret := newTwoFactorTest(t)
+	ctx := context.Background()
+	code := currentCode(t, secret)
+
+	ok, err := uc.checkSecondFactor(ctx, users.users[1], code)
+	if err != nil || !ok {
+		t.Fatalf("first use = %v, %v; want true", ok, err)
+	}
+	ok, err = uc.checkSecondFactor(ctx, users.users[1], code)
+	if err != nil || ok {
+		t.Fatalf("second use of the same step = %v, %v; want false", ok, err)
+	}
+}
+
+func TestLoginTwoFactorBurnsChallengeAfterMaxAttempts(t *testing.T) {
+	uc, users, secret := newTwoFactorTest(t)
+	ctx := context.Background()
+
+	challenge, err := uc.challengeTwoFactor(ctx, users.users[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := entity.TwoFactorLoginRequest{ChallengeToken: challenge.ChallengeToken, Code: "000000"}
+	if req.Code == currentCode(t, secret) {
+		req.Code = "000001"
+	}
+	for i := 0; i < twoFactorMaxAttempts; i++ {
+		if _, err := uc.LoginTwoFactor(ctx, req, entity.ClientInfo{}); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+
+	req.Code = currentCode(t, secret)
+	if _, err := uc.LoginTwoFactor(ctx, req, entity.ClientInfo{}); !errors.Is(err, ErrInvalidAuthToken) {
+		t.Fatalf("valid code after %d failures: err = %v, want ErrInvalidAuthToken", twoFactorMaxAttempts, err)
+	}
+}
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	uc, users, _ := newTwoFactorTest(t)
+	ctx := context.Background()
+
+	codes, err := uc.generateRecoveryCodes(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes.RecoveryCodes), recoveryCodeCount)
+	}
+	// Users may type the code in lower case and without dashes.
+	code := strings.ToLower(strings.ReplaceAll(codes.RecoveryCodes[0], "-", ""))
+
+	ok, err := uc.checkSecondFactor(ctx, users.users[1], code)
+	if err != nil || !ok {
+		t.Fatalf("first use = %v, %v; want true", ok, err)
+	}
+	ok, err = uc.checkSecondFactor(ctx, users.users[1], codes.RecoveryCodes[0])
+	if err != nil || ok {
+		t.Fatalf("second use = %v, %v; want false", ok, err)
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+
+	sealed, err := sealTOTPSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, totpSecretPrefix) || strings.Contains(sealed, secret) {
+		t.Fatalf("sealed secret %q is not encrypted", sealed)
+	}
+	if len(sealed) > 128 {
+		t.Fatalf("sealed secret is %d bytes, longer than the column", len(sealed))
+	}
+	if got, current, err := openTOTPSecret(sealed); err != nil || got != secret || !current {
+		t.Fatalf("openTOTPSecret = %q, %v, %v; want %q", got, current, err, secret)
+	}
+	if got, current, err := openTOTPSecret(secret); err != nil || got != secret || current {
+		t.Fatalf("openTOTPSecret(plain) = %q, %v, %v; want %q", got, current, err, secret)
+	}
+	tampered := []byte(sealed)
+	if i := len(tampered) - 5; tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+	if _, _, err := openTOTPSecret(string(tampered)); err == nil {
+		t.Fatal("tampered secret was accepted")
+	}
+}
+
+func TestEncryptTOTPSecrets(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	users := &fakeUserRepo{users: map[int64]*entity.User{
+		1: {ID: 1, TOTPSecret: secret},
+		2: {ID: 2},
+	}}
+	uc := NewAuthUseCase(users, nil, nil, nil, nil, nil, nil)
+
+	if err := uc.EncryptTOTPSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stored := users.users[1].TOTPSecret
+	if !strings.HasPrefix(stored, totpSecretPrefix) {
+		t.Fatalf("secret was not encrypted: %q", stored)
+	}
+	if got, _, _ := openTOTPSecret(stored); got != secret {
+		t.Fatalf("encrypted secret opens to %q, want %q", got, secret)
+	}
+	if users.users[2].TOTPSecret != "" {
+		t.Fatal("user without 2FA got a secret")
+	}
+}
+
+func TestTOTPKeySurvivesJWTSecretRotation(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	defer func(jwt, key string) {
+		config.Conf.App.JwtSecret, config.Conf.Security.TOTPKey = jwt, key
+	}(config.Conf.App.JwtSecret, config.Conf.Security.TOTPKey)
+
+	// Sealed before security.totp_key existed.
+	config.Conf.Security.TOTPKey = ""
+	legacy, err := sealTOTPSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &fakeUserRepo{users: map[int64]*entity.User{1: {ID: 1, TOTPSecret: legacy}}}
+	uc := NewAuthUseCase(users, nil, nil, nil, nil, nil, nil)
+
+	config.Conf.Security.TOTPKey = "totp-key"
+	if got, current, err := openTOTPSecret(legacy); err != nil || got != secret || current {
+		t.Fatalf("legacy secret opens to %q, %v, %v; want %q under the old key", got, current, err, secret)
+	}
+	if err := uc.EncryptTOTPSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resealed := users.users[1].TOTPSecret
+	if resealed == legacy {
+		t.Fatal("legacy secret was not re-encrypted with security.totp_key")
+	}
+
+	config.Conf.App.JwtSecret = "rotated-secret"
+	if got, current, err := openTOTPSecret(resealed); err != nil || got != secret || !current {
+		t.Fatalf("after JWT rotation secret opens to %q, %v, %v; want %q", got, current, err, secret)
+	}
+
+	config.Conf.Security.TOTPKey = "other-key"
+	if _, _, err := openTOTPSecret(resealed); !errors.Is(err, ErrTOTPSecretUnreadable) {
+		t.Fatalf("open with another key: err = %v, want ErrTOTPSecretUnreadable", err)
+	}
+}
+
+func TestResetTwoFactor(t *testing.T) {
+	uc, users, _ := newTwoFactorTest(t)
+	users.users[2] = &entity.User{ID: 2, Role: entity.RoleAdmin}
+
+	if err := uc.ResetTwoFactor(context.Background(), 1, 1); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("self reset: err = %v, want ErrInvalidArgument", err)
+	}
+	if err := uc.ResetTwoFactor(context.Background(), 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if u := users.users[1]; u.TOTPSecret != "" || u.TOTPEnabledAt != nil {
+		t.Fatalf("two-factor still set after reset: %+v", u)
+	}
+	if err := uc.ResetTwoFactor(context.Background(), 2, 1); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Fatalf("second reset: err = %v, want ErrTwoFactorNotEnabled", err)
+	}
+}
+
+func TestResetTwoFactorByEmail(t *testing.T) {
+	uc, users, _ := newTwoFactorTest(t)
+
+	if err := uc.ResetTwoFactorByEmail(context.Background(), " A@Example.com "); err != nil {
+		t.Fatal(err)
+	}
+	if u := users.users[1]; u.TOTPSecret != "" || u.TOTPEnabledAt != nil {
+		t.Fatalf("two-factor still set after reset: %+v", u)
+	}
+	if err := uc.ResetTwoFactorByEmail(context.Background(), "nobody@example.com"); err == nil {
+		t.Error("unknown email: want an error")
+	}
+}
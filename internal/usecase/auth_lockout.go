@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAccountLocked is returned while an account is locked after repeated
+// failed logins. The returned error is an *AccountLockedError.
+var ErrAccountLocked = errors.New("too many failed login attempts, try again later")
+
+// AccountLockedError carries when the lock ends, for Retry-After.
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string { return ErrAccountLocked.Error() }
+
+func (e *AccountLockedError) Unwrap() error { return ErrAccountLocked }
+
+// checkLockout refuses the login while the account is locked, even with the
+// right password.
+func checkLockout(user *entity.User) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return &AccountLockedError{Until: *user.LockedUntil}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a wrong password or second-factor code. Once the
+// count reaches the threshold the account is locked, starting at the base
+// duration and doubling with every further failure up to the maximum.
+func (uc *AuthUseCase) recordLoginFailure(ctx context.Context, user *entity.User, client entity.ClientInfo) {
+	conf := config.GetConf().RateLimit.Lockout
+	if conf.Threshold <= 0 {
+		return
+	}
+
+	now := time.Now()
+	failures, err := uc.userRepo.RecordLoginFailure(ctx, user.ID, now, now.Add(-conf.ResetAfter))
+	if err != nil {
+		log.Errorw("Record login failure failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+		return
+	}
+	if failures < conf.Threshold {
+		return
+	}
+
+	lock := lockoutDuration(failures-conf.Threshold, conf.Base, conf.Max)
+	until := now.Add(lock)
+	if err := uc.userRepo.SetLockedUntil(ctx, user.ID, until); err != nil {
+		log.Errorw("Lock account failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+		return
+	}
+
+	log.Warnw("Account locked after failed logins",
+		log.Pair("user_id", user.ID),
+		log.Pair("failures", failures),
+		log.Pair("locked_for", lock.String()),
+		log.Pair("ip", client.IP),
+	)
+	event := &entity.SystemEvent{
+		EventType:     entity.EventTypeSecurity,
+		EventCategory: entity.CategorySecurityThreat,
+		Severity:      entity.SeverityWarning,
+		UserID:        user.ID,
+		Username:      user.Username,
+		Action:        "ACCOUNT_LOCKED",
+		Resource:      "users",
+		ResourceID:    user.ID,
+		IP:            client.IP,
+		UserAgent:     truncateRunes(client.UserAgent, 255),
+		Status:        429,
+		Message:       fmt.Sprintf("Account locked for %s after %d failed logins", lock, failures),
+	}
+	if err := uc.eventRepo.Create(context.WithoutCancel(ctx), event); err != nil {
+		log.Errorw("Record security event failed", log.Pair("error", err.Error()))
+	}
+}
+
+// clearLoginFailures resets the failure count and lifts the lock after a
+// successful login or password reset.
+func (uc *AuthUseCase) clearLoginFailures(ctx context.Context, user *entity.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	if err := uc.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+		log.Warnw("Reset login failures failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+	}
+}
+
+// lockoutDuration returns base doubled n times, capped at max.
+func lockoutDuration(n int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		base = time.Minute
+	}
+	if max < base {
+		max = base
+	}
+	d := base
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
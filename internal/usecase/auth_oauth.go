@@ -0,0 +1,283 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/oauth"
+	"blog/pkg/util"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// oauthFlowTTL bounds how long the user may take on the provider's consent page.
+const oauthFlowTTL = 10 * time.Minute
+
+var (
+	ErrOAuthProviderDisabled = errors.New("oauth provider is not enabled")
+	ErrOAuthInvalidState     = errors.New("invalid or expired oauth state")
+	ErrOAuthEmailUnverified  = errors.New("provider did not return a verified email")
+)
+
+// oauthFlow is the per-login state kept in a signed cookie between the
+// redirect to the provider and the callback.
+type oauthFlow struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
+
+// OAuthProviders returns the names of the enabled providers.
+func (uc *AuthUseCase) OAuthProviders() []string {
+	var names []string
+	for _, name := range []string{oauth.ProviderGitHub, oauth.ProviderGoogle} {
+		if _, err := oauthProvider(name); err == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// StartOAuth begins the authorization-code flow. It returns the provider's
+// consent URL and a signed flow token that the caller must hand back to
+// CompleteOAuth (the handler keeps it in an HttpOnly cookie).
+func (uc *AuthUseCase) StartOAuth(providerName string) (authURL, flowToken string, err error) {
+	provider, err := oauthProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	flow := oauthFlow{
+		Provider: providerName,
+		State:    oauth.NewState(),
+		Verifier: oauth.NewVerifier(),
+		Expires:  time.Now().Add(oauthFlowTTL).Unix(),
+	}
+	flowToken, err = sealOAuthFlow(flow)
+	if err != nil {
+		return "", "", err
+	}
+	return provider.AuthCodeURL(flow.State, flow.Verifier), flowToken, nil
+}
+
+// CompleteOAuth validates the callback against the flow token, exchanges the
+// code, and signs the user in. Accounts are matched by provider identity
+// first, then linked by verified email, and created otherwise. Accounts with
+// 2FA get a challenge instead of tokens, as with password login.
+func (uc *AuthUseCase) CompleteOAuth(ctx context.Context, providerName, code, state, flowToken string, client entity.ClientInfo) (*entity.LoginResponse, *entity.TwoFactorChallengeResponse, error) {
+	provider, err := oauthProvider(providerName)
+	if err != nil {
+		return nil, nil, err
+	}
+	flow, err := openOAuthFlow(flowToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if flow.Provider != providerName || code == "" ||
+		!hmac.Equal([]byte(flow.State), []byte(state)) {
+		return nil, nil, ErrOAuthInvalidState
+	}
+
+	accessToken, err := provider.Exchange(ctx, code, flow.Verifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile, err := provider.FetchProfile(ctx, accessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := uc.findOrCreateOAuthUser(ctx, providerName, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Status == "banned" {
+		return nil, nil, errors.New("user is banned")
+	}
+
+	if user.TOTPEnabledAt != nil {
+		challenge, err := uc.challengeTwoFactor(ctx, user)
+		return nil, challenge, err
+	}
+
+	tokenPair, err := uc.startSession(ctx, user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &entity.LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		ExpiresIn:    tokenPair.ExpiresIn,
+		User: entity.UserResponse{
+			Username:           user.Username,
+			Email:              user.Email,
+			Role:               user.Role,
+			Avatar:             user.Avatar,
+			EmailNotifications: user.EmailNotifications,
+			EmailVerified:      user.EmailVerifiedAt != nil,
+			TwoFactorEnabled:   user.TOTPEnabledAt != nil,
+		},
+	}, nil, nil
+}
+
+func (uc *AuthUseCase) findOrCreateOAuthUser(ctx context.Context, providerName string, profile *oauth.Profile) (*entity.User, error) {
+	if user, err := uc.userRepo.GetByProvider(ctx, providerName, profile.ID); err == nil {
+		return user, nil
+	}
+
+	// Only a verified address may be used to link or create an account,
+	// otherwise anyone could claim someone else's email at the provider.
+	email := strings.ToLower(strings.TrimSpace(profile.Email))
+	if email == "" || !profile.EmailVerified {
+		return nil, ErrOAuthEmailUnverified
+	}
+
+	if user, err := uc.userRepo.GetByEmail(ctx, email); err == nil {
+		// Link the identity to the existing account. Password login keeps
+		// working for verified accounts since it is keyed by email. An
+		// account already linked to another provider is signed in without
+		// being re-linked.
+		if user.Provider == "email" || user.Provider == "" {
+			user.Provider = providerName
+			user.ProviderID = profile.ID
+			if user.Avatar == "" {
+				user.Avatar = profile.AvatarURL
+			}
+			// An unverified account may have been registered by someone
+			// else with this address. Drop whatever they set up (password,
+			// two-factor, sessions) so that only the provider proves
+			// ownership from now on.
+			unverified := user.EmailVerifiedAt == nil
+			if unverified {
+				now := time.Now()
+				user.EmailVerifiedAt = &now
+				user.Password = ""
+				user.TOTPSecret = ""
+				user.TOTPEnabledAt = nil
+				user.TOTPLastStep = 0
+			}
+			if err := uc.userRepo.Update(ctx, user); err != nil {
+				return nil, err
+			}
+			if unverified {
+				if err := uc.userRepo.BumpTokenVersion(ctx, user.ID); err != nil {
+					return nil, err
+				}
+				// Tokens issued below must carry the new version.
+				user.TokenVersion++
+				if err := uc.sessionRepo.RevokeUser(ctx, user.ID); err != nil {
+					return nil, err
+				}
+				if err := uc.recoveryCodeRepo.DeleteByUser(ctx, user.ID); err != nil {
+					return nil, err
+				}
+			}
+			log.Infow("OAuth identity linked",
+				log.Pair("user_id", user.ID),
+				log.Pair("provider", providerName),
+			)
+		}
+		return user, nil
+	}
+
+	username := strings.TrimSpace(profile.Name)
+	if username == "" {
+		username = util.GenerateRandomUsername()
+	}
+	if r := []rune(username); len(r) > 50 {
+		username = string(r[:50])
+	}
+
+	now := time.Now()
+	user := &entity.User{
+		Username:           username,
+		Email:              email,
+		EmailVerifiedAt:    &now,
+		Role:               "visitor",
+		Avatar:             profile.AvatarURL,
+		Provider:           providerName,
+		ProviderID:         profile.ID,
+		EmailNotifications: true,
+	}
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	uc.webhooks.UserRegistered(ctx, user)
+	return user, nil
+}
+
+// oauthProvider builds the client for a provider from the current config.
+func oauthProvider(name string) (*oauth.Provider, error) {
+	conf := config.GetConf()
+	var pc config.OAuthProviderConfig
+	switch name {
+	case oauth.ProviderGitHub:
+		pc = conf.OAuth.Github
+	case oauth.ProviderGoogle:
+		pc = conf.OAuth.Google
+	default:
+		return nil, ErrOAuthProviderDisabled
+	}
+	if pc.ClientID == "" {
+		return nil, ErrOAuthProviderDisabled
+	}
+
+	redirectURL := pc.RedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimRight(conf.App.SiteURL, "/") + "/api/v1/auth/oauth/" + name + "/callback"
+	}
+	return &oauth.Provider{
+		Name:         name,
+		ClientID:     pc.ClientID,
+		ClientSecret: pc.ClientSecret,
+		AuthURL:      pc.AuthURL,
+		TokenURL:     pc.TokenURL,
+		UserInfoURL:  pc.UserInfoURL,
+		EmailsURL:    pc.EmailsURL,
+		RedirectURL:  redirectURL,
+		Scopes:       pc.Scopes,
+	}, nil
+}
+
+// sealOAuthFlow encodes the flow as base64(json).base64(hmac) using the app secret.
+func sealOAuthFlow(flow oauthFlow) (string, error) {
+	payload, err := json.Marshal(flow)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + oauthFlowSignature(body), nil
+}
+
+func openOAuthFlow(token string) (*oauthFlow, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(oauthFlowSignature(body))) {
+		return nil, ErrOAuthInvalidState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrOAuthInvalidState
+	}
+	var flow oauthFlow
+	if err := json.Unmarshal(payload, &flow); err != nil {
+		return nil, ErrOAuthInvalidState
+	}
+	if time.Now().Unix() > flow.Expires {
+		return nil, ErrOAuthInvalidState
+	}
+	return &flow, nil
+}
+
+func oauthFlowSignature(body string) string {
+	mac := hmac.New(sha256.New, []byte(config.GetConf().App.JwtSecret))
+	mac.Write([]byte("oauth-flow:" + body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
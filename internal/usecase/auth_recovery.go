@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/util"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"time"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	emailVerifyTTL   = 48 * time.Hour
+)
+
+var (
+	ErrInvalidAuthToken     = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+)
+
+// ForgotPassword emails a password reset link if the address belongs to an
+// active account. It reports success either way so the endpoint cannot be
+// used to discover which emails are registered.
+func (uc *AuthUseCase) ForgotPassword(ctx context.Context, email string) error {
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.Status == "banned" {
+		return nil
+	}
+
+	// Only the newest link works.
+	if err := uc.authTokenRepo.InvalidateUser(ctx, user.ID, entity.AuthTokenPasswordReset); err != nil {
+		return err
+	}
+	token, err := uc.issueAuthToken(ctx, user.ID, entity.AuthTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	link := siteURL() + "/reset-password?token=" + url.QueryEscape(token)
+	return uc.notifier.SendAccountEmail(ctx, mailKindPasswordReset, user, link, "1 hour")
+}
+
+// ResetPassword sets a new password using a reset token and revokes every
+// existing session.
+func (uc *AuthUseCase) ResetPassword(ctx context.Context, req entity.ResetPasswordRequest) error {
+	user, err := uc.redeemAuthToken(ctx, req.Token, entity.AuthTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	// Receiving the reset email proves ownership of the address.
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	if err := uc.userRepo.BumpTokenVersion(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := uc.sessionRepo.RevokeUser(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := uc.authTokenRepo.InvalidateUser(ctx, user.ID, entity.AuthTokenPasswordReset); err != nil {
+		log.Warnw("Invalidate reset tokens failed", log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+	}
+	// The owner has proven themselves; lift any lock left by guessing.
+	uc.clearLoginFailures(ctx, user)
+
+	log.Infow("Password reset", log.Pair("user_id", user.ID))
+	return nil
+}
+
+// SendVerificationEmail emails a fresh verification link to the user.
+func (uc *AuthUseCase) SendVerificationEmail(ctx context.Context, userID int64) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := uc.authTokenRepo.InvalidateUser(ctx, user.ID, entity.AuthTokenEmailVerify); err != nil {
+		return err
+	}
+	token, err := uc.issueAuthToken(ctx, user.ID, entity.AuthTokenEmailVerify, emailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	link := siteURL() + "/verify-email?token=" + url.QueryEscape(token)
+	return uc.notifier.SendAccountEmail(ctx, mailKindVerifyEmail, user, link, "48 hours")
+}
+
+// VerifyEmail marks the user's email as verified using a verification token.
+func (uc *AuthUseCase) VerifyEmail(ctx context.Context, token string) error {
+	user, err := uc.redeemAuthToken(ctx, token, entity.AuthTokenEmailVerify)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return uc.userRepo.Update(ctx, user)
+}
+
+// issueAuthToken stores the hash of a new random token and returns the token.
+func (uc *AuthUseCase) issueAuthToken(ctx context.Context, userID int64, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	err := uc.authTokenRepo.Create(ctx, &entity.AuthToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashAuthToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// redeemAuthToken validates and consumes a token, returning its user.
+func (uc *AuthUseCase) redeemAuthToken(ctx context.Context, token, purpose string) (*entity.User, error) {
+	if token == "" {
+		return nil, ErrInvalidAuthToken
+	}
+	stored, err := uc.authTokenRepo.GetByHash(ctx, purpose, hashAuthToken(token))
+	if err != nil {
+		return nil, ErrInvalidAuthToken
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidAuthToken
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil || user.Status == "banned" {
+		return nil, ErrInvalidAuthToken
+	}
+
+	ok, err := uc.authTokenRepo.Consume(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidAuthToken
+	}
+	return user, nil
+}
+
+func hashAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
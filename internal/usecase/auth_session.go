@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/jwt"
+	"blog/pkg/log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const sessionPruneInterval = time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// startSession opens a new session family for a login and issues its first
+// token pair.
+func (uc *AuthUseCase) startSession(ctx context.Context, user *entity.User, client entity.ClientInfo) (*jwt.TokenPair, error) {
+	return uc.issueSessionTokens(ctx, user, uuid.New().String(), time.Now(), client)
+}
+
+// issueSessionTokens signs a token pair for the session family and records
+// the refresh token.
+func (uc *AuthUseCase) issueSessionTokens(ctx context.Context, user *entity.User, familyID string, startedAt time.Time, client entity.ClientInfo) (*jwt.TokenPair, error) {
+	tokenPair, err := jwt.GenerateTokenPair(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+	err = uc.sessionRepo.Create(ctx, &entity.Session{
+		ID:        tokenPair.RefreshID,
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		IP:        client.IP,
+		UserAgent: truncateRunes(client.UserAgent, 255),
+		StartedAt: startedAt,
+		ExpiresAt: tokenPair.RefreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokenPair, nil
+}
+
+// rotateSession exchanges a refresh token for a new pair in the same session.
+// A token that was already rotated is evidence of theft: whichever party uses
+// it second, the whole family is revoked so both are signed out.
+func (uc *AuthUseCase) rotateSession(ctx context.Context, user *entity.User, claims *jwt.Claims, client entity.ClientInfo) (*jwt.TokenPair, error) {
+	if claims.ID == "" || claims.SessionID == "" {
+		// Stateless token issued before sessions were tracked. It cannot be
+		// retired, so accepting it would allow unlimited replays; the user
+		// signs in again instead.
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, claims.ID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.UserID != user.ID || session.FamilyID != claims.SessionID || session.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.RotatedAt != nil {
+		uc.revokeReusedSession(ctx, session, client)
+		return nil, ErrRefreshTokenReused
+	}
+
+	rotated, err := uc.sessionRepo.MarkRotated(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Lost a race with another refresh using the same token.
+		uc.revokeReusedSession(ctx, session, client)
+		return nil, ErrRefreshTokenReused
+	}
+	return uc.issueSessionTokens(ctx, user, session.FamilyID, session.StartedAt, client)
+}
+
+func (uc *AuthUseCase) revokeReusedSession(ctx context.Context, session *entity.Session, client entity.ClientInfo) {
+	if _, err := uc.sessionRepo.RevokeFamily(ctx, session.UserID, session.FamilyID); err != nil {
+		log.Errorw("Revoke reused session failed", log.Pair("session_id", session.FamilyID), log.Pair("error", err.Error()))
+	}
+	log.Warnw("Refresh token reuse detected",
+		log.Pair("user_id", session.UserID),
+		log.Pair("session_id", session.FamilyID),
+		log.Pair("ip", client.IP),
+	)
+
+	event := &entity.SystemEvent{
+		EventType:     entity.EventTypeSecurity,
+		EventCategory: entity.CategorySecurityThreat,
+		Severity:      entity.SeverityCritical,
+		UserID:        session.UserID,
+		Action:        "REFRESH_TOKEN_REUSED",
+		Resource:      "sessions",
+		IP:            client.IP,
+		UserAgent:     truncateRunes(client.UserAgent, 255),
+		Message:       fmt.Sprintf("Rotated refresh token presented again; session %s revoked", session.FamilyID),
+	}
+	if err := uc.eventRepo.Create(context.WithoutCancel(ctx), event); err != nil {
+		log.Errorw("Record security event failed", log.Pair("error", err.Error()))
+	}
+}
+
+// ListSessions returns the user's active sessions. currentSessionID marks the
+// session the request was made from.
+func (uc *AuthUseCase) ListSessions(ctx context.Context, userID int64, currentSessionID string) ([]entity.SessionResponse, error) {
+	sessions, err := uc.sessionRepo.ListActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]entity.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, entity.SessionResponse{
+			ID:         s.FamilyID,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			StartedAt:  s.StartedAt,
+			LastSeenAt: s.CreatedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.FamilyID == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+// RevokeSession signs out a single session of the user.
+func (uc *AuthUseCase) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	ok, err := uc.sessionRepo.RevokeFamily(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	log.Infow("Session revoked", log.Pair("user_id", userID), log.Pair("session_id", sessionID))
+	return nil
+}
+
+// RunSessionPruner deletes expired session rows until ctx is cancelled.
+// Rotated tokens are kept until they expire so reuse can still be detected.
+func (uc *AuthUseCase) RunSessionPruner(ctx context.Context) {
+	ticker := time.NewTicker(sessionPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := uc.sessionRepo.DeleteExpired(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Warnw("Prune expired sessions failed", log.Pair("error", err.Error()))
+		} else if n > 0 {
+			log.Infow("Pruned expired sessions", log.Pair("count", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/pkg/cache"
+	"blog/pkg/log"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Cache namespaces. A namespace is invalidated as a whole by moving it to a
+// new generation, which orphans its keys until they expire or are evicted;
+// this works the same in memory and in Redis without scanning keys.
+const (
+	cacheNSPostLists = "posts" // Public post lists and search results
+	cacheNSPost      = "post"  // Single posts by slug, also deleted one by one
+)
+
+const (
+	cacheKeyCategories = "categories"
+	cacheKeyTags       = "tags"
+)
+
+// minCacheTTL skips caching a response that would expire almost at once,
+// e.g. right before a scheduled post goes live.
+const minCacheTTL = time.Second
+
+// responseCache stores use case responses as JSON. Cache failures are logged
+// and treated as misses: the database stays the source of truth.
+type responseCache struct {
+	c cache.Cache
+}
+
+func newResponseCache(c cache.Cache) responseCache {
+	if c == nil {
+		c = cache.Nop{}
+	}
+	return responseCache{c: c}
+}
+
+// ttl returns the configured TTL, capped so the entry expires no later than until.
+func (rc responseCache) ttl(until *time.Time) time.Duration {
+	ttl := config.GetConf().Cache.TTL
+	if until != nil {
+		if d := time.Until(*until); d < ttl {
+			ttl = d
+		}
+	}
+	return ttl
+}
+
+func (rc responseCache) get(ctx context.Context, key string, v any) bool {
+	data, err := rc.c.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, cache.ErrMiss) {
+			log.Warnw("Cache get failed", log.Pair("key", key), log.Pair("error", err.Error()))
+		}
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		log.Warnw("Cache entry undecodable", log.Pair("key", key), log.Pair("error", err.Error()))
+		return false
+	}
+	return true
+}
+
+func (rc responseCache) set(ctx context.Context, key string, v any, ttl time.Duration) {
+	if ttl < minCacheTTL {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Warnw("Cache entry unencodable", log.Pair("key", key), log.Pair("error", err.Error()))
+		return
+	}
+	if err := rc.c.Set(ctx, key, data, ttl); err != nil {
+		log.Warnw("Cache set failed", log.Pair("key", key), log.Pair("error", err.Error()))
+	}
+}
+
+func (rc responseCache) delete(ctx context.Context, keys ...string) {
+	if err := rc.c.Delete(ctx, keys...); err != nil {
+		log.Warnw("Cache delete failed", log.Pair("keys", keys), log.Pair("error", err.Error()))
+	}
+}
+
+// key returns the key for suffix in the current generation of namespace ns.
+func (rc responseCache) key(ctx context.Context, ns, suffix string) string {
+	genKey := "gen:" + ns
+	gen, err := rc.c.Get(ctx, genKey)
+	if err != nil {
+		// Start a fresh generation rather than reusing an old number, so
+		// an evicted generation key cannot revive stale entries.
+		gen = rc.bumpOne(ctx, ns)
+	}
+	return ns + ":" + string(gen) + ":" + suffix
+}
+
+// bump invalidates every key of the namespaces.
+func (rc responseCache) bump(ctx context.Context, namespaces ...string) {
+	for _, ns := range namespaces {
+		rc.bumpOne(ctx, ns)
+	}
+}
+
+func (rc responseCache) bumpOne(ctx context.Context, ns string) []byte {
+	gen := []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := rc.c.Set(ctx, "gen:"+ns, gen, 0); err != nil {
+		log.Warnw("Cache invalidation failed", log.Pair("namespace", ns), log.Pair("error", err.Error()))
+	}
+	return gen
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/cache"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheGenerations(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(100)
+	rc := newResponseCache(lru)
+
+	key := rc.key(ctx, cacheNSPostLists, "page:1")
+	if again := rc.key(ctx, cacheNSPostLists, "page:1"); again != key {
+		t.Fatalf("key changed without invalidation: %q, then %q", key, again)
+	}
+	rc.set(ctx, key, []string{"a"}, time.Minute)
+	other := rc.key(ctx, cacheNSPost, "hello")
+	rc.set(ctx, other, "post", time.Minute)
+
+	rc.bump(ctx, cacheNSPostLists)
+	bumped := rc.key(ctx, cacheNSPostLists, "page:1")
+	if bumped == key {
+		t.Fatal("bump kept the key")
+	}
+	var got []string
+	if rc.get(ctx, bumped, &got) {
+		t.Fatalf("entry survived invalidation: %v", got)
+	}
+	var post string
+	if !rc.get(ctx, rc.key(ctx, cacheNSPost, "hello"), &post) || post != "post" {
+		t.Fatal("bump invalidated another namespace")
+	}
+
+	// An evicted generation key starts a fresh generation instead of
+	// reviving entries of an earlier one.
+	_ = lru.Delete(ctx, "gen:"+cacheNSPostLists)
+	fresh := rc.key(ctx, cacheNSPostLists, "page:1")
+	if fresh == key || fresh == bumped {
+		t.Fatalf("generation reused after eviction: %q", fresh)
+	}
+}
+
+func TestResponseCacheSkipsShortTTL(t *testing.T) {
+	ctx := context.Background()
+	rc := newResponseCache(cache.NewLRU(10))
+
+	rc.set(ctx, "k", "v", minCacheTTL/2)
+	var got string
+	if rc.get(ctx, "k", &got) {
+		t.Fatal("entry expiring almost at once was cached")
+	}
+}
+
+type viewsPostRepo struct {
+	PostRepo
+	views map[int64]int
+}
+
+func (r *viewsPostRepo) GetViews(_ context.Context, id int64) (int, error) {
+	return r.views[id], nil
+}
+
+func TestGetBySlugOverlaysLiveViews(t *testing.T) {
+	ctx := context.Background()
+	posts := &viewsPostRepo{views: map[int64]int{7: 42}}
+	uc := NewPostUseCase(posts, nil, nil, nil, nil, nil, nil, nil, nil, cache.NewLRU(10))
+
+	key := uc.cache.key(ctx, cacheNSPost, "hello")
+	uc.cache.set(ctx, key, entity.PostResponse{ID: 7, Slug: "hello", Views: 3}, time.Minute)
+
+	resp, err := uc.GetBySlug(ctx, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Views != 42 {
+		t.Fatalf("Views = %d, want the live count 42", resp.Views)
+	}
+}
@@ -2,16 +2,18 @@ package usecase
 
 import (
 	"blog/internal/entity"
+	"blog/pkg/cache"
 	"context"
 	"strings"
 )
 
 type CategoryUseCase struct {
 	categoryRepo CategoryRepo
+	cache        responseCache
 }
 
-func NewCategoryUseCase(categoryRepo CategoryRepo) *CategoryUseCase {
-	return &CategoryUseCase{categoryRepo: categoryRepo}
+func NewCategoryUseCase(categoryRepo CategoryRepo, c cache.Cache) *CategoryUseCase {
+	return &CategoryUseCase{categoryRepo: categoryRepo, cache: newResponseCache(c)}
 }
 
 func (uc *CategoryUseCase) Create(ctx context.Context, req entity.CreateCategoryRequest) error {
@@ -27,10 +29,19 @@ func (uc *CategoryUseCase) Create(ctx context.Context, req entity.CreateCategory
 		Count: 0,
 	}
 
-	return uc.categoryRepo.Create(ctx, category)
+	if err := uc.categoryRepo.Create(ctx, category); err != nil {
+		return err
+	}
+	uc.cache.delete(ctx, cacheKeyCategories)
+	return nil
 }
 
 func (uc *CategoryUseCase) List(ctx context.Context) ([]entity.CategoryResponse, error) {
+	var cached []entity.CategoryResponse
+	if uc.cache.get(ctx, cacheKeyCategories, &cached) {
+		return cached, nil
+	}
+
 	categories, err := uc.categoryRepo.List(ctx)
 	if err != nil {
 		return nil, err
@@ -46,11 +57,18 @@ func (uc *CategoryUseCase) List(ctx context.Context) ([]entity.CategoryResponse,
 		}
 	}
 
+	uc.cache.set(ctx, cacheKeyCategories, responses, uc.cache.ttl(nil))
 	return responses, nil
 }
 
 func (uc *CategoryUseCase) Delete(ctx context.Context, id int64) error {
-	return uc.categoryRepo.Delete(ctx, id)
+	if err := uc.categoryRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	uc.cache.delete(ctx, cacheKeyCategories)
+	// Cached posts carry the category name.
+	uc.cache.bump(ctx, cacheNSPost, cacheNSPostLists)
+	return nil
 }
 
 // generateSlug generates slug: convert to lowercase, replace spaces with -
@@ -1,24 +1,42 @@
 package usecase
 
 import (
+	"blog/config"
 	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/util"
 	"context"
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 )
 
+// ErrEmailNotVerified is returned when commenting requires a verified email.
+var ErrEmailNotVerified = errors.New("please verify your email before commenting")
+
 type CommentUseCase struct {
 	commentRepo CommentRepo
 	postRepo    PostRepo
 	userRepo    UserRepo
+	spamChecker SpamChecker
+	spamTrainer SpamTrainer
+	notifier    *NotificationUseCase
+	webhooks    *WebhookUseCase
 }
 
-func NewCommentUseCase(commentRepo CommentRepo, postRepo PostRepo, userRepo UserRepo) *CommentUseCase {
+// NewCommentUseCase creates the comment use case. spamChecker, spamTrainer and
+// notifier may be nil to disable spam scoring, learning from moderation
+// decisions and email notifications respectively; webhooks may be nil too.
+func NewCommentUseCase(commentRepo CommentRepo, postRepo PostRepo, userRepo UserRepo, spamChecker SpamChecker, spamTrainer SpamTrainer, notifier *NotificationUseCase, webhooks *WebhookUseCase) *CommentUseCase {
 	return &CommentUseCase{
 		commentRepo: commentRepo,
 		postRepo:    postRepo,
 		userRepo:    userRepo,
+		spamChecker: spamChecker,
+		spamTrainer: spamTrainer,
+		notifier:    notifier,
+		webhooks:    webhooks,
 	}
 }
 
@@ -36,7 +54,8 @@ func (uc *CommentUseCase) Create(ctx context.Context, postID, userID int64, req
 	}
 
 	// Ensure post exists (and is accessible). Here we only validate existence.
-	if _, err := uc.postRepo.GetByID(ctx, postID); err != nil {
+	post, err := uc.postRepo.GetByID(ctx, postID)
+	if err != nil {
 		return nil, errors.New("post not found")
 	}
 
@@ -52,9 +71,21 @@ func (uc *CommentUseCase) Create(ctx context.Context, postID, userID int64, req
 		if pc.ParentID != nil {
 			return nil, errors.New("only one-level replies are allowed")
 		}
+		if pc.Status != entity.CommentStatusApproved {
+			return nil, errors.New("comment not found")
+		}
 		parentComment = pc
 	}
 
+	userInfo, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	moderator := HasPermission(userInfo.Role, PermCommentsModerate)
+	if config.Conf.Comment.RequireVerifiedEmail && !moderator && userInfo.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
 	comment := &entity.Comment{
 		PostID:   postID,
 		UserID:   userID,
@@ -62,14 +93,35 @@ func (uc *CommentUseCase) Create(ctx context.Context, postID, userID int64, req
 		Content:  content,
 	}
 
-	if err := uc.commentRepo.Create(ctx, comment); err != nil {
+	comment.Status, err = uc.initialStatus(ctx, userInfo)
+	if err != nil {
 		return nil, err
 	}
+	if uc.spamChecker != nil && !moderator {
+		verdict, err := uc.spamChecker.Check(ctx, comment)
+		if err != nil {
+			return nil, err
+		}
+		comment.SpamScore = verdict.Score
+		comment.SpamReasons = truncateRunes(strings.Join(verdict.Reasons, "; "), 500)
+		if verdict.Score >= config.Conf.Comment.SpamThreshold {
+			comment.Status = entity.CommentStatusSpam
+		}
+	}
 
-	userInfo, err := uc.userRepo.GetByID(ctx, userID)
-	if err != nil {
+	if err := uc.commentRepo.Create(ctx, comment); err != nil {
 		return nil, err
 	}
+	if comment.Status == entity.CommentStatusApproved {
+		uc.notifyPublished(ctx, comment)
+		uc.webhooks.CommentCreated(ctx, comment, post, userInfo)
+	}
+
+	// Don't tell spammers they were caught; to them it simply awaits moderation.
+	visibleStatus := comment.Status
+	if visibleStatus == entity.CommentStatusSpam {
+		visibleStatus = entity.CommentStatusPending
+	}
 
 	var replyToUser *entity.CommentUser
 	if parentComment != nil {
@@ -86,6 +138,7 @@ func (uc *CommentUseCase) Create(ctx context.Context, postID, userID int64, req
 		ID:        comment.ID,
 		ParentID:  comment.ParentID,
 		Content:   comment.Content,
+		Status:    visibleStatus,
 		CreatedAt: comment.CreatedAt,
 		User: entity.CommentUser{
 			Username: userInfo.Username,
@@ -95,6 +148,32 @@ func (uc *CommentUseCase) Create(ctx context.Context, postID, userID int64, req
 	}, nil
 }
 
+// initialStatus applies the configured moderation policy to a new comment.
+// Moderators are never held; other users are held until they have enough approved comments.
+func (uc *CommentUseCase) initialStatus(ctx context.Context, user *entity.User) (string, error) {
+	if HasPermission(user.Role, PermCommentsModerate) {
+		return entity.CommentStatusApproved, nil
+	}
+
+	cfg := config.Conf.Comment
+	required := int64(cfg.AutoApproveAfter)
+	if cfg.HoldFirstTime && required < 1 {
+		required = 1
+	}
+	if required <= 0 {
+		return entity.CommentStatusApproved, nil
+	}
+
+	approved, err := uc.commentRepo.CountByUserAndStatus(ctx, user.ID, entity.CommentStatusApproved)
+	if err != nil {
+		return "", err
+	}
+	if approved < required {
+		return entity.CommentStatusPending, nil
+	}
+	return entity.CommentStatusApproved, nil
+}
+
 // List returns paginated top-level comments with optional one-level replies.
 func (uc *CommentUseCase) List(ctx context.Context, postID int64, page, limit int, order string, withReplies bool) (*entity.PaginatedCommentsResponse, error) {
 	if page <= 0 {
@@ -206,7 +285,116 @@ func (uc *CommentUseCase) ListAllAdmin(ctx context.Context) ([]entity.AdminComme
 	if err != nil {
 		return nil, err
 	}
+	return uc.toAdminResponses(ctx, comments)
+}
 
+// ListQueue returns a page of comments in the given moderation state (pending by default).
+func (uc *CommentUseCase) ListQueue(ctx context.Context, status string, page, limit int) (*entity.PaginatedAdminCommentsResponse, error) {
+	if status == "" {
+		status = entity.CommentStatusPending
+	}
+	if !entity.IsValidCommentStatus(status) {
+		return nil, fmt.Errorf("%w: invalid comment status %q", ErrInvalidArgument, status)
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	comments, total, err := uc.commentRepo.ListByStatus(ctx, status, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	data, err := uc.toAdminResponses(ctx, comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.PaginatedAdminCommentsResponse{
+		Data: data,
+		Pagination: entity.Pagination{
+			Total:      int(total),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: (int(total) + limit - 1) / limit,
+		},
+	}, nil
+}
+
+// Moderate sets the moderation status of the given comments in bulk.
+// Approve and spam decisions are fed to the spam classifier as training data.
+func (uc *CommentUseCase) Moderate(ctx context.Context, req entity.ModerateCommentsRequest) (*entity.ModerateCommentsResponse, error) {
+	if !entity.IsValidCommentStatus(req.Status) {
+		return nil, fmt.Errorf("%w: invalid comment status %q", ErrInvalidArgument, req.Status)
+	}
+	comments, err := uc.commentRepo.GetByIDs(ctx, req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := uc.commentRepo.UpdateStatus(ctx, req.IDs, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.train(ctx, comments, req.Status)
+	if req.Status == entity.CommentStatusApproved {
+		for i := range comments {
+			if comments[i].Status != entity.CommentStatusApproved {
+				comments[i].Status = entity.CommentStatusApproved
+				uc.notifyPublished(ctx, &comments[i])
+				uc.publishApproved(ctx, &comments[i])
+			}
+		}
+	}
+	return &entity.ModerateCommentsResponse{Updated: updated}, nil
+}
+
+// train brings the classifier in line with the new moderation status of the
+// comments: approved is learned as ham, spam as spam, and any earlier
+// contradicting lesson is unlearned. Failures are logged, not returned,
+// because the moderation decision itself has already been saved.
+func (uc *CommentUseCase) train(ctx context.Context, comments []entity.Comment, status string) {
+	if uc.spamTrainer == nil {
+		return
+	}
+
+	var label string
+	switch status {
+	case entity.CommentStatusApproved:
+		label = entity.SpamLabelHam
+	case entity.CommentStatusSpam:
+		label = entity.SpamLabelSpam
+	}
+
+	for _, c := range comments {
+		if c.TrainedAs == label {
+			continue
+		}
+		if c.TrainedAs != "" {
+			if err := uc.spamTrainer.Unlearn(ctx, c.Content, c.TrainedAs); err != nil {
+				log.Errorw("Unlearn comment failed", log.Pair("comment_id", c.ID), log.Pair("error", err.Error()))
+				continue
+			}
+		}
+		if label != "" {
+			if err := uc.spamTrainer.Learn(ctx, c.Content, label); err != nil {
+				log.Errorw("Learn comment failed", log.Pair("comment_id", c.ID), log.Pair("error", err.Error()))
+				label = ""
+			}
+		}
+		if err := uc.commentRepo.SetTrainedAs(ctx, c.ID, label); err != nil {
+			log.Errorw("Save comment training label failed", log.Pair("comment_id", c.ID), log.Pair("error", err.Error()))
+		}
+	}
+}
+
+// toAdminResponses attaches user and post context to comments for moderation views.
+func (uc *CommentUseCase) toAdminResponses(ctx context.Context, comments []entity.Comment) ([]entity.AdminCommentResponse, error) {
 	// Batch load users and posts to avoid N+1 queries.
 	userIDSet := make(map[int64]struct{})
 	postIDSet := make(map[int64]struct{})
@@ -256,13 +444,16 @@ func (uc *CommentUseCase) ListAllAdmin(ctx context.Context) ([]entity.AdminComme
 			continue
 		}
 		resp = append(resp, entity.AdminCommentResponse{
-			ID:        c.ID,
-			ParentID:  c.ParentID,
-			Content:   c.Content,
-			CreatedAt: c.CreatedAt,
-			PostID:    c.PostID,
-			PostTitle: title,
-			User:      user,
+			ID:          c.ID,
+			ParentID:    c.ParentID,
+			Content:     c.Content,
+			Status:      c.Status,
+			SpamScore:   c.SpamScore,
+			SpamReasons: splitReasons(c.SpamReasons),
+			CreatedAt:   c.CreatedAt,
+			PostID:      c.PostID,
+			PostTitle:   title,
+			User:        user,
 		})
 	}
 
@@ -278,6 +469,53 @@ func (uc *CommentUseCase) DeleteAdmin(ctx context.Context, id int64) error {
 	return uc.commentRepo.DeleteCascade(ctx, id)
 }
 
+// notifyPublished queues notification emails for a newly visible comment
+// without holding up the request.
+func (uc *CommentUseCase) notifyPublished(ctx context.Context, comment *entity.Comment) {
+	if uc.notifier == nil {
+		return
+	}
+	c := *comment
+	ctx = context.WithoutCancel(ctx)
+	util.SafeGo(func() {
+		uc.notifier.CommentPublished(ctx, &c)
+	})
+}
+
+// publishApproved publishes comment.created for a comment a moderator just
+// approved. Failures are logged only.
+func (uc *CommentUseCase) publishApproved(ctx context.Context, comment *entity.Comment) {
+	if uc.webhooks == nil {
+		return
+	}
+	post, err := uc.postRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		log.Warnw("Load post for comment webhook failed", log.Pair("comment_id", comment.ID), log.Pair("error", err.Error()))
+		return
+	}
+	author, err := uc.userRepo.GetByID(ctx, comment.UserID)
+	if err != nil {
+		log.Warnw("Load author for comment webhook failed", log.Pair("comment_id", comment.ID), log.Pair("error", err.Error()))
+		return
+	}
+	uc.webhooks.CommentCreated(ctx, comment, post, author)
+}
+
+func splitReasons(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "; ")
+}
+
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
+
 func containsHTML(content string) bool {
 	return htmlTagPattern.MatchString(content)
 }
@@ -4,6 +4,7 @@ import (
 	"blog/internal/entity"
 	"context"
 	"errors"
+	"time"
 )
 
 // ErrInvalidArgument indicates request parameters are invalid and should map to HTTP 400.
@@ -16,27 +17,69 @@ type UserRepo interface {
 	GetByIDs(ctx context.Context, ids []int64) ([]entity.User, error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	GetByUsername(ctx context.Context, username string) (*entity.User, error)
+	GetByProvider(ctx context.Context, provider, providerID string) (*entity.User, error)
 	List(ctx context.Context) ([]entity.User, error)
 	Update(ctx context.Context, user *entity.User) error
 	BumpTokenVersion(ctx context.Context, id int64) error
+	// AdvanceTOTPStep records step as the last used TOTP step; it reports
+	// false if a code for this or a later step was already accepted.
+	AdvanceTOTPStep(ctx context.Context, id int64, step int64) (bool, error)
+	// ListWithTOTPSecret returns the ID and TOTP secret of every user that
+	// has one.
+	ListWithTOTPSecret(ctx context.Context) ([]entity.User, error)
+	// ReplaceTOTPSecret swaps the stored secret if it still equals old; it
+	// reports false otherwise.
+	ReplaceTOTPSecret(ctx context.Context, id int64, old, secret string) (bool, error)
+	// RecordLoginFailure counts a failed login and returns the consecutive
+	// failure count; failures before staleBefore are forgotten first.
+	RecordLoginFailure(ctx context.Context, id int64, now, staleBefore time.Time) (int, error)
+	SetLockedUntil(ctx context.Context, id int64, until time.Time) error
+	ResetLoginFailures(ctx context.Context, id int64) error
 	Delete(ctx context.Context, id int64) error
 }
 
 // PostRepo post repository interface
 type PostRepo interface {
 	Create(ctx context.Context, post *entity.Post) error
-	// CreateWithTags creates the post and its tag associations atomically.
-	CreateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64) error
+	// CreateWithTags creates the post, its tag associations and, unless rev
+	// is nil, its first revision atomically.
+	CreateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64, rev *entity.PostRevision) error
 	GetByID(ctx context.Context, id int64) (*entity.Post, error)
 	GetBySlug(ctx context.Context, slug string) (*entity.Post, error)
 	GetByIDs(ctx context.Context, ids []int64) ([]entity.Post, error)
 	SlugExists(ctx context.Context, slug string, excludeID int64) (bool, error)
 	List(ctx context.Context, filters map[string]interface{}, page, limit int) ([]entity.Post, int64, error)
-	Update(ctx context.Context, post *entity.Post) error
-	// UpdateWithTags updates the post and replaces tag associations atomically.
-	UpdateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64) error
+	// UpdateWithTags updates the post, replaces tag associations unless
+	// tagIDs is nil and, unless rev is nil, records a revision atomically.
+	UpdateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64, rev *entity.PostRevision) error
 	Delete(ctx context.Context, id int64) error
-	IncrementViews(ctx context.Context, id int64) error
+	// AddViews adds counts (post ID -> views) to the posts' view counters.
+	AddViews(ctx context.Context, counts map[int64]int64) error
+	// GetViews returns the view counter of the post.
+	GetViews(ctx context.Context, id int64) (int, error)
+	// Search runs a relevance-ranked full-text query over title, excerpt and content.
+	Search(ctx context.Context, query string, filters map[string]interface{}, page, limit int) ([]entity.PostSearchHit, int64, error)
+	// RebuildSearchIndex fills the search index for posts that have none yet.
+	RebuildSearchIndex(ctx context.Context) error
+	// CountPublishedByAuthor counts live posts per author ID.
+	CountPublishedByAuthor(ctx context.Context, now time.Time) (map[int64]int64, error)
+	// BackfillAuthorIDs sets the author ID of older posts from their byline.
+	BackfillAuthorIDs(ctx context.Context) error
+	// ListNewlyLive returns posts that went live in (since, now] and never
+	// had a post.published event.
+	ListNewlyLive(ctx context.Context, since, now time.Time) ([]entity.Post, error)
+	// MarkPublished records the post's post.published event, once per post,
+	// in the same transaction as setting its published_event_at. It reports
+	// whether the event was recorded.
+	MarkPublished(ctx context.Context, id int64, now time.Time, event *entity.DomainEvent) (bool, error)
+	// MarkAllPublished sets published_event_at on every live post without
+	// recording events, for posts that went live before the scheduler ran.
+	MarkAllPublished(ctx context.Context, now time.Time) error
+	// ListExpired returns published posts whose unpublish time has passed.
+	ListExpired(ctx context.Context, now time.Time) ([]entity.Post, error)
+	// Expire moves a post that is due to expire back to draft and records
+	// event in the same transaction. It reports whether the post expired.
+	Expire(ctx context.Context, id int64, now time.Time, event *entity.DomainEvent) (bool, error)
 
 	// Tag associations
 	AddTags(ctx context.Context, postID int64, tagIDs []int64) error
@@ -47,6 +90,44 @@ type PostRepo interface {
 	// Statistics
 	Count(ctx context.Context) (int64, error)
 	GetRecent(ctx context.Context, limit int) ([]entity.Post, error)
+	// NextPublishAt returns the earliest publish time after t among published
+	// posts, or nil when none is scheduled.
+	NextPublishAt(ctx context.Context, t time.Time) (*time.Time, error)
+}
+
+// PostRevisionRepo post revision repository interface. Revisions are
+// written by PostRepo, in the transaction that saves the post.
+type PostRevisionRepo interface {
+	ListByPostID(ctx context.Context, postID int64) ([]entity.PostRevision, error)
+	GetByRevision(ctx context.Context, postID int64, revision int) (*entity.PostRevision, error)
+}
+
+// PostRelatedRepo stores precomputed related-post scores
+type PostRelatedRepo interface {
+	// ReplaceAll swaps every stored score for rows atomically.
+	ReplaceAll(ctx context.Context, rows []entity.PostRelated) error
+	// ListRelated returns the best scoring related posts live at now.
+	ListRelated(ctx context.Context, postID int64, now time.Time, limit int) ([]entity.Post, error)
+}
+
+// SeriesRepo series repository interface
+type SeriesRepo interface {
+	// Create saves the series with postIDs as its parts, in order.
+	Create(ctx context.Context, series *entity.Series, postIDs []int64) error
+	// Update saves the series and, unless postIDs is nil, replaces its parts.
+	Update(ctx context.Context, series *entity.Series, postIDs []int64) error
+	GetByID(ctx context.Context, id int64) (*entity.Series, error)
+	GetBySlug(ctx context.Context, slug string) (*entity.Series, error)
+	SlugExists(ctx context.Context, slug string, excludeID int64) (bool, error)
+	List(ctx context.Context) ([]entity.Series, error)
+	// ListLive returns the series with at least one post live at now.
+	ListLive(ctx context.Context, now time.Time) ([]entity.Series, error)
+	Delete(ctx context.Context, id int64) error
+	// ListPosts returns the memberships of a series in reading order.
+	ListPosts(ctx context.Context, seriesID int64) ([]entity.SeriesPost, error)
+	CountPosts(ctx context.Context, seriesIDs []int64) (map[int64]int, error)
+	// GetMembership maps each given post that is in a series to its membership.
+	GetMembership(ctx context.Context, postIDs []int64) (map[int64]entity.SeriesPost, error)
 }
 
 // CategoryRepo category repository interface
@@ -82,12 +163,70 @@ type MediaRepo interface {
 	List(ctx context.Context) ([]entity.Media, error)
 	Delete(ctx context.Context, id int64) error
 	Count(ctx context.Context) (int64, error)
+	// UpdateStorage saves the backend, keys and URLs of a media file and its variants.
+	UpdateStorage(ctx context.Context, media *entity.Media) error
+	// ReplaceURL rewrites a media URL in post content and covers and in user avatars.
+	ReplaceURL(ctx context.Context, oldURL, newURL string) error
+	// Search lists media newest first. Filters: query (name), type, folderId
+	// (int64), inRoot (bool), from and to (time.Time, upload date), unused (bool).
+	Search(ctx context.Context, filters map[string]interface{}, page, limit int) ([]entity.Media, int64, error)
+	// Update saves the name, alt text, caption and folder of a media file.
+	Update(ctx context.Context, media *entity.Media) error
+	// CountReferences returns how many posts use each of the media files.
+	CountReferences(ctx context.Context, mediaIDs []int64) (map[int64]int64, error)
+	ListUsage(ctx context.Context, mediaID int64) ([]entity.MediaUsage, error)
+	// RebuildReferences rescans every post for media URLs.
+	RebuildReferences(ctx context.Context) error
+	// ListUnused lists media created before the given time that no post
+	// references and no user has as avatar.
+	ListUnused(ctx context.Context, before time.Time) ([]entity.Media, error)
+	// LinkedKeys returns the storage keys that post content and covers and
+	// user avatars link to, whatever host, scheme or query the links use.
+	LinkedKeys(ctx context.Context) (map[string]bool, error)
+	// SizeByType sums the bytes of media files and their variants by media type.
+	SizeByType(ctx context.Context) (map[string]int64, error)
+}
+
+// MediaFolderRepo media folder repository interface
+type MediaFolderRepo interface {
+	Create(ctx context.Context, folder *entity.MediaFolder) error
+	GetByID(ctx context.Context, id int64) (*entity.MediaFolder, error)
+	List(ctx context.Context) ([]entity.MediaFolder, error)
+	NameExists(ctx context.Context, name string, excludeID int64) (bool, error)
+	Update(ctx context.Context, folder *entity.MediaFolder) error
+	// Delete removes the folder and moves its media to the library root.
+	Delete(ctx context.Context, id int64) error
+	// CountMedia returns the number of media files in each folder.
+	CountMedia(ctx context.Context) (map[int64]int64, error)
 }
 
 // AnalyticsRepo analytics repository interface
 type AnalyticsRepo interface {
 	Create(ctx context.Context, log *entity.Analytics) error
-	GetLogs(ctx context.Context, startDate, endDate string, limit int) ([]entity.Analytics, error)
+	CreateBatch(ctx context.Context, logs []entity.Analytics) error
+	GetLogs(ctx context.Context, startDate, endDate string, traffic entity.Traffic, limit int) ([]entity.Analytics, error)
+
+	// Rollups. Days are UTC dates formatted YYYY-MM-DD; ranges include both ends.
+	// RollupDay rebuilds the rollups of day from raw rows with from <= timestamp < to.
+	RollupDay(ctx context.Context, day string, from, to int64) error
+	// LatestRollupDay returns the last rolled-up day, or "" when there is none.
+	LatestRollupDay(ctx context.Context) (string, error)
+	// EarliestTimestamp returns the timestamp of the oldest raw row, or 0 when there is none.
+	EarliestTimestamp(ctx context.Context) (int64, error)
+	// DailySeries returns site-wide daily totals, or those of one post when postID is set.
+	DailySeries(ctx context.Context, startDay, endDay string, postID int64, traffic entity.Traffic) ([]entity.AnalyticsPoint, error)
+	TopPaths(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error)
+	TopPosts(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsTopPost, error)
+	TopReferrers(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error)
+	TopCountries(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error)
+
+	// Privacy
+	// GetOrCreateSalt returns the salt of day, storing candidate if there is none yet.
+	GetOrCreateSalt(ctx context.Context, day, candidate string) (string, error)
+	DeleteSaltsBefore(ctx context.Context, day string) error
+	// PurgeBefore deletes raw rows older than the timestamp, or only clears
+	// their IP, user agent and visitor key when anonymize is set.
+	PurgeBefore(ctx context.Context, timestamp int64, anonymize bool) (int64, error)
 }
 
 // SystemEventRepo system event repository interface for unified event logging
@@ -97,6 +236,8 @@ type SystemEventRepo interface {
 	GetByRequestID(ctx context.Context, requestID string) ([]entity.SystemEvent, error)
 	GetByUserID(ctx context.Context, userID int64, limit int) ([]entity.SystemEvent, error)
 	GetByEventType(ctx context.Context, eventType entity.EventType, limit int) ([]entity.SystemEvent, error)
+	// AnonymizeBefore clears IP and user agent of events created before t.
+	AnonymizeBefore(ctx context.Context, t time.Time) (int64, error)
 }
 
 // CommentRepo comment repository interface
@@ -106,12 +247,123 @@ type CommentRepo interface {
 	ListTopLevel(ctx context.Context, postID int64, page, limit int, order string) ([]entity.Comment, int64, error)
 	ListReplies(ctx context.Context, postID int64, parentIDs []int64, order string) ([]entity.Comment, error)
 	ListAll(ctx context.Context) ([]entity.Comment, error)
+	ListByStatus(ctx context.Context, status string, page, limit int) ([]entity.Comment, int64, error)
+	CountByUserAndStatus(ctx context.Context, userID int64, status string) (int64, error)
+	UpdateStatus(ctx context.Context, ids []int64, status string) (int64, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]entity.Comment, error)
+	// CountRecentByContent counts comments with exactly this content created
+	// since the given time: how many userID posted, and how many other users posted it.
+	CountRecentByContent(ctx context.Context, content string, userID int64, since time.Time) (own, otherUsers int64, err error)
+	SetTrainedAs(ctx context.Context, id int64, label string) error
 	DeleteCascade(ctx context.Context, id int64) error
 }
 
+// AuthTokenRepo repository interface for emailed single-use tokens
+type AuthTokenRepo interface {
+	Create(ctx context.Context, token *entity.AuthToken) error
+	GetByHash(ctx context.Context, purpose, tokenHash string) (*entity.AuthToken, error)
+	// Consume marks the token used; it reports false if it was already used.
+	Consume(ctx context.Context, id int64) (bool, error)
+	// InvalidateUser marks all unused tokens of the user for the purpose as used.
+	InvalidateUser(ctx context.Context, userID int64, purpose string) error
+	// RecordFailure counts a failed attempt to complete the token's step.
+	RecordFailure(ctx context.Context, id int64) error
+}
+
+// RecoveryCodeRepo repository interface for two-factor recovery codes
+type RecoveryCodeRepo interface {
+	// Replace deletes the user's codes and stores the given hashes.
+	Replace(ctx context.Context, userID int64, codeHashes []string) error
+	// Consume marks an unused code used; it reports false if there is none.
+	Consume(ctx context.Context, userID int64, codeHash string) (bool, error)
+	CountUnused(ctx context.Context, userID int64) (int64, error)
+	DeleteByUser(ctx context.Context, userID int64) error
+}
+
+// SessionRepo repository interface for refresh token sessions
+type SessionRepo interface {
+	Create(ctx context.Context, session *entity.Session) error
+	GetByID(ctx context.Context, id string) (*entity.Session, error)
+	// MarkRotated sets rotated_at; it reports false if the token was already
+	// rotated or revoked.
+	MarkRotated(ctx context.Context, id string) (bool, error)
+	// ListActive returns the current (unrotated, unrevoked, unexpired) token
+	// of each of the user's sessions, most recently used first.
+	ListActive(ctx context.Context, userID int64) ([]entity.Session, error)
+	// RevokeFamily revokes every token of the session; it reports false if
+	// the user has no such active session.
+	RevokeFamily(ctx context.Context, userID int64, familyID string) (bool, error)
+	RevokeUser(ctx context.Context, userID int64) error
+	IsRevoked(ctx context.Context, familyID string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// MailOutboxRepo mail outbox repository interface
+type MailOutboxRepo interface {
+	Create(ctx context.Context, mail *entity.MailOutbox) error
+	// ListDue returns pending messages whose next attempt is due, oldest first.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]entity.MailOutbox, error)
+	// Claim leases a due message to this instance until the given time and
+	// reports whether it did; false means another instance claimed it.
+	Claim(ctx context.Context, id int64, now, until time.Time) (bool, error)
+	Update(ctx context.Context, mail *entity.MailOutbox) error
+}
+
+// DomainEventRepo domain event repository interface
+type DomainEventRepo interface {
+	// ListDue returns pending events whose next attempt is due, and claimed
+	// ones whose claim has lapsed, oldest first.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]entity.DomainEvent, error)
+	// Claim leases a due event to this instance until the given time and
+	// reports whether it did; false means another instance claimed it.
+	Claim(ctx context.Context, id int64, now, until time.Time) (bool, error)
+	Update(ctx context.Context, event *entity.DomainEvent) error
+	// GetCursor returns the time the named scheduler cursor has reached, or
+	// nil if it was never set.
+	GetCursor(ctx context.Context, name string) (*time.Time, error)
+	SetCursor(ctx context.Context, name string, checkedUntil time.Time) error
+}
+
+// WebhookRepo webhook repository interface
+type WebhookRepo interface {
+	Create(ctx context.Context, webhook *entity.Webhook) error
+	GetByID(ctx context.Context, id int64) (*entity.Webhook, error)
+	List(ctx context.Context) ([]entity.Webhook, error)
+	ListActive(ctx context.Context) ([]entity.Webhook, error)
+	Update(ctx context.Context, webhook *entity.Webhook) error
+	// Delete removes the webhook and its delivery log.
+	Delete(ctx context.Context, id int64) error
+
+	// Deliveries
+	CreateDeliveries(ctx context.Context, deliveries []entity.WebhookDelivery) error
+	CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// ListDueDeliveries returns pending deliveries whose next attempt is due,
+	// and claimed ones whose claim has lapsed, oldest first.
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	// ClaimDelivery leases a due delivery to this instance until the given
+	// time and reports whether it did; false means another instance claimed it.
+	ClaimDelivery(ctx context.Context, id int64, now, until time.Time) (bool, error)
+	// ListDeliveries returns the delivery log of a webhook, newest first.
+	ListDeliveries(ctx context.Context, webhookID int64, page, limit int) ([]entity.WebhookDelivery, int64, error)
+	UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// PruneDeliveries deletes finished deliveries created before cutoff.
+	PruneDeliveries(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// SpamRepo stores the token statistics of the comment spam classifier.
+type SpamRepo interface {
+	GetTokenCounts(ctx context.Context, tokens []string) (map[string]entity.SpamToken, error)
+	GetCorpus(ctx context.Context) (spamDocs, hamDocs int64, err error)
+	// Learn adds delta (+1 to learn, -1 to unlearn) to the label's document count
+	// and to the count of every token under that label.
+	Learn(ctx context.Context, tokens []string, label string, delta int64) error
+}
+
 // LikeRepo like repository interface
 type LikeRepo interface {
 	Create(ctx context.Context, like *entity.Like) error
 	GetCount(ctx context.Context, slug string) (int64, error)
 	ExistsBySlugAndIP(ctx context.Context, slug, ip string) (bool, error)
+	// AnonymizeBefore clears IP and user agent of likes created before t.
+	AnonymizeBefore(ctx context.Context, t time.Time) (int64, error)
 }
@@ -23,7 +23,15 @@ func (uc *LikeUseCase) GetCount(ctx context.Context, slug string) (int64, error)
 
 // Like adds a new like and returns the updated count
 // Returns ErrAlreadyLiked if the IP has already liked this slug
-func (uc *LikeUseCase) Like(ctx context.Context, slug, ip, userAgent string) (int64, error) {
+// Likes from bots are not stored; they get the current count
+func (uc *LikeUseCase) Like(ctx context.Context, slug, ip, userAgent string, bot bool) (int64, error) {
+	if bot {
+		return uc.likeRepo.GetCount(ctx, slug)
+	}
+
+	// Stored per privacy.ip_mode; the mapping is stable, so dedupe still works
+	ip = anonymizeIP(ip)
+
 	// Check if this IP has already liked
 	exists, err := uc.likeRepo.ExistsBySlugAndIP(ctx, slug, ip)
 	if err != nil {
@@ -3,26 +3,44 @@ package usecase
 import (
 	"blog/config"
 	"blog/internal/entity"
+	"blog/pkg/imaging"
 	"blog/pkg/log"
+	"blog/pkg/storage"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrMediaInUse is returned when deleting a media file that posts still use.
+var ErrMediaInUse = errors.New("media is used by posts")
+
 type MediaUseCase struct {
-	mediaRepo MediaRepo
+	mediaRepo  MediaRepo
+	folderRepo MediaFolderRepo
+	stores     map[string]storage.Storage // By driver name
+	driver     string                     // Where new uploads are stored
+
+	gcMu      sync.Mutex
+	gcRunning bool
+	gcLast    *entity.MediaGCReport
 }
 
-func NewMediaUseCase(mediaRepo MediaRepo) *MediaUseCase {
-	return &MediaUseCase{mediaRepo: mediaRepo}
+// NewMediaUseCase stores uploads in stores[driver]. The other stores are
+// used to serve and delete media uploaded before the driver changed.
+func NewMediaUseCase(mediaRepo MediaRepo, folderRepo MediaFolderRepo, stores map[string]storage.Storage, driver string) *MediaUseCase {
+	return &MediaUseCase{mediaRepo: mediaRepo, folderRepo: folderRepo, stores: stores, driver: driver}
 }
 
 func (uc *MediaUseCase) Upload(ctx context.Context, file *multipart.FileHeader, baseURL string, uploadType string) (*entity.MediaResponse, error) {
@@ -43,100 +61,510 @@ func (uc *MediaUseCase) Upload(ctx context.Context, file *multipart.FileHeader,
 		}
 	}
 
-	fullUploadPath := filepath.Join("static", uploadPath)
-	if err := os.MkdirAll(fullUploadPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create upload directory: %w", err)
-	}
-
-	// Generate unique filename: UUID + original extension
-	uniqueFilename := uuid.New().String() + ext
-	savePath := filepath.Join(fullUploadPath, uniqueFilename)
-
 	src, err := file.Open()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
 	}
 	defer src.Close()
 
-	dst, err := os.Create(savePath)
+	data, err := io.ReadAll(src)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create destination file: %w", err)
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
 	}
-	defer dst.Close()
 
-	if _, err := dst.ReadFrom(src); err != nil {
-		return nil, fmt.Errorf("failed to save file: %w", err)
+	// Strip metadata from images and generate their variants
+	var processed *imaging.Result
+	if mediaType == "image" {
+		processed, err = imaging.Process(data, detectedMime, imageOptions(uploadType))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid image: %v", ErrInvalidArgument, err)
+		}
+		data = processed.Data
+	}
+
+	store := uc.stores[uc.driver]
+	var saved []string
+	removeSaved := func() {
+		for _, key := range saved {
+			if err := store.Delete(context.WithoutCancel(ctx), key); err != nil {
+				log.Warnf("failed to delete file %s: %v", key, err)
+			}
+		}
+	}
+	put := func(key string, data []byte, mimeType string) error {
+		if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), mimeType); err != nil {
+			return err
+		}
+		saved = append(saved, key)
+		return nil
 	}
 
-	url := fmt.Sprintf("%s/static/%s/%s", baseURL, uploadPath, uniqueFilename)
+	// Generate unique filename: UUID + original extension
+	baseName := uuid.New().String()
+	key := path.Join(uploadPath, baseName+ext)
+	if err := put(key, data, detectedMime); err != nil {
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
 
 	media := &entity.Media{
-		URL:      url,
+		URL:      absoluteURL(store.URL(key), baseURL),
 		Name:     file.Filename,
 		Type:     mediaType,
-		Size:     file.Size,
+		Size:     int64(len(data)),
 		MimeType: detectedMime,
-		Path:     savePath,
+		Storage:  uc.driver,
+		Path:     key,
 		Date:     time.Now().Format(time.RFC3339),
 	}
 
+	if processed != nil {
+		media.Width = processed.Width
+		media.Height = processed.Height
+		media.BlurHash = processed.BlurHash
+		for _, v := range processed.Variants {
+			// <uuid>-480w.jpg for resized variants, <uuid>.webp at full size
+			name := baseName
+			if v.Width != processed.Width {
+				name += fmt.Sprintf("-%dw", v.Width)
+			}
+			variantKey := path.Join(uploadPath, name+variantExtensions[v.MimeType])
+			if err := put(variantKey, v.Data, v.MimeType); err != nil {
+				removeSaved()
+				return nil, fmt.Errorf("failed to save image variant: %w", err)
+			}
+			media.Variants = append(media.Variants, entity.MediaVariant{
+				URL:      absoluteURL(store.URL(variantKey), baseURL),
+				Width:    v.Width,
+				Height:   v.Height,
+				MimeType: v.MimeType,
+				Size:     int64(len(v.Data)),
+				Path:     variantKey,
+			})
+		}
+	}
+
 	if err := uc.mediaRepo.Create(ctx, media); err != nil {
-		// If database save fails, delete uploaded file
-		os.Remove(savePath)
+		// If database save fails, delete uploaded files
+		removeSaved()
 		return nil, err
 	}
 
-	return &entity.MediaResponse{
-		ID:   media.ID,
-		URL:  media.URL,
-		Name: media.Name,
-		Type: media.Type,
-		Date: media.Date,
+	resp := toMediaResponse(media)
+	return &resp, nil
+}
+
+// List searches the library; see MediaRepo.Search for the filters.
+func (uc *MediaUseCase) List(ctx context.Context, filters map[string]interface{}, page, limit int) (*entity.PaginatedMediaResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	mediaList, total, err := uc.mediaRepo.Search(ctx, filters, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(mediaList))
+	for i := range mediaList {
+		ids[i] = mediaList[i].ID
+	}
+	usage, err := uc.mediaRepo.CountReferences(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]entity.MediaResponse, len(mediaList))
+	for i := range mediaList {
+		data[i] = toMediaResponse(&mediaList[i])
+		data[i].UsageCount = usage[mediaList[i].ID]
+	}
+
+	return &entity.PaginatedMediaResponse{
+		Data: data,
+		Pagination: entity.Pagination{
+			Total:      int(total),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: (int(total) + limit - 1) / limit,
+		},
 	}, nil
 }
 
-func (uc *MediaUseCase) List(ctx context.Context) ([]entity.MediaResponse, error) {
-	mediaList, err := uc.mediaRepo.List(ctx)
+// Update edits the name, alt text, caption or folder of a media file.
+func (uc *MediaUseCase) Update(ctx context.Context, id int64, req entity.UpdateMediaRequest) (*entity.MediaResponse, error) {
+	media, err := uc.mediaRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	responses := make([]entity.MediaResponse, len(mediaList))
-	for i, m := range mediaList {
-		responses[i] = entity.MediaResponse{
-			ID:   m.ID,
-			URL:  m.URL,
-			Name: m.Name,
-			Type: m.Type,
-			Date: m.Date,
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidArgument)
+		}
+		media.Name = name
+	}
+	if req.Alt != nil {
+		media.Alt = strings.TrimSpace(*req.Alt)
+	}
+	if req.Caption != nil {
+		media.Caption = strings.TrimSpace(*req.Caption)
+	}
+	if req.FolderID != nil {
+		if *req.FolderID == 0 {
+			media.FolderID = nil
+		} else {
+			if _, err := uc.folderRepo.GetByID(ctx, *req.FolderID); err != nil {
+				return nil, fmt.Errorf("%w: folder not found", ErrInvalidArgument)
+			}
+			media.FolderID = req.FolderID
 		}
 	}
 
-	return responses, nil
+	if err := uc.mediaRepo.Update(ctx, media); err != nil {
+		return nil, err
+	}
+
+	counts, err := uc.mediaRepo.CountReferences(ctx, []int64{media.ID})
+	if err != nil {
+		return nil, err
+	}
+	resp := toMediaResponse(media)
+	resp.UsageCount = counts[media.ID]
+	return &resp, nil
 }
 
-func (uc *MediaUseCase) Delete(ctx context.Context, id int64) error {
+// Usage lists the posts that use a media file in their content or cover.
+func (uc *MediaUseCase) Usage(ctx context.Context, id int64) ([]entity.MediaUsage, error) {
+	if _, err := uc.mediaRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	usage, err := uc.mediaRepo.ListUsage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		usage = []entity.MediaUsage{}
+	}
+	return usage, nil
+}
+
+// RebuildReferences rescans all posts for the media they use, indexing posts
+// written before usage tracking existed.
+func (uc *MediaUseCase) RebuildReferences(ctx context.Context) error {
+	return uc.mediaRepo.RebuildReferences(ctx)
+}
+
+// Delete removes a media file and its variants. A file that posts still use
+// is only deleted with force; otherwise ErrMediaInUse is returned.
+func (uc *MediaUseCase) Delete(ctx context.Context, id int64, force bool) error {
 	media, err := uc.mediaRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	if !force {
+		counts, err := uc.mediaRepo.CountReferences(ctx, []int64{id})
+		if err != nil {
+			return err
+		}
+		if counts[id] > 0 {
+			return ErrMediaInUse
+		}
+	}
+
 	if err := uc.mediaRepo.Delete(ctx, id); err != nil {
 		return err
 	}
 
-	// Delete file from disk
-	if media.Path != "" {
-		if err := os.Remove(media.Path); err != nil {
-			// If file doesn't exist or deletion fails, only log but don't return error
-			// Because database record has been deleted
-			log.Warnf("failed to delete file %s: %v", media.Path, err)
+	store, ok := uc.stores[mediaStorage(media)]
+	if !ok {
+		log.Warnf("media %d is in unconfigured storage %q, files not deleted", id, mediaStorage(media))
+		return nil
+	}
+	for _, key := range mediaKeys(media) {
+		if key == "" {
+			continue
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			// Only log: the database record has been deleted
+			log.Warnf("failed to delete file %s: %v", key, err)
 		}
 	}
 
 	return nil
 }
 
+// SignedURL returns a time-limited URL of a media file, which works even when
+// the bucket is private. Local files are public, so theirs does not expire.
+func (uc *MediaUseCase) SignedURL(ctx context.Context, id int64, baseURL string) (*entity.MediaURLResponse, error) {
+	media, err := uc.mediaRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := uc.stores[mediaStorage(media)]
+	if !ok {
+		return nil, fmt.Errorf("media %d is in unconfigured storage %q", id, mediaStorage(media))
+	}
+
+	expiry := config.GetConf().Storage.PresignExpiry
+	if expiry <= 0 {
+		expiry = 15 * time.Minute
+	}
+	url, err := store.SignedURL(ctx, mediaKeys(media)[0], expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.MediaURLResponse{
+		URL:       absoluteURL(url, baseURL),
+		ExpiresAt: time.Now().Add(expiry),
+	}, nil
+}
+
+// MigrateStorage copies every media file not yet in the target store there,
+// points the media records and the posts and avatars that embed their URLs
+// at the copies, and with deleteSource removes the originals. It can be run
+// again after a failure: media already moved are skipped. siteURL prefixes
+// the URLs of local files. It returns how many media were moved.
+func (uc *MediaUseCase) MigrateStorage(ctx context.Context, target, siteURL string, deleteSource bool) (int, error) {
+	dst, ok := uc.stores[target]
+	if !ok {
+		return 0, fmt.Errorf("%w: storage %q is not configured", ErrInvalidArgument, target)
+	}
+	mediaList, err := uc.mediaRepo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for i := range mediaList {
+		media := &mediaList[i]
+		from := mediaStorage(media)
+		if from == target {
+			continue
+		}
+		src, ok := uc.stores[from]
+		if !ok {
+			return moved, fmt.Errorf("media %d is in unconfigured storage %q", media.ID, from)
+		}
+
+		keys := mediaKeys(media)
+		for _, key := range keys {
+			if err := copyObject(ctx, src, key, dst, key); err != nil {
+				return moved, fmt.Errorf("copy media %d file %s: %w", media.ID, key, err)
+			}
+		}
+
+		replaced := map[string]string{}
+		media.Storage, media.Path = target, keys[0]
+		replaced[media.URL] = absoluteURL(dst.URL(keys[0]), siteURL)
+		media.URL = replaced[media.URL]
+		for j := range media.Variants {
+			v := &media.Variants[j]
+			v.Path = keys[j+1]
+			replaced[v.URL] = absoluteURL(dst.URL(v.Path), siteURL)
+			v.URL = replaced[v.URL]
+		}
+		if err := uc.mediaRepo.UpdateStorage(ctx, media); err != nil {
+			return moved, err
+		}
+		for oldURL, newURL := range replaced {
+			if err := uc.mediaRepo.ReplaceURL(ctx, oldURL, newURL); err != nil {
+				return moved, err
+			}
+		}
+
+		if deleteSource {
+			for _, key := range keys {
+				if err := src.Delete(ctx, key); err != nil {
+					log.Warnf("failed to delete file %s: %v", key, err)
+				}
+			}
+		}
+		moved++
+		log.Infow("Media moved", log.Pair("id", media.ID), log.Pair("from", from), log.Pair("to", target))
+	}
+	return moved, nil
+}
+
+func (uc *MediaUseCase) ListFolders(ctx context.Context) ([]entity.MediaFolderResponse, error) {
+	folders, err := uc.folderRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := uc.folderRepo.CountMedia(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]entity.MediaFolderResponse, len(folders))
+	for i, f := range folders {
+		responses[i] = toMediaFolderResponse(&f, counts[f.ID])
+	}
+	return responses, nil
+}
+
+func (uc *MediaUseCase) CreateFolder(ctx context.Context, req entity.MediaFolderRequest) (*entity.MediaFolderResponse, error) {
+	name, err := uc.validFolderName(ctx, req.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	folder := &entity.MediaFolder{Name: name}
+	if err := uc.folderRepo.Create(ctx, folder); err != nil {
+		return nil, err
+	}
+	resp := toMediaFolderResponse(folder, 0)
+	return &resp, nil
+}
+
+func (uc *MediaUseCase) RenameFolder(ctx context.Context, id int64, req entity.MediaFolderRequest) (*entity.MediaFolderResponse, error) {
+	folder, err := uc.folderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if folder.Name, err = uc.validFolderName(ctx, req.Name, id); err != nil {
+		return nil, err
+	}
+	if err := uc.folderRepo.Update(ctx, folder); err != nil {
+		return nil, err
+	}
+	counts, err := uc.folderRepo.CountMedia(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := toMediaFolderResponse(folder, counts[folder.ID])
+	return &resp, nil
+}
+
+// DeleteFolder removes a folder; its media move to the library root.
+func (uc *MediaUseCase) DeleteFolder(ctx context.Context, id int64) error {
+	if _, err := uc.folderRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return uc.folderRepo.Delete(ctx, id)
+}
+
+func (uc *MediaUseCase) validFolderName(ctx context.Context, name string, excludeID int64) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: folder name cannot be empty", ErrInvalidArgument)
+	}
+	exists, err := uc.folderRepo.NameExists(ctx, name, excludeID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("%w: folder %q already exists", ErrInvalidArgument, name)
+	}
+	return name, nil
+}
+
+func toMediaFolderResponse(f *entity.MediaFolder, mediaCount int64) entity.MediaFolderResponse {
+	return entity.MediaFolderResponse{
+		ID:         f.ID,
+		Name:       f.Name,
+		MediaCount: mediaCount,
+		CreatedAt:  f.CreatedAt,
+	}
+}
+
+func copyObject(ctx context.Context, src storage.Storage, srcKey string, dst storage.Storage, dstKey string) error {
+	info, err := src.Stat(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	r, err := src.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(srcKey))
+	}
+	return dst.Put(ctx, dstKey, r, info.Size, contentType)
+}
+
+// mediaStorage is the driver name of the store holding a media's files.
+func mediaStorage(m *entity.Media) string {
+	if m.Storage == "" {
+		return "local"
+	}
+	return m.Storage
+}
+
+// mediaKeys returns the storage keys of a media file followed by those of
+// its variants. Local rows from before pluggable storage hold the file path,
+// static/<key>.
+func mediaKeys(m *entity.Media) []string {
+	key := func(p string) string {
+		if m.Storage == "" {
+			return strings.TrimPrefix(filepath.ToSlash(p), "static/")
+		}
+		return p
+	}
+	keys := []string{key(m.Path)}
+	for _, v := range m.Variants {
+		keys = append(keys, key(v.Path))
+	}
+	return keys
+}
+
+// absoluteURL prefixes site-relative URLs, as the local store returns, with baseURL.
+func absoluteURL(url, baseURL string) string {
+	if strings.HasPrefix(url, "/") {
+		return strings.TrimSuffix(baseURL, "/") + url
+	}
+	return url
+}
+
+func toMediaResponse(m *entity.Media) entity.MediaResponse {
+	resp := entity.MediaResponse{
+		ID:       m.ID,
+		URL:      m.URL,
+		Name:     m.Name,
+		Type:     m.Type,
+		MimeType: m.MimeType,
+		Size:     m.Size,
+		Date:     m.Date,
+		FolderID: m.FolderID,
+		Alt:      m.Alt,
+		Caption:  m.Caption,
+		Width:    m.Width,
+		Height:   m.Height,
+		BlurHash: m.BlurHash,
+	}
+	for _, v := range m.Variants {
+		resp.Variants = append(resp.Variants, entity.MediaVariantResponse{
+			URL:      v.URL,
+			Width:    v.Width,
+			Height:   v.Height,
+			MimeType: v.MimeType,
+			Size:     v.Size,
+		})
+	}
+	return resp
+}
+
+// imageOptions returns how an uploaded image is processed. Avatars are only
+// stripped of metadata; post images also get srcset variants.
+func imageOptions(uploadType string) imaging.Options {
+	conf := config.GetConf().Media
+	if uploadType == "avatar" {
+		return imaging.Options{Quality: conf.Quality}
+	}
+	return imaging.Options{
+		Widths:   conf.Widths,
+		Quality:  conf.Quality,
+		WebP:     conf.WebP,
+		MaxWidth: conf.MaxWidth,
+	}
+}
+
 // getMediaType determines file type based on MIME type
 func getMediaType(contentType string) string {
 	if strings.HasPrefix(contentType, "image/") {
@@ -148,6 +576,13 @@ func getMediaType(contentType string) string {
 	return "document"
 }
 
+// variantExtensions are the file extensions of image variants by MIME type.
+var variantExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
 const (
 	maxAvatarSize    int64 = 2 * 1024 * 1024
 	maxPostMediaSize int64 = 50 * 1024 * 1024
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/storage"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
+// ErrGCRunning is returned when a reconciliation is requested while one runs.
+var ErrGCRunning = errors.New("media reconciliation is already running")
+
+// quarantinePrefix is where quarantined files are moved, in the same store.
+const quarantinePrefix = "quarantine/"
+
+// Reconciliation actions; report only looks.
+const (
+	GCActionReport     = "report"
+	GCActionQuarantine = "quarantine"
+	GCActionDelete     = "delete"
+)
+
+// RunGC reconciles storage every media.gc.interval while media.gc.enabled is
+// set, until ctx is cancelled.
+func (uc *MediaUseCase) RunGC(ctx context.Context) {
+	for {
+		interval := config.GetConf().Media.GC.Interval
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		conf := config.GetConf().Media.GC
+		if !conf.Enabled {
+			continue
+		}
+		report, err := uc.ReconcileStorage(ctx, conf.Action)
+		if err != nil {
+			log.Warnw("Media reconciliation failed", log.Pair("error", err.Error()))
+			continue
+		}
+		log.Infow("Media reconciliation finished",
+			log.Pair("action", report.Action),
+			log.Pair("orphan_files", len(report.OrphanFiles)),
+			log.Pair("missing_files", len(report.MissingFiles)),
+			log.Pair("unused_media", len(report.UnusedMedia)),
+			log.Pair("quarantined", report.Quarantined),
+			log.Pair("deleted", report.Deleted),
+		)
+	}
+}
+
+// LastGCReport returns the report of the latest reconciliation, or nil.
+func (uc *MediaUseCase) LastGCReport() *entity.MediaGCReport {
+	uc.gcMu.Lock()
+	defer uc.gcMu.Unlock()
+	return uc.gcLast
+}
+
+// ReconcileStorage compares the upload directories of every configured store
+// with the media table and post references. It finds files no media record
+// points to, records whose file is gone, and media no post or avatar uses.
+// With the quarantine or delete action the orphan files are moved under
+// quarantine/ or deleted, and with media.gc.unused so are the unused media;
+// records without a file are only reported. Orphan files a post or avatar
+// still links to are reported but kept, as are files younger than
+// media.gc.min_age, whose post may not be saved yet.
+func (uc *MediaUseCase) ReconcileStorage(ctx context.Context, action string) (*entity.MediaGCReport, error) {
+	if action == "" {
+		action = GCActionReport
+	}
+	if action != GCActionReport && action != GCActionQuarantine && action != GCActionDelete {
+		return nil, fmt.Errorf("%w: invalid action %q", ErrInvalidArgument, action)
+	}
+
+	uc.gcMu.Lock()
+	if uc.gcRunning {
+		uc.gcMu.Unlock()
+		return nil, ErrGCRunning
+	}
+	uc.gcRunning = true
+	uc.gcMu.Unlock()
+
+	report, err := uc.reconcile(ctx, action, config.GetConf().Media.GC)
+
+	uc.gcMu.Lock()
+	uc.gcRunning = false
+	if err == nil {
+		uc.gcLast = report
+	}
+	uc.gcMu.Unlock()
+	return report, err
+}
+
+func (uc *MediaUseCase) reconcile(ctx context.Context, action string, conf config.MediaGCConfig) (*entity.MediaGCReport, error) {
+	report := &entity.MediaGCReport{
+		Action:       action,
+		StartedAt:    time.Now(),
+		OrphanFiles:  []entity.OrphanFile{},
+		MissingFiles: []entity.MissingFile{},
+		UnusedMedia:  []entity.MediaResponse{},
+	}
+	minAge := conf.MinAge
+	if minAge <= 0 {
+		minAge = 24 * time.Hour
+	}
+	cutoff := report.StartedAt.Add(-minAge)
+	fail := func(format string, args ...any) {
+		report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+	}
+
+	mediaList, err := uc.mediaRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]map[string]bool{}
+	for i := range mediaList {
+		name := mediaStorage(&mediaList[i])
+		if known[name] == nil {
+			known[name] = map[string]bool{}
+		}
+		for _, key := range mediaKeys(&mediaList[i]) {
+			known[name][key] = true
+		}
+	}
+	linked, err := uc.mediaRepo.LinkedKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files without a record
+	names := make([]string, 0, len(uc.stores))
+	for name := range uc.stores {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	existing := map[string]map[string]bool{}
+	for _, name := range names {
+		existing[name] = map[string]bool{}
+		for _, prefix := range uploadPrefixes() {
+			err := uc.stores[name].Walk(ctx, prefix, func(obj storage.ObjectInfo) error {
+				existing[name][obj.Key] = true
+				if known[name][obj.Key] || obj.ModTime.After(cutoff) {
+					return nil
+				}
+				report.OrphanFiles = append(report.OrphanFiles, entity.OrphanFile{
+					Storage: name,
+					Key:     obj.Key,
+					Size:    obj.Size,
+					ModTime: obj.ModTime,
+					Linked:  linked[obj.Key],
+				})
+				report.OrphanBytes += obj.Size
+				return nil
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				fail("list %s storage %s: %v", name, prefix, err)
+			}
+		}
+		err := uc.stores[name].Walk(ctx, quarantinePrefix, func(obj storage.ObjectInfo) error {
+			report.QuarantineBytes += obj.Size
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			fail("list %s storage %s: %v", name, quarantinePrefix, err)
+		}
+	}
+	report.RemainingOrphanBytes = report.OrphanBytes
+
+	// Records without a file
+	for i := range mediaList {
+		media := &mediaList[i]
+		name := mediaStorage(media)
+		store, ok := uc.stores[name]
+		if !ok {
+			fail("media %d: storage %q is not configured", media.ID, name)
+			continue
+		}
+		for _, key := range mediaKeys(media) {
+			if key == "" || existing[name][key] {
+				continue
+			}
+			if _, err := store.Stat(ctx, key); errors.Is(err, storage.ErrNotFound) {
+				report.MissingFiles = append(report.MissingFiles, entity.MissingFile{MediaID: media.ID, Storage: name, Key: key})
+			} else if err != nil {
+				fail("stat %s: %v", key, err)
+			}
+		}
+	}
+
+	unused, err := uc.mediaRepo.ListUnused(ctx, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for i := range unused {
+		report.UnusedMedia = append(report.UnusedMedia, toMediaResponse(&unused[i]))
+	}
+
+	if action != GCActionReport {
+		for _, orphan := range report.OrphanFiles {
+			if orphan.Linked {
+				continue
+			}
+			store := uc.stores[orphan.Storage]
+			if action == GCActionQuarantine {
+				if err := quarantine(ctx, store, orphan.Key); err != nil {
+					fail("quarantine %s: %v", orphan.Key, err)
+					continue
+				}
+				report.Quarantined++
+				report.QuarantineBytes += orphan.Size
+			} else {
+				if err := store.Delete(ctx, orphan.Key); err != nil {
+					fail("delete %s: %v", orphan.Key, err)
+					continue
+				}
+				report.Deleted++
+			}
+			report.RemainingOrphanBytes -= orphan.Size
+		}
+		if conf.Unused {
+			for i := range unused {
+				uc.removeUnused(ctx, &unused[i], action, report, fail)
+			}
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// removeUnused quarantines or deletes an unused media file with its record,
+// unless a post started using it since the scan.
+func (uc *MediaUseCase) removeUnused(ctx context.Context, media *entity.Media, action string, report *entity.MediaGCReport, fail func(string, ...any)) {
+	if action == GCActionDelete {
+		switch err := uc.Delete(ctx, media.ID, false); {
+		case errors.Is(err, ErrMediaInUse):
+		case err != nil:
+			fail("delete media %d: %v", media.ID, err)
+		default:
+			report.Deleted++
+		}
+		return
+	}
+
+	counts, err := uc.mediaRepo.CountReferences(ctx, []int64{media.ID})
+	if err != nil {
+		fail("media %d: %v", media.ID, err)
+		return
+	}
+	if counts[media.ID] > 0 {
+		return
+	}
+	store, ok := uc.stores[mediaStorage(media)]
+	if !ok {
+		return
+	}
+	for _, key := range mediaKeys(media) {
+		if key == "" {
+			continue
+		}
+		if err := quarantine(ctx, store, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			fail("quarantine %s: %v", key, err)
+			return
+		}
+		report.Quarantined++
+	}
+	report.QuarantineBytes += media.Size
+	for _, v := range media.Variants {
+		report.QuarantineBytes += v.Size
+	}
+	if err := uc.mediaRepo.Delete(ctx, media.ID); err != nil {
+		fail("delete media %d: %v", media.ID, err)
+	}
+}
+
+// StorageUsage sums the space taken by media files and their variants, and
+// by the files without a record that the last reconciliation found.
+func (uc *MediaUseCase) StorageUsage(ctx context.Context) (*entity.StorageUsageResponse, error) {
+	sizes, err := uc.mediaRepo.SizeByType(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := uc.mediaRepo.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &entity.StorageUsageResponse{
+		QuotaBytes: config.GetConf().Storage.QuotaMB * 1024 * 1024,
+		ByType:     sizes,
+		Files:      files,
+	}
+	for _, size := range sizes {
+		usage.TotalBytes += size
+	}
+	if last := uc.LastGCReport(); last != nil {
+		usage.OrphanBytes = last.RemainingOrphanBytes
+		usage.QuarantineBytes = last.QuarantineBytes
+		usage.TotalBytes += usage.OrphanBytes + usage.QuarantineBytes
+	}
+	if usage.QuotaBytes > 0 {
+		usage.Percent = int(min(usage.TotalBytes*100/usage.QuotaBytes, 100))
+	}
+	return usage, nil
+}
+
+// quarantine moves an object under quarantine/ in the same store.
+func quarantine(ctx context.Context, store storage.Storage, key string) error {
+	if err := copyObject(ctx, store, key, store, quarantinePrefix+key); err != nil {
+		return err
+	}
+	return store.Delete(ctx, key)
+}
+
+// uploadPrefixes are the directories uploads are stored under. Only they are
+// reconciled, as the local store also serves other static files.
+func uploadPrefixes() []string {
+	uploadPath := config.GetConf().App.UploadPath
+	if uploadPath == "" {
+		uploadPath = "uploads"
+	}
+	return []string{path.Clean(uploadPath) + "/", "avatar/"}
+}
@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/storage"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// gcMediaRepo serves the queries a reconciliation makes from memory.
+type gcMediaRepo struct {
+	MediaRepo
+	media  []entity.Media
+	linked map[string]bool
+}
+
+func (r *gcMediaRepo) List(context.Context) ([]entity.Media, error) { return r.media, nil }
+
+func (r *gcMediaRepo) LinkedKeys(context.Context) (map[string]bool, error) { return r.linked, nil }
+
+func (r *gcMediaRepo) ListUnused(context.Context, time.Time) ([]entity.Media, error) {
+	return nil, nil
+}
+
+func (r *gcMediaRepo) SizeByType(context.Context) (map[string]int64, error) {
+	sizes := map[string]int64{}
+	for _, m := range r.media {
+		sizes[m.Type] += m.Size
+	}
+	return sizes, nil
+}
+
+func (r *gcMediaRepo) Count(context.Context) (int64, error) { return int64(len(r.media)), nil }
+
+func writeOldFile(t *testing.T, dir, key string, size int) {
+	t.Helper()
+	name := filepath.Join(dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileKeepsLinkedOrphansAndCountsTheirBytes(t *testing.T) {
+	dir := t.TempDir()
+	writeOldFile(t, dir, "uploads/kept.png", 100)
+	writeOldFile(t, dir, "uploads/linked.png", 10)
+	writeOldFile(t, dir, "uploads/orphan.png", 20)
+	writeOldFile(t, dir, "quarantine/uploads/earlier.png", 5)
+
+	repo := &gcMediaRepo{
+		media:  []entity.Media{{ID: 1, Type: "image", Size: 100, Storage: "local", Path: "uploads/kept.png"}},
+		linked: map[string]bool{"uploads/linked.png": true},
+	}
+	uc := NewMediaUseCase(repo, nil, map[string]storage.Storage{"local": storage.NewLocal(storage.LocalConfig{Dir: dir})}, "local")
+
+	report, err := uc.ReconcileStorage(context.Background(), GCActionQuarantine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.OrphanFiles) != 2 || report.OrphanBytes != 30 {
+		t.Fatalf("orphans = %+v (%d bytes), want linked.png and orphan.png", report.OrphanFiles, report.OrphanBytes)
+	}
+	if report.Quarantined != 1 {
+		t.Errorf("quarantined %d files, want only orphan.png", report.Quarantined)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "linked.png")); err != nil {
+		t.Errorf("linked orphan was moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "quarantine", "uploads", "orphan.png")); err != nil {
+		t.Errorf("orphan was not quarantined: %v", err)
+	}
+	if report.RemainingOrphanBytes != 10 || report.QuarantineBytes != 25 {
+		t.Errorf("remaining orphan bytes %d, quarantine bytes %d, want 10 and 25",
+			report.RemainingOrphanBytes, report.QuarantineBytes)
+	}
+
+	config.Conf.Storage.QuotaMB = 0
+	usage, err := uc.StorageUsage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.TotalBytes != 135 || usage.OrphanBytes != 10 || usage.QuarantineBytes != 25 {
+		t.Errorf("usage = %+v, want 135 bytes in total", usage)
+	}
+}
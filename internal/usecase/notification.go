@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/mailer"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	outboxPollInterval = 15 * time.Second
+	outboxBatchSize    = 20
+	outboxMaxAttempts  = 8
+	outboxSendTimeout  = 30 * time.Second
+	// outboxClaimLease is how long a claimed message is reserved for the
+	// instance sending it; a crashed instance's messages are retried after.
+	outboxClaimLease = 5 * time.Minute
+	// outboxMaxBackoff caps the exponential retry delay (1m, 2m, 4m, ...).
+	outboxMaxBackoff = 6 * time.Hour
+	siteName         = "Voocel Journal"
+)
+
+// ErrInvalidUnsubscribeLink is returned when an unsubscribe signature does not verify.
+var ErrInvalidUnsubscribeLink = errors.New("invalid unsubscribe link")
+
+// NotificationUseCase renders notification emails into the mail outbox and
+// delivers the outbox in the background.
+type NotificationUseCase struct {
+	userRepo    UserRepo
+	postRepo    PostRepo
+	commentRepo CommentRepo
+	outboxRepo  MailOutboxRepo
+	mailer      mailer.Mailer
+	wake        chan struct{}
+}
+
+func NewNotificationUseCase(userRepo UserRepo, postRepo PostRepo, commentRepo CommentRepo, outboxRepo MailOutboxRepo, m mailer.Mailer) *NotificationUseCase {
+	return &NotificationUseCase{
+		userRepo:    userRepo,
+		postRepo:    postRepo,
+		commentRepo: commentRepo,
+		outboxRepo:  outboxRepo,
+		mailer:      m,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// CommentPublished queues emails for a comment that just became visible:
+// the author of the parent comment is told about a reply, and the post
+// author about a new comment. Nobody is notified about their own comment,
+// and nobody gets two emails for the same comment. Failures are logged only.
+func (uc *NotificationUseCase) CommentPublished(ctx context.Context, comment *entity.Comment) {
+	post, err := uc.postRepo.GetByID(ctx, comment.PostID)
+	if err != nil {
+		log.Warnw("Comment notification: load post failed", log.Pair("comment_id", comment.ID), log.Pair("error", err.Error()))
+		return
+	}
+	actor, err := uc.userRepo.GetByID(ctx, comment.UserID)
+	if err != nil {
+		log.Warnw("Comment notification: load commenter failed", log.Pair("comment_id", comment.ID), log.Pair("error", err.Error()))
+		return
+	}
+
+	notified := map[int64]bool{actor.ID: true}
+
+	if comment.ParentID != nil {
+		if parent, err := uc.commentRepo.GetByID(ctx, *comment.ParentID); err == nil && !notified[parent.UserID] {
+			if recipient, err := uc.userRepo.GetByID(ctx, parent.UserID); err == nil {
+				notified[recipient.ID] = true
+				uc.enqueue(ctx, mailKindCommentReply, recipient, actor, post, comment)
+			}
+		}
+	}
+
+	if post.AuthorID != 0 && !notified[post.AuthorID] {
+		if recipient, err := uc.userRepo.GetByID(ctx, post.AuthorID); err == nil {
+			uc.enqueue(ctx, mailKindPostComment, recipient, actor, post, comment)
+		}
+	}
+}
+
+func (uc *NotificationUseCase) enqueue(ctx context.Context, kind string, recipient, actor *entity.User, post *entity.Post, comment *entity.Comment) {
+	if !recipient.EmailNotifications || recipient.Status == "banned" || recipient.Email == "" {
+		return
+	}
+
+	unsubscribeURL := uc.UnsubscribeURL(recipient.ID)
+	data := commentMailData{
+		SiteName:       siteName,
+		RecipientName:  recipient.Username,
+		ActorName:      actor.Username,
+		PostTitle:      post.Title,
+		PostURL:        siteURL() + "/post/" + post.Slug + "#comment-" + strconv.FormatInt(comment.ID, 10),
+		Comment:        truncateRunes(comment.Content, 500),
+		UnsubscribeURL: unsubscribeURL,
+	}
+	// RFC 8058 one-click unsubscribe, honored by most mail clients.
+	headers := map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	if err := uc.queue(ctx, kind, recipient.Email, data, headers); err != nil {
+		log.Errorw("Queue notification email failed", log.Pair("kind", kind), log.Pair("error", err.Error()))
+	}
+}
+
+// PostPublished emails the author of a post that just went live, so a
+// scheduled post does not go out unnoticed. It is a post.published hook.
+func (uc *NotificationUseCase) PostPublished(ctx context.Context, event *entity.DomainEvent, post *entity.Post) error {
+	if post == nil || post.AuthorID == 0 {
+		return nil
+	}
+	author, err := uc.userRepo.GetByID(ctx, post.AuthorID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil
+		}
+		return err
+	}
+	if !author.EmailNotifications || author.Status == "banned" || author.Email == "" {
+		return nil
+	}
+
+	unsubscribeURL := uc.UnsubscribeURL(author.ID)
+	data := postMailData{
+		SiteName:       siteName,
+		RecipientName:  author.Username,
+		PostTitle:      post.Title,
+		PostURL:        siteURL() + "/post/" + post.Slug,
+		UnsubscribeURL: unsubscribeURL,
+	}
+	headers := map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return uc.queue(ctx, mailKindPostPublished, author.Email, data, headers)
+}
+
+// SendAccountEmail queues a transactional account email (password reset,
+// email verification). These ignore the notification preference.
+func (uc *NotificationUseCase) SendAccountEmail(ctx context.Context, kind string, user *entity.User, actionURL, expiresIn string) error {
+	return uc.queue(ctx, kind, user.Email, accountMailData{
+		SiteName:      siteName,
+		RecipientName: user.Username,
+		ActionURL:     actionURL,
+		ExpiresIn:     expiresIn,
+	}, nil)
+}
+
+// queue renders the template for kind and stores the message in the outbox.
+func (uc *NotificationUseCase) queue(ctx context.Context, kind, to string, data any, headers map[string]string) error {
+	subject, text, html, err := mailTemplates[kind].render(data)
+	if err != nil {
+		return err
+	}
+
+	mail := &entity.MailOutbox{
+		Kind:          kind,
+		To:            to,
+		Subject:       subject,
+		TextBody:      text,
+		HTMLBody:      html,
+		Status:        entity.MailStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if len(headers) > 0 {
+		encoded, _ := json.Marshal(headers)
+		mail.Headers = string(encoded)
+	}
+	if err := uc.outboxRepo.Create(ctx, mail); err != nil {
+		return err
+	}
+
+	// Nudge the worker so the email goes out without waiting for the next poll.
+	select {
+	case uc.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// UnsubscribeURL returns the signed one-click unsubscribe link for a user.
+func (uc *NotificationUseCase) UnsubscribeURL(userID int64) string {
+	q := url.Values{}
+	q.Set("u", strconv.FormatInt(userID, 10))
+	q.Set("sig", unsubscribeSignature(userID))
+	return siteURL() + "/api/v1/notifications/unsubscribe?" + q.Encode()
+}
+
+// VerifyUnsubscribe checks an unsubscribe link without changing anything.
+func (uc *NotificationUseCase) VerifyUnsubscribe(userID int64, sig string) error {
+	expected := unsubscribeSignature(userID)
+	if userID <= 0 || !hmac.Equal([]byte(sig), []byte(expected)) {
+		return ErrInvalidUnsubscribeLink
+	}
+	return nil
+}
+
+// Unsubscribe turns off email notifications for the user if the signature is valid.
+func (uc *NotificationUseCase) Unsubscribe(ctx context.Context, userID int64, sig string) error {
+	if err := uc.VerifyUnsubscribe(userID, sig); err != nil {
+		return err
+	}
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailNotifications {
+		return nil
+	}
+	user.EmailNotifications = false
+	return uc.userRepo.Update(ctx, user)
+}
+
+// unsubscribeSignature signs the user ID with the app secret so links cannot
+// be forged for other users. The link never expires, as mail clients may
+// follow it long after delivery.
+func unsubscribeSignature(userID int64) string {
+	mac := hmac.New(sha256.New, []byte(config.GetConf().App.JwtSecret))
+	mac.Write([]byte("unsubscribe:" + strconv.FormatInt(userID, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// RunOutbox delivers queued mail until ctx is cancelled.
+func (uc *NotificationUseCase) RunOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		uc.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-uc.wake:
+		}
+	}
+}
+
+func (uc *NotificationUseCase) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		mails, err := uc.outboxRepo.ListDue(ctx, time.Now(), outboxBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorw("Load mail outbox failed", log.Pair("error", err.Error()))
+			}
+			return
+		}
+		for i := range mails {
+			if ctx.Err() != nil {
+				return
+			}
+			// Another instance may be delivering the same message.
+			now := time.Now()
+			claimed, err := uc.outboxRepo.Claim(ctx, mails[i].ID, now, now.Add(outboxClaimLease))
+			if err != nil {
+				log.Warnw("Claim mail failed", log.Pair("mail_id", mails[i].ID), log.Pair("error", err.Error()))
+				continue
+			}
+			if claimed {
+				uc.deliver(ctx, &mails[i])
+			}
+		}
+		if len(mails) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (uc *NotificationUseCase) deliver(ctx context.Context, mail *entity.MailOutbox) {
+	msg := mailer.Message{
+		To:      mail.To,
+		Subject: mail.Subject,
+		Text:    mail.TextBody,
+		HTML:    mail.HTMLBody,
+	}
+	if mail.Headers != "" {
+		_ = json.Unmarshal([]byte(mail.Headers), &msg.Headers)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	err := uc.mailer.Send(sendCtx, msg)
+	cancel()
+
+	mail.Attempts++
+	if err == nil {
+		now := time.Now()
+		mail.Status = entity.MailStatusSent
+		mail.SentAt = &now
+		mail.LastError = ""
+	} else {
+		mail.LastError = truncateRunes(err.Error(), 500)
+		if mail.Attempts >= outboxMaxAttempts {
+			mail.Status = entity.MailStatusFailed
+		} else {
+			mail.Status = entity.MailStatusPending
+			mail.NextAttemptAt = time.Now().Add(outboxBackoff(mail.Attempts))
+		}
+		log.Warnw("Send mail failed",
+			log.Pair("mail_id", mail.ID),
+			log.Pair("attempts", mail.Attempts),
+			log.Pair("error", err.Error()),
+		)
+	}
+
+	// Use a fresh context so the result is recorded even during shutdown.
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := uc.outboxRepo.Update(saveCtx, mail); err != nil {
+		log.Errorw("Update mail outbox failed", log.Pair("mail_id", mail.ID), log.Pair("error", err.Error()))
+	}
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	d := time.Minute << (attempts - 1)
+	if d <= 0 || d > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return d
+}
+
+func siteURL() string {
+	return strings.TrimRight(config.GetConf().App.SiteURL, "/")
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Mail kinds, stored on the outbox row.
+const (
+	mailKindCommentReply  = "comment_reply"  // Someone replied to your comment
+	mailKindPostComment   = "post_comment"   // Someone commented on your post
+	mailKindPostPublished = "post_published" // Your scheduled post went live
+	mailKindPasswordReset = "password_reset"
+	mailKindVerifyEmail   = "verify_email"
+)
+
+// commentMailData is the data passed to the comment notification templates.
+type commentMailData struct {
+	SiteName       string
+	RecipientName  string
+	ActorName      string
+	PostTitle      string
+	PostURL        string
+	Comment        string
+	UnsubscribeURL string
+}
+
+// postMailData is the data passed to the post lifecycle templates.
+type postMailData struct {
+	SiteName       string
+	RecipientName  string
+	PostTitle      string
+	PostURL        string
+	UnsubscribeURL string
+}
+
+// accountMailData is the data passed to the account (transactional) templates.
+type accountMailData struct {
+	SiteName      string
+	RecipientName string
+	ActionURL     string
+	ExpiresIn     string // Human-readable lifetime of the link, e.g. "1 hour"
+}
+
+type mailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+func newMailTemplate(subject, text, html string) mailTemplate {
+	return mailTemplate{
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New("text").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New("html").Parse(html)),
+	}
+}
+
+// render returns the subject, plain-text body and HTML body.
+func (t mailTemplate) render(data any) (string, string, string, error) {
+	var subject, text, html strings.Builder
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return "", "", "", err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return "", "", "", err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return "", "", "", err
+	}
+	return strings.TrimSpace(subject.String()), text.String(), html.String(), nil
+}
+
+const mailFooterText = `
+--
+You received this email because you have notifications enabled on {{.SiteName}}.
+Unsubscribe: {{.UnsubscribeURL}}
+`
+
+const mailFooterHTML = `
+<hr style="border:none;border-top:1px solid #e5e5e5;margin:24px 0"/>
+<p style="color:#888;font-size:12px">You received this email because you have notifications enabled on {{.SiteName}}.
+<a href="{{.UnsubscribeURL}}" style="color:#888">Unsubscribe</a></p>`
+
+var mailTemplates = map[string]mailTemplate{
+	mailKindCommentReply: newMailTemplate(
+		`{{.ActorName}} replied to your comment on "{{.PostTitle}}"`,
+		`Hi {{.RecipientName}},
+
+{{.ActorName}} replied to your comment on "{{.PostTitle}}":
+
+{{.Comment}}
+
+Read the conversation: {{.PostURL}}
+`+mailFooterText,
+		`<div style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.6;color:#222;max-width:560px">
+<p>Hi {{.RecipientName}},</p>
+<p><strong>{{.ActorName}}</strong> replied to your comment on <a href="{{.PostURL}}">{{.PostTitle}}</a>:</p>
+<blockquote style="margin:0;padding:8px 16px;border-left:3px solid #ddd;color:#444;white-space:pre-wrap">{{.Comment}}</blockquote>
+<p><a href="{{.PostURL}}">Read the conversation</a></p>`+mailFooterHTML+`
+</div>`,
+	),
+	mailKindPostComment: newMailTemplate(
+		`New comment on "{{.PostTitle}}"`,
+		`Hi {{.RecipientName}},
+
+{{.ActorName}} commented on your post "{{.PostTitle}}":
+
+{{.Comment}}
+
+View it here: {{.PostURL}}
+`+mailFooterText,
+		`<div style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.6;color:#222;max-width:560px">
+<p>Hi {{.RecipientName}},</p>
+<p><strong>{{.ActorName}}</strong> commented on your post <a href="{{.PostURL}}">{{.PostTitle}}</a>:</p>
+<blockquote style="margin:0;padding:8px 16px;border-left:3px solid #ddd;color:#444;white-space:pre-wrap">{{.Comment}}</blockquote>
+<p><a href="{{.PostURL}}">View it on the site</a></p>`+mailFooterHTML+`
+</div>`,
+	),
+	mailKindPostPublished: newMailTemplate(
+		`Your post "{{.PostTitle}}" is live`,
+		`Hi {{.RecipientName}},
+
+Your post "{{.PostTitle}}" has just been published on {{.SiteName}}.
+
+Read it here: {{.PostURL}}
+`+mailFooterText,
+		`<div style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.6;color:#222;max-width:560px">
+<p>Hi {{.RecipientName}},</p>
+<p>Your post <a href="{{.PostURL}}">{{.PostTitle}}</a> has just been published on {{.SiteName}}.</p>
+<p><a href="{{.PostURL}}">Read it on the site</a></p>`+mailFooterHTML+`
+</div>`,
+	),
+	mailKindPasswordReset: newMailTemplate(
+		`Reset your {{.SiteName}} password`,
+		`Hi {{.RecipientName}},
+
+Someone asked to reset the password for your {{.SiteName}} account.
+Open this link to choose a new password (valid for {{.ExpiresIn}}):
+
+{{.ActionURL}}
+
+If you didn't ask for this, ignore this email; your password stays the same.
+`,
+		`<div style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.6;color:#222;max-width:560px">
+<p>Hi {{.RecipientName}},</p>
+<p>Someone asked to reset the password for your {{.SiteName}} account.</p>
+<p><a href="{{.ActionURL}}" style="display:inline-block;padding:10px 18px;background:#222;color:#fff;text-decoration:none;border-radius:6px">Choose a new password</a></p>
+<p style="color:#888;font-size:13px">The link is valid for {{.ExpiresIn}}. If you didn't ask for this, ignore this email; your password stays the same.</p>
+</div>`,
+	),
+	mailKindVerifyEmail: newMailTemplate(
+		`Confirm your email for {{.SiteName}}`,
+		`Hi {{.RecipientName}},
+
+Please confirm this email address for your {{.SiteName}} account (link valid for {{.ExpiresIn}}):
+
+{{.ActionURL}}
+`,
+		`<div style="font-family:-apple-system,Segoe UI,Helvetica,Arial,sans-serif;font-size:15px;line-height:1.6;color:#222;max-width:560px">
+<p>Hi {{.RecipientName}},</p>
+<p>Please confirm this email address for your {{.SiteName}} account.</p>
+<p><a href="{{.ActionURL}}" style="display:inline-block;padding:10px 18px;background:#222;color:#fff;text-decoration:none;border-radius:6px">Confirm email</a></p>
+<p style="color:#888;font-size:13px">The link is valid for {{.ExpiresIn}}.</p>
+</div>`,
+	),
+}
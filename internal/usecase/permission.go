@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"errors"
+)
+
+// ErrForbidden is returned when the user's role does not allow an action.
+var ErrForbidden = errors.New("forbidden")
+
+// Permission is an action in the admin panel a role may be granted.
+type Permission string
+
+const (
+	PermAdminAccess      Permission = "admin.access"      // Open the admin panel
+	PermPostsWrite       Permission = "posts.write"       // Create posts and edit one's own drafts
+	PermPostsEditAny     Permission = "posts.edit_any"    // Read, edit and delete any post
+	PermPostsPublish     Permission = "posts.publish"     // Publish posts
+	PermMediaUpload      Permission = "media.upload"      // Upload and browse media
+	PermMediaManage      Permission = "media.manage"      // Edit, delete and file any media
+	PermTaxonomyManage   Permission = "taxonomy.manage"   // Categories and tags
+	PermCommentsModerate Permission = "comments.moderate" // Comment queue and deletion
+	PermAnalyticsView    Permission = "analytics.view"    // Dashboard and traffic reports
+	PermUsersManage      Permission = "users.manage"      // Ban users and change roles
+	PermSettingsManage   Permission = "settings.manage"   // System events and storage maintenance
+)
+
+var authorPermissions = []Permission{
+	PermAdminAccess,
+	PermPostsWrite,
+	PermMediaUpload,
+}
+
+var editorPermissions = append([]Permission{
+	PermPostsEditAny,
+	PermPostsPublish,
+	PermMediaManage,
+	PermTaxonomyManage,
+	PermCommentsModerate,
+	PermAnalyticsView,
+}, authorPermissions...)
+
+var adminPermissions = append([]Permission{
+	PermUsersManage,
+	PermSettingsManage,
+}, editorPermissions...)
+
+// rolePermissions is the permission matrix. Visitors have no permissions.
+var rolePermissions = map[string]map[Permission]bool{
+	entity.RoleAuthor: permissionSet(authorPermissions),
+	entity.RoleEditor: permissionSet(editorPermissions),
+	entity.RoleAdmin:  permissionSet(adminPermissions),
+}
+
+func permissionSet(perms []Permission) map[Permission]bool {
+	set := make(map[Permission]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set
+}
+
+// HasPermission reports whether role grants perm.
+func HasPermission(role string, perm Permission) bool {
+	return rolePermissions[role][perm]
+}
+
+// IsStaffRole reports whether role may open the admin panel.
+func IsStaffRole(role string) bool {
+	return HasPermission(role, PermAdminAccess)
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	switch role {
+	case entity.RoleAdmin, entity.RoleEditor, entity.RoleAuthor, entity.RoleVisitor:
+		return true
+	}
+	return false
+}
@@ -2,10 +2,13 @@ package usecase
 
 import (
 	"blog/internal/entity"
-	"blog/pkg/geoip"
+	"blog/pkg/cache"
 	"blog/pkg/log"
+	"blog/pkg/markdown"
+	"blog/pkg/search"
 	"blog/pkg/util"
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -13,26 +16,47 @@ import (
 )
 
 type PostUseCase struct {
-	postRepo      PostRepo
-	categoryRepo  CategoryRepo
-	tagRepo       TagRepo
-	analyticsRepo AnalyticsRepo
+	postRepo     PostRepo
+	categoryRepo CategoryRepo
+	tagRepo      TagRepo
+	revisionRepo PostRevisionRepo
+	userRepo     UserRepo
+	seriesRepo   SeriesRepo
+	views        *ViewRecorder
+	related      *RelatedPosts
+	webhooks     *WebhookUseCase
+	cache        responseCache
 }
 
-func NewPostUseCase(postRepo PostRepo, categoryRepo CategoryRepo, tagRepo TagRepo, analyticsRepo AnalyticsRepo) *PostUseCase {
+func NewPostUseCase(postRepo PostRepo, categoryRepo CategoryRepo, tagRepo TagRepo, revisionRepo PostRevisionRepo, userRepo UserRepo, seriesRepo SeriesRepo, views *ViewRecorder, related *RelatedPosts, webhooks *WebhookUseCase, c cache.Cache) *PostUseCase {
 	return &PostUseCase{
-		postRepo:      postRepo,
-		categoryRepo:  categoryRepo,
-		tagRepo:       tagRepo,
-		analyticsRepo: analyticsRepo,
+		postRepo:     postRepo,
+		categoryRepo: categoryRepo,
+		tagRepo:      tagRepo,
+		revisionRepo: revisionRepo,
+		userRepo:     userRepo,
+		seriesRepo:   seriesRepo,
+		views:        views,
+		related:      related,
+		webhooks:     webhooks,
+		cache:        newResponseCache(c),
 	}
 }
 
-func (uc *PostUseCase) Create(ctx context.Context, req entity.CreatePostRequest, author string) error {
+// Create saves a new post owned by the editor, or by req.AuthorID when an
+// editor assigns it to someone else. Authors can only create drafts.
+func (uc *PostUseCase) Create(ctx context.Context, req entity.CreatePostRequest, editor entity.Editor) error {
 	status, err := normalizePostStatus(req.Status)
 	if err != nil {
 		return err
 	}
+	if status == "published" && !HasPermission(editor.Role, PermPostsPublish) {
+		return fmt.Errorf("%w: your role cannot publish posts", ErrForbidden)
+	}
+	authorID, authorName, err := uc.resolveAuthor(ctx, req.AuthorID, editor)
+	if err != nil {
+		return err
+	}
 
 	if strings.TrimSpace(req.Excerpt) == "" {
 		req.Excerpt = deriveExcerpt(req.Content, 180)
@@ -42,6 +66,10 @@ func (uc *PostUseCase) Create(ctx context.Context, req entity.CreatePostRequest,
 	if err != nil {
 		return err
 	}
+	unpublishAt, err := normalizeUnpublishAt(req.UnpublishAt)
+	if err != nil {
+		return err
+	}
 
 	// Generate slug if not provided
 	slug := strings.TrimSpace(req.Slug)
@@ -59,15 +87,21 @@ func (uc *PostUseCase) Create(ctx context.Context, req entity.CreatePostRequest,
 		Slug:       slug,
 		Excerpt:    req.Excerpt,
 		Content:    req.Content,
-		Author:     author,
+		Author:     authorName,
+		AuthorID:   authorID,
 		PublishAt:  publishAt,
 		CategoryID: req.CategoryID,
 		Cover:      req.Cover,
 		Status:     status,
 		Views:      0,
 	}
+	post.UnpublishAt = unpublishAt
+	if err := validateUnpublishAt(post, time.Now()); err != nil {
+		return err
+	}
 
-	if err := uc.postRepo.CreateWithTags(ctx, post, req.Tags); err != nil {
+	rev := newRevision(post, req.Tags, editor, revisionActionCreate, 0)
+	if err := uc.postRepo.CreateWithTags(ctx, post, req.Tags, rev); err != nil {
 		return err
 	}
 
@@ -79,6 +113,12 @@ func (uc *PostUseCase) Create(ctx context.Context, req entity.CreatePostRequest,
 		)
 	}
 
+	uc.related.Schedule()
+	uc.webhooks.PostChanged(ctx, entity.WebhookEventPostCreated, post)
+
+	uc.cache.bump(ctx, cacheNSPostLists)
+	uc.cache.delete(ctx, cacheKeyCategories)
+
 	return nil
 }
 
@@ -108,97 +148,91 @@ func (uc *PostUseCase) GetByID(ctx context.Context, id int64) (*entity.PostRespo
 	return uc.assemblePostResponse(ctx, post)
 }
 
-// GetBySlug retrieves a post by slug
+// GetBySlug retrieves a post by slug. The response is cached whatever the
+// post's status; callers check status and publish time on every request.
+// The view count is read live, as it changes with every visit.
 func (uc *PostUseCase) GetBySlug(ctx context.Context, slug string) (*entity.PostResponse, error) {
+	key := uc.cache.key(ctx, cacheNSPost, slug)
+	var cached entity.PostResponse
+	if uc.cache.get(ctx, key, &cached) {
+		views, err := uc.postRepo.GetViews(ctx, cached.ID)
+		if err != nil {
+			log.Warnw("Load post views failed", log.Pair("post_id", cached.ID), log.Pair("error", err.Error()))
+		} else {
+			cached.Views = views
+		}
+		return &cached, nil
+	}
+
 	post, err := uc.postRepo.GetBySlug(ctx, slug)
 	if err != nil {
 		return nil, err
 	}
 
-	return uc.assemblePostResponse(ctx, post)
+	resp, err := uc.assemblePostResponse(ctx, post)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Series != nil {
+		// The series block changes when another part goes live.
+		uc.setPublicCache(ctx, key, resp)
+	} else {
+		uc.cache.set(ctx, key, resp, uc.cache.ttl(nil))
+	}
+	return resp, nil
 }
 
-// GetBySlugWithAnalytics retrieves a post by slug and logs the visit (used by public API)
-func (uc *PostUseCase) GetBySlugWithAnalytics(ctx context.Context, slug, ip, userAgent string) (*entity.PostResponse, error) {
-	post, err := uc.postRepo.GetBySlug(ctx, slug)
+// GetBySlugWithAnalytics retrieves a post by slug and records the view (used by public API).
+// Without track only the view count is incremented; no analytics row is kept.
+// Views by bots (botReason set) are logged as such but not counted.
+func (uc *PostUseCase) GetBySlugWithAnalytics(ctx context.Context, slug, ip, userAgent string, track bool, botReason string) (*entity.PostResponse, error) {
+	post, err := uc.GetBySlug(ctx, slug)
 	if err != nil {
 		return nil, err
 	}
 
-	// Increment views (async with timeout)
-	util.SafeGo(func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := uc.postRepo.IncrementViews(bgCtx, post.ID); err != nil {
-			log.Warnw("Failed to increment views",
-				log.Pair("post_id", post.ID),
-				log.Pair("error", err.Error()),
-			)
-		}
-	})
-
-	// Log visit (async) - use slug in PagePath for SEO-friendly URLs
-	util.SafeGo(func() {
-		uc.logVisit(post.ID, post.Slug, post.Title, ip, userAgent)
-	})
+	// Use slug in PagePath for SEO-friendly URLs
+	uc.recordView(post.ID, post.Slug, post.Title, ip, userAgent, track, botReason)
 
-	return uc.assemblePostResponse(ctx, post)
+	return post, nil
 }
 
-// GetByIDWithAnalytics retrieves a post by ID and logs the visit
-func (uc *PostUseCase) GetByIDWithAnalytics(ctx context.Context, id int64, ip, userAgent string) (*entity.PostResponse, error) {
+// GetByIDWithAnalytics retrieves a post by ID and records the view
+func (uc *PostUseCase) GetByIDWithAnalytics(ctx context.Context, id int64, ip, userAgent string, track bool, botReason string) (*entity.PostResponse, error) {
 	post, err := uc.postRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Increment views (async with timeout)
-	util.SafeGo(func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := uc.postRepo.IncrementViews(bgCtx, id); err != nil {
-			log.Warnw("Failed to increment views",
-				log.Pair("post_id", id),
-				log.Pair("error", err.Error()),
-			)
-		}
-	})
-
-	// Log visit (async)
-	util.SafeGo(func() {
-		uc.logVisit(id, post.Slug, post.Title, ip, userAgent)
-	})
+	uc.recordView(id, post.Slug, post.Title, ip, userAgent, track, botReason)
 
 	return uc.assemblePostResponse(ctx, post)
 }
 
-// logVisit records a page visit to analytics
-func (uc *PostUseCase) logVisit(postID int64, postSlug, postTitle, ip, userAgent string) {
-	if uc.analyticsRepo == nil {
+// recordView counts a human post view and, when tracking, queues its
+// analytics row; both are written in the next batch.
+func (uc *PostUseCase) recordView(postID int64, postSlug, postTitle, ip, userAgent string, track bool, botReason string) {
+	if uc.views == nil {
 		return
 	}
 
-	location := geoip.Lookup(ip)
-
 	pID := postID
-	analyticsLog := &entity.Analytics{
+	if botReason == "" {
+		uc.views.RecordView(postID)
+	}
+	if !track {
+		return
+	}
+	uc.views.RecordVisit(entity.Analytics{
 		PagePath:  "/post/" + postSlug,
 		PostID:    &pID,
 		PostTitle: postTitle,
 		IP:        ip,
-		Location:  location,
 		UserAgent: userAgent,
 		Timestamp: time.Now().Unix(),
-	}
-
-	bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := uc.analyticsRepo.Create(bgCtx, analyticsLog); err != nil {
-		log.Warnw("Failed to create post visit analytics",
-			log.Pair("post_id", postID),
-			log.Pair("error", err.Error()),
-		)
-	}
+		Bot:       botReason != "",
+		BotReason: botReason,
+	})
 }
 
 // ensureUniqueSlug ensures the slug is unique by appending a number suffix if needed
@@ -218,37 +252,29 @@ func (uc *PostUseCase) ensureUniqueSlug(ctx context.Context, baseSlug string, ex
 }
 
 // LogHomeVisit records a homepage visit to analytics
-func (uc *PostUseCase) LogHomeVisit(ip, userAgent string) {
-	if uc.analyticsRepo == nil {
+func (uc *PostUseCase) LogHomeVisit(ip, userAgent, botReason string) {
+	if uc.views == nil {
 		return
 	}
 
-	location := geoip.Lookup(ip)
-
-	analyticsLog := &entity.Analytics{
+	uc.views.RecordVisit(entity.Analytics{
 		PagePath:  "/",
 		IP:        ip,
-		Location:  location,
 		UserAgent: userAgent,
 		Timestamp: time.Now().Unix(),
-	}
+		Bot:       botReason != "",
+		BotReason: botReason,
+	})
+}
 
-	bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := uc.analyticsRepo.Create(bgCtx, analyticsLog); err != nil {
-		log.Warnw("Failed to create home visit analytics",
-			log.Pair("error", err.Error()),
-		)
-	}
+// cachedPostList is a page of post responses as stored in the cache.
+type cachedPostList struct {
+	Data  []entity.PostResponse
+	Total int64
 }
 
 func (uc *PostUseCase) List(ctx context.Context, filters map[string]interface{}, page, limit int) (interface{}, error) {
-	posts, total, err := uc.postRepo.List(ctx, filters, page, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	responses, err := uc.assemblePostResponsesBatch(ctx, posts)
+	responses, total, err := uc.listResponses(ctx, filters, page, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -271,6 +297,181 @@ func (uc *PostUseCase) List(ctx context.Context, filters map[string]interface{},
 	return responses, nil
 }
 
+// listResponses loads a page of posts. Public listings (published posts up to
+// now) are cached; anything else, such as the admin listing, is not.
+func (uc *PostUseCase) listResponses(ctx context.Context, filters map[string]interface{}, page, limit int) ([]entity.PostResponse, int64, error) {
+	key, cacheable := publicListCacheKey(filters, page, limit)
+	if cacheable {
+		key = uc.cache.key(ctx, cacheNSPostLists, key)
+		var cached cachedPostList
+		if uc.cache.get(ctx, key, &cached) {
+			return cached.Data, cached.Total, nil
+		}
+	}
+
+	posts, total, err := uc.postRepo.List(ctx, filters, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	responses, err := uc.assemblePostResponsesBatch(ctx, posts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if cacheable {
+		uc.setPublicCache(ctx, key, cachedPostList{Data: responses, Total: total})
+	}
+	return responses, total, nil
+}
+
+// publicListCacheKey returns the cache key suffix for a public listing, which
+// filters on published status and publish time; other filters are not cached.
+func publicListCacheKey(filters map[string]interface{}, page, limit int) (string, bool) {
+	if filters["status"] != "published" {
+		return "", false
+	}
+	var categoryID, authorID int64
+	var search string
+	for k, v := range filters {
+		switch k {
+		case "status", "beforePublishAt":
+		case "categoryId":
+			categoryID, _ = v.(int64)
+		case "authorId":
+			authorID, _ = v.(int64)
+		case "search":
+			search, _ = v.(string)
+		default:
+			return "", false
+		}
+	}
+	if _, ok := filters["beforePublishAt"].(time.Time); !ok {
+		return "", false
+	}
+	return fmt.Sprintf("list:%d:%d:%d:%d:%q", categoryID, authorID, page, limit, search), true
+}
+
+// setPublicCache caches a response that depends on which posts are live. It
+// expires when the next scheduled post goes live, so that post shows up on
+// time rather than after the TTL.
+func (uc *PostUseCase) setPublicCache(ctx context.Context, key string, v any) {
+	next, err := uc.postRepo.NextPublishAt(ctx, time.Now())
+	if err != nil {
+		log.Warnw("Load next scheduled post failed", log.Pair("error", err.Error()))
+		return
+	}
+	uc.cache.set(ctx, key, v, uc.cache.ttl(next))
+}
+
+// Related returns up to limit live posts most similar to the live post with
+// the slug, from the scores RelatedPosts precomputes.
+func (uc *PostUseCase) Related(ctx context.Context, slug string, limit int) ([]entity.PostResponse, error) {
+	key := uc.cache.key(ctx, cacheNSPostLists, fmt.Sprintf("related:%s:%d", slug, limit))
+	var cached []entity.PostResponse
+	if uc.cache.get(ctx, key, &cached) {
+		return cached, nil
+	}
+
+	now := time.Now()
+	post, err := uc.postRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if !isLive(post, now) {
+		return nil, errors.New("post not found")
+	}
+
+	posts, err := uc.related.List(ctx, post.ID, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := uc.assemblePostResponsesBatch(ctx, posts)
+	if err != nil {
+		return nil, err
+	}
+	uc.setPublicCache(ctx, key, responses)
+	return responses, nil
+}
+
+// maxSearchQueryRunes bounds the length of a public search query.
+const maxSearchQueryRunes = 100
+
+// Search returns published, already-live posts ranked by relevance to query,
+// with highlighted title and content snippet for each hit.
+func (uc *PostUseCase) Search(ctx context.Context, query string, page, limit int) (*entity.PaginatedSearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: search query cannot be empty", ErrInvalidArgument)
+	}
+	if len([]rune(query)) > maxSearchQueryRunes {
+		return nil, fmt.Errorf("%w: search query exceeds %d characters", ErrInvalidArgument, maxSearchQueryRunes)
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	key := uc.cache.key(ctx, cacheNSPostLists, fmt.Sprintf("search:%d:%d:%q", page, limit, strings.ToLower(query)))
+	var cached entity.PaginatedSearchResponse
+	if uc.cache.get(ctx, key, &cached) {
+		return &cached, nil
+	}
+
+	filters := map[string]interface{}{
+		"status":          "published",
+		"beforePublishAt": time.Now(),
+	}
+	hits, total, err := uc.postRepo.Search(ctx, query, filters, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := make([]entity.Post, len(hits))
+	for i := range hits {
+		posts[i] = hits[i].Post
+	}
+	assembled, err := uc.assemblePostResponsesBatch(ctx, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := search.Terms(query)
+	results := make([]entity.SearchResult, 0, len(hits))
+	for i, p := range assembled {
+		snippetSource := markdown.ToPlainText(p.Content)
+		if snippetSource == "" {
+			snippetSource = p.Excerpt
+		}
+		results = append(results, entity.SearchResult{
+			ID:        p.ID,
+			Slug:      p.Slug,
+			Title:     search.Highlight(p.Title, terms),
+			Snippet:   search.Snippet(snippetSource, terms, 160),
+			Cover:     p.Cover,
+			Category:  p.Category,
+			Tags:      p.Tags,
+			PublishAt: p.PublishAt,
+			ReadTime:  p.ReadTime,
+			Score:     hits[i].Rank,
+		})
+	}
+
+	resp := &entity.PaginatedSearchResponse{
+		Query: query,
+		Data:  results,
+		Pagination: entity.Pagination{
+			Total:      int(total),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+		},
+	}
+	uc.setPublicCache(ctx, key, resp)
+	return resp, nil
+}
+
 func (uc *PostUseCase) assemblePostResponsesBatch(ctx context.Context, posts []entity.Post) ([]entity.PostResponse, error) {
 	if len(posts) == 0 {
 		return []entity.PostResponse{}, nil
@@ -334,20 +535,22 @@ func (uc *PostUseCase) assemblePostResponsesBatch(ctx context.Context, posts []e
 	for i := range posts {
 		p := posts[i]
 		resp := entity.PostResponse{
-			ID:         p.ID,
-			Slug:       p.Slug,
-			Title:      p.Title,
-			Excerpt:    p.Excerpt,
-			Content:    p.Content,
-			Author:     p.Author,
-			PublishAt:  p.PublishAt,
-			CategoryID: p.CategoryID,
-			Category:   categoryNameByID[p.CategoryID],
-			ReadTime:   calculateReadTime(p.Content),
-			Cover:      p.Cover,
-			Views:      p.Views,
-			Status:     p.Status,
-			Tags:       []string{},
+			ID:          p.ID,
+			Slug:        p.Slug,
+			Title:       p.Title,
+			Excerpt:     p.Excerpt,
+			Content:     p.Content,
+			Author:      p.Author,
+			AuthorID:    p.AuthorID,
+			PublishAt:   p.PublishAt,
+			UnpublishAt: p.UnpublishAt,
+			CategoryID:  p.CategoryID,
+			Category:    categoryNameByID[p.CategoryID],
+			ReadTime:    calculateReadTime(p.Content),
+			Cover:       p.Cover,
+			Views:       p.Views,
+			Status:      p.Status,
+			Tags:        []string{},
 		}
 
 		for _, tagID := range tagIDsByPostID[p.ID] {
@@ -360,11 +563,20 @@ func (uc *PostUseCase) assemblePostResponsesBatch(ctx context.Context, posts []e
 	return responses, nil
 }
 
-func (uc *PostUseCase) Update(ctx context.Context, id int64, req entity.UpdatePostRequest) error {
+func (uc *PostUseCase) Update(ctx context.Context, id int64, req entity.UpdatePostRequest, editor entity.Editor) error {
+	return uc.update(ctx, id, req, editor, revisionActionUpdate, 0)
+}
+
+// update applies req to the post and records a revision with the given action.
+func (uc *PostUseCase) update(ctx context.Context, id int64, req entity.UpdatePostRequest, editor entity.Editor, action string, restoredFrom int) error {
 	post, err := uc.postRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if err := authorizePostEdit(post, editor); err != nil {
+		return err
+	}
+	oldSlug, oldCategoryID := post.Slug, post.CategoryID
 
 	// Update fields
 	if req.Title != "" {
@@ -381,22 +593,30 @@ func (uc *PostUseCase) Update(ctx context.Context, id int64, req entity.UpdatePo
 			post.Slug = slug
 		}
 	}
-	if req.Excerpt != "" {
-		post.Excerpt = req.Excerpt
+	if req.Excerpt != nil {
+		post.Excerpt = *req.Excerpt
 	}
 	if req.Content != "" {
 		post.Content = req.Content
 	}
-	if req.Cover != "" {
-		post.Cover = req.Cover
+	if req.Cover != nil {
+		post.Cover = strings.TrimSpace(*req.Cover)
 	}
 	if req.Status != "" {
 		status, err := normalizePostStatus(req.Status)
 		if err != nil {
 			return err
 		}
+		if status == "published" && !HasPermission(editor.Role, PermPostsPublish) {
+			return fmt.Errorf("%w: your role cannot publish posts", ErrForbidden)
+		}
 		post.Status = status
 	}
+	if req.AuthorID != 0 && req.AuthorID != post.AuthorID {
+		if post.AuthorID, post.Author, err = uc.resolveAuthor(ctx, req.AuthorID, editor); err != nil {
+			return err
+		}
+	}
 	if req.PublishAt != "" {
 		publishAt, err := normalizePublishAt(req.PublishAt, time.Now())
 		if err != nil {
@@ -404,6 +624,14 @@ func (uc *PostUseCase) Update(ctx context.Context, id int64, req entity.UpdatePo
 		}
 		post.PublishAt = publishAt
 	}
+	if req.UnpublishAt != nil {
+		if post.UnpublishAt, err = normalizeUnpublishAt(*req.UnpublishAt); err != nil {
+			return err
+		}
+	}
+	if err := validateUnpublishAt(post, time.Now()); err != nil {
+		return err
+	}
 
 	// Handle category change
 	if req.CategoryID != 0 && req.CategoryID != post.CategoryID {
@@ -422,29 +650,87 @@ func (uc *PostUseCase) Update(ctx context.Context, id int64, req entity.UpdatePo
 		post.CategoryID = req.CategoryID
 	}
 
-	// Update post (and tags optionally)
-	if req.Tags != nil {
-		if err := uc.postRepo.UpdateWithTags(ctx, post, req.Tags); err != nil {
-			return err
-		}
-	} else {
-		if err := uc.postRepo.Update(ctx, post); err != nil {
+	// Update post (and tags optionally) together with its revision
+	tagIDs := req.Tags
+	if tagIDs == nil {
+		if tagIDs, err = uc.postRepo.GetTagIDs(ctx, post.ID); err != nil {
 			return err
 		}
 	}
+	rev := newRevision(post, tagIDs, editor, action, restoredFrom)
+	if err := uc.postRepo.UpdateWithTags(ctx, post, req.Tags, rev); err != nil {
+		return err
+	}
+
+	uc.related.Schedule()
+	uc.webhooks.PostChanged(ctx, entity.WebhookEventPostUpdated, post)
+
+	uc.invalidatePost(ctx, oldSlug, post.Slug)
+	uc.invalidateSeries(ctx, post.ID)
+	if post.CategoryID != oldCategoryID {
+		uc.cache.delete(ctx, cacheKeyCategories)
+	}
 
 	return nil
 }
 
-func (uc *PostUseCase) Delete(ctx context.Context, id int64) error {
+// invalidatePost drops the cached post under each of its slugs and every
+// cached listing, which may include it.
+func (uc *PostUseCase) invalidatePost(ctx context.Context, slugs ...string) {
+	keys := make([]string, 0, len(slugs))
+	for _, slug := range slugs {
+		keys = append(keys, uc.cache.key(ctx, cacheNSPost, slug))
+	}
+	uc.cache.delete(ctx, keys...)
+	uc.cache.bump(ctx, cacheNSPostLists)
+}
+
+// HandleLifecycleEvent refreshes what depends on a post going live or
+// expiring: its cached page and lists, its series and related posts. It is
+// a post.published and post.unpublished hook.
+func (uc *PostUseCase) HandleLifecycleEvent(ctx context.Context, event *entity.DomainEvent, post *entity.Post) error {
+	if post == nil {
+		return nil
+	}
+	uc.invalidatePost(ctx, post.Slug)
+	uc.invalidateSeries(ctx, post.ID)
+	uc.related.Schedule()
+	return nil
+}
+
+// invalidateSeries drops every cached post when the post is part of a
+// series, as the other parts link to it.
+func (uc *PostUseCase) invalidateSeries(ctx context.Context, postID int64) {
+	members, err := uc.seriesRepo.GetMembership(ctx, []int64{postID})
+	if err != nil {
+		log.Warnw("Load post series failed",
+			log.Pair("post_id", postID),
+			log.Pair("error", err.Error()),
+		)
+		return
+	}
+	if len(members) > 0 {
+		uc.cache.bump(ctx, cacheNSPost)
+	}
+}
+
+func (uc *PostUseCase) Delete(ctx context.Context, id int64, editor entity.Editor) error {
 	post, err := uc.postRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if err := authorizePostEdit(post, editor); err != nil {
+		return err
+	}
 
+	uc.invalidateSeries(ctx, post.ID)
 	if err := uc.postRepo.Delete(ctx, id); err != nil {
 		return err
 	}
+	uc.invalidatePost(ctx, post.Slug)
+	uc.cache.delete(ctx, cacheKeyCategories)
+	uc.related.Schedule()
+	uc.webhooks.PostChanged(ctx, entity.WebhookEventPostDeleted, post)
 
 	if err := uc.categoryRepo.DecrementCount(ctx, post.CategoryID); err != nil {
 		log.Warnw("Decrement category count failed",
@@ -458,25 +744,36 @@ func (uc *PostUseCase) Delete(ctx context.Context, id int64) error {
 
 func (uc *PostUseCase) assemblePostResponse(ctx context.Context, post *entity.Post) (*entity.PostResponse, error) {
 	resp := &entity.PostResponse{
-		ID:         post.ID,
-		Slug:       post.Slug,
-		Title:      post.Title,
-		Excerpt:    post.Excerpt,
-		Content:    post.Content,
-		Author:     post.Author,
-		PublishAt:  post.PublishAt,
-		CategoryID: post.CategoryID,
-		Cover:      post.Cover,
-		Views:      post.Views,
-		Status:     post.Status,
-		ReadTime:   calculateReadTime(post.Content),
-		Tags:       []string{},
+		ID:          post.ID,
+		Slug:        post.Slug,
+		Title:       post.Title,
+		Excerpt:     post.Excerpt,
+		Content:     post.Content,
+		Author:      post.Author,
+		AuthorID:    post.AuthorID,
+		PublishAt:   post.PublishAt,
+		UnpublishAt: post.UnpublishAt,
+		CategoryID:  post.CategoryID,
+		Cover:       post.Cover,
+		Views:       post.Views,
+		Status:      post.Status,
+		ReadTime:    calculateReadTime(post.Content),
+		Tags:        []string{},
 	}
 
 	if category, err := uc.categoryRepo.GetByID(ctx, post.CategoryID); err == nil {
 		resp.Category = category.Name
 	}
 
+	series, err := seriesBlock(ctx, uc.seriesRepo, uc.postRepo, post.ID)
+	if err != nil {
+		log.Warnw("Load post series failed",
+			log.Pair("post_id", post.ID),
+			log.Pair("error", err.Error()),
+		)
+	}
+	resp.Series = series
+
 	if tagIDs, err := uc.postRepo.GetTagIDs(ctx, post.ID); err == nil && len(tagIDs) > 0 {
 		if tags, err := uc.tagRepo.GetByIDs(ctx, tagIDs); err == nil {
 			for _, tag := range tags {
@@ -547,3 +844,39 @@ func normalizePublishAt(input string, now time.Time) (time.Time, error) {
 	}
 	return time.Time{}, fmt.Errorf("%w: invalid publishAt: expected RFC3339 time, e.g. 2025-12-14T16:30:00+08:00", ErrInvalidArgument)
 }
+
+// isLive reports whether the post is published and inside its publish
+// window at now, matching the repository's live filter.
+func isLive(post *entity.Post, now time.Time) bool {
+	return post.Status == "published" && !post.PublishAt.After(now) &&
+		(post.UnpublishAt == nil || post.UnpublishAt.After(now))
+}
+
+// normalizeUnpublishAt parses an optional RFC3339 expiry time; empty means none.
+func normalizeUnpublishAt(input string) (*time.Time, error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid unpublishAt: expected RFC3339 time, e.g. 2025-12-31T23:59:59+08:00", ErrInvalidArgument)
+	}
+	return &t, nil
+}
+
+// validateUnpublishAt checks that a post expires after it goes live, and that
+// a published post does not expire in the past, which would unpublish it at
+// once.
+func validateUnpublishAt(post *entity.Post, now time.Time) error {
+	if post.UnpublishAt == nil {
+		return nil
+	}
+	if !post.UnpublishAt.After(post.PublishAt) {
+		return fmt.Errorf("%w: unpublishAt must be after publishAt", ErrInvalidArgument)
+	}
+	if post.Status == "published" && !post.UnpublishAt.After(now) {
+		return fmt.Errorf("%w: unpublishAt is in the past", ErrInvalidArgument)
+	}
+	return nil
+}
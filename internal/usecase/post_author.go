@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// authorizePostEdit enforces ownership: without posts.edit_any a user may
+// only change their own drafts.
+func authorizePostEdit(post *entity.Post, editor entity.Editor) error {
+	if HasPermission(editor.Role, PermPostsEditAny) {
+		return nil
+	}
+	if !HasPermission(editor.Role, PermPostsWrite) || post.AuthorID != editor.ID {
+		return fmt.Errorf("%w: you can only edit your own posts", ErrForbidden)
+	}
+	if post.Status != "draft" {
+		return fmt.Errorf("%w: published posts can only be changed by an editor", ErrForbidden)
+	}
+	return nil
+}
+
+// CheckPostAccess reports whether the editor may see the post in the admin
+// panel: users without posts.edit_any only see their own posts.
+func (uc *PostUseCase) CheckPostAccess(ctx context.Context, id int64, editor entity.Editor) error {
+	post, err := uc.postRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !HasPermission(editor.Role, PermPostsEditAny) && post.AuthorID != editor.ID {
+		return fmt.Errorf("%w: you can only view your own posts", ErrForbidden)
+	}
+	return nil
+}
+
+// resolveAuthor returns the ID and byline of the post's author: the editor
+// by default, or another staff member when the editor may assign posts.
+func (uc *PostUseCase) resolveAuthor(ctx context.Context, authorID int64, editor entity.Editor) (int64, string, error) {
+	if authorID == 0 || authorID == editor.ID {
+		return editor.ID, editor.Username, nil
+	}
+	if !HasPermission(editor.Role, PermPostsEditAny) {
+		return 0, "", fmt.Errorf("%w: you cannot assign posts to other authors", ErrForbidden)
+	}
+	user, err := uc.userRepo.GetByID(ctx, authorID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return 0, "", fmt.Errorf("%w: author %d not found", ErrInvalidArgument, authorID)
+		}
+		return 0, "", err
+	}
+	if !IsStaffRole(user.Role) {
+		return 0, "", fmt.Errorf("%w: user %d is not an author", ErrInvalidArgument, authorID)
+	}
+	return user.ID, user.Username, nil
+}
+
+// ListAuthors returns everyone with live posts, most prolific first.
+func (uc *PostUseCase) ListAuthors(ctx context.Context) ([]entity.AuthorResponse, error) {
+	counts, err := uc.postRepo.CountPublishedByAuthor(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	users, err := uc.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make([]entity.AuthorResponse, 0, len(users))
+	for i := range users {
+		authors = append(authors, toAuthorResponse(&users[i], counts[users[i].ID]))
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].PostCount != authors[j].PostCount {
+			return authors[i].PostCount > authors[j].PostCount
+		}
+		return authors[i].Username < authors[j].Username
+	})
+	return authors, nil
+}
+
+// GetAuthorPage returns an author's public profile with a page of their live
+// posts. Users who never had a staff role and have no posts have no page.
+func (uc *PostUseCase) GetAuthorPage(ctx context.Context, id int64, page, limit int) (*entity.AuthorPageResponse, error) {
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	filters := map[string]interface{}{
+		"status":          "published",
+		"beforePublishAt": time.Now(),
+		"authorId":        user.ID,
+	}
+	posts, total, err := uc.listResponses(ctx, filters, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 && !IsStaffRole(user.Role) {
+		return nil, errors.New("author not found")
+	}
+
+	return &entity.AuthorPageResponse{
+		Author: toAuthorResponse(user, total),
+		Data:   posts,
+		Pagination: entity.Pagination{
+			Total:      int(total),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+		},
+	}, nil
+}
+
+func toAuthorResponse(user *entity.User, postCount int64) entity.AuthorResponse {
+	return entity.AuthorResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Bio:       user.Bio,
+		Avatar:    user.Avatar,
+		PostCount: postCount,
+	}
+}
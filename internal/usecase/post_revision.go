@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/diff"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	revisionActionCreate  = "create"
+	revisionActionUpdate  = "update"
+	revisionActionRestore = "restore"
+)
+
+// newRevision snapshots the post as it is about to be saved. The post
+// repository stores it in the same transaction as the post, so every save
+// has a revision.
+func newRevision(post *entity.Post, tagIDs []int64, editor entity.Editor, action string, restoredFrom int) *entity.PostRevision {
+	return &entity.PostRevision{
+		PostID:       post.ID,
+		Title:        post.Title,
+		Slug:         post.Slug,
+		Excerpt:      post.Excerpt,
+		Content:      post.Content,
+		Cover:        post.Cover,
+		CategoryID:   post.CategoryID,
+		TagIDs:       joinIDs(tagIDs),
+		Status:       post.Status,
+		PublishAt:    post.PublishAt,
+		EditorID:     editor.ID,
+		EditorName:   editor.Username,
+		Action:       action,
+		RestoredFrom: restoredFrom,
+	}
+}
+
+// ListRevisions returns the revision history of a post, newest first.
+func (uc *PostUseCase) ListRevisions(ctx context.Context, postID int64) ([]entity.PostRevisionSummary, error) {
+	if _, err := uc.postRepo.GetByID(ctx, postID); err != nil {
+		return nil, err
+	}
+	revs, err := uc.revisionRepo.ListByPostID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]entity.PostRevisionSummary, 0, len(revs))
+	for _, r := range revs {
+		resp = append(resp, entity.PostRevisionSummary{
+			ID:           r.ID,
+			Revision:     r.Revision,
+			Title:        r.Title,
+			Status:       r.Status,
+			EditorID:     r.EditorID,
+			EditorName:   r.EditorName,
+			Action:       r.Action,
+			RestoredFrom: r.RestoredFrom,
+			CreatedAt:    r.CreatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// GetRevision returns a single revision including its content.
+func (uc *PostUseCase) GetRevision(ctx context.Context, postID int64, revision int) (*entity.PostRevisionResponse, error) {
+	rev, err := uc.revisionRepo.GetByRevision(ctx, postID, revision)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.PostRevisionResponse{PostRevision: *rev, Tags: splitIDs(rev.TagIDs)}, nil
+}
+
+// DiffRevisions returns a unified diff turning revision `from` into revision `to`.
+func (uc *PostUseCase) DiffRevisions(ctx context.Context, postID int64, from, to int) (*entity.PostRevisionDiffResponse, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("%w: from and to must be positive revision numbers", ErrInvalidArgument)
+	}
+	fromRev, err := uc.revisionRepo.GetByRevision(ctx, postID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := uc.revisionRepo.GetByRevision(ctx, postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.PostRevisionDiffResponse{
+		PostID: postID,
+		From:   from,
+		To:     to,
+		Diff: diff.Unified(
+			renderRevision(fromRev),
+			renderRevision(toRev),
+			fmt.Sprintf("revision %d", from),
+			fmt.Sprintf("revision %d", to),
+			3,
+		),
+	}, nil
+}
+
+// RestoreRevision writes the content of an earlier revision back to the post.
+// The post keeps its current status and publish time unless withStatus is
+// set, so restoring old text does not unpublish or reschedule it. The
+// restore is itself recorded as a new revision, so it can be undone.
+func (uc *PostUseCase) RestoreRevision(ctx context.Context, postID int64, revision int, withStatus bool, editor entity.Editor) error {
+	rev, err := uc.revisionRepo.GetByRevision(ctx, postID, revision)
+	if err != nil {
+		return err
+	}
+
+	tags := splitIDs(rev.TagIDs)
+	if tags == nil {
+		tags = []int64{} // non-nil: clear tags that were added after this revision
+	}
+	// Excerpt and cover are sent even when empty, so that values added
+	// after this revision are cleared.
+	req := entity.UpdatePostRequest{
+		Title:      rev.Title,
+		Slug:       rev.Slug,
+		Excerpt:    &rev.Excerpt,
+		Content:    rev.Content,
+		CategoryID: rev.CategoryID,
+		Tags:       tags,
+		Cover:      &rev.Cover,
+	}
+	if withStatus {
+		req.Status = rev.Status
+		req.PublishAt = rev.PublishAt.Format(time.RFC3339Nano)
+	}
+	return uc.update(ctx, postID, req, editor, revisionActionRestore, revision)
+}
+
+// renderRevision flattens a revision into text so that metadata changes
+// show up in the diff alongside content changes.
+func renderRevision(r *entity.PostRevision) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Title: %s\n", r.Title)
+	fmt.Fprintf(&b, "Slug: %s\n", r.Slug)
+	fmt.Fprintf(&b, "Excerpt: %s\n", r.Excerpt)
+	fmt.Fprintf(&b, "Cover: %s\n", r.Cover)
+	fmt.Fprintf(&b, "Category: %d\n", r.CategoryID)
+	fmt.Fprintf(&b, "Tags: %s\n", r.TagIDs)
+	fmt.Fprintf(&b, "Status: %s\n", r.Status)
+	fmt.Fprintf(&b, "PublishAt: %s\n", r.PublishAt.Format(time.RFC3339))
+	b.WriteString("\n")
+	b.WriteString(r.Content)
+	return b.String()
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitIDs(s string) []int64 {
+	if s == "" {
+		return nil
+	}
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
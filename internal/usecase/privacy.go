@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/privacy"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	retentionInterval = time.Hour
+	// minAnalyticsRetentionDays keeps the raw rows the rollup job rebuilds
+	// the current and previous day from.
+	minAnalyticsRetentionDays = 2
+)
+
+// anonymizeIP applies privacy.ip_mode with the long-lived key, so the same
+// address maps to the same value across days. Likes rely on that to stay
+// one per address.
+func anonymizeIP(ip string) string {
+	return privacy.Anonymize(config.GetConf().Privacy.IPMode, ip, ipHashKey())
+}
+
+// ipHashKey is privacy.salt, or a key derived from the JWT secret when unset.
+func ipHashKey() []byte {
+	conf := config.GetConf()
+	if conf.Privacy.Salt != "" {
+		return []byte(conf.Privacy.Salt)
+	}
+	sum := sha256.Sum256([]byte("ip-hash:" + conf.App.JwtSecret))
+	return sum[:]
+}
+
+// anonymizingEventRepo anonymizes the IP of every event before storing it.
+type anonymizingEventRepo struct {
+	SystemEventRepo
+}
+
+// WithAnonymizedIPs wraps repo so events are stored with IPs anonymized per
+// privacy.ip_mode, whichever middleware or use case records them.
+func WithAnonymizedIPs(repo SystemEventRepo) SystemEventRepo {
+	return anonymizingEventRepo{SystemEventRepo: repo}
+}
+
+func (r anonymizingEventRepo) Create(ctx context.Context, event *entity.SystemEvent) error {
+	event.IP = anonymizeIP(event.IP)
+	return r.SystemEventRepo.Create(ctx, event)
+}
+
+// dailySalts hands out the random salt of each UTC day for visitor hashes.
+// Salts live in the database so all instances count a visitor the same way.
+type dailySalts struct {
+	repo AnalyticsRepo
+
+	mu    sync.Mutex
+	salts map[string][]byte
+}
+
+func newDailySalts(repo AnalyticsRepo) *dailySalts {
+	return &dailySalts{repo: repo, salts: make(map[string][]byte)}
+}
+
+func (s *dailySalts) get(ctx context.Context, day string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if salt, ok := s.salts[day]; ok {
+		return salt, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	stored, err := s.repo.GetOrCreateSalt(ctx, day, hex.EncodeToString(b))
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only today and yesterday are ever asked for again.
+	for d := range s.salts {
+		if d < day {
+			delete(s.salts, d)
+		}
+	}
+	s.salts[day] = salt
+	return salt, nil
+}
+
+// RetentionUseCase removes personal data from raw records once they are
+// older than privacy.retention allows.
+type RetentionUseCase struct {
+	analyticsRepo AnalyticsRepo
+	likeRepo      LikeRepo
+	eventRepo     SystemEventRepo
+}
+
+func NewRetentionUseCase(analyticsRepo AnalyticsRepo, likeRepo LikeRepo, eventRepo SystemEventRepo) *RetentionUseCase {
+	return &RetentionUseCase{analyticsRepo: analyticsRepo, likeRepo: likeRepo, eventRepo: eventRepo}
+}
+
+// RunRetention applies the retention policy hourly until ctx is cancelled.
+func (uc *RetentionUseCase) RunRetention(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		uc.apply(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (uc *RetentionUseCase) apply(ctx context.Context, now time.Time) {
+	conf := config.GetConf().Privacy.Retention
+
+	// Visitor salts are only needed until the day's last visits are flushed.
+	yesterday := utcDay(now).AddDate(0, 0, -1).Format(rollupDayLayout)
+	if err := uc.analyticsRepo.DeleteSaltsBefore(ctx, yesterday); err != nil {
+		log.Warnw("Delete visitor salts failed", log.Pair("error", err.Error()))
+	}
+
+	if days := conf.AnalyticsDays; days > 0 {
+		days = max(days, minAnalyticsRetentionDays)
+		anonymize := conf.Action == "anonymize"
+		n, err := uc.analyticsRepo.PurgeBefore(ctx, now.AddDate(0, 0, -days).Unix(), anonymize)
+		uc.report("analytics", n, err)
+	}
+	if conf.EventDays > 0 {
+		n, err := uc.eventRepo.AnonymizeBefore(ctx, now.AddDate(0, 0, -conf.EventDays))
+		uc.report("system_events", n, err)
+	}
+	if conf.LikeDays > 0 {
+		n, err := uc.likeRepo.AnonymizeBefore(ctx, now.AddDate(0, 0, -conf.LikeDays))
+		uc.report("likes", n, err)
+	}
+}
+
+func (uc *RetentionUseCase) report(table string, n int64, err error) {
+	if err != nil {
+		log.Warnw("Retention failed", log.Pair("table", table), log.Pair("error", err.Error()))
+		return
+	}
+	if n > 0 {
+		log.Infow("Retention applied", log.Pair("table", table), log.Pair("rows", n))
+	}
+}
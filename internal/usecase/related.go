@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/cache"
+	"blog/pkg/log"
+	"blog/pkg/markdown"
+	"blog/pkg/search"
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// relatedPerPost is how many related posts are stored per post. It is
+	// more than are shown, as scheduled posts are filtered out when read.
+	relatedPerPost = 20
+	// relatedMaxTerms keeps the strongest terms of each post's TF-IDF vector.
+	relatedMaxTerms = 200
+	// relatedDebounce delays a rebuild so that a burst of saves runs one.
+	relatedDebounce = 5 * time.Second
+	// relatedMinScore drops pairs that have too little in common.
+	relatedMinScore = 0.05
+)
+
+// Weights of the similarity signals; they add up to 1.
+const (
+	relatedWeightText     = 0.5
+	relatedWeightTags     = 0.35
+	relatedWeightCategory = 0.15
+)
+
+// RelatedPosts precomputes "read next" suggestions. Scores combine shared
+// tags, the same category and the TF-IDF cosine similarity of title, excerpt
+// and content. The whole table is rebuilt, since a new post changes the
+// document frequencies every score depends on.
+type RelatedPosts struct {
+	postRepo    PostRepo
+	relatedRepo PostRelatedRepo
+	cache       responseCache
+	rebuild     chan struct{}
+}
+
+func NewRelatedPosts(postRepo PostRepo, relatedRepo PostRelatedRepo, c cache.Cache) *RelatedPosts {
+	return &RelatedPosts{
+		postRepo:    postRepo,
+		relatedRepo: relatedRepo,
+		cache:       newResponseCache(c),
+		rebuild:     make(chan struct{}, 1),
+	}
+}
+
+// Schedule asks Run to rebuild the scores soon. It never blocks.
+func (r *RelatedPosts) Schedule() {
+	select {
+	case r.rebuild <- struct{}{}:
+	default:
+	}
+}
+
+// Run builds the scores at start and again after each Schedule, until ctx
+// is cancelled.
+func (r *RelatedPosts) Run(ctx context.Context) {
+	r.Schedule()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.rebuild:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relatedDebounce):
+		}
+		// Saves during the wait are covered by this rebuild.
+		select {
+		case <-r.rebuild:
+		default:
+		}
+
+		start := time.Now()
+		n, err := r.Rebuild(ctx)
+		if err != nil {
+			log.Warnw("Rebuild related posts failed", log.Pair("error", err.Error()))
+			continue
+		}
+		log.Infow("Related posts rebuilt",
+			log.Pair("pairs", n),
+			log.Pair("duration", time.Since(start).String()),
+		)
+	}
+}
+
+// Rebuild recomputes the scores of all published posts, scheduled ones
+// included, and returns the number of pairs stored.
+func (r *RelatedPosts) Rebuild(ctx context.Context) (int, error) {
+	posts, _, err := r.postRepo.List(ctx, map[string]interface{}{"status": "published"}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	tagIDs, err := r.postRepo.GetTagIDsByPostIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := scoreRelated(posts, tagIDs)
+	if err := r.relatedRepo.ReplaceAll(ctx, rows); err != nil {
+		return 0, err
+	}
+	r.cache.bump(ctx, cacheNSPostLists)
+	return len(rows), nil
+}
+
+// List returns up to limit posts related to postID that are live at now.
+func (r *RelatedPosts) List(ctx context.Context, postID int64, now time.Time, limit int) ([]entity.Post, error) {
+	return r.relatedRepo.ListRelated(ctx, postID, now, limit)
+}
+
+type termWeight struct {
+	doc    int
+	weight float64
+}
+
+// scoreRelated returns, for every post, its relatedPerPost best matches.
+func scoreRelated(posts []entity.Post, tagIDs map[int64][]int64) []entity.PostRelated {
+	n := len(posts)
+	if n < 2 {
+		return nil
+	}
+
+	// Term frequencies, with title and excerpt terms counting more.
+	counts := make([]map[string]float64, n)
+	df := map[string]int{}
+	for i := range posts {
+		tf := map[string]float64{}
+		addTerms(tf, posts[i].Title, 3)
+		addTerms(tf, posts[i].Excerpt, 2)
+		addTerms(tf, markdown.ToPlainText(posts[i].Content), 1)
+		for term := range tf {
+			df[term]++
+		}
+		counts[i] = tf
+	}
+
+	// L2-normalized TF-IDF vectors, indexed by term. Terms found in a single
+	// post cannot match another one and are left out of the index.
+	index := map[string][]termWeight{}
+	for i, tf := range counts {
+		vec := make(map[string]float64, len(tf))
+		var norm float64
+		for term, c := range tf {
+			w := (1 + math.Log(c)) * math.Log(float64(n)/float64(df[term]))
+			if w <= 0 {
+				continue
+			}
+			vec[term] = w
+			norm += w * w
+		}
+		if norm == 0 {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for _, term := range topTerms(vec, df, relatedMaxTerms) {
+			index[term] = append(index[term], termWeight{doc: i, weight: vec[term] / norm})
+		}
+	}
+	vectors := make([]map[string]float64, n)
+	for term, postings := range index {
+		for _, p := range postings {
+			if vectors[p.doc] == nil {
+				vectors[p.doc] = map[string]float64{}
+			}
+			vectors[p.doc][term] = p.weight
+		}
+	}
+
+	tagSets := make([]map[int64]bool, n)
+	for i := range posts {
+		set := map[int64]bool{}
+		for _, id := range tagIDs[posts[i].ID] {
+			set[id] = true
+		}
+		tagSets[i] = set
+	}
+
+	var rows []entity.PostRelated
+	for i := range posts {
+		text := make(map[int]float64)
+		for term, w := range vectors[i] {
+			for _, p := range index[term] {
+				if p.doc != i {
+					text[p.doc] += w * p.weight
+				}
+			}
+		}
+
+		var scored []entity.PostRelated
+		for j := range posts {
+			if j == i {
+				continue
+			}
+			score := relatedWeightText*text[j] + relatedWeightTags*tagSimilarity(tagSets[i], tagSets[j])
+			if posts[i].CategoryID != 0 && posts[i].CategoryID == posts[j].CategoryID {
+				score += relatedWeightCategory
+			}
+			if score < relatedMinScore {
+				continue
+			}
+			scored = append(scored, entity.PostRelated{PostID: posts[i].ID, RelatedID: posts[j].ID, Score: score})
+		}
+		sort.Slice(scored, func(a, b int) bool {
+			if scored[a].Score != scored[b].Score {
+				return scored[a].Score > scored[b].Score
+			}
+			return scored[a].RelatedID > scored[b].RelatedID
+		})
+		if len(scored) > relatedPerPost {
+			scored = scored[:relatedPerPost]
+		}
+		rows = append(rows, scored...)
+	}
+	return rows
+}
+
+// addTerms counts the CJK-aware tokens of text, each weight times.
+func addTerms(tf map[string]float64, text string, weight float64) {
+	for _, token := range search.Tokenize(text) {
+		tf[token] += weight
+	}
+}
+
+// topTerms returns up to max terms of vec, strongest first, that occur in
+// more than one post.
+func topTerms(vec map[string]float64, df map[string]int, max int) []string {
+	terms := make([]string, 0, len(vec))
+	for term := range vec {
+		if df[term] > 1 {
+			terms = append(terms, term)
+		}
+	}
+	sort.Slice(terms, func(a, b int) bool {
+		if vec[terms[a]] != vec[terms[b]] {
+			return vec[terms[a]] > vec[terms[b]]
+		}
+		return terms[a] < terms[b]
+	})
+	if len(terms) > max {
+		terms = terms[:max]
+	}
+	return terms
+}
+
+// tagSimilarity is the cosine similarity of two tag sets.
+func tagSimilarity(a, b map[int64]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for id := range a {
+		if b[id] {
+			shared++
+		}
+	}
+	return float64(shared) / math.Sqrt(float64(len(a)*len(b)))
+}
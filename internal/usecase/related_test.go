@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"math"
+	"testing"
+)
+
+func TestScoreRelatedWeights(t *testing.T) {
+	// Titles share no terms, so only tags and category contribute.
+	tests := []struct {
+		name       string
+		categories [2]int64
+		tags       [2][]int64
+		want       float64 // 0: the pair is not stored
+	}{
+		{"same category", [2]int64{1, 1}, [2][]int64{nil, nil}, relatedWeightCategory},
+		{"uncategorized", [2]int64{0, 0}, [2][]int64{nil, nil}, 0},
+		{"same tags", [2]int64{1, 2}, [2][]int64{{7}, {7}}, relatedWeightTags},
+		{"some tags", [2]int64{1, 2}, [2][]int64{{7, 8}, {7}}, relatedWeightTags / math.Sqrt2},
+		{"tags and category", [2]int64{1, 1}, [2][]int64{{7}, {7}}, relatedWeightTags + relatedWeightCategory},
+		{"nothing shared", [2]int64{1, 2}, [2][]int64{{7}, {8}}, 0},
+	}
+	for _, tt := range tests {
+		posts := []entity.Post{
+			{ID: 1, Title: "alpha", CategoryID: tt.categories[0]},
+			{ID: 2, Title: "bravo", CategoryID: tt.categories[1]},
+		}
+		tagIDs := map[int64][]int64{1: tt.tags[0], 2: tt.tags[1]}
+
+		var got float64
+		for _, row := range scoreRelated(posts, tagIDs) {
+			if row.PostID == 1 && row.RelatedID == 2 {
+				got = row.Score
+			}
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: score = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScoreRelatedText(t *testing.T) {
+	posts := []entity.Post{
+		{ID: 1, Title: "goroutine scheduler internals"},
+		{ID: 2, Title: "goroutine scheduler tuning"},
+		{ID: 3, Title: "sourdough bread"},
+	}
+	scores := map[int64]float64{}
+	for _, row := range scoreRelated(posts, nil) {
+		if row.PostID == 1 {
+			scores[row.RelatedID] = row.Score
+		}
+	}
+	if s := scores[2]; s <= 0 || s > relatedWeightText {
+		t.Errorf("shared terms: score = %v, want in (0, %v]", s, relatedWeightText)
+	}
+	if _, ok := scores[3]; ok {
+		t.Errorf("unrelated post stored with score %v", scores[3])
+	}
+}
+
+func TestScoreRelatedPrefersNewerOnTies(t *testing.T) {
+	posts := []entity.Post{
+		{ID: 1, Title: "alpha", CategoryID: 1},
+		{ID: 2, Title: "bravo", CategoryID: 1},
+		{ID: 3, Title: "charlie", CategoryID: 1},
+	}
+	var order []int64
+	for _, row := range scoreRelated(posts, nil) {
+		if row.PostID == 1 {
+			order = append(order, row.RelatedID)
+		}
+	}
+	if len(order) != 2 || order[0] != 3 || order[1] != 2 {
+		t.Errorf("related to 1 = %v, want [3 2]", order)
+	}
+}
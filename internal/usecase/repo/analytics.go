@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type analyticsRepo struct {
@@ -21,10 +22,21 @@ func (r *analyticsRepo) Create(ctx context.Context, log *entity.Analytics) error
 	return r.db.WithContext(ctx).Create(log).Error
 }
 
-func (r *analyticsRepo) GetLogs(ctx context.Context, startDate, endDate string, limit int) ([]entity.Analytics, error) {
+func (r *analyticsRepo) CreateBatch(ctx context.Context, logs []entity.Analytics) error {
+	return r.db.WithContext(ctx).CreateInBatches(logs, 500).Error
+}
+
+func (r *analyticsRepo) GetLogs(ctx context.Context, startDate, endDate string, traffic entity.Traffic, limit int) ([]entity.Analytics, error) {
 	var logs []entity.Analytics
 	query := r.db.WithContext(ctx).Model(&entity.Analytics{})
 
+	switch traffic {
+	case entity.TrafficHuman:
+		query = query.Where("bot = ?", false)
+	case entity.TrafficBot:
+		query = query.Where("bot = ?", true)
+	}
+
 	if startDate != "" {
 		start, err := time.Parse("2006-01-02", startDate)
 		if err == nil {
@@ -46,3 +58,247 @@ func (r *analyticsRepo) GetLogs(ctx context.Context, startDate, endDate string,
 	err := query.Order("timestamp DESC").Find(&logs).Error
 	return logs, err
 }
+
+// rollupCounts selects views and visitors split into human and bot traffic.
+// Visitors are counted by their anonymous key, falling back to the IP for
+// rows recorded before keys existed.
+const rollupCounts = "COALESCE(SUM(CASE WHEN bot THEN 0 ELSE 1 END), 0) AS views, " +
+	"COUNT(DISTINCT CASE WHEN bot THEN NULL ELSE COALESCE(NULLIF(visitor_id, ''), ip) END) AS visitors, " +
+	"COALESCE(SUM(CASE WHEN bot THEN 1 ELSE 0 END), 0) AS bot_views, " +
+	"COUNT(DISTINCT CASE WHEN bot THEN COALESCE(NULLIF(visitor_id, ''), ip) END) AS bot_visitors"
+
+type rollupRow struct {
+	Name        string
+	Views       int64
+	Visitors    int64
+	BotViews    int64
+	BotVisitors int64
+}
+
+func (r *analyticsRepo) RollupDay(ctx context.Context, day string, from, to int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		raw := func() *gorm.DB {
+			return tx.Model(&entity.Analytics{}).Where("timestamp >= ? AND timestamp < ?", from, to)
+		}
+
+		for _, model := range []any{
+			&entity.AnalyticsDaily{},
+			&entity.AnalyticsDailyPath{},
+			&entity.AnalyticsDailyPost{},
+			&entity.AnalyticsDailyCountry{},
+			&entity.AnalyticsDailyReferrer{},
+		} {
+			if err := tx.Where("day = ?", day).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		total := entity.AnalyticsDaily{Day: day}
+		if err := raw().Select(rollupCounts).Scan(&total).Error; err != nil {
+			return err
+		}
+		if total.Views+total.BotViews == 0 {
+			return nil
+		}
+		if err := tx.Create(&total).Error; err != nil {
+			return err
+		}
+
+		var paths []rollupRow
+		if err := raw().Select("page_path AS name, " + rollupCounts).
+			Group("page_path").Scan(&paths).Error; err != nil {
+			return err
+		}
+		pathRows := make([]entity.AnalyticsDailyPath, len(paths))
+		for i, row := range paths {
+			pathRows[i] = entity.AnalyticsDailyPath{Day: day, Path: row.Name, Views: row.Views, Visitors: row.Visitors, BotViews: row.BotViews, BotVisitors: row.BotVisitors}
+		}
+		if err := createRollups(tx, pathRows); err != nil {
+			return err
+		}
+
+		var posts []entity.AnalyticsDailyPost
+		if err := raw().Select("post_id, " + rollupCounts).
+			Where("post_id IS NOT NULL").Group("post_id").Scan(&posts).Error; err != nil {
+			return err
+		}
+		for i := range posts {
+			posts[i].Day = day
+		}
+		if err := createRollups(tx, posts); err != nil {
+			return err
+		}
+
+		var countries []rollupRow
+		if err := raw().Select("COALESCE(country, '') AS name, " + rollupCounts).
+			Group("COALESCE(country, '')").Scan(&countries).Error; err != nil {
+			return err
+		}
+		countryRows := make([]entity.AnalyticsDailyCountry, len(countries))
+		for i, row := range countries {
+			code := row.Name
+			if code == "" {
+				code = entity.UnknownCountry
+			}
+			countryRows[i] = entity.AnalyticsDailyCountry{Day: day, Country: code, Views: row.Views, Visitors: row.Visitors, BotViews: row.BotViews, BotVisitors: row.BotVisitors}
+		}
+		if err := createRollups(tx, countryRows); err != nil {
+			return err
+		}
+
+		var referrers []rollupRow
+		if err := raw().Select("referrer_host AS name, " + rollupCounts).
+			Where("referrer_host <> ''").Group("referrer_host").Scan(&referrers).Error; err != nil {
+			return err
+		}
+		referrerRows := make([]entity.AnalyticsDailyReferrer, len(referrers))
+		for i, row := range referrers {
+			referrerRows[i] = entity.AnalyticsDailyReferrer{Day: day, Host: row.Name, Views: row.Views, Visitors: row.Visitors, BotViews: row.BotViews, BotVisitors: row.BotVisitors}
+		}
+		return createRollups(tx, referrerRows)
+	})
+}
+
+func createRollups[T any](tx *gorm.DB, rows []T) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+func (r *analyticsRepo) LatestRollupDay(ctx context.Context) (string, error) {
+	var day string
+	err := r.db.WithContext(ctx).Model(&entity.AnalyticsDaily{}).
+		Select("COALESCE(MAX(day), '')").Scan(&day).Error
+	return day, err
+}
+
+func (r *analyticsRepo) EarliestTimestamp(ctx context.Context) (int64, error) {
+	var ts int64
+	err := r.db.WithContext(ctx).Model(&entity.Analytics{}).
+		Select("COALESCE(MIN(timestamp), 0)").Scan(&ts).Error
+	return ts, err
+}
+
+// trafficColumns returns the rollup expressions counting views and visitors
+// of the selected traffic.
+func trafficColumns(traffic entity.Traffic) (views, visitors string) {
+	switch traffic {
+	case entity.TrafficBot:
+		return "bot_views", "bot_visitors"
+	case entity.TrafficAll:
+		return "views + bot_views", "visitors + bot_visitors"
+	default:
+		return "views", "visitors"
+	}
+}
+
+func (r *analyticsRepo) DailySeries(ctx context.Context, startDay, endDay string, postID int64, traffic entity.Traffic) ([]entity.AnalyticsPoint, error) {
+	var points []entity.AnalyticsPoint
+	query := r.db.WithContext(ctx).Model(&entity.AnalyticsDaily{})
+	if postID != 0 {
+		query = r.db.WithContext(ctx).Model(&entity.AnalyticsDailyPost{}).Where("post_id = ?", postID)
+	}
+	views, visitors := trafficColumns(traffic)
+	err := query.Select("day, "+views+" AS views, "+visitors+" AS visitors").
+		Where("day >= ? AND day <= ?", startDay, endDay).
+		Order("day ASC").
+		Scan(&points).Error
+	return points, err
+}
+
+func (r *analyticsRepo) TopPaths(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+	return r.topRollup(ctx, &entity.AnalyticsDailyPath{}, "path", startDay, endDay, traffic, limit)
+}
+
+func (r *analyticsRepo) TopReferrers(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+	return r.topRollup(ctx, &entity.AnalyticsDailyReferrer{}, "host", startDay, endDay, traffic, limit)
+}
+
+func (r *analyticsRepo) TopCountries(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+	return r.topRollup(ctx, &entity.AnalyticsDailyCountry{}, "country", startDay, endDay, traffic, limit)
+}
+
+func (r *analyticsRepo) TopPosts(ctx context.Context, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsTopPost, error) {
+	var items []entity.AnalyticsTopPost
+	views, visitors := trafficColumns(traffic)
+	err := r.db.WithContext(ctx).Model(&entity.AnalyticsDailyPost{}).
+		Select("post_id, SUM("+views+") AS views, SUM("+visitors+") AS visitors").
+		Where("day >= ? AND day <= ?", startDay, endDay).
+		Group("post_id").
+		Having("SUM(" + views + ") > 0").
+		Order("views DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// topRollup ranks the values of column in a rollup table by views over the range.
+func (r *analyticsRepo) topRollup(ctx context.Context, model any, column, startDay, endDay string, traffic entity.Traffic, limit int) ([]entity.AnalyticsRankItem, error) {
+	var items []entity.AnalyticsRankItem
+	views, visitors := trafficColumns(traffic)
+	err := r.db.WithContext(ctx).Model(model).
+		Select(column+" AS name, SUM("+views+") AS views, SUM("+visitors+") AS visitors").
+		Where("day >= ? AND day <= ?", startDay, endDay).
+		Group(column).
+		Having("SUM(" + views + ") > 0").
+		Order("views DESC").
+		Limit(limit).
+		Scan(&items).Error
+	return items, err
+}
+
+func (r *analyticsRepo) GetOrCreateSalt(ctx context.Context, day, candidate string) (string, error) {
+	db := r.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.AnalyticsSalt{Day: day, Salt: candidate}).Error
+	if err != nil {
+		return "", err
+	}
+	// Another instance may have stored its candidate first.
+	var salt entity.AnalyticsSalt
+	if err := db.Where("day = ?", day).First(&salt).Error; err != nil {
+		return "", err
+	}
+	return salt.Salt, nil
+}
+
+func (r *analyticsRepo) DeleteSaltsBefore(ctx context.Context, day string) error {
+	return r.db.WithContext(ctx).Where("day < ?", day).Delete(&entity.AnalyticsSalt{}).Error
+}
+
+// purgeBatchSize bounds each statement so a first purge of a large table
+// does not hold long locks.
+const purgeBatchSize = 5000
+
+func (r *analyticsRepo) PurgeBefore(ctx context.Context, timestamp int64, anonymize bool) (int64, error) {
+	var total int64
+	for {
+		var ids []int64
+		query := r.db.WithContext(ctx).Model(&entity.Analytics{}).Where("timestamp < ?", timestamp)
+		if anonymize {
+			query = query.Where("(ip <> '' OR user_agent <> '' OR visitor_id <> '')")
+		}
+		if err := query.Limit(purgeBatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		var result *gorm.DB
+		if anonymize {
+			result = r.db.WithContext(ctx).Model(&entity.Analytics{}).Where("id IN ?", ids).
+				Updates(map[string]any{"ip": "", "user_agent": "", "visitor_id": ""})
+		} else {
+			result = r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&entity.Analytics{})
+		}
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
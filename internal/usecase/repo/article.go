@@ -5,6 +5,7 @@ import (
 	"blog/internal/usecase"
 	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -19,25 +20,47 @@ func NewPostRepo(db *gorm.DB) usecase.PostRepo {
 }
 
 func (r *postRepo) Create(ctx context.Context, post *entity.Post) error {
-	return r.db.WithContext(ctx).Create(post).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if err := refreshMediaReferences(tx, post); err != nil {
+			return err
+		}
+		return refreshSearchVector(tx, post)
+	})
 }
 
-func (r *postRepo) CreateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64) error {
+// CreateWithTags creates the post, its tag associations and, unless rev is
+// nil, its first revision in one transaction.
+func (r *postRepo) CreateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64, rev *entity.PostRevision) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(post).Error; err != nil {
 			return err
 		}
-		if len(tagIDs) == 0 {
-			return nil
+		if err := refreshSearchVector(tx, post); err != nil {
+			return err
+		}
+		if err := refreshMediaReferences(tx, post); err != nil {
+			return err
+		}
+		if len(tagIDs) > 0 {
+			postTags := make([]entity.PostTag, 0, len(tagIDs))
+			for _, tagID := range tagIDs {
+				postTags = append(postTags, entity.PostTag{
+					PostID: post.ID,
+					TagID:  tagID,
+				})
+			}
+			if err := tx.Create(&postTags).Error; err != nil {
+				return err
+			}
 		}
-		postTags := make([]entity.PostTag, 0, len(tagIDs))
-		for _, tagID := range tagIDs {
-			postTags = append(postTags, entity.PostTag{
-				PostID: post.ID,
-				TagID:  tagID,
-			})
+		if rev == nil {
+			return nil
 		}
-		return tx.Create(&postTags).Error
+		rev.PostID = post.ID
+		return createRevision(tx, rev)
 	})
 }
 
@@ -93,22 +116,10 @@ func (r *postRepo) List(ctx context.Context, filters map[string]interface{}, pag
 	var posts []entity.Post
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&entity.Post{})
-
-	// Apply filter conditions
-	if categoryID, ok := filters["categoryId"].(int64); ok && categoryID != 0 {
-		query = query.Where("category_id = ?", categoryID)
-	}
-	if status, ok := filters["status"].(string); ok && status != "" {
-		query = query.Where("status = ?", status)
-	}
+	query := applyPostFilters(r.db.WithContext(ctx).Model(&entity.Post{}), filters)
 	if search, ok := filters["search"].(string); ok && search != "" {
 		query = query.Where("title LIKE ? OR excerpt LIKE ?", "%"+search+"%", "%"+search+"%")
 	}
-	// Filter by date (for scheduled publishing: only show posts with date <= current date)
-	if beforePublishAt, ok := filters["beforePublishAt"].(time.Time); ok && !beforePublishAt.IsZero() {
-		query = query.Where("publish_at <= ?", beforePublishAt)
-	}
 
 	// Get total count
 	err := query.Count(&total).Error
@@ -131,30 +142,100 @@ func (r *postRepo) List(ctx context.Context, filters map[string]interface{}, pag
 	return posts, total, nil
 }
 
-func (r *postRepo) Update(ctx context.Context, post *entity.Post) error {
-	return r.db.WithContext(ctx).Save(post).Error
+// liveCondition keeps posts whose publish window contains the time bound to
+// both placeholders: already published and not yet expired.
+const liveCondition = "posts.publish_at <= ? AND (posts.unpublish_at IS NULL OR posts.unpublish_at > ?)"
+
+// applyPostFilters applies the structured (non-text) post filters shared by List and Search.
+func applyPostFilters(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
+	if categoryID, ok := filters["categoryId"].(int64); ok && categoryID != 0 {
+		query = query.Where("category_id = ?", categoryID)
+	}
+	if tagID, ok := filters["tagId"].(int64); ok && tagID != 0 {
+		query = query.Where("id IN (?)", query.Session(&gorm.Session{NewDB: true}).Model(&entity.PostTag{}).Select("post_id").Where("tag_id = ?", tagID))
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if authorID, ok := filters["authorId"].(int64); ok && authorID != 0 {
+		query = query.Where("author_id = ?", authorID)
+	}
+	// Filter by date (for scheduled publishing: only show posts with date <= current date)
+	if beforePublishAt, ok := filters["beforePublishAt"].(time.Time); ok && !beforePublishAt.IsZero() {
+		query = query.Where(liveCondition, beforePublishAt, beforePublishAt)
+	}
+	return query
 }
 
-func (r *postRepo) UpdateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64) error {
+// CountPublishedByAuthor counts the posts each author has live at now.
+func (r *postRepo) CountPublishedByAuthor(ctx context.Context, now time.Time) (map[int64]int64, error) {
+	var rows []struct {
+		AuthorID int64
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.Post{}).
+		Select("author_id, COUNT(*) AS count").
+		Where("status = ? AND publish_at <= ? AND author_id != 0", "published", now).
+		Group("author_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.AuthorID] = row.Count
+	}
+	return counts, nil
+}
+
+// BackfillAuthorIDs links posts written before author accounts to the user
+// whose username matches their byline, preferring the oldest account.
+func (r *postRepo) BackfillAuthorIDs(ctx context.Context) error {
+	return r.db.WithContext(ctx).Exec(`UPDATE posts SET author_id = (
+		SELECT MIN(users.id) FROM users WHERE users.username = posts.author
+	) WHERE author_id = 0 AND EXISTS (
+		SELECT 1 FROM users WHERE users.username = posts.author
+	)`).Error
+}
+
+// UpdateWithTags saves the post, replaces its tag associations unless
+// tagIDs is nil and, unless rev is nil, records a revision, all in one
+// transaction.
+func (r *postRepo) UpdateWithTags(ctx context.Context, post *entity.Post, tagIDs []int64, rev *entity.PostRevision) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Save(post).Error; err != nil {
+		// published_event_at belongs to the scheduler; a stale copy must not clear it.
+		if err := tx.Omit("published_event_at").Save(post).Error; err != nil {
 			return err
 		}
-		// Replace all tag associations
-		if err := tx.Where("post_id = ?", post.ID).Delete(&entity.PostTag{}).Error; err != nil {
+		if err := refreshSearchVector(tx, post); err != nil {
 			return err
 		}
-		if len(tagIDs) == 0 {
-			return nil
+		if err := refreshMediaReferences(tx, post); err != nil {
+			return err
+		}
+		if tagIDs != nil {
+			// Replace all tag associations
+			if err := tx.Where("post_id = ?", post.ID).Delete(&entity.PostTag{}).Error; err != nil {
+				return err
+			}
+			if len(tagIDs) > 0 {
+				postTags := make([]entity.PostTag, 0, len(tagIDs))
+				for _, tagID := range tagIDs {
+					postTags = append(postTags, entity.PostTag{
+						PostID: post.ID,
+						TagID:  tagID,
+					})
+				}
+				if err := tx.Create(&postTags).Error; err != nil {
+					return err
+				}
+			}
 		}
-		postTags := make([]entity.PostTag, 0, len(tagIDs))
-		for _, tagID := range tagIDs {
-			postTags = append(postTags, entity.PostTag{
-				PostID: post.ID,
-				TagID:  tagID,
-			})
+		if rev == nil {
+			return nil
 		}
-		return tx.Create(&postTags).Error
+		rev.PostID = post.ID
+		return createRevision(tx, rev)
 	})
 }
 
@@ -166,14 +247,47 @@ func (r *postRepo) Delete(ctx context.Context, id int64) error {
 		if err := tx.Where("post_id = ?", id).Delete(&entity.PostTag{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("post_id = ?", id).Delete(&entity.PostRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", id).Delete(&entity.MediaReference{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_id = ?", id).Delete(&entity.SeriesPost{}).Error; err != nil {
+			return err
+		}
 		return tx.Where("id = ?", id).Delete(&entity.Post{}).Error
 	})
 }
 
-func (r *postRepo) IncrementViews(ctx context.Context, id int64) error {
-	return r.db.WithContext(ctx).Model(&entity.Post{}).
+func (r *postRepo) AddViews(ctx context.Context, counts map[int64]int64) error {
+	// Lock rows in ID order so concurrent flushes cannot deadlock.
+	ids := make([]int64, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			err := tx.Model(&entity.Post{}).
+				Where("id = ?", id).
+				UpdateColumn("views", gorm.Expr("views + ?", counts[id])).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *postRepo) GetViews(ctx context.Context, id int64) (int, error) {
+	var views int
+	err := r.db.WithContext(ctx).Model(&entity.Post{}).
 		Where("id = ?", id).
-		UpdateColumn("views", gorm.Expr("views + 1")).Error
+		Select("views").
+		Scan(&views).Error
+	return views, err
 }
 
 // AddTags adds post-tag associations
@@ -240,3 +354,91 @@ func (r *postRepo) GetRecent(ctx context.Context, limit int) ([]entity.Post, err
 		Find(&posts).Error
 	return posts, err
 }
+
+func (r *postRepo) NextPublishAt(ctx context.Context, t time.Time) (*time.Time, error) {
+	var post entity.Post
+	result := r.db.WithContext(ctx).
+		Select("publish_at").
+		Where("status = ? AND publish_at > ?", "published", t).
+		Order("publish_at ASC").
+		Limit(1).
+		Find(&post)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &post.PublishAt, nil
+}
+
+// ListNewlyLive returns the posts that went live in (since, now] and have
+// never had a post.published event.
+func (r *postRepo) ListNewlyLive(ctx context.Context, since, now time.Time) ([]entity.Post, error) {
+	var posts []entity.Post
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND publish_at > ? AND publish_at <= ?", "published", since, now).
+		Where("published_event_at IS NULL").
+		Order("publish_at ASC").
+		Find(&posts).Error
+	return posts, err
+}
+
+// MarkPublished sets published_event_at and records event, atomically. It
+// reports false when the post already had its event or is no longer live.
+func (r *postRepo) MarkPublished(ctx context.Context, id int64, now time.Time, event *entity.DomainEvent) (bool, error) {
+	marked := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Post{}).
+			Where("id = ? AND status = ? AND publish_at <= ? AND published_event_at IS NULL", id, "published", now).
+			UpdateColumn("published_event_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		marked = true
+		_, err := createDomainEvent(tx, event)
+		return err
+	})
+	return marked, err
+}
+
+func (r *postRepo) MarkAllPublished(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.Post{}).
+		Where("status = ? AND publish_at <= ? AND published_event_at IS NULL", "published", now).
+		UpdateColumn("published_event_at", now).Error
+}
+
+// ListExpired returns the published posts whose unpublish time has passed.
+func (r *postRepo) ListExpired(ctx context.Context, now time.Time) ([]entity.Post, error) {
+	var posts []entity.Post
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND unpublish_at IS NOT NULL AND unpublish_at <= ?", "published", now).
+		Order("unpublish_at ASC").
+		Find(&posts).Error
+	return posts, err
+}
+
+// Expire moves the post back to draft and records event, atomically. It
+// reports false when the post was edited in the meantime and is no longer
+// due to expire.
+func (r *postRepo) Expire(ctx context.Context, id int64, now time.Time, event *entity.DomainEvent) (bool, error) {
+	expired := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Post{}).
+			Where("id = ? AND status = ? AND unpublish_at IS NOT NULL AND unpublish_at <= ?", id, "published", now).
+			Update("status", "draft")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		expired = true
+		_, err := createDomainEvent(tx, event)
+		return err
+	})
+	return expired, err
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type authTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewAuthTokenRepo(db *gorm.DB) usecase.AuthTokenRepo {
+	return &authTokenRepo{db: db}
+}
+
+func (r *authTokenRepo) Create(ctx context.Context, token *entity.AuthToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *authTokenRepo) GetByHash(ctx context.Context, purpose, tokenHash string) (*entity.AuthToken, error) {
+	var token entity.AuthToken
+	err := r.db.WithContext(ctx).Where("purpose = ? AND token_hash = ?", purpose, tokenHash).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *authTokenRepo) Consume(ctx context.Context, id int64) (bool, error) {
+	// The used_at IS NULL guard makes concurrent redemptions race-safe.
+	res := r.db.WithContext(ctx).Model(&entity.AuthToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *authTokenRepo) InvalidateUser(ctx context.Context, userID int64, purpose string) error {
+	return r.db.WithContext(ctx).Model(&entity.AuthToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *authTokenRepo) RecordFailure(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Model(&entity.AuthToken{}).
+		Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// claimDue leases a queued row to the calling instance: it moves the row to
+// the claimed status and pushes next_attempt_at to until, provided the row
+// is still due, either pending or claimed by an instance whose lease has
+// lapsed. The row lock taken by the UPDATE makes concurrent claims
+// exclusive; it reports whether this caller won.
+func claimDue(db *gorm.DB, model any, id int64, pending, claimed string, now, until time.Time) (bool, error) {
+	result := db.Model(model).
+		Where("id = ? AND status IN ? AND next_attempt_at <= ?", id, []string{pending, claimed}, now).
+		Updates(map[string]any{"status": claimed, "next_attempt_at": until})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
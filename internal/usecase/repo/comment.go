@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -41,7 +42,7 @@ func (r *commentRepo) ListTopLevel(ctx context.Context, postID int64, page, limi
 	)
 
 	q := r.db.WithContext(ctx).Model(&entity.Comment{}).
-		Where("post_id = ? AND parent_id IS NULL", postID)
+		Where("post_id = ? AND parent_id IS NULL AND status = ?", postID, entity.CommentStatusApproved)
 
 	if err := q.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -67,7 +68,7 @@ func (r *commentRepo) ListReplies(ctx context.Context, postID int64, parentIDs [
 
 	var comments []entity.Comment
 	err := r.db.WithContext(ctx).
-		Where("post_id = ? AND parent_id IN ?", postID, parentIDs).
+		Where("post_id = ? AND parent_id IN ? AND status = ?", postID, parentIDs, entity.CommentStatusApproved).
 		Order("created_at " + normalizeOrder(order)).
 		Find(&comments).Error
 	return comments, err
@@ -92,6 +93,77 @@ func (r *commentRepo) ListAll(ctx context.Context) ([]entity.Comment, error) {
 	return comments, err
 }
 
+// ListByStatus returns a page of comments in the given moderation state, oldest first
+// so the queue is worked through in arrival order.
+func (r *commentRepo) ListByStatus(ctx context.Context, status string, page, limit int) ([]entity.Comment, int64, error) {
+	var (
+		comments []entity.Comment
+		total    int64
+	)
+
+	q := r.db.WithContext(ctx).Model(&entity.Comment{}).Where("status = ?", status)
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page > 0 && limit > 0 {
+		q = q.Offset((page - 1) * limit).Limit(limit)
+	}
+
+	if err := q.Order("created_at ASC").Find(&comments).Error; err != nil {
+		return nil, 0, err
+	}
+	return comments, total, nil
+}
+
+func (r *commentRepo) CountByUserAndStatus(ctx context.Context, userID int64, status string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.Comment{}).
+		Where("user_id = ? AND status = ?", userID, status).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *commentRepo) UpdateStatus(ctx context.Context, ids []int64, status string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res := r.db.WithContext(ctx).Model(&entity.Comment{}).
+		Where("id IN ?", ids).
+		Update("status", status)
+	return res.RowsAffected, res.Error
+}
+
+func (r *commentRepo) GetByIDs(ctx context.Context, ids []int64) ([]entity.Comment, error) {
+	if len(ids) == 0 {
+		return []entity.Comment{}, nil
+	}
+	var comments []entity.Comment
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *commentRepo) CountRecentByContent(ctx context.Context, content string, userID int64, since time.Time) (int64, int64, error) {
+	var counts struct {
+		Own        int64
+		OtherUsers int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.Comment{}).
+		Select("COUNT(CASE WHEN user_id = ? THEN 1 END) AS own, "+
+			"COUNT(DISTINCT CASE WHEN user_id <> ? THEN user_id END) AS other_users", userID, userID).
+		Where("content = ? AND created_at >= ?", content, since).
+		Scan(&counts).Error
+	return counts.Own, counts.OtherUsers, err
+}
+
+func (r *commentRepo) SetTrainedAs(ctx context.Context, id int64, label string) error {
+	return r.db.WithContext(ctx).Model(&entity.Comment{}).
+		Where("id = ?", id).
+		UpdateColumn("trained_as", label).Error
+}
+
 func (r *commentRepo) DeleteCascade(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Where("id = ? OR parent_id = ?", id, id).Delete(&entity.Comment{}).Error
 }
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type domainEventRepo struct {
+	db *gorm.DB
+}
+
+func NewDomainEventRepo(db *gorm.DB) usecase.DomainEventRepo {
+	return &domainEventRepo{db: db}
+}
+
+// createDomainEvent records the event unless one with the same type, post
+// and time exists, and reports whether it was recorded.
+func createDomainEvent(tx *gorm.DB, event *entity.DomainEvent) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListDue returns pending events whose next attempt is due, and claimed ones
+// whose claim has lapsed, oldest first.
+func (r *domainEventRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]entity.DomainEvent, error) {
+	var events []entity.DomainEvent
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND next_attempt_at <= ?", []string{entity.EventStatusPending, entity.EventStatusProcessing}, now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *domainEventRepo) Claim(ctx context.Context, id int64, now, until time.Time) (bool, error) {
+	return claimDue(r.db.WithContext(ctx), &entity.DomainEvent{}, id, entity.EventStatusPending, entity.EventStatusProcessing, now, until)
+}
+
+func (r *domainEventRepo) Update(ctx context.Context, event *entity.DomainEvent) error {
+	return r.db.WithContext(ctx).Save(event).Error
+}
+
+func (r *domainEventRepo) GetCursor(ctx context.Context, name string) (*time.Time, error) {
+	var cursor entity.SchedulerCursor
+	err := r.db.WithContext(ctx).Where("name = ?", name).Take(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cursor.CheckedUntil, nil
+}
+
+func (r *domainEventRepo) SetCursor(ctx context.Context, name string, checkedUntil time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"checked_until", "updated_at"}),
+	}).Create(&entity.SchedulerCursor{Name: name, CheckedUntil: checkedUntil}).Error
+}
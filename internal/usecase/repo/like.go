@@ -4,6 +4,7 @@ import (
 	"blog/internal/entity"
 	"blog/internal/usecase"
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -36,3 +37,10 @@ func (r *likeRepo) ExistsBySlugAndIP(ctx context.Context, slug, ip string) (bool
 		Count(&count).Error
 	return count > 0, err
 }
+
+func (r *likeRepo) AnonymizeBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entity.Like{}).
+		Where("created_at < ? AND (ip <> '' OR user_agent <> '')", t).
+		Updates(map[string]any{"ip": "", "user_agent": ""})
+	return result.RowsAffected, result.Error
+}
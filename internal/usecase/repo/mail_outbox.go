@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type mailOutboxRepo struct {
+	db *gorm.DB
+}
+
+func NewMailOutboxRepo(db *gorm.DB) usecase.MailOutboxRepo {
+	return &mailOutboxRepo{db: db}
+}
+
+func (r *mailOutboxRepo) Create(ctx context.Context, mail *entity.MailOutbox) error {
+	return r.db.WithContext(ctx).Create(mail).Error
+}
+
+// ListDue returns pending messages whose next attempt is due, and claimed
+// ones whose claim has lapsed, oldest first.
+func (r *mailOutboxRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]entity.MailOutbox, error) {
+	var mails []entity.MailOutbox
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND next_attempt_at <= ?", []string{entity.MailStatusPending, entity.MailStatusSending}, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&mails).Error
+	return mails, err
+}
+
+func (r *mailOutboxRepo) Claim(ctx context.Context, id int64, now, until time.Time) (bool, error) {
+	return claimDue(r.db.WithContext(ctx), &entity.MailOutbox{}, id, entity.MailStatusPending, entity.MailStatusSending, now, until)
+}
+
+func (r *mailOutboxRepo) Update(ctx context.Context, mail *entity.MailOutbox) error {
+	return r.db.WithContext(ctx).Save(mail).Error
+}
@@ -5,6 +5,11 @@ import (
 	"blog/internal/usecase"
 	"context"
 	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -23,7 +28,7 @@ func (r *mediaRepo) Create(ctx context.Context, media *entity.Media) error {
 
 func (r *mediaRepo) GetByID(ctx context.Context, id int64) (*entity.Media, error) {
 	var media entity.Media
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&media).Error
+	err := r.db.WithContext(ctx).Preload("Variants", orderVariants).Where("id = ?", id).First(&media).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("media not found")
@@ -35,12 +40,25 @@ func (r *mediaRepo) GetByID(ctx context.Context, id int64) (*entity.Media, error
 
 func (r *mediaRepo) List(ctx context.Context) ([]entity.Media, error) {
 	var media []entity.Media
-	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&media).Error
+	err := r.db.WithContext(ctx).Preload("Variants", orderVariants).Order("created_at DESC").Find(&media).Error
 	return media, err
 }
 
+// orderVariants lists variants narrowest first, as srcset candidates.
+func orderVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("width ASC, id ASC")
+}
+
 func (r *mediaRepo) Delete(ctx context.Context, id int64) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.Media{}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("media_id = ?", id).Delete(&entity.MediaVariant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("media_id = ?", id).Delete(&entity.MediaReference{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entity.Media{}).Error
+	})
 }
 
 // Count counts total number of media files
@@ -49,3 +67,371 @@ func (r *mediaRepo) Count(ctx context.Context) (int64, error) {
 	err := r.db.WithContext(ctx).Model(&entity.Media{}).Count(&count).Error
 	return count, err
 }
+
+func (r *mediaRepo) UpdateStorage(ctx context.Context, media *entity.Media) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.Media{}).Where("id = ?", media.ID).Updates(map[string]any{
+			"storage": media.Storage,
+			"path":    media.Path,
+			"url":     media.URL,
+		}).Error
+		if err != nil {
+			return err
+		}
+		for _, v := range media.Variants {
+			err := tx.Model(&entity.MediaVariant{}).Where("id = ?", v.ID).Updates(map[string]any{
+				"path": v.Path,
+				"url":  v.URL,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *mediaRepo) ReplaceURL(ctx context.Context, oldURL, newURL string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// UpdateColumn keeps updated_at: moving files is not an edit of the post.
+		err := tx.Model(&entity.Post{}).Where("content LIKE ?", "%"+oldURL+"%").
+			UpdateColumn("content", gorm.Expr("REPLACE(content, ?, ?)", oldURL, newURL)).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Post{}).Where("cover = ?", oldURL).UpdateColumn("cover", newURL).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.User{}).Where("avatar = ?", oldURL).UpdateColumn("avatar", newURL).Error
+	})
+}
+
+func (r *mediaRepo) Search(ctx context.Context, filters map[string]interface{}, page, limit int) ([]entity.Media, int64, error) {
+	var (
+		media []entity.Media
+		total int64
+	)
+
+	query := r.db.WithContext(ctx).Model(&entity.Media{})
+	if q, ok := filters["query"].(string); ok && q != "" {
+		query = query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	if mediaType, ok := filters["type"].(string); ok && mediaType != "" {
+		query = query.Where("type = ?", mediaType)
+	}
+	if folderID, ok := filters["folderId"].(int64); ok && folderID > 0 {
+		query = query.Where("folder_id = ?", folderID)
+	}
+	if inRoot, ok := filters["inRoot"].(bool); ok && inRoot {
+		query = query.Where("folder_id IS NULL")
+	}
+	if from, ok := filters["from"].(time.Time); ok && !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if to, ok := filters["to"].(time.Time); ok && !to.IsZero() {
+		query = query.Where("created_at < ?", to)
+	}
+	if unused, ok := filters["unused"].(bool); ok && unused {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM media_references WHERE media_references.media_id = media.id)")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page > 0 && limit > 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+	err := query.Preload("Variants", orderVariants).Order("created_at DESC, id DESC").Find(&media).Error
+	return media, total, err
+}
+
+func (r *mediaRepo) Update(ctx context.Context, media *entity.Media) error {
+	return r.db.WithContext(ctx).Model(media).
+		Select("name", "alt", "caption", "folder_id").
+		Updates(media).Error
+}
+
+func (r *mediaRepo) CountReferences(ctx context.Context, mediaIDs []int64) (map[int64]int64, error) {
+	counts := make(map[int64]int64, len(mediaIDs))
+	if len(mediaIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		MediaID int64
+		Count   int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.MediaReference{}).
+		Select("media_id, COUNT(DISTINCT post_id) AS count").
+		Where("media_id IN ?", mediaIDs).
+		Group("media_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.MediaID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *mediaRepo) ListUsage(ctx context.Context, mediaID int64) ([]entity.MediaUsage, error) {
+	var usage []entity.MediaUsage
+	err := r.db.WithContext(ctx).Model(&entity.MediaReference{}).
+		Select("posts.id AS post_id, posts.title, posts.slug, posts.status, media_references.field").
+		Joins("JOIN posts ON posts.id = media_references.post_id").
+		Where("media_references.media_id = ?", mediaID).
+		Order("posts.id DESC, media_references.field ASC").
+		Scan(&usage).Error
+	return usage, err
+}
+
+func (r *mediaRepo) RebuildReferences(ctx context.Context) error {
+	const batchSize = 100
+	var lastID int64
+	for {
+		var posts []entity.Post
+		err := r.db.WithContext(ctx).
+			Select("id", "content", "cover").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&posts).Error
+		if err != nil {
+			return err
+		}
+		for i := range posts {
+			if err := refreshMediaReferences(r.db.WithContext(ctx), &posts[i]); err != nil {
+				return err
+			}
+		}
+		if len(posts) < batchSize {
+			return nil
+		}
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+func (r *mediaRepo) ListUnused(ctx context.Context, before time.Time) ([]entity.Media, error) {
+	var media []entity.Media
+	err := r.db.WithContext(ctx).Preload("Variants", orderVariants).
+		Where("created_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM media_references WHERE media_references.media_id = media.id)").
+		Order("created_at ASC").
+		Find(&media).Error
+	if err != nil || len(media) == 0 {
+		return media, err
+	}
+
+	avatars, err := r.avatarKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unused := media[:0]
+	for _, m := range media {
+		if !avatars[m.Path] && !slices.ContainsFunc(m.Variants, func(v entity.MediaVariant) bool { return avatars[v.Path] }) {
+			unused = append(unused, m)
+		}
+	}
+	return unused, nil
+}
+
+func (r *mediaRepo) LinkedKeys(ctx context.Context) (map[string]bool, error) {
+	keys, err := r.avatarKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	const batchSize = 100
+	var lastID int64
+	for {
+		var posts []entity.Post
+		err := r.db.WithContext(ctx).
+			Select("id", "content", "cover").
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&posts).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			for _, link := range append(mediaLinks(post.Content), post.Cover) {
+				for _, key := range linkedKeys(link) {
+					keys[key] = true
+				}
+			}
+		}
+		if len(posts) < batchSize {
+			return keys, nil
+		}
+		lastID = posts[len(posts)-1].ID
+	}
+}
+
+// avatarKeys returns the storage keys user avatars link to.
+func (r *mediaRepo) avatarKeys(ctx context.Context) (map[string]bool, error) {
+	var avatars []string
+	err := r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("avatar <> ''").
+		Distinct().Pluck("avatar", &avatars).Error
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	for _, avatar := range avatars {
+		for _, key := range linkedKeys(avatar) {
+			keys[key] = true
+		}
+	}
+	return keys, nil
+}
+
+func (r *mediaRepo) SizeByType(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Type string
+		Size int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.Media{}).
+		Select("type, COALESCE(SUM(size), 0) AS size").
+		Group("type").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	var variants []struct {
+		Type string
+		Size int64
+	}
+	err = r.db.WithContext(ctx).Model(&entity.MediaVariant{}).
+		Select("media.type, COALESCE(SUM(media_variants.size), 0) AS size").
+		Joins("JOIN media ON media.id = media_variants.media_id").
+		Group("media.type").
+		Scan(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64, len(rows))
+	for _, row := range append(rows, variants...) {
+		sizes[row.Type] += row.Size
+	}
+	return sizes, nil
+}
+
+var (
+	// absoluteURLPattern finds absolute URLs anywhere in Markdown or HTML content.
+	absoluteURLPattern = regexp.MustCompile(`https?://[^\s"'<>()\[\]]+`)
+	// markdownLinkPattern finds Markdown link and image targets, which may be relative.
+	markdownLinkPattern = regexp.MustCompile(`\]\(\s*<?([^\s<>()]+)`)
+	// htmlLinkPattern finds HTML attributes holding a single URL.
+	htmlLinkPattern = regexp.MustCompile(`(?i)\b(?:src|href|poster|data-src)\s*=\s*["']([^"']+)["']`)
+	// srcsetPattern finds srcset attributes, a list of "url width" candidates.
+	srcsetPattern = regexp.MustCompile(`(?i)\bsrcset\s*=\s*["']([^"']+)["']`)
+)
+
+// mediaLinks returns the URLs linked from Markdown or HTML content,
+// absolute or relative.
+func mediaLinks(content string) []string {
+	var links []string
+	for _, u := range absoluteURLPattern.FindAllString(content, -1) {
+		links = append(links, strings.TrimRight(u, ".,;:!?*_"))
+	}
+	for _, re := range []*regexp.Regexp{markdownLinkPattern, htmlLinkPattern} {
+		for _, m := range re.FindAllStringSubmatch(content, -1) {
+			links = append(links, m[1])
+		}
+	}
+	for _, m := range srcsetPattern.FindAllStringSubmatch(content, -1) {
+		for _, candidate := range strings.Split(m[1], ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				links = append(links, fields[0])
+			}
+		}
+	}
+	return links
+}
+
+// linkedKeys returns the storage keys a link may point to: every suffix of
+// its path, since the base URL a store serves keys under is not known here.
+// Scheme, host, query and fragment are ignored, so /static/uploads/a.png,
+// https://cdn.example.com/uploads/a.png?v=2 and uploads/a.png all yield
+// uploads/a.png.
+func linkedKeys(link string) []string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil
+	}
+	p := strings.Trim(u.Path, "/")
+	if p == "" {
+		return nil
+	}
+	var keys []string
+	for {
+		keys = append(keys, p)
+		_, rest, ok := strings.Cut(p, "/")
+		if !ok {
+			return keys
+		}
+		p = rest
+	}
+}
+
+// refreshMediaReferences rewrites the media references of a post from the
+// links in its content and cover, matched against the storage keys of media
+// files and their variants.
+func refreshMediaReferences(tx *gorm.DB, post *entity.Post) error {
+	candidates := map[string][]string{}
+	add := func(link, field string) {
+		for _, key := range linkedKeys(link) {
+			if !slices.Contains(candidates[key], field) {
+				candidates[key] = append(candidates[key], field)
+			}
+		}
+	}
+	for _, link := range mediaLinks(post.Content) {
+		add(link, entity.MediaRefContent)
+	}
+	add(post.Cover, entity.MediaRefCover)
+
+	if err := tx.Where("post_id = ?", post.ID).Delete(&entity.MediaReference{}).Error; err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+
+	var matches []struct {
+		ID   int64
+		Path string
+	}
+	if err := tx.Model(&entity.Media{}).Select("id, path").Where("path IN ?", keys).Scan(&matches).Error; err != nil {
+		return err
+	}
+	var variantMatches []struct {
+		ID   int64
+		Path string
+	}
+	err := tx.Model(&entity.MediaVariant{}).Select("media_id AS id, path").Where("path IN ?", keys).Scan(&variantMatches).Error
+	if err != nil {
+		return err
+	}
+
+	seen := map[entity.MediaReference]bool{}
+	refs := make([]entity.MediaReference, 0, len(matches)+len(variantMatches))
+	for _, m := range append(matches, variantMatches...) {
+		for _, field := range candidates[m.Path] {
+			ref := entity.MediaReference{MediaID: m.ID, PostID: post.ID, Field: field}
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	return tx.Create(&refs).Error
+}
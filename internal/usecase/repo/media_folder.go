@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type mediaFolderRepo struct {
+	db *gorm.DB
+}
+
+func NewMediaFolderRepo(db *gorm.DB) usecase.MediaFolderRepo {
+	return &mediaFolderRepo{db: db}
+}
+
+func (r *mediaFolderRepo) Create(ctx context.Context, folder *entity.MediaFolder) error {
+	return r.db.WithContext(ctx).Create(folder).Error
+}
+
+func (r *mediaFolderRepo) GetByID(ctx context.Context, id int64) (*entity.MediaFolder, error) {
+	var folder entity.MediaFolder
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&folder).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("media folder not found")
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *mediaFolderRepo) List(ctx context.Context) ([]entity.MediaFolder, error) {
+	var folders []entity.MediaFolder
+	err := r.db.WithContext(ctx).Order("name ASC").Find(&folders).Error
+	return folders, err
+}
+
+func (r *mediaFolderRepo) NameExists(ctx context.Context, name string, excludeID int64) (bool, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&entity.MediaFolder{}).Where("name = ?", name)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *mediaFolderRepo) Update(ctx context.Context, folder *entity.MediaFolder) error {
+	return r.db.WithContext(ctx).Save(folder).Error
+}
+
+func (r *mediaFolderRepo) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.Media{}).Where("folder_id = ?", id).UpdateColumn("folder_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entity.MediaFolder{}).Error
+	})
+}
+
+func (r *mediaFolderRepo) CountMedia(ctx context.Context) (map[int64]int64, error) {
+	var rows []struct {
+		FolderID int64
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.Media{}).
+		Select("folder_id, COUNT(*) AS count").
+		Where("folder_id IS NOT NULL").
+		Group("folder_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.FolderID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMediaLinks(t *testing.T) {
+	content := `Intro ![cat](/static/uploads/cat.png "Cat") and [doc](uploads/doc.pdf).
+See https://blog.example.com/static/uploads/a.jpg, then
+<img src="/static/uploads/b.webp" srcset="/static/uploads/b-480.webp 480w, /static/uploads/b-960.webp 960w">
+<video poster='//cdn.example.com/uploads/poster.jpg'></video>`
+
+	got := mediaLinks(content)
+	for _, want := range []string{
+		"/static/uploads/cat.png",
+		"uploads/doc.pdf",
+		"https://blog.example.com/static/uploads/a.jpg",
+		"/static/uploads/b.webp",
+		"/static/uploads/b-480.webp",
+		"/static/uploads/b-960.webp",
+		"//cdn.example.com/uploads/poster.jpg",
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("mediaLinks missing %q, got %q", want, got)
+		}
+	}
+}
+
+func TestLinkedKeys(t *testing.T) {
+	tests := []struct {
+		link string
+		want []string
+	}{
+		{"/static/uploads/a.png", []string{"static/uploads/a.png", "uploads/a.png", "a.png"}},
+		{"https://cdn.example.com/uploads/a.png?v=2#top", []string{"uploads/a.png", "a.png"}},
+		{"http://OLD.example.com:8080/static/uploads/a%20b.png", []string{"static/uploads/a b.png", "uploads/a b.png", "a b.png"}},
+		{"uploads/a.png", []string{"uploads/a.png", "a.png"}},
+		{"https://example.com/", nil},
+		{"data:image/png;base64,AAAA", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := linkedKeys(tt.link); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("linkedKeys(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+// A relative link and an absolute one on another host resolve to the same
+// storage key as the media row, so the file counts as used.
+func TestRelativeLinkMatchesStoragePath(t *testing.T) {
+	const mediaPath = "uploads/2024/cat.png"
+	for _, content := range []string{
+		"![cat](/static/uploads/2024/cat.png)",
+		`<img src="https://cdn.example.com/uploads/2024/cat.png?w=640">`,
+	} {
+		found := false
+		for _, link := range mediaLinks(content) {
+			found = found || slices.Contains(linkedKeys(link), mediaPath)
+		}
+		if !found {
+			t.Errorf("%q does not reference %s", content, mediaPath)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type postRelatedRepo struct {
+	db *gorm.DB
+}
+
+func NewPostRelatedRepo(db *gorm.DB) usecase.PostRelatedRepo {
+	return &postRelatedRepo{db: db}
+}
+
+// ReplaceAll swaps every stored score for rows in one transaction, so readers
+// never see a half-built table.
+func (r *postRelatedRepo) ReplaceAll(ctx context.Context, rows []entity.PostRelated) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&entity.PostRelated{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+}
+
+// ListRelated returns the highest scoring posts related to postID that are
+// live at now.
+func (r *postRelatedRepo) ListRelated(ctx context.Context, postID int64, now time.Time, limit int) ([]entity.Post, error) {
+	var posts []entity.Post
+	err := r.db.WithContext(ctx).
+		Model(&entity.Post{}).
+		Select("posts.*").
+		Joins("JOIN post_related ON post_related.related_id = posts.id").
+		Where("post_related.post_id = ? AND posts.status = ?", postID, "published").
+		Where(liveCondition, now, now).
+		Order("post_related.score DESC, posts.publish_at DESC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListRelatedSkipsExpiredPosts(t *testing.T) {
+	db, statements := dryRunMySQL(t)
+	repo := &postRelatedRepo{db: db}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := repo.ListRelated(context.Background(), 7, now, 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(*statements) != 1 {
+		t.Fatalf("got %d statements: %q", len(*statements), *statements)
+	}
+	want := "posts.publish_at <= '2024-05-01 12:00:00' AND (posts.unpublish_at IS NULL OR posts.unpublish_at > '2024-05-01 12:00:00')"
+	if got := (*statements)[0]; !strings.Contains(got, want) {
+		t.Errorf("query lacks the live window:\n%s", got)
+	}
+}
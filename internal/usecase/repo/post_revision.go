@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type postRevisionRepo struct {
+	db *gorm.DB
+}
+
+func NewPostRevisionRepo(db *gorm.DB) usecase.PostRevisionRepo {
+	return &postRevisionRepo{db: db}
+}
+
+// createRevision assigns the next revision number and stores the snapshot.
+// It must run inside a transaction, usually the one that saved the post.
+func createRevision(tx *gorm.DB, rev *entity.PostRevision) error {
+	// Lock the post row so concurrent saves get distinct revision numbers.
+	var post entity.Post
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", rev.PostID).First(&post).Error; err != nil {
+		return err
+	}
+	var latest int
+	if err := tx.Model(&entity.PostRevision{}).
+		Where("post_id = ?", rev.PostID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+	rev.Revision = latest + 1
+	return tx.Create(rev).Error
+}
+
+func (r *postRevisionRepo) ListByPostID(ctx context.Context, postID int64) ([]entity.PostRevision, error) {
+	var revs []entity.PostRevision
+	err := r.db.WithContext(ctx).
+		Omit("content").
+		Where("post_id = ?", postID).
+		Order("revision DESC").
+		Find(&revs).Error
+	return revs, err
+}
+
+func (r *postRevisionRepo) GetByRevision(ctx context.Context, postID int64, revision int) (*entity.PostRevision, error) {
+	var rev entity.PostRevision
+	err := r.db.WithContext(ctx).Where("post_id = ? AND revision = ?", postID, revision).First(&rev).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, err
+	}
+	return &rev, nil
+}
@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/markdown"
+	"blog/pkg/search"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Field weights for the MySQL fallback, mirroring setweight A/B/C on Postgres.
+	titleWeight   = 1.0
+	excerptWeight = 0.4
+	contentWeight = 0.1
+	// fallbackLengthNorm is the field length, in characters, at which a
+	// match counts half as much, so that long posts do not win on volume.
+	fallbackLengthNorm = 500
+	// searchIndexVersion is bumped when the indexed tokens change, so that
+	// RebuildSearchIndex refreshes the vectors written by older versions.
+	// Version 2 adds CJK unigrams.
+	searchIndexVersion = 2
+)
+
+// Search runs a ranked full-text query. On PostgreSQL it uses the weighted
+// search_vector column (GIN indexed); other dialects fall back to LIKE
+// matching ranked in SQL.
+func (r *postRepo) Search(ctx context.Context, query string, filters map[string]interface{}, page, limit int) ([]entity.PostSearchHit, int64, error) {
+	tokens := search.Tokenize(query)
+	if len(tokens) == 0 {
+		return []entity.PostSearchHit{}, 0, nil
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	if r.db.Dialector.Name() == "postgres" {
+		return r.searchPostgres(ctx, strings.Join(tokens, " "), filters, page, limit)
+	}
+	return r.searchFallback(ctx, tokens, filters, page, limit)
+}
+
+func (r *postRepo) searchPostgres(ctx context.Context, normalized string, filters map[string]interface{}, page, limit int) ([]entity.PostSearchHit, int64, error) {
+	var total int64
+	query := applyPostFilters(r.db.WithContext(ctx).Model(&entity.Post{}), filters).
+		Where("search_vector @@ plainto_tsquery('simple', ?)", normalized)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		entity.Post
+		Rank float64
+	}
+	err := query.
+		Select("posts.*, ts_rank_cd(search_vector, plainto_tsquery('simple', ?)) AS rank", normalized).
+		Order("rank DESC, publish_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]entity.PostSearchHit, len(rows))
+	for i := range rows {
+		hits[i] = entity.PostSearchHit{Post: rows[i].Post, Rank: rows[i].Rank}
+	}
+	return hits, total, nil
+}
+
+func (r *postRepo) searchFallback(ctx context.Context, tokens []string, filters map[string]interface{}, page, limit int) ([]entity.PostSearchHit, int64, error) {
+	query := applyPostFilters(r.db.WithContext(ctx).Model(&entity.Post{}), filters)
+	for _, t := range tokens {
+		like := "%" + t + "%"
+		query = query.Where("(title LIKE ? OR excerpt LIKE ? OR content LIKE ?)", like, like, like)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	rank, args := fallbackRank(tokens)
+	var rows []struct {
+		entity.Post
+		Rank float64
+	}
+	err := query.
+		Select("posts.*, "+rank+" AS `rank`", args...).
+		Order("`rank` DESC, publish_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]entity.PostSearchHit, len(rows))
+	for i := range rows {
+		hits[i] = entity.PostSearchHit{Post: rows[i].Post, Rank: rows[i].Rank}
+	}
+	return hits, total, nil
+}
+
+// fallbackRank builds the SQL expression scoring a post by weighted term
+// frequency: the occurrences of each token in title, excerpt and content,
+// damped by the length of the field. It returns the expression and its
+// arguments, in order.
+func fallbackRank(tokens []string) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for _, field := range []struct {
+		column string
+		weight float64
+	}{
+		{"title", titleWeight},
+		{"excerpt", excerptWeight},
+		{"content", contentWeight},
+	} {
+		for _, t := range tokens {
+			terms = append(terms, fmt.Sprintf(
+				"%g * (CHAR_LENGTH(%[2]s) - CHAR_LENGTH(REPLACE(LOWER(%[2]s), ?, ''))) / %[3]d.0 / (1 + CHAR_LENGTH(%[2]s) / %[4]d.0)",
+				field.weight, field.column, utf8.RuneCountInString(t), fallbackLengthNorm,
+			))
+			args = append(args, t)
+		}
+	}
+	return "(" + strings.Join(terms, " + ") + ")", args
+}
+
+// RebuildSearchIndex computes search_vector for posts that predate the index
+// or were indexed by an older tokenizer.
+func (r *postRepo) RebuildSearchIndex(ctx context.Context) error {
+	if r.db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	const batchSize = 100
+	for {
+		var posts []entity.Post
+		err := r.db.WithContext(ctx).
+			Where("search_vector IS NULL OR search_index_version < ?", searchIndexVersion).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&posts).Error
+		if err != nil {
+			return err
+		}
+		for i := range posts {
+			if err := refreshSearchVector(r.db.WithContext(ctx), &posts[i]); err != nil {
+				return err
+			}
+		}
+		if len(posts) < batchSize {
+			return nil
+		}
+	}
+}
+
+// refreshSearchVector rewrites the weighted tsvector of a post. Text is
+// pre-tokenized in Go (CJK bigrams and unigrams) and indexed with the 'simple' config so
+// that no language-specific stemming interferes with Chinese content.
+func refreshSearchVector(tx *gorm.DB, post *entity.Post) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec(`UPDATE posts SET search_vector =
+		setweight(to_tsvector('simple', ?), 'A') ||
+		setweight(to_tsvector('simple', ?), 'B') ||
+		setweight(to_tsvector('simple', ?), 'C'),
+		search_index_version = ?
+		WHERE id = ?`,
+		search.Normalize(post.Title),
+		search.Normalize(post.Excerpt),
+		search.Normalize(markdown.ToPlainText(post.Content)),
+		searchIndexVersion,
+		post.ID,
+	).Error
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dryRunMySQL returns a MySQL handle that builds statements without a
+// server, and the SQL of every query it was asked to run.
+func dryRunMySQL(t *testing.T) (*gorm.DB, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/blog",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statements []string
+	err = db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+		// Dry runs keep the built SQL on the statement; drop it as a real
+		// run would, so that a chained query is built afresh.
+		tx.Statement.SQL.Reset()
+		tx.Statement.Vars = nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, &statements
+}
+
+func TestSearchFallbackRanksInSQL(t *testing.T) {
+	db, statements := dryRunMySQL(t)
+	repo := &postRepo{db: db}
+
+	if _, _, err := repo.Search(context.Background(), "Go 并发", nil, 3, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(*statements) != 2 {
+		t.Fatalf("got %d statements, want count and page: %q", len(*statements), *statements)
+	}
+
+	count, page := (*statements)[0], (*statements)[1]
+	if !strings.Contains(count, "SELECT count(*)") || strings.Contains(count, "LIMIT") {
+		t.Errorf("total must count every match, got %s", count)
+	}
+	for _, want := range []string{
+		"REPLACE(LOWER(title), 'go', '')",
+		"REPLACE(LOWER(content), '并发', '')",
+		"ORDER BY `rank` DESC, publish_at DESC",
+		"LIMIT 10 OFFSET 20",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page query missing %q: %s", want, page)
+		}
+	}
+}
+
+func TestFallbackRankArgs(t *testing.T) {
+	expr, args := fallbackRank([]string{"go", "并发"})
+	if got := strings.Count(expr, "?"); got != len(args) {
+		t.Fatalf("%d placeholders for %d args", got, len(args))
+	}
+	// The token length divides the removed characters, so multi-byte
+	// tokens count once per occurrence.
+	if !strings.Contains(expr, "/ 2.0 /") || strings.Contains(expr, "/ 6.0 /") {
+		t.Errorf("token length must be counted in characters: %s", expr)
+	}
+	if len(args) != 6 {
+		t.Errorf("got %d args, want one per token and field", len(args))
+	}
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type seriesRepo struct {
+	db *gorm.DB
+}
+
+func NewSeriesRepo(db *gorm.DB) usecase.SeriesRepo {
+	return &seriesRepo{db: db}
+}
+
+// Create saves the series with postIDs as its parts, in order.
+func (r *seriesRepo) Create(ctx context.Context, series *entity.Series, postIDs []int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(series).Error; err != nil {
+			return err
+		}
+		return replaceSeriesPosts(tx, series.ID, postIDs)
+	})
+}
+
+// Update saves the series and, unless postIDs is nil, replaces its parts.
+func (r *seriesRepo) Update(ctx context.Context, series *entity.Series, postIDs []int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(series).Error; err != nil {
+			return err
+		}
+		if postIDs == nil {
+			return nil
+		}
+		return replaceSeriesPosts(tx, series.ID, postIDs)
+	})
+}
+
+func replaceSeriesPosts(tx *gorm.DB, seriesID int64, postIDs []int64) error {
+	if err := tx.Where("series_id = ?", seriesID).Delete(&entity.SeriesPost{}).Error; err != nil {
+		return err
+	}
+	if len(postIDs) == 0 {
+		return nil
+	}
+	members := make([]entity.SeriesPost, 0, len(postIDs))
+	for i, postID := range postIDs {
+		members = append(members, entity.SeriesPost{SeriesID: seriesID, PostID: postID, Position: i + 1})
+	}
+	return tx.Create(&members).Error
+}
+
+func (r *seriesRepo) GetByID(ctx context.Context, id int64) (*entity.Series, error) {
+	var series entity.Series
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&series).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("series not found")
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *seriesRepo) GetBySlug(ctx context.Context, slug string) (*entity.Series, error) {
+	var series entity.Series
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&series).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("series not found")
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (r *seriesRepo) SlugExists(ctx context.Context, slug string, excludeID int64) (bool, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&entity.Series{}).Where("slug = ?", slug)
+	if excludeID != 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *seriesRepo) List(ctx context.Context) ([]entity.Series, error) {
+	var series []entity.Series
+	err := r.db.WithContext(ctx).Order("title ASC").Find(&series).Error
+	return series, err
+}
+
+// ListLive returns the series with at least one post live at now.
+func (r *seriesRepo) ListLive(ctx context.Context, now time.Time) ([]entity.Series, error) {
+	var series []entity.Series
+	err := r.db.WithContext(ctx).
+		Where(`EXISTS (
+			SELECT 1 FROM series_posts JOIN posts ON posts.id = series_posts.post_id
+			WHERE series_posts.series_id = series.id AND posts.status = ? AND `+liveCondition+`
+		)`, "published", now, now).
+		Order("title ASC").
+		Find(&series).Error
+	return series, err
+}
+
+func (r *seriesRepo) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", id).Delete(&entity.SeriesPost{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entity.Series{}).Error
+	})
+}
+
+// ListPosts returns the memberships of a series in reading order.
+func (r *seriesRepo) ListPosts(ctx context.Context, seriesID int64) ([]entity.SeriesPost, error) {
+	var members []entity.SeriesPost
+	err := r.db.WithContext(ctx).
+		Where("series_id = ?", seriesID).
+		Order("position ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// CountPosts returns the number of posts in each of the series.
+func (r *seriesRepo) CountPosts(ctx context.Context, seriesIDs []int64) (map[int64]int, error) {
+	counts := make(map[int64]int, len(seriesIDs))
+	if len(seriesIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		SeriesID int64
+		Count    int
+	}
+	err := r.db.WithContext(ctx).Model(&entity.SeriesPost{}).
+		Select("series_id, COUNT(*) AS count").
+		Where("series_id IN ?", seriesIDs).
+		Group("series_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.SeriesID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetMembership returns the series membership of each post that has one.
+func (r *seriesRepo) GetMembership(ctx context.Context, postIDs []int64) (map[int64]entity.SeriesPost, error) {
+	members := make(map[int64]entity.SeriesPost, len(postIDs))
+	if len(postIDs) == 0 {
+		return members, nil
+	}
+	var rows []entity.SeriesPost
+	if err := r.db.WithContext(ctx).Where("post_id IN ?", postIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		members[row.PostID] = row
+	}
+	return members, nil
+}
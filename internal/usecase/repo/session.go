@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type sessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepo(db *gorm.DB) usecase.SessionRepo {
+	return &sessionRepo{db: db}
+}
+
+func (r *sessionRepo) Create(ctx context.Context, session *entity.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepo) GetByID(ctx context.Context, id string) (*entity.Session, error) {
+	var session entity.Session
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) MarkRotated(ctx context.Context, id string) (bool, error) {
+	// The guard makes concurrent refreshes with the same token race-safe:
+	// only one of them can rotate it.
+	res := r.db.WithContext(ctx).Model(&entity.Session{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *sessionRepo) ListActive(ctx context.Context, userID int64) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepo) RevokeFamily(ctx context.Context, userID int64, familyID string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&entity.Session{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *sessionRepo) RevokeUser(ctx context.Context, userID int64) error {
+	return r.db.WithContext(ctx).Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepo) IsRevoked(ctx context.Context, familyID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.Session{}).
+		Where("family_id = ? AND revoked_at IS NOT NULL", familyID).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *sessionRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&entity.Session{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type spamRepo struct {
+	db *gorm.DB
+}
+
+func NewSpamRepo(db *gorm.DB) usecase.SpamRepo {
+	return &spamRepo{db: db}
+}
+
+func (r *spamRepo) GetTokenCounts(ctx context.Context, tokens []string) (map[string]entity.SpamToken, error) {
+	out := make(map[string]entity.SpamToken, len(tokens))
+	if len(tokens) == 0 {
+		return out, nil
+	}
+	var rows []entity.SpamToken
+	if err := r.db.WithContext(ctx).Where("token IN ?", tokens).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.Token] = row
+	}
+	return out, nil
+}
+
+func (r *spamRepo) GetCorpus(ctx context.Context) (spamDocs, hamDocs int64, err error) {
+	var rows []entity.SpamCorpus
+	if err := r.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return 0, 0, err
+	}
+	for _, row := range rows {
+		switch row.Label {
+		case entity.SpamLabelSpam:
+			spamDocs = row.Documents
+		case entity.SpamLabelHam:
+			hamDocs = row.Documents
+		}
+	}
+	return spamDocs, hamDocs, nil
+}
+
+func (r *spamRepo) Learn(ctx context.Context, tokens []string, label string, delta int64) error {
+	column := "ham_count"
+	if label == entity.SpamLabelSpam {
+		column = "spam_count"
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Make sure every row exists, then adjust counts in place so concurrent
+		// training never loses an increment. Counts are clamped at zero.
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&entity.SpamCorpus{Label: label}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.SpamCorpus{}).Where("label = ?", label).
+			UpdateColumn("documents", gorm.Expr("GREATEST(documents + ?, 0)", delta)).Error; err != nil {
+			return err
+		}
+
+		if len(tokens) == 0 {
+			return nil
+		}
+		rows := make([]entity.SpamToken, 0, len(tokens))
+		for _, t := range tokens {
+			rows = append(rows, entity.SpamToken{Token: t})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.SpamToken{}).Where("token IN ?", tokens).
+			UpdateColumn(column, gorm.Expr("GREATEST("+column+" + ?, 0)", delta)).Error
+	})
+}
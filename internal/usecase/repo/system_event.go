@@ -4,6 +4,7 @@ import (
 	"blog/internal/entity"
 	"blog/internal/usecase"
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -106,3 +107,10 @@ func (r *systemEventRepo) GetByEventType(ctx context.Context, eventType entity.E
 		Find(&events).Error
 	return events, err
 }
+
+func (r *systemEventRepo) AnonymizeBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&entity.SystemEvent{}).
+		Where("created_at < ? AND (ip <> '' OR user_agent <> '')", t).
+		Updates(map[string]any{"ip": "", "user_agent": ""})
+	return result.RowsAffected, result.Error
+}
@@ -5,6 +5,7 @@ import (
 	"blog/internal/usecase"
 	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -68,6 +69,18 @@ func (r *userRepo) GetByUsername(ctx context.Context, username string) (*entity.
 	return &user, nil
 }
 
+func (r *userRepo) GetByProvider(ctx context.Context, provider, providerID string) (*entity.User, error) {
+	var user entity.User
+	err := r.db.WithContext(ctx).Where("provider = ? AND provider_id = ?", provider, providerID).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepo) Update(ctx context.Context, user *entity.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
@@ -78,6 +91,62 @@ func (r *userRepo) BumpTokenVersion(ctx context.Context, id int64) error {
 		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
 }
 
+func (r *userRepo) AdvanceTOTPStep(ctx context.Context, id int64, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		UpdateColumn("totp_last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *userRepo) ListWithTOTPSecret(ctx context.Context) ([]entity.User, error) {
+	var users []entity.User
+	err := r.db.WithContext(ctx).
+		Select("id", "totp_secret").
+		Where("totp_secret IS NOT NULL AND totp_secret <> ''").
+		Find(&users).Error
+	return users, err
+}
+
+func (r *userRepo) ReplaceTOTPSecret(ctx context.Context, id int64, old, secret string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ? AND totp_secret = ?", id, old).
+		UpdateColumn("totp_secret", secret)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *userRepo) RecordLoginFailure(ctx context.Context, id int64, now, staleBefore time.Time) (int, error) {
+	var count int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Columns are assigned in key order, so failed_logins still sees the
+		// previous last_failed_login_at on MySQL as well.
+		err := tx.Model(&entity.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"failed_logins":        gorm.Expr("CASE WHEN last_failed_login_at IS NULL OR last_failed_login_at < ? THEN 1 ELSE failed_logins + 1 END", staleBefore),
+			"last_failed_login_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&entity.User{}).Where("id = ?", id).Select("failed_logins").Scan(&count).Error
+	})
+	return count, err
+}
+
+func (r *userRepo) SetLockedUntil(ctx context.Context, id int64, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", id).
+		UpdateColumn("locked_until", until).Error
+}
+
+func (r *userRepo) ResetLoginFailures(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ? AND (failed_logins > 0 OR locked_until IS NOT NULL)", id).
+		UpdateColumns(map[string]interface{}{
+			"failed_logins":        0,
+			"last_failed_login_at": nil,
+			"locked_until":         nil,
+		}).Error
+}
+
 func (r *userRepo) Delete(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.User{}).Error
 }
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"blog/internal/entity"
+	"blog/internal/usecase"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepo(db *gorm.DB) usecase.WebhookRepo {
+	return &webhookRepo{db: db}
+}
+
+func (r *webhookRepo) Create(ctx context.Context, webhook *entity.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepo) GetByID(ctx context.Context, id int64) (*entity.Webhook, error) {
+	var webhook entity.Webhook
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&webhook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepo) List(ctx context.Context) ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepo) ListActive(ctx context.Context) ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	err := r.db.WithContext(ctx).Where("active = ?", true).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepo) Update(ctx context.Context, webhook *entity.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+// Delete removes the webhook and its delivery log.
+func (r *webhookRepo) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&entity.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entity.Webhook{}).Error
+	})
+}
+
+func (r *webhookRepo) CreateDeliveries(ctx context.Context, deliveries []entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *webhookRepo) CreateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+// ListDueDeliveries returns pending deliveries whose next attempt is due,
+// and claimed ones whose claim has lapsed, oldest first.
+func (r *webhookRepo) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND next_attempt_at <= ?", []string{entity.WebhookDeliveryPending, entity.WebhookDeliverySending}, now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepo) ClaimDelivery(ctx context.Context, id int64, now, until time.Time) (bool, error) {
+	return claimDue(r.db.WithContext(ctx), &entity.WebhookDelivery{}, id, entity.WebhookDeliveryPending, entity.WebhookDeliverySending, now, until)
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first.
+func (r *webhookRepo) ListDeliveries(ctx context.Context, webhookID int64, page, limit int) ([]entity.WebhookDelivery, int64, error) {
+	var deliveries []entity.WebhookDelivery
+	var total int64
+	query := r.db.WithContext(ctx).Model(&entity.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page > 0 && limit > 0 {
+		query = query.Offset((page - 1) * limit).Limit(limit)
+	}
+	err := query.Order("id DESC").Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+func (r *webhookRepo) UpdateDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+// PruneDeliveries deletes finished deliveries created before cutoff.
+func (r *webhookRepo) PruneDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status IN ? AND created_at < ?", []string{entity.WebhookDeliverySuccess, entity.WebhookDeliveryFailed}, cutoff).
+		Delete(&entity.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
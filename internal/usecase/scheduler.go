@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	schedulerBatchSize   = 20
+	schedulerMaxAttempts = 8
+	schedulerHookTimeout = 30 * time.Second
+	// schedulerClaimLease is how long a claimed event is reserved for the
+	// instance running its hooks; a crashed instance's events are retried
+	// after.
+	schedulerClaimLease = 10 * time.Minute
+	// schedulerOverlap is how far before its cursor the scheduler looks
+	// again, so a post saved with a publish time just before another
+	// instance's check is not skipped. Posts get one event regardless.
+	schedulerOverlap = 5 * time.Minute
+	// publishCursor names the cursor of post publish times.
+	publishCursor = "post.published"
+)
+
+// EventHook reacts to a domain event. post is nil when the post was deleted
+// before the event was processed. A hook that fails is retried with backoff
+// until it succeeds; hooks that already succeeded do not run again.
+type EventHook func(ctx context.Context, event *entity.DomainEvent, post *entity.Post) error
+
+type namedHook struct {
+	name string
+	fn   EventHook
+}
+
+// Scheduler turns scheduled changes into domain events. It notices posts
+// whose publish time has passed and posts whose unpublish time has passed,
+// records one event per change and runs the hooks registered for it.
+// Publishing itself stays a read-time filter; the scheduler only makes the
+// moment observable.
+type Scheduler struct {
+	postRepo  PostRepo
+	eventRepo DomainEventRepo
+	hooks     map[string][]namedHook
+}
+
+func NewScheduler(postRepo PostRepo, eventRepo DomainEventRepo) *Scheduler {
+	return &Scheduler{
+		postRepo:  postRepo,
+		eventRepo: eventRepo,
+		hooks:     map[string][]namedHook{},
+	}
+}
+
+// On registers hook for eventType under a name that identifies it across
+// retries. Hooks must be registered before Run starts.
+func (s *Scheduler) On(eventType, name string, hook EventHook) {
+	s.hooks[eventType] = append(s.hooks[eventType], namedHook{name: name, fn: hook})
+}
+
+// Run checks for due changes and processes pending events every
+// scheduler.interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	interval := config.GetConf().Scheduler.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.detect(ctx, time.Now())
+		s.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// detect records a post.published event for each post that went live since
+// the last check, and expires posts past their unpublish time.
+func (s *Scheduler) detect(ctx context.Context, now time.Time) {
+	s.detectPublished(ctx, now)
+
+	expired, err := s.postRepo.ListExpired(ctx, now)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorw("Scheduler: list expired posts failed", log.Pair("error", err.Error()))
+		}
+		return
+	}
+	for _, post := range expired {
+		ok, err := s.postRepo.Expire(ctx, post.ID, now, newDomainEvent(entity.EventPostUnpublished, post.ID, *post.UnpublishAt, now))
+		if err != nil {
+			log.Errorw("Scheduler: expire post failed", log.Pair("post_id", post.ID), log.Pair("error", err.Error()))
+			continue
+		}
+		if ok {
+			log.Infow("Post unpublished", log.Pair("post_id", post.ID), log.Pair("slug", post.Slug))
+		}
+	}
+}
+
+// detectPublished records the first publication of posts whose publish time
+// passed since the cursor, then advances the cursor. On first start posts
+// already live are marked as announced without events; after downtime it
+// picks up where it stopped.
+func (s *Scheduler) detectPublished(ctx context.Context, now time.Time) {
+	since, err := s.eventRepo.GetCursor(ctx, publishCursor)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Errorw("Scheduler: load cursor failed", log.Pair("error", err.Error()))
+		}
+		return
+	}
+	if since == nil {
+		if err := s.postRepo.MarkAllPublished(ctx, now); err != nil {
+			if ctx.Err() == nil {
+				log.Errorw("Scheduler: mark live posts failed", log.Pair("error", err.Error()))
+			}
+			return
+		}
+	} else {
+		posts, err := s.postRepo.ListNewlyLive(ctx, since.Add(-schedulerOverlap), now)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorw("Scheduler: list newly live posts failed", log.Pair("error", err.Error()))
+			}
+			return
+		}
+		failed := false
+		for _, post := range posts {
+			recorded, err := s.postRepo.MarkPublished(ctx, post.ID, now, newDomainEvent(entity.EventPostPublished, post.ID, post.PublishAt, now))
+			if err != nil {
+				log.Errorw("Scheduler: record event failed", log.Pair("post_id", post.ID), log.Pair("error", err.Error()))
+				failed = true
+				continue
+			}
+			if recorded {
+				log.Infow("Post published", log.Pair("post_id", post.ID), log.Pair("slug", post.Slug))
+			}
+		}
+		if failed {
+			// Keep the cursor so the failed posts are retried.
+			return
+		}
+	}
+	if err := s.eventRepo.SetCursor(ctx, publishCursor, now); err != nil && ctx.Err() == nil {
+		log.Errorw("Scheduler: save cursor failed", log.Pair("error", err.Error()))
+	}
+}
+
+func (s *Scheduler) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := s.eventRepo.ListDue(ctx, time.Now(), schedulerBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorw("Scheduler: load events failed", log.Pair("error", err.Error()))
+			}
+			return
+		}
+		for i := range events {
+			if ctx.Err() != nil {
+				return
+			}
+			// Another instance may be running the same event's hooks.
+			now := time.Now()
+			claimed, err := s.eventRepo.Claim(ctx, events[i].ID, now, now.Add(schedulerClaimLease))
+			if err != nil {
+				log.Warnw("Scheduler: claim event failed", log.Pair("event_id", events[i].ID), log.Pair("error", err.Error()))
+				continue
+			}
+			if claimed {
+				s.process(ctx, &events[i])
+			}
+		}
+		if len(events) < schedulerBatchSize {
+			return
+		}
+	}
+}
+
+// process runs the hooks of a claimed event that have not succeeded yet.
+func (s *Scheduler) process(ctx context.Context, event *entity.DomainEvent) {
+	post, err := s.postRepo.GetByID(ctx, event.PostID)
+	if err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			// Released as it was, so it is retried on the next tick.
+			log.Warnw("Scheduler: load post failed", log.Pair("event_id", event.ID), log.Pair("error", err.Error()))
+			event.Status = entity.EventStatusPending
+			s.save(event)
+			return
+		}
+		post = nil
+	}
+
+	done := map[string]bool{}
+	if event.DoneHooks != "" {
+		for _, name := range strings.Split(event.DoneHooks, ",") {
+			done[name] = true
+		}
+	}
+	var failures []string
+	for _, hook := range s.hooks[event.Type] {
+		if done[hook.name] {
+			continue
+		}
+		hookCtx, cancel := context.WithTimeout(ctx, schedulerHookTimeout)
+		err := hook.fn(hookCtx, event, post)
+		cancel()
+		if err != nil {
+			failures = append(failures, hook.name+": "+err.Error())
+			log.Warnw("Scheduler: event hook failed",
+				log.Pair("event_id", event.ID),
+				log.Pair("type", event.Type),
+				log.Pair("hook", hook.name),
+				log.Pair("error", err.Error()),
+			)
+			continue
+		}
+		done[hook.name] = true
+		if event.DoneHooks != "" {
+			event.DoneHooks += ","
+		}
+		event.DoneHooks += hook.name
+	}
+
+	event.Attempts++
+	if len(failures) == 0 {
+		now := time.Now()
+		event.Status = entity.EventStatusDone
+		event.ProcessedAt = &now
+		event.LastError = ""
+	} else {
+		event.LastError = truncateRunes(strings.Join(failures, "; "), 500)
+		if event.Attempts >= schedulerMaxAttempts {
+			event.Status = entity.EventStatusFailed
+		} else {
+			event.Status = entity.EventStatusPending
+			event.NextAttemptAt = time.Now().Add(outboxBackoff(event.Attempts))
+		}
+	}
+	s.save(event)
+}
+
+// save records the outcome of processing event, which also releases its
+// claim.
+func (s *Scheduler) save(event *entity.DomainEvent) {
+	// Use a fresh context so the result is recorded even during shutdown.
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.eventRepo.Update(saveCtx, event); err != nil {
+		log.Errorw("Scheduler: update event failed", log.Pair("event_id", event.ID), log.Pair("error", err.Error()))
+	}
+}
+
+func newDomainEvent(eventType string, postID int64, occurredAt, now time.Time) *entity.DomainEvent {
+	return &entity.DomainEvent{
+		Type:          eventType,
+		PostID:        postID,
+		OccurredAt:    occurredAt,
+		Status:        entity.EventStatusPending,
+		NextAttemptAt: now,
+	}
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// schedulerPostRepo answers the scheduler's queries from memory.
+type schedulerPostRepo struct {
+	PostRepo
+	posts     map[int64]*entity.Post
+	announced []int64 // Posts a post.published event was recorded for
+}
+
+func (r *schedulerPostRepo) ListNewlyLive(_ context.Context, since, now time.Time) ([]entity.Post, error) {
+	var posts []entity.Post
+	for _, p := range r.posts {
+		if p.Status == "published" && p.PublishAt.After(since) && !p.PublishAt.After(now) && p.PublishedEventAt == nil {
+			posts = append(posts, *p)
+		}
+	}
+	return posts, nil
+}
+
+func (r *schedulerPostRepo) MarkPublished(_ context.Context, id int64, now time.Time, event *entity.DomainEvent) (bool, error) {
+	p := r.posts[id]
+	if p.PublishedEventAt != nil {
+		return false, nil
+	}
+	p.PublishedEventAt = &now
+	r.announced = append(r.announced, event.PostID)
+	return true, nil
+}
+
+func (r *schedulerPostRepo) MarkAllPublished(_ context.Context, now time.Time) error {
+	for _, p := range r.posts {
+		if p.Status == "published" && !p.PublishAt.After(now) && p.PublishedEventAt == nil {
+			p.PublishedEventAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *schedulerPostRepo) ListExpired(context.Context, time.Time) ([]entity.Post, error) {
+	return nil, nil
+}
+
+type schedulerEventRepo struct {
+	DomainEventRepo
+	cursor *time.Time
+}
+
+func (r *schedulerEventRepo) GetCursor(context.Context, string) (*time.Time, error) {
+	return r.cursor, nil
+}
+
+func (r *schedulerEventRepo) SetCursor(_ context.Context, _ string, checkedUntil time.Time) error {
+	r.cursor = &checkedUntil
+	return nil
+}
+
+func TestSchedulerAnnouncesEachPostOnce(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	posts := &schedulerPostRepo{posts: map[int64]*entity.Post{
+		1: {ID: 1, Status: "published", PublishAt: start.Add(-time.Hour)},
+		2: {ID: 2, Status: "published", PublishAt: start.Add(time.Hour)},
+		3: {ID: 3, Status: "published", PublishAt: start.Add(72 * time.Hour)},
+	}}
+	events := &schedulerEventRepo{}
+	s := NewScheduler(posts, events)
+
+	// First start: posts already live are marked, not announced.
+	s.detect(ctx, start)
+	if posts.posts[1].PublishedEventAt == nil || events.cursor == nil || !events.cursor.Equal(start) {
+		t.Fatalf("first start: post 1 marked at %v, cursor %v", posts.posts[1].PublishedEventAt, events.cursor)
+	}
+
+	s.detect(ctx, start.Add(2*time.Hour))
+
+	// Moving the publish time of a live post does not announce it again.
+	posts.posts[2].PublishAt = start.Add(3 * time.Hour)
+	s.detect(ctx, start.Add(4*time.Hour))
+
+	// Three days of downtime: the cursor picks up where it stopped.
+	s.detect(ctx, start.Add(100*time.Hour))
+
+	if want := []int64{2, 3}; !slices.Equal(posts.announced, want) {
+		t.Fatalf("announced posts %v, want %v", posts.announced, want)
+	}
+}
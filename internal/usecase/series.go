@@ -0,0 +1,303 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/cache"
+	"blog/pkg/util"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type SeriesUseCase struct {
+	seriesRepo SeriesRepo
+	postRepo   PostRepo
+	cache      responseCache
+}
+
+func NewSeriesUseCase(seriesRepo SeriesRepo, postRepo PostRepo, c cache.Cache) *SeriesUseCase {
+	return &SeriesUseCase{seriesRepo: seriesRepo, postRepo: postRepo, cache: newResponseCache(c)}
+}
+
+// List returns every series with its number of posts, for the admin panel.
+func (uc *SeriesUseCase) List(ctx context.Context) ([]entity.SeriesResponse, error) {
+	series, err := uc.seriesRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(series))
+	for _, s := range series {
+		ids = append(ids, s.ID)
+	}
+	counts, err := uc.seriesRepo.CountPosts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]entity.SeriesResponse, 0, len(series))
+	for i := range series {
+		item := toSeriesResponse(&series[i])
+		item.PostCount = counts[series[i].ID]
+		resp = append(resp, item)
+	}
+	return resp, nil
+}
+
+// Get returns a series with all its posts, drafts included, for the admin panel.
+func (uc *SeriesUseCase) Get(ctx context.Context, id int64) (*entity.SeriesResponse, error) {
+	series, err := uc.seriesRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := seriesPosts(ctx, uc.seriesRepo, uc.postRepo, series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toSeriesResponse(series)
+	for i := range posts {
+		item := toSeriesPostItem(&posts[i], i+1)
+		item.Status = posts[i].Status
+		resp.Posts = append(resp.Posts, item)
+	}
+	resp.PostCount = len(resp.Posts)
+	return &resp, nil
+}
+
+// GetBySlug returns a series with its live posts, numbered in reading order.
+// A series with no live posts is not found.
+func (uc *SeriesUseCase) GetBySlug(ctx context.Context, slug string) (*entity.SeriesResponse, error) {
+	series, err := uc.seriesRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := seriesPosts(ctx, uc.seriesRepo, uc.postRepo, series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toSeriesResponse(series)
+	for _, post := range livePosts(posts, time.Now()) {
+		resp.Posts = append(resp.Posts, toSeriesPostItem(&post, len(resp.Posts)+1))
+	}
+	if len(resp.Posts) == 0 {
+		return nil, errors.New("series not found")
+	}
+	resp.PostCount = len(resp.Posts)
+	return &resp, nil
+}
+
+func (uc *SeriesUseCase) Create(ctx context.Context, req entity.SeriesRequest) (*entity.SeriesResponse, error) {
+	series := &entity.Series{}
+	if err := uc.apply(ctx, series, req); err != nil {
+		return nil, err
+	}
+	if req.PostIDs == nil {
+		req.PostIDs = []int64{}
+	}
+	if err := uc.seriesRepo.Create(ctx, series, req.PostIDs); err != nil {
+		return nil, err
+	}
+	uc.invalidate(ctx)
+	return uc.Get(ctx, series.ID)
+}
+
+func (uc *SeriesUseCase) Update(ctx context.Context, id int64, req entity.SeriesRequest) (*entity.SeriesResponse, error) {
+	series, err := uc.seriesRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.apply(ctx, series, req); err != nil {
+		return nil, err
+	}
+	if err := uc.seriesRepo.Update(ctx, series, req.PostIDs); err != nil {
+		return nil, err
+	}
+	uc.invalidate(ctx)
+	return uc.Get(ctx, series.ID)
+}
+
+// Delete removes the series; its posts stay, outside any series.
+func (uc *SeriesUseCase) Delete(ctx context.Context, id int64) error {
+	if _, err := uc.seriesRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if err := uc.seriesRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	uc.invalidate(ctx)
+	return nil
+}
+
+// apply validates req and copies it onto series.
+func (uc *SeriesUseCase) apply(ctx context.Context, series *entity.Series, req entity.SeriesRequest) error {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return fmt.Errorf("%w: title cannot be empty", ErrInvalidArgument)
+	}
+	slug := strings.TrimSpace(req.Slug)
+	if slug == "" {
+		slug = util.GenerateSlug(title)
+	}
+	if !util.IsValidSlug(slug) {
+		return fmt.Errorf("%w: invalid slug %q", ErrInvalidArgument, slug)
+	}
+	exists, err := uc.seriesRepo.SlugExists(ctx, slug, series.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: slug %q is already used by another series", ErrInvalidArgument, slug)
+	}
+	if err := uc.validatePosts(ctx, series.ID, req.PostIDs); err != nil {
+		return err
+	}
+
+	series.Title = title
+	series.Slug = slug
+	series.Description = strings.TrimSpace(req.Description)
+	return nil
+}
+
+// validatePosts checks that the posts exist, are listed once and are not
+// already part of another series.
+func (uc *SeriesUseCase) validatePosts(ctx context.Context, seriesID int64, postIDs []int64) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	seen := make(map[int64]bool, len(postIDs))
+	for _, id := range postIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: post %d is listed twice", ErrInvalidArgument, id)
+		}
+		seen[id] = true
+	}
+	posts, err := uc.postRepo.GetByIDs(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+	if len(posts) != len(postIDs) {
+		return fmt.Errorf("%w: some posts do not exist", ErrInvalidArgument)
+	}
+	members, err := uc.seriesRepo.GetMembership(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range postIDs {
+		if m, ok := members[id]; ok && m.SeriesID != seriesID {
+			return fmt.Errorf("%w: post %d already belongs to another series", ErrInvalidArgument, id)
+		}
+	}
+	return nil
+}
+
+// invalidate drops cached posts, whose series block may have changed.
+func (uc *SeriesUseCase) invalidate(ctx context.Context) {
+	uc.cache.bump(ctx, cacheNSPost)
+}
+
+// seriesPosts loads the posts of a series in reading order.
+func seriesPosts(ctx context.Context, seriesRepo SeriesRepo, postRepo PostRepo, seriesID int64) ([]entity.Post, error) {
+	members, err := seriesRepo.ListPosts(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.PostID)
+	}
+	posts, err := postRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]entity.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	ordered := make([]entity.Post, 0, len(posts))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered, nil
+}
+
+// livePosts keeps the posts that are live at now.
+func livePosts(posts []entity.Post, now time.Time) []entity.Post {
+	live := make([]entity.Post, 0, len(posts))
+	for _, p := range posts {
+		if isLive(&p, now) {
+			live = append(live, p)
+		}
+	}
+	return live
+}
+
+// seriesBlock places a post within its series, counting live posts only. It
+// returns nil when the post is in no series or is not live itself.
+func seriesBlock(ctx context.Context, seriesRepo SeriesRepo, postRepo PostRepo, postID int64) (*entity.PostSeriesBlock, error) {
+	members, err := seriesRepo.GetMembership(ctx, []int64{postID})
+	if err != nil {
+		return nil, err
+	}
+	member, ok := members[postID]
+	if !ok {
+		return nil, nil
+	}
+	series, err := seriesRepo.GetByID(ctx, member.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := seriesPosts(ctx, seriesRepo, postRepo, series.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	live := livePosts(posts, time.Now())
+	for i := range live {
+		if live[i].ID != postID {
+			continue
+		}
+		block := &entity.PostSeriesBlock{
+			ID:    series.ID,
+			Title: series.Title,
+			Slug:  series.Slug,
+			Part:  i + 1,
+			Total: len(live),
+		}
+		if i > 0 {
+			block.Prev = &entity.SeriesLink{Slug: live[i-1].Slug, Title: live[i-1].Title}
+		}
+		if i+1 < len(live) {
+			block.Next = &entity.SeriesLink{Slug: live[i+1].Slug, Title: live[i+1].Title}
+		}
+		return block, nil
+	}
+	return nil, nil
+}
+
+func toSeriesResponse(s *entity.Series) entity.SeriesResponse {
+	return entity.SeriesResponse{
+		ID:          s.ID,
+		Title:       s.Title,
+		Slug:        s.Slug,
+		Description: s.Description,
+		Posts:       []entity.SeriesPostItem{},
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+func toSeriesPostItem(p *entity.Post, part int) entity.SeriesPostItem {
+	return entity.SeriesPostItem{
+		ID:        p.ID,
+		Slug:      p.Slug,
+		Title:     p.Title,
+		Excerpt:   p.Excerpt,
+		Cover:     p.Cover,
+		PublishAt: p.PublishAt,
+		Part:      part,
+	}
+}
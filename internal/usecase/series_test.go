@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLivePosts(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+	posts := []entity.Post{
+		{ID: 1, Status: "published", PublishAt: now.Add(-time.Hour)},
+		{ID: 2, Status: "published", PublishAt: now.Add(time.Hour)},
+		{ID: 3, Status: "draft", PublishAt: now.Add(-time.Hour)},
+		{ID: 4, Status: "published", PublishAt: now.Add(-time.Hour), UnpublishAt: at(-time.Minute)},
+		{ID: 5, Status: "published", PublishAt: now.Add(-time.Hour), UnpublishAt: at(0)},
+		{ID: 6, Status: "published", PublishAt: now, UnpublishAt: at(time.Minute)},
+	}
+
+	var got []int64
+	for _, p := range livePosts(posts, now) {
+		got = append(got, p.ID)
+	}
+	if want := []int64{1, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("live = %v, want %v", got, want)
+	}
+}
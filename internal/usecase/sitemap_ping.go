@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pingClient is used for sitemap pings, which should answer quickly.
+var pingClient = &http.Client{Timeout: 10 * time.Second}
+
+// PingSitemaps tells each endpoint in scheduler.sitemap_ping that the
+// sitemap changed, as ?sitemap=<site URL>/sitemap.xml. It is a
+// post.published and post.unpublished hook.
+func PingSitemaps(ctx context.Context, event *entity.DomainEvent, post *entity.Post) error {
+	endpoints := config.GetConf().Scheduler.SitemapPing
+	if len(endpoints) == 0 || siteURL() == "" {
+		return nil
+	}
+	sitemap := url.QueryEscape(siteURL() + "/sitemap.xml")
+
+	var errs []error
+	for _, endpoint := range endpoints {
+		sep := "?"
+		if strings.Contains(endpoint, "?") {
+			sep = "&"
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+sep+"sitemap="+sitemap, nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resp, err := pingClient.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			errs = append(errs, fmt.Errorf("ping %s: %s", endpoint, resp.Status))
+		}
+	}
+	return errors.Join(errs...)
+}
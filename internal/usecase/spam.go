@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/spam"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SpamChecker scores a comment before it is stored. Higher scores mean the
+// comment is more likely spam; 0 means no signal at all.
+type SpamChecker interface {
+	Check(ctx context.Context, comment *entity.Comment) (entity.SpamVerdict, error)
+}
+
+// SpamTrainer learns from moderation decisions.
+// label is entity.SpamLabelSpam or entity.SpamLabelHam.
+type SpamTrainer interface {
+	Learn(ctx context.Context, content, label string) error
+	Unlearn(ctx context.Context, content, label string) error
+}
+
+// SpamFilter runs several checkers and keeps the highest score.
+// A failing checker is logged and skipped so spam filtering never blocks commenting.
+type SpamFilter struct {
+	checkers []SpamChecker
+}
+
+func NewSpamFilter(checkers ...SpamChecker) *SpamFilter {
+	return &SpamFilter{checkers: checkers}
+}
+
+func (f *SpamFilter) Check(ctx context.Context, comment *entity.Comment) (entity.SpamVerdict, error) {
+	var out entity.SpamVerdict
+	for _, checker := range f.checkers {
+		v, err := checker.Check(ctx, comment)
+		if err != nil {
+			log.Warnw("Spam checker failed",
+				log.Pair("checker", fmt.Sprintf("%T", checker)),
+				log.Pair("error", err.Error()),
+			)
+			continue
+		}
+		if v.Score > out.Score {
+			out.Score = v.Score
+		}
+		out.Reasons = append(out.Reasons, v.Reasons...)
+	}
+	return out, nil
+}
+
+// LinkSpamChecker flags comments containing more links than allowed.
+type LinkSpamChecker struct {
+	maxLinks int
+}
+
+func NewLinkSpamChecker(maxLinks int) *LinkSpamChecker {
+	return &LinkSpamChecker{maxLinks: maxLinks}
+}
+
+func (c *LinkSpamChecker) Check(_ context.Context, comment *entity.Comment) (entity.SpamVerdict, error) {
+	n := len(spam.Links(comment.Content))
+	if n <= c.maxLinks {
+		return entity.SpamVerdict{}, nil
+	}
+	// Each extra link adds 0.1 on top of a base of 0.6.
+	score := 0.6 + 0.1*float64(n-c.maxLinks)
+	if score > 1 {
+		score = 1
+	}
+	return entity.SpamVerdict{
+		Score:   score,
+		Reasons: []string{fmt.Sprintf("%d links (max %d)", n, c.maxLinks)},
+	}, nil
+}
+
+// BlocklistSpamChecker flags comments containing any blocklisted word or phrase.
+type BlocklistSpamChecker struct {
+	words []string
+}
+
+func NewBlocklistSpamChecker(words []string) *BlocklistSpamChecker {
+	normalized := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			normalized = append(normalized, w)
+		}
+	}
+	return &BlocklistSpamChecker{words: normalized}
+}
+
+func (c *BlocklistSpamChecker) Check(_ context.Context, comment *entity.Comment) (entity.SpamVerdict, error) {
+	content := strings.ToLower(comment.Content)
+	for _, w := range c.words {
+		if strings.Contains(content, w) {
+			return entity.SpamVerdict{
+				Score:   1,
+				Reasons: []string{fmt.Sprintf("blocklisted word %q", w)},
+			}, nil
+		}
+	}
+	return entity.SpamVerdict{}, nil
+}
+
+const (
+	// duplicateMinLength is the shortest content, in runes, that counts as a
+	// duplicate when posted by different users. Short replies such as
+	// "Thanks!" or "+1" are naturally repeated by many readers.
+	duplicateMinLength = 40
+	// duplicateMinUsers is how many other users must have posted the same
+	// content before it looks like a campaign rather than a coincidence.
+	duplicateMinUsers = 2
+)
+
+// DuplicateSpamChecker flags content that was already posted recently,
+// either by the same user or, for longer text, by several other users.
+type DuplicateSpamChecker struct {
+	commentRepo CommentRepo
+	window      time.Duration
+}
+
+func NewDuplicateSpamChecker(commentRepo CommentRepo, window time.Duration) *DuplicateSpamChecker {
+	return &DuplicateSpamChecker{commentRepo: commentRepo, window: window}
+}
+
+func (c *DuplicateSpamChecker) Check(ctx context.Context, comment *entity.Comment) (entity.SpamVerdict, error) {
+	own, others, err := c.commentRepo.CountRecentByContent(ctx, comment.Content, comment.UserID, time.Now().Add(-c.window))
+	if err != nil {
+		return entity.SpamVerdict{}, err
+	}
+	switch {
+	case own > 0:
+		return entity.SpamVerdict{
+			Score:   0.95,
+			Reasons: []string{fmt.Sprintf("repeats %d recent comment(s) by the same user", own)},
+		}, nil
+	case others >= duplicateMinUsers && utf8.RuneCountInString(strings.TrimSpace(comment.Content)) >= duplicateMinLength:
+		return entity.SpamVerdict{
+			Score:   0.95,
+			Reasons: []string{fmt.Sprintf("same text posted by %d other users", others)},
+		}, nil
+	}
+	return entity.SpamVerdict{}, nil
+}
+
+// minTrainingDocs is how many comments of each label the classifier needs
+// before its score is trusted.
+const minTrainingDocs = 5
+
+// BayesSpamClassifier is a naive Bayes classifier trained from admin
+// moderation decisions, with token statistics kept in the database.
+type BayesSpamClassifier struct {
+	spamRepo SpamRepo
+}
+
+func NewBayesSpamClassifier(spamRepo SpamRepo) *BayesSpamClassifier {
+	return &BayesSpamClassifier{spamRepo: spamRepo}
+}
+
+func (c *BayesSpamClassifier) Check(ctx context.Context, comment *entity.Comment) (entity.SpamVerdict, error) {
+	spamDocs, hamDocs, err := c.spamRepo.GetCorpus(ctx)
+	if err != nil {
+		return entity.SpamVerdict{}, err
+	}
+	if spamDocs < minTrainingDocs || hamDocs < minTrainingDocs {
+		return entity.SpamVerdict{}, nil
+	}
+
+	tokens := spam.Tokens(comment.Content)
+	rows, err := c.spamRepo.GetTokenCounts(ctx, tokens)
+	if err != nil {
+		return entity.SpamVerdict{}, err
+	}
+	counts := make(map[string]spam.Counts, len(rows))
+	for token, row := range rows {
+		counts[token] = spam.Counts{Spam: row.SpamCount, Ham: row.HamCount}
+	}
+
+	score := spam.Score(tokens, counts, spamDocs, hamDocs)
+	v := entity.SpamVerdict{Score: score}
+	if score >= 0.5 {
+		v.Reasons = []string{fmt.Sprintf("bayes %.2f", score)}
+	}
+	return v, nil
+}
+
+func (c *BayesSpamClassifier) Learn(ctx context.Context, content, label string) error {
+	return c.spamRepo.Learn(ctx, spam.Tokens(content), label, 1)
+}
+
+func (c *BayesSpamClassifier) Unlearn(ctx context.Context, content, label string) error {
+	return c.spamRepo.Learn(ctx, spam.Tokens(content), label, -1)
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type duplicateCommentRepo struct {
+	CommentRepo
+	comments []entity.Comment
+}
+
+func (r *duplicateCommentRepo) CountRecentByContent(_ context.Context, content string, userID int64, since time.Time) (int64, int64, error) {
+	var own int64
+	others := map[int64]bool{}
+	for _, c := range r.comments {
+		if c.Content != content || c.CreatedAt.Before(since) {
+			continue
+		}
+		if c.UserID == userID {
+			own++
+		} else {
+			others[c.UserID] = true
+		}
+	}
+	return own, int64(len(others)), nil
+}
+
+func TestDuplicateSpamChecker(t *testing.T) {
+	long := strings.Repeat("Great deals on watches, visit my profile! ", 2)
+	quote := "I had exactly the same problem with the migration last week."
+	now := time.Now()
+	repo := &duplicateCommentRepo{comments: []entity.Comment{
+		{UserID: 1, Content: "Thanks!", CreatedAt: now},
+		{UserID: 2, Content: "Thanks!", CreatedAt: now},
+		{UserID: 3, Content: "Thanks!", CreatedAt: now},
+		{UserID: 4, Content: "Nice post", CreatedAt: now},
+		{UserID: 5, Content: long, CreatedAt: now},
+		{UserID: 6, Content: long, CreatedAt: now},
+		{UserID: 8, Content: quote, CreatedAt: now},
+		{UserID: 7, Content: "An old comment repeated again", CreatedAt: now.Add(-48 * time.Hour)},
+	}}
+	checker := NewDuplicateSpamChecker(repo, 24*time.Hour)
+
+	tests := []struct {
+		name     string
+		userID   int64
+		content  string
+		wantSpam bool
+	}{
+		{"short text from many readers", 9, "Thanks!", false},
+		{"same user repeats short text", 4, "Nice post", true},
+		{"long text from several users", 9, long, true},
+		{"long text from one other user", 9, quote, false},
+		{"repeat outside the window", 7, "An old comment repeated again", false},
+	}
+	for _, tt := range tests {
+		v, err := checker.Check(context.Background(), &entity.Comment{UserID: tt.userID, Content: tt.content})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if gotSpam := v.Score >= 0.9; gotSpam != tt.wantSpam {
+			t.Errorf("%s: score %v (%v), want spam=%v", tt.name, v.Score, v.Reasons, tt.wantSpam)
+		}
+	}
+}
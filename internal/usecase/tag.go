@@ -2,25 +2,36 @@ package usecase
 
 import (
 	"blog/internal/entity"
+	"blog/pkg/cache"
 	"context"
 )
 
 type TagUseCase struct {
 	tagRepo TagRepo
+	cache   responseCache
 }
 
-func NewTagUseCase(tagRepo TagRepo) *TagUseCase {
-	return &TagUseCase{tagRepo: tagRepo}
+func NewTagUseCase(tagRepo TagRepo, c cache.Cache) *TagUseCase {
+	return &TagUseCase{tagRepo: tagRepo, cache: newResponseCache(c)}
 }
 
 func (uc *TagUseCase) Create(ctx context.Context, req entity.CreateTagRequest) error {
 	tag := &entity.Tag{
 		Name: req.Name,
 	}
-	return uc.tagRepo.Create(ctx, tag)
+	if err := uc.tagRepo.Create(ctx, tag); err != nil {
+		return err
+	}
+	uc.cache.delete(ctx, cacheKeyTags)
+	return nil
 }
 
 func (uc *TagUseCase) List(ctx context.Context) ([]entity.TagResponse, error) {
+	var cached []entity.TagResponse
+	if uc.cache.get(ctx, cacheKeyTags, &cached) {
+		return cached, nil
+	}
+
 	tags, err := uc.tagRepo.List(ctx)
 	if err != nil {
 		return nil, err
@@ -34,9 +45,16 @@ func (uc *TagUseCase) List(ctx context.Context) ([]entity.TagResponse, error) {
 		}
 	}
 
+	uc.cache.set(ctx, cacheKeyTags, responses, uc.cache.ttl(nil))
 	return responses, nil
 }
 
 func (uc *TagUseCase) Delete(ctx context.Context, id int64) error {
-	return uc.tagRepo.Delete(ctx, id)
+	if err := uc.tagRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	uc.cache.delete(ctx, cacheKeyTags)
+	// Cached posts carry the tag names.
+	uc.cache.bump(ctx, cacheNSPost, cacheNSPostLists)
+	return nil
 }
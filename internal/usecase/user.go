@@ -4,6 +4,7 @@ import (
 	"blog/internal/entity"
 	"context"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -22,10 +23,13 @@ func (uc *UserUseCase) GetByID(ctx context.Context, id int64) (*entity.UserRespo
 	}
 
 	return &entity.UserResponse{
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		Avatar:   user.Avatar,
+		Username:           user.Username,
+		Email:              user.Email,
+		Role:               user.Role,
+		Avatar:             user.Avatar,
+		EmailNotifications: user.EmailNotifications,
+		EmailVerified:      user.EmailVerifiedAt != nil,
+		TwoFactorEnabled:   user.TOTPEnabledAt != nil,
 	}, nil
 }
 
@@ -44,6 +48,9 @@ func (uc *UserUseCase) UpdateProfile(ctx context.Context, id int64, req entity.U
 	if req.Avatar != "" {
 		user.Avatar = req.Avatar
 	}
+	if req.EmailNotifications != nil {
+		user.EmailNotifications = *req.EmailNotifications
+	}
 
 	return uc.userRepo.Update(ctx, user)
 }
@@ -56,21 +63,35 @@ func (uc *UserUseCase) ListAll(ctx context.Context) ([]entity.AdminUserResponse,
 	}
 
 	resp := make([]entity.AdminUserResponse, 0, len(users))
-	for _, u := range users {
-		resp = append(resp, entity.AdminUserResponse{
-			ID:       u.ID,
-			Username: u.Username,
-			Email:    u.Email,
-			Role:     u.Role,
-			Status:   u.Status,
-			Provider: u.Provider,
-			Avatar:   u.Avatar,
-			JoinedAt: u.CreatedAt.Format(time.RFC3339),
-		})
+	for i := range users {
+		resp = append(resp, *toAdminUserResponse(&users[i]))
 	}
 	return resp, nil
 }
 
+// UpdateRole changes a user's role. Admins cannot change their own role, so
+// the site always keeps the admin making the change.
+func (uc *UserUseCase) UpdateRole(ctx context.Context, actorID, id int64, role string) (*entity.AdminUserResponse, error) {
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("%w: invalid role %q", ErrInvalidArgument, role)
+	}
+	if actorID == id {
+		return nil, fmt.Errorf("%w: you cannot change your own role", ErrInvalidArgument)
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != role {
+		user.Role = role
+		if err := uc.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return toAdminUserResponse(user), nil
+}
+
 // UpdateStatus updates user's status to active/banned.
 func (uc *UserUseCase) UpdateStatus(ctx context.Context, id int64, status string) (*entity.AdminUserResponse, error) {
 	if status != "active" && status != "banned" {
@@ -93,14 +114,18 @@ func (uc *UserUseCase) UpdateStatus(ctx context.Context, id int64, status string
 		}
 	}
 
+	return toAdminUserResponse(user), nil
+}
+
+func toAdminUserResponse(u *entity.User) *entity.AdminUserResponse {
 	return &entity.AdminUserResponse{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		Role:     user.Role,
-		Status:   user.Status,
-		Provider: user.Provider,
-		Avatar:   user.Avatar,
-		JoinedAt: user.CreatedAt.Format(time.RFC3339),
-	}, nil
+		ID:       u.ID,
+		Username: u.Username,
+		Email:    u.Email,
+		Role:     u.Role,
+		Status:   u.Status,
+		Provider: u.Provider,
+		Avatar:   u.Avatar,
+		JoinedAt: u.CreatedAt.Format(time.RFC3339),
+	}
 }
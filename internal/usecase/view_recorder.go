@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"blog/pkg/geoip"
+	"blog/pkg/log"
+	"blog/pkg/privacy"
+	"context"
+	"sync"
+	"time"
+)
+
+// viewFlushTimeout bounds one flush, including the final one on shutdown.
+const viewFlushTimeout = 10 * time.Second
+
+// ViewRecorder buffers post view increments and analytics rows in memory and
+// writes them in batches, so a page view costs no database round trip and no
+// goroutine. Views of the same post are coalesced into one UPDATE per flush.
+//
+// Run flushes every analytics.flush_interval, or earlier once batch_size
+// visits are queued. When max_queue visits are waiting, new ones are dropped
+// and counted rather than growing memory without bound.
+type ViewRecorder struct {
+	postRepo      PostRepo
+	analyticsRepo AnalyticsRepo
+	salts         *dailySalts
+	flushNow      chan struct{}
+
+	mu          sync.Mutex
+	views       map[int64]int64
+	visits      []entity.Analytics
+	dropped     int64
+	flushed     int64
+	flushErrors int64
+	lastFlushAt *time.Time
+}
+
+func NewViewRecorder(postRepo PostRepo, analyticsRepo AnalyticsRepo) *ViewRecorder {
+	return &ViewRecorder{
+		postRepo:      postRepo,
+		analyticsRepo: analyticsRepo,
+		salts:         newDailySalts(analyticsRepo),
+		flushNow:      make(chan struct{}, 1),
+		views:         make(map[int64]int64),
+	}
+}
+
+// RecordView counts one view of a post.
+func (r *ViewRecorder) RecordView(postID int64) {
+	r.mu.Lock()
+	r.views[postID]++
+	r.mu.Unlock()
+}
+
+// RecordVisit queues an analytics row with the client's full IP. Location,
+// country and visitor key are resolved at flush time, before the IP is
+// anonymized per privacy.ip_mode.
+func (r *ViewRecorder) RecordVisit(visit entity.Analytics) {
+	conf := config.GetConf().Analytics
+
+	r.mu.Lock()
+	if len(r.visits) >= conf.MaxQueue {
+		r.dropped++
+		r.mu.Unlock()
+		return
+	}
+	r.visits = append(r.visits, visit)
+	full := len(r.visits) >= conf.BatchSize
+	r.mu.Unlock()
+
+	if full {
+		select {
+		case r.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Stats reports the buffer state for monitoring.
+func (r *ViewRecorder) Stats() entity.ViewRecorderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pendingViews int64
+	for _, n := range r.views {
+		pendingViews += n
+	}
+	return entity.ViewRecorderStats{
+		QueuedVisits: len(r.visits),
+		QueueLimit:   config.GetConf().Analytics.MaxQueue,
+		PendingPosts: len(r.views),
+		PendingViews: pendingViews,
+		Dropped:      r.dropped,
+		Flushed:      r.flushed,
+		FlushErrors:  r.flushErrors,
+		LastFlushAt:  r.lastFlushAt,
+	}
+}
+
+// Run flushes until ctx is cancelled, then drains what is left.
+func (r *ViewRecorder) Run(ctx context.Context) {
+	interval := config.GetConf().Analytics.FlushInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush()
+			return
+		case <-ticker.C:
+			r.flush()
+		case <-r.flushNow:
+			r.flush()
+		}
+	}
+}
+
+// flush writes everything buffered so far. Failed view counts are merged
+// back for the next flush; failed visits are re-queued while there is room.
+func (r *ViewRecorder) flush() {
+	r.mu.Lock()
+	views, visits := r.views, r.visits
+	if len(views) == 0 && len(visits) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	r.views = make(map[int64]int64, len(views))
+	r.visits = nil
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), viewFlushTimeout)
+	defer cancel()
+
+	var viewsErr, visitsErr error
+	if len(views) > 0 {
+		if viewsErr = r.postRepo.AddViews(ctx, views); viewsErr != nil {
+			log.Warnw("Flush post views failed", log.Pair("posts", len(views)), log.Pair("error", viewsErr.Error()))
+		}
+	}
+	if len(visits) > 0 {
+		rows, err := r.prepareVisits(ctx, visits)
+		if err == nil {
+			err = r.analyticsRepo.CreateBatch(ctx, rows)
+		}
+		if visitsErr = err; visitsErr != nil {
+			log.Warnw("Flush visits failed", log.Pair("visits", len(visits)), log.Pair("error", visitsErr.Error()))
+		}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastFlushAt = &now
+	if viewsErr != nil {
+		r.flushErrors++
+		for id, n := range views {
+			r.views[id] += n
+		}
+	}
+	if visitsErr != nil {
+		r.flushErrors++
+		room := config.GetConf().Analytics.MaxQueue - len(r.visits)
+		if room < 0 {
+			room = 0
+		}
+		if room < len(visits) {
+			r.dropped += int64(len(visits) - room)
+			visits = visits[:room]
+		}
+		r.visits = append(visits, r.visits...)
+	} else {
+		r.flushed += int64(len(visits))
+	}
+}
+
+// prepareVisits returns the rows to store for visits: enriched from the full
+// IP, then anonymized. visits itself is left as recorded so a failed flush
+// can retry it.
+func (r *ViewRecorder) prepareVisits(ctx context.Context, visits []entity.Analytics) ([]entity.Analytics, error) {
+	mode := config.GetConf().Privacy.IPMode
+	rows := make([]entity.Analytics, len(visits))
+	for i, visit := range visits {
+		if visit.Location == "" {
+			visit.Location = geoip.Lookup(visit.IP)
+			visit.Country = geoip.Country(visit.IP)
+		}
+
+		if mode == privacy.ModeTruncate || mode == privacy.ModeHash {
+			salt, err := r.salts.get(ctx, time.Unix(visit.Timestamp, 0).UTC().Format(rollupDayLayout))
+			if err != nil {
+				return nil, err
+			}
+			visit.VisitorID = visitorID(&visit, salt)
+			visit.IP = privacy.Anonymize(mode, visit.IP, salt)
+		} else {
+			visit.VisitorID = visitorID(&visit, nil)
+		}
+		rows[i] = visit
+	}
+	return rows, nil
+}
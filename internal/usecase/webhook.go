@@ -0,0 +1,637 @@
+package usecase
+
+import (
+	"blog/internal/entity"
+	"blog/pkg/log"
+	"blog/pkg/util"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookPollInterval  = 15 * time.Second
+	webhookBatchSize     = 20
+	webhookMaxAttempts   = 8
+	webhookTimeout       = 10 * time.Second
+	webhookPruneInterval = time.Hour
+	// webhookConcurrency is how many endpoints are sent to at once; each
+	// endpoint gets its deliveries one at a time, in order.
+	webhookConcurrency = 8
+	// webhookClaimLease is how long a claimed delivery is reserved for the
+	// instance sending it; a crashed instance's deliveries are retried after.
+	webhookClaimLease = 5 * time.Minute
+	// webhookLogRetention is how long finished deliveries stay in the log.
+	webhookLogRetention = 30 * 24 * time.Hour
+)
+
+// WebhookUseCase manages webhook subscriptions and delivers content events
+// to them. Events are stored as one delivery per subscribed webhook and sent
+// in the background, so a slow or failing endpoint never holds up a request
+// and deliveries survive restarts.
+//
+// Each request is a POST with a JSON entity.WebhookPayload and the headers:
+//
+//	X-Webhook-Event:     the event type
+//	X-Webhook-Delivery:  the delivery ID, stable across retries
+//	X-Webhook-Timestamp: Unix seconds when the attempt was signed
+//	X-Webhook-Signature: "sha256=" + hex HMAC-SHA256 of "<timestamp>.<body>"
+//
+// Any 2xx response counts as delivered; anything else is retried with
+// exponential backoff. Endpoints are sent to concurrently, so a slow one
+// only delays its own deliveries.
+type WebhookUseCase struct {
+	webhookRepo WebhookRepo
+	client      *http.Client
+	wake        chan struct{}
+}
+
+func NewWebhookUseCase(webhookRepo WebhookRepo) *WebhookUseCase {
+	return &WebhookUseCase{
+		webhookRepo: webhookRepo,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			// A redirect would resend the signed payload to another host.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (uc *WebhookUseCase) List(ctx context.Context) ([]entity.WebhookResponse, error) {
+	webhooks, err := uc.webhookRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]entity.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		resp = append(resp, toWebhookResponse(&webhooks[i]))
+	}
+	return resp, nil
+}
+
+func (uc *WebhookUseCase) Get(ctx context.Context, id int64) (*entity.WebhookResponse, error) {
+	webhook, err := uc.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := toWebhookResponse(webhook)
+	return &resp, nil
+}
+
+// Create adds a webhook. The response includes the secret, generated when
+// none was given; it is not shown again.
+func (uc *WebhookUseCase) Create(ctx context.Context, req entity.WebhookRequest) (*entity.WebhookResponse, error) {
+	webhook := &entity.Webhook{Active: true}
+	if err := applyWebhookRequest(webhook, req); err != nil {
+		return nil, err
+	}
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+	if err := uc.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	resp := toWebhookResponse(webhook)
+	resp.Secret = webhook.Secret
+	return &resp, nil
+}
+
+// Update edits a webhook. Deliveries already queued keep their payload.
+func (uc *WebhookUseCase) Update(ctx context.Context, id int64, req entity.WebhookRequest) (*entity.WebhookResponse, error) {
+	webhook, err := uc.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookRequest(webhook, req); err != nil {
+		return nil, err
+	}
+	if err := uc.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	resp := toWebhookResponse(webhook)
+	if req.Secret != "" {
+		resp.Secret = webhook.Secret
+	}
+	return &resp, nil
+}
+
+func (uc *WebhookUseCase) Delete(ctx context.Context, id int64) error {
+	if _, err := uc.webhookRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return uc.webhookRepo.Delete(ctx, id)
+}
+
+// Deliveries returns the delivery log of a webhook, newest first.
+func (uc *WebhookUseCase) Deliveries(ctx context.Context, id int64, page, limit int) (*entity.PaginatedWebhookDeliveryResponse, error) {
+	if _, err := uc.webhookRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	deliveries, total, err := uc.webhookRepo.ListDeliveries(ctx, id, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []entity.WebhookDelivery{}
+	}
+	return &entity.PaginatedWebhookDeliveryResponse{
+		Data: deliveries,
+		Pagination: entity.Pagination{
+			Total:      int(total),
+			Page:       page,
+			Limit:      limit,
+			TotalPages: (int(total) + limit - 1) / limit,
+		},
+	}, nil
+}
+
+// SendTest delivers a ping event to the webhook right away, whether or not
+// it is active, and returns the logged result. Test deliveries are not
+// retried.
+func (uc *WebhookUseCase) SendTest(ctx context.Context, id int64) (*entity.WebhookDelivery, error) {
+	webhook, err := uc.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	eventID, payload, err := newWebhookPayload(entity.WebhookEventPing, map[string]any{
+		"webhookId": webhook.ID,
+		"message":   "Test event from " + siteName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Created claimed, so the background worker leaves it alone.
+	delivery := &entity.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       eventID,
+		Event:         entity.WebhookEventPing,
+		Payload:       payload,
+		Status:        entity.WebhookDeliverySending,
+		NextAttemptAt: time.Now().Add(webhookClaimLease),
+	}
+	if err := uc.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	err = uc.send(ctx, webhook, delivery)
+	delivery.Attempts++
+	if err == nil {
+		now := time.Now()
+		delivery.Status = entity.WebhookDeliverySuccess
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.Status = entity.WebhookDeliveryFailed
+		delivery.LastError = truncateRunes(err.Error(), 500)
+	}
+	if err := uc.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Publish queues event for every active webhook subscribed to it. It is a
+// no-op on a nil WebhookUseCase, so callers need not check whether webhooks
+// are wired in.
+func (uc *WebhookUseCase) Publish(ctx context.Context, event string, data any) error {
+	if uc == nil {
+		return nil
+	}
+	webhooks, err := uc.webhookRepo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	var subscribed []entity.Webhook
+	for _, webhook := range webhooks {
+		if slices.Contains(splitWebhookEvents(webhook.Events), event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	eventID, payload, err := newWebhookPayload(event, data)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	deliveries := make([]entity.WebhookDelivery, 0, len(subscribed))
+	for _, webhook := range subscribed {
+		deliveries = append(deliveries, entity.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       payload,
+			Status:        entity.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := uc.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	// Nudge the worker so the event goes out without waiting for the next poll.
+	select {
+	case uc.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// PostChanged publishes post.created, post.updated or post.deleted. Failures
+// are logged only; the change itself has already been saved.
+func (uc *WebhookUseCase) PostChanged(ctx context.Context, event string, post *entity.Post) {
+	if err := uc.Publish(ctx, event, toWebhookPost(post)); err != nil {
+		log.Errorw("Queue webhook event failed", log.Pair("event", event), log.Pair("post_id", post.ID), log.Pair("error", err.Error()))
+	}
+}
+
+// PostPublished publishes post.published. It is a scheduler hook, so it
+// fires once when a post goes live, including scheduled posts.
+func (uc *WebhookUseCase) PostPublished(ctx context.Context, event *entity.DomainEvent, post *entity.Post) error {
+	if post == nil {
+		return nil
+	}
+	return uc.Publish(ctx, entity.WebhookEventPostPublished, toWebhookPost(post))
+}
+
+// CommentCreated publishes comment.created for a comment that became
+// visible: approved when posted, or later by a moderator. Failures are
+// logged only.
+func (uc *WebhookUseCase) CommentCreated(ctx context.Context, comment *entity.Comment, post *entity.Post, author *entity.User) {
+	data := entity.WebhookComment{
+		ID:        comment.ID,
+		PostID:    post.ID,
+		PostSlug:  post.Slug,
+		PostTitle: post.Title,
+		ParentID:  comment.ParentID,
+		Author:    author.Username,
+		Content:   comment.Content,
+		Status:    comment.Status,
+		URL:       siteURL() + "/post/" + post.Slug + "#comment-" + strconv.FormatInt(comment.ID, 10),
+		CreatedAt: comment.CreatedAt,
+	}
+	if err := uc.Publish(ctx, entity.WebhookEventCommentCreated, data); err != nil {
+		log.Errorw("Queue webhook event failed", log.Pair("event", entity.WebhookEventCommentCreated), log.Pair("comment_id", comment.ID), log.Pair("error", err.Error()))
+	}
+}
+
+// UserRegistered publishes user.registered for a new account, by email or
+// OAuth. Failures are logged only.
+func (uc *WebhookUseCase) UserRegistered(ctx context.Context, user *entity.User) {
+	data := entity.WebhookUser{
+		ID:        user.ID,
+		Username:  user.Username,
+		Provider:  user.Provider,
+		CreatedAt: user.CreatedAt,
+	}
+	if err := uc.Publish(ctx, entity.WebhookEventUserRegistered, data); err != nil {
+		log.Errorw("Queue webhook event failed", log.Pair("event", entity.WebhookEventUserRegistered), log.Pair("user_id", user.ID), log.Pair("error", err.Error()))
+	}
+}
+
+// RunDeliveries sends queued deliveries and prunes the delivery log until
+// ctx is cancelled.
+func (uc *WebhookUseCase) RunDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+
+	for {
+		uc.deliverDue(ctx)
+		if time.Since(lastPrune) >= webhookPruneInterval {
+			lastPrune = time.Now()
+			if n, err := uc.webhookRepo.PruneDeliveries(ctx, lastPrune.Add(-webhookLogRetention)); err != nil {
+				if ctx.Err() == nil {
+					log.Warnw("Prune webhook deliveries failed", log.Pair("error", err.Error()))
+				}
+			} else if n > 0 {
+				log.Infow("Pruned webhook deliveries", log.Pair("deleted", n))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-uc.wake:
+		}
+	}
+}
+
+func (uc *WebhookUseCase) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := uc.webhookRepo.ListDueDeliveries(ctx, time.Now(), webhookBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Errorw("Load webhook deliveries failed", log.Pair("error", err.Error()))
+			}
+			return
+		}
+
+		// One queue per endpoint, in the order the deliveries are due.
+		var webhookIDs []int64
+		queues := map[int64][]*entity.WebhookDelivery{}
+		for i := range deliveries {
+			id := deliveries[i].WebhookID
+			if _, ok := queues[id]; !ok {
+				webhookIDs = append(webhookIDs, id)
+			}
+			queues[id] = append(queues[id], &deliveries[i])
+		}
+		webhooks := make(map[int64]*entity.Webhook, len(webhookIDs))
+		for _, id := range webhookIDs {
+			webhook, err := uc.webhookRepo.GetByID(ctx, id)
+			if err != nil && !strings.Contains(err.Error(), "not found") {
+				if ctx.Err() == nil {
+					log.Warnw("Load webhook failed", log.Pair("webhook_id", id), log.Pair("error", err.Error()))
+				}
+				return
+			}
+			webhooks[id] = webhook
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, webhookConcurrency)
+		for _, id := range webhookIDs {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			util.SafeGo(func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				uc.deliverQueue(ctx, webhooks[id], queues[id])
+			})
+		}
+		wg.Wait()
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// deliverQueue sends the deliveries of one webhook in order. Once an
+// attempt fails the endpoint is taken to be down, and the rest of the queue
+// is postponed to the same retry time instead of each waiting out its own
+// timeout; they keep their attempt counts.
+func (uc *WebhookUseCase) deliverQueue(ctx context.Context, webhook *entity.Webhook, deliveries []*entity.WebhookDelivery) {
+	var retryAt time.Time
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		// Another instance may be sending the same delivery.
+		now := time.Now()
+		claimed, err := uc.webhookRepo.ClaimDelivery(ctx, delivery.ID, now, now.Add(webhookClaimLease))
+		if err != nil {
+			log.Warnw("Claim webhook delivery failed", log.Pair("delivery_id", delivery.ID), log.Pair("error", err.Error()))
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if !retryAt.IsZero() {
+			delivery.Status = entity.WebhookDeliveryPending
+			delivery.NextAttemptAt = retryAt
+			uc.saveDelivery(delivery)
+			continue
+		}
+		uc.deliver(ctx, webhook, delivery)
+		if delivery.Status == entity.WebhookDeliveryPending {
+			retryAt = delivery.NextAttemptAt
+		}
+	}
+}
+
+// deliver makes one attempt at a claimed delivery and records the outcome.
+// webhook is nil when it was deleted after the delivery was queued.
+func (uc *WebhookUseCase) deliver(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) {
+	var err error
+	switch {
+	case webhook == nil:
+		err = fmt.Errorf("webhook deleted")
+	case !webhook.Active:
+		err = fmt.Errorf("webhook disabled")
+	default:
+		err = uc.send(ctx, webhook, delivery)
+		delivery.Attempts++
+	}
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = entity.WebhookDeliverySuccess
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	} else {
+		delivery.LastError = truncateRunes(err.Error(), 500)
+		if webhook == nil || !webhook.Active || delivery.Attempts >= webhookMaxAttempts {
+			delivery.Status = entity.WebhookDeliveryFailed
+		} else {
+			delivery.Status = entity.WebhookDeliveryPending
+			delivery.NextAttemptAt = time.Now().Add(outboxBackoff(delivery.Attempts))
+		}
+		log.Warnw("Webhook delivery failed",
+			log.Pair("delivery_id", delivery.ID),
+			log.Pair("webhook_id", delivery.WebhookID),
+			log.Pair("attempts", delivery.Attempts),
+			log.Pair("error", err.Error()),
+		)
+	}
+	uc.saveDelivery(delivery)
+}
+
+// saveDelivery records the outcome of an attempt, which also releases the
+// delivery's claim.
+func (uc *WebhookUseCase) saveDelivery(delivery *entity.WebhookDelivery) {
+	// Use a fresh context so the result is recorded even during shutdown.
+	saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := uc.webhookRepo.UpdateDelivery(saveCtx, delivery); err != nil {
+		log.Errorw("Update webhook delivery failed", log.Pair("delivery_id", delivery.ID), log.Pair("error", err.Error()))
+	}
+}
+
+// send POSTs the signed payload and records the response on delivery.
+func (uc *WebhookUseCase) send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", strings.ReplaceAll(siteName, " ", "-")+"-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+webhookSignature(webhook.Secret, timestamp, delivery.Payload))
+
+	start := time.Now()
+	resp, err := uc.client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.ResponseStatus = 0
+		delivery.ResponseBody = ""
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = truncateRunes(strings.ToValidUTF8(string(body), ""), 1000)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// webhookSignature signs "<timestamp>.<payload>", so a captured request
+// cannot be replayed with a fresh timestamp.
+func webhookSignature(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookPayload(event string, data any) (string, string, error) {
+	id := uuid.New().String()
+	payload, err := json.Marshal(entity.WebhookPayload{
+		ID:        id,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return id, string(payload), nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// applyWebhookRequest validates req and copies it onto webhook.
+func applyWebhookRequest(webhook *entity.Webhook, req entity.WebhookRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidArgument)
+	}
+	if len([]rune(name)) > 100 {
+		return fmt.Errorf("%w: name exceeds 100 characters", ErrInvalidArgument)
+	}
+
+	rawURL := strings.TrimSpace(req.URL)
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidArgument)
+	}
+	if len(rawURL) > 500 {
+		return fmt.Errorf("%w: url exceeds 500 characters", ErrInvalidArgument)
+	}
+
+	var events []string
+	for _, event := range req.Events {
+		event = strings.TrimSpace(event)
+		if !slices.Contains(entity.WebhookEvents, event) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidArgument, event)
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("%w: subscribe to at least one event", ErrInvalidArgument)
+	}
+
+	secret := strings.TrimSpace(req.Secret)
+	if secret != "" {
+		if len(secret) < 16 || len(secret) > 255 {
+			return fmt.Errorf("%w: secret must be 16 to 255 characters", ErrInvalidArgument)
+		}
+		webhook.Secret = secret
+	}
+
+	webhook.Name = name
+	webhook.URL = rawURL
+	webhook.Events = strings.Join(events, ",")
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	return nil
+}
+
+func splitWebhookEvents(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func toWebhookResponse(w *entity.Webhook) entity.WebhookResponse {
+	return entity.WebhookResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.URL,
+		Events:    splitWebhookEvents(w.Events),
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+func toWebhookPost(p *entity.Post) entity.WebhookPost {
+	return entity.WebhookPost{
+		ID:          p.ID,
+		Slug:        p.Slug,
+		Title:       p.Title,
+		Excerpt:     p.Excerpt,
+		Status:      p.Status,
+		Author:      p.Author,
+		AuthorID:    p.AuthorID,
+		CategoryID:  p.CategoryID,
+		PublishAt:   p.PublishAt,
+		UnpublishAt: p.UnpublishAt,
+		URL:         siteURL() + "/post/" + p.Slug,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
@@ -0,0 +1,327 @@
+package usecase
+
+import (
+	"blog/config"
+	"blog/internal/entity"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhookRepo keeps webhooks and deliveries in memory. Methods the tests
+// do not reach are left to the embedded nil interface.
+type fakeWebhookRepo struct {
+	WebhookRepo
+	mu         sync.Mutex
+	webhooks   map[int64]*entity.Webhook
+	deliveries map[int64]*entity.WebhookDelivery
+	lost       map[int64]bool // Deliveries another instance claims first
+	nextID     int64
+}
+
+func newFakeWebhookRepo(webhooks ...entity.Webhook) *fakeWebhookRepo {
+	r := &fakeWebhookRepo{
+		webhooks:   map[int64]*entity.Webhook{},
+		deliveries: map[int64]*entity.WebhookDelivery{},
+		lost:       map[int64]bool{},
+	}
+	for i := range webhooks {
+		r.webhooks[webhooks[i].ID] = &webhooks[i]
+	}
+	return r
+}
+
+func (r *fakeWebhookRepo) GetByID(_ context.Context, id int64) (*entity.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.webhooks[id]
+	if !ok {
+		return nil, errors.New("webhook not found")
+	}
+	c := *w
+	return &c, nil
+}
+
+func (r *fakeWebhookRepo) ListActive(context.Context) ([]entity.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []entity.Webhook
+	for _, w := range r.webhooks {
+		if w.Active {
+			webhooks = append(webhooks, *w)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *fakeWebhookRepo) CreateDeliveries(_ context.Context, deliveries []entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range deliveries {
+		r.nextID++
+		deliveries[i].ID = r.nextID
+		c := deliveries[i]
+		r.deliveries[c.ID] = &c
+	}
+	return nil
+}
+
+func (r *fakeWebhookRepo) ListDueDeliveries(_ context.Context, now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []entity.WebhookDelivery
+	for id := int64(1); id <= r.nextID && len(due) < limit; id++ {
+		d, ok := r.deliveries[id]
+		if ok && (d.Status == entity.WebhookDeliveryPending || d.Status == entity.WebhookDeliverySending) && !d.NextAttemptAt.After(now) {
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeWebhookRepo) ClaimDelivery(_ context.Context, id int64, now, until time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.deliveries[id]
+	if r.lost[id] {
+		d.Status, d.NextAttemptAt = entity.WebhookDeliverySending, until
+		return false, nil
+	}
+	if d.Status != entity.WebhookDeliveryPending && d.Status != entity.WebhookDeliverySending || d.NextAttemptAt.After(now) {
+		return false, nil
+	}
+	d.Status, d.NextAttemptAt = entity.WebhookDeliverySending, until
+	return true, nil
+}
+
+func (r *fakeWebhookRepo) UpdateDelivery(_ context.Context, delivery *entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *delivery
+	r.deliveries[c.ID] = &c
+	return nil
+}
+
+func (r *fakeWebhookRepo) delivery(id int64) entity.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.deliveries[id]
+}
+
+func (r *fakeWebhookRepo) queue(t *testing.T, webhookIDs ...int64) {
+	t.Helper()
+	var deliveries []entity.WebhookDelivery
+	for _, id := range webhookIDs {
+		deliveries = append(deliveries, entity.WebhookDelivery{
+			WebhookID:     id,
+			Event:         entity.WebhookEventPostCreated,
+			Payload:       `{}`,
+			Status:        entity.WebhookDeliveryPending,
+			NextAttemptAt: time.Now().Add(-time.Second),
+		})
+	}
+	if err := r.CreateDeliveries(context.Background(), deliveries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliverDueSlowEndpointDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	repo := newFakeWebhookRepo(
+		entity.Webhook{ID: 1, URL: slow.URL, Secret: "s", Active: true},
+		entity.Webhook{ID: 2, URL: fast.URL, Secret: "s", Active: true},
+	)
+	repo.queue(t, 1, 2, 2)
+	uc := NewWebhookUseCase(repo)
+
+	done := make(chan struct{})
+	go func() {
+		uc.deliverDue(context.Background())
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.delivery(2).Status != entity.WebhookDeliverySuccess || repo.delivery(3).Status != entity.WebhookDeliverySuccess {
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatal("deliveries to the fast endpoint waited for the slow one")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := repo.delivery(1).Status; got != entity.WebhookDeliverySending {
+		t.Errorf("slow delivery status %q while in flight, want %q", got, entity.WebhookDeliverySending)
+	}
+
+	close(release)
+	<-done
+	if got := repo.delivery(1).Status; got != entity.WebhookDeliverySuccess {
+		t.Errorf("slow delivery status %q, want %q", got, entity.WebhookDeliverySuccess)
+	}
+}
+
+func TestDeliverDueSkipsDeliveriesClaimedElsewhere(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Header.Get("X-Webhook-Delivery"))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepo(entity.Webhook{ID: 1, URL: srv.URL, Secret: "s", Active: true})
+	repo.queue(t, 1, 1)
+	repo.lost[1] = true
+	NewWebhookUseCase(repo).deliverDue(context.Background())
+
+	if len(sent) != 1 || sent[0] != "2" {
+		t.Fatalf("sent deliveries %v, want only 2", sent)
+	}
+	if got := repo.delivery(1); got.Status != entity.WebhookDeliverySending || got.Attempts != 0 {
+		t.Errorf("delivery claimed elsewhere = %q after %d attempts, want it left to the other instance", got.Status, got.Attempts)
+	}
+}
+
+func TestDeliverDuePostponesQueueOfFailingEndpoint(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepo(entity.Webhook{ID: 1, URL: srv.URL, Secret: "s", Active: true})
+	repo.queue(t, 1, 1, 1)
+	NewWebhookUseCase(repo).deliverDue(context.Background())
+
+	if requests != 1 {
+		t.Fatalf("%d requests to a failing endpoint, want 1", requests)
+	}
+	first := repo.delivery(1)
+	if first.Status != entity.WebhookDeliveryPending || first.Attempts != 1 || first.ResponseStatus != http.StatusServiceUnavailable {
+		t.Errorf("failed delivery = %q, %d attempts, response %d", first.Status, first.Attempts, first.ResponseStatus)
+	}
+	for _, id := range []int64{2, 3} {
+		d := repo.delivery(id)
+		if d.Status != entity.WebhookDeliveryPending || d.Attempts != 0 || !d.NextAttemptAt.Equal(first.NextAttemptAt) {
+			t.Errorf("delivery %d = %q, %d attempts, next at %v; want pending with no attempt, next at %v",
+				id, d.Status, d.Attempts, d.NextAttemptAt, first.NextAttemptAt)
+		}
+	}
+}
+
+type fakeCommentRepo struct {
+	CommentRepo
+	comments map[int64]*entity.Comment
+}
+
+func (r *fakeCommentRepo) Create(_ context.Context, comment *entity.Comment) error {
+	comment.ID = int64(len(r.comments) + 1)
+	c := *comment
+	r.comments[c.ID] = &c
+	return nil
+}
+
+func (r *fakeCommentRepo) CountByUserAndStatus(_ context.Context, userID int64, status string) (int64, error) {
+	var n int64
+	for _, c := range r.comments {
+		if c.UserID == userID && c.Status == status {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakeCommentRepo) GetByIDs(_ context.Context, ids []int64) ([]entity.Comment, error) {
+	var comments []entity.Comment
+	for _, id := range ids {
+		if c, ok := r.comments[id]; ok {
+			comments = append(comments, *c)
+		}
+	}
+	return comments, nil
+}
+
+func (r *fakeCommentRepo) UpdateStatus(_ context.Context, ids []int64, status string) (int64, error) {
+	for _, id := range ids {
+		r.comments[id].Status = status
+	}
+	return int64(len(ids)), nil
+}
+
+type fakePostRepo struct {
+	PostRepo
+	posts map[int64]*entity.Post
+}
+
+func (r *fakePostRepo) GetByID(_ context.Context, id int64) (*entity.Post, error) {
+	p, ok := r.posts[id]
+	if !ok {
+		return nil, errors.New("post not found")
+	}
+	c := *p
+	return &c, nil
+}
+
+func TestCommentCreatedOnlyOnceVisible(t *testing.T) {
+	saved := config.Conf.Comment
+	defer func() { config.Conf.Comment = saved }()
+	config.Conf.Comment.HoldFirstTime = true
+	config.Conf.Comment.RequireVerifiedEmail = false
+
+	ctx := context.Background()
+	webhooks := newFakeWebhookRepo(entity.Webhook{ID: 1, URL: "http://example.invalid", Secret: "s", Active: true, Events: entity.WebhookEventCommentCreated})
+	comments := &fakeCommentRepo{comments: map[int64]*entity.Comment{}}
+	posts := &fakePostRepo{posts: map[int64]*entity.Post{3: {ID: 3, Slug: "hello", Title: "Hello"}}}
+	users := &fakeUserRepo{users: map[int64]*entity.User{5: {ID: 5, Username: "reader", Role: entity.RoleVisitor}}}
+	uc := NewCommentUseCase(comments, posts, users, nil, nil, nil, NewWebhookUseCase(webhooks))
+
+	resp, err := uc.Create(ctx, 3, 5, entity.CreateCommentRequest{Content: "First!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != entity.CommentStatusPending {
+		t.Fatalf("first comment status %q, want it held", resp.Status)
+	}
+	if len(webhooks.deliveries) != 0 {
+		t.Fatalf("comment awaiting moderation queued %d webhook deliveries", len(webhooks.deliveries))
+	}
+
+	approve := entity.ModerateCommentsRequest{IDs: []int64{resp.ID}, Status: entity.CommentStatusApproved}
+	if _, err := uc.Moderate(ctx, approve); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks.deliveries) != 1 {
+		t.Fatalf("approval queued %d webhook deliveries, want 1", len(webhooks.deliveries))
+	}
+	var payload struct {
+		Event string
+		Data  entity.WebhookComment
+	}
+	if err := json.Unmarshal([]byte(webhooks.deliveries[1].Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != entity.WebhookEventCommentCreated || payload.Data.ID != resp.ID || payload.Data.Status != entity.CommentStatusApproved || payload.Data.Author != "reader" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	// Approving an approved comment again publishes nothing new.
+	if _, err := uc.Moderate(ctx, approve); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks.deliveries) != 1 {
+		t.Errorf("re-approval queued %d webhook deliveries in total, want 1", len(webhooks.deliveries))
+	}
+}
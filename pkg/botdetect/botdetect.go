@@ -0,0 +1,89 @@
+// Package botdetect tells automated clients from people by the request
+// alone: the user agent and the headers real browsers always send. Request
+// rate, the third signal, needs shared state and is left to the caller.
+package botdetect
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Reason says why a request was classified as a bot; empty means human.
+type Reason string
+
+const (
+	Human     Reason = ""
+	UserAgent Reason = "user_agent" // Known or self-declared bot, or a non-browser client
+	Headless  Reason = "headless"   // Browser automation or a browser missing standard headers
+	Rate      Reason = "rate"       // More requests than a person makes
+)
+
+// knownBots are user agent fragments of crawlers, link previewers, monitors
+// and HTTP libraries that do not match the generic pattern below.
+var knownBots = []string{
+	"slurp", "baiduspider", "yandex", "sogou", "exabot", "bingpreview",
+	"facebookexternalhit", "facebookcatalog", "ia_archiver", "whatsapp",
+	"mediapartners-google", "adsbot-google", "google-inspectiontool",
+	"google-read-aloud", "feedfetcher", "lighthouse", "pagespeed", "gtmetrix",
+	"pingdom", "statuscake", "uptime", "site24x7", "datadog",
+	"curl/", "wget/", "httpie/", "python-requests", "python-urllib", "aiohttp",
+	"httpx", "go-http-client", "java/", "okhttp", "apache-httpclient",
+	"libwww-perl", "node-fetch", "axios/", "undici", "postmanruntime",
+	"insomnia", "scrapy", "feedparser", "rss", "newsblur", "inoreader",
+}
+
+// genericBot matches self-declared bots ("Googlebot/2.1", "AhrefsBot",
+// "ClaudeBot"): a word ending in one of these.
+var genericBot = regexp.MustCompile(`(?:bot|crawler|spider|scraper|fetcher|archiver)\b`)
+
+// notBots are user agent words genericBot would match by mistake, such as
+// the phone brand in "Android 9; CUBOT P30".
+var notBots = strings.NewReplacer("cubot", "")
+
+// headless are user agent fragments of browser automation.
+var headless = []string{
+	"headlesschrome", "phantomjs", "slimerjs", "puppeteer", "playwright",
+	"selenium", "webdriver", "cypress",
+}
+
+// Classify inspects the user agent and, when h is not nil, the headers every
+// mainstream browser sends. It errs towards human: a bot it misses is only
+// counted, while a person classified as a bot disappears from the reports.
+func Classify(userAgent string, h http.Header) Reason {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return UserAgent
+	}
+	for _, s := range headless {
+		if strings.Contains(ua, s) {
+			return Headless
+		}
+	}
+	if genericBot.MatchString(notBots.Replace(ua)) {
+		return UserAgent
+	}
+	for _, s := range knownBots {
+		if strings.Contains(ua, s) {
+			return UserAgent
+		}
+	}
+	// Every mainstream browser, old Opera aside, starts with Mozilla/.
+	if !strings.HasPrefix(ua, "mozilla/") && !strings.HasPrefix(ua, "opera/") {
+		return UserAgent
+	}
+
+	if h != nil {
+		// Headless Chrome reports itself in client hints even when the user
+		// agent is spoofed.
+		if strings.Contains(strings.ToLower(h.Get("Sec-CH-UA")), "headless") {
+			return Headless
+		}
+		// Browsers always send Accept-Language; automation tools driving a
+		// bare HTTP stack or a default headless profile often do not.
+		if h.Get("Accept-Language") == "" {
+			return Headless
+		}
+	}
+	return Human
+}
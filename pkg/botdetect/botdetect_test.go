@@ -0,0 +1,69 @@
+package botdetect
+
+import (
+	"net/http"
+	"testing"
+)
+
+const (
+	chrome  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	safari  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+	cubot   = "Mozilla/5.0 (Linux; Android 9; CUBOT P30) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	opera   = "Opera/9.80 (Windows NT 6.1) Presto/2.12.388 Version/12.18"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Reason
+	}{
+		{"chrome", chrome, Human},
+		{"safari", safari, Human},
+		{"firefox", firefox, Human},
+		{"old opera", opera, Human},
+		{"phone brand ending in bot", cubot, Human},
+		{"empty", "  ", UserAgent},
+		{"googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", UserAgent},
+		{"self-declared crawler", "Mozilla/5.0 (compatible; AhrefsBot/7.0; +http://ahrefs.com/robot/)", UserAgent},
+		{"generic spider", "Mozilla/5.0 (compatible; Baiduspider/2.0)", UserAgent},
+		{"link preview", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", UserAgent},
+		{"known bot inside a browser UA", "Mozilla/5.0 (compatible; Yahoo! Slurp)", UserAgent},
+		{"monitor", "Mozilla/5.0 (compatible; UptimeRobot/2.0)", UserAgent},
+		{"curl", "curl/8.4.0", UserAgent},
+		{"http library", "python-requests/2.31.0", UserAgent},
+		{"go client", "Go-http-client/1.1", UserAgent},
+		{"not a browser", "MyApp/1.0", UserAgent},
+		{"headless chrome", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/124.0.0.0 Safari/537.36", Headless},
+		{"phantomjs", "Mozilla/5.0 (Unknown; Linux x86_64) AppleWebKit/538.1 (KHTML, like Gecko) PhantomJS/2.1.1 Safari/538.1", Headless},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.ua, nil); got != tt.want {
+			t.Errorf("%s: Classify(%q) = %q, want %q", tt.name, tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		ua      string
+		headers map[string]string
+		want    Reason
+	}{
+		{"browser headers", chrome, map[string]string{"Accept-Language": "en-US,en;q=0.9", "Sec-CH-UA": `"Chromium";v="124"`}, Human},
+		{"no accept-language", chrome, map[string]string{"Sec-CH-UA": `"Chromium";v="124"`}, Headless},
+		{"headless client hint", chrome, map[string]string{"Accept-Language": "en", "Sec-CH-UA": `"HeadlessChrome";v="124"`}, Headless},
+		{"user agent decides first", "curl/8.4.0", map[string]string{"Accept-Language": "en"}, UserAgent},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		if got := Classify(tt.ua, h); got != tt.want {
+			t.Errorf("%s: Classify = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
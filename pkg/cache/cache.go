@@ -0,0 +1,29 @@
+// Package cache provides a byte-oriented key/value cache with TTLs: an
+// in-process LRU for a single instance, or Redis for several.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is absent or expired.
+var ErrMiss = errors.New("cache: miss")
+
+// Cache stores values by key. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value for key or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value for ttl; a ttl <= 0 keeps it until evicted or deleted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Nop caches nothing; every Get misses.
+type Nop struct{}
+
+func (Nop) Get(context.Context, string) ([]byte, error)              { return nil, ErrMiss }
+func (Nop) Set(context.Context, string, []byte, time.Duration) error { return nil }
+func (Nop) Delete(context.Context, ...string) error                  { return nil }
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+	// Reading a makes b the least recently used entry.
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrMiss) {
+		t.Errorf("b: err = %v, want ErrMiss", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
+
+func TestLRUOverwriteKeepsSize(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "a", []byte("2"), 0)
+	_ = c.Set(ctx, "b", []byte("3"), 0)
+
+	got, err := c.Get(ctx, "a")
+	if err != nil || string(got) != "2" {
+		t.Fatalf("a = %q, %v; want 2", got, err)
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	_ = c.Set(ctx, "short", []byte("x"), time.Minute)
+	_ = c.Set(ctx, "forever", []byte("y"), 0)
+
+	now = now.Add(59 * time.Second)
+	if _, err := c.Get(ctx, "short"); err != nil {
+		t.Fatalf("before expiry: %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("at expiry: err = %v, want ErrMiss", err)
+	}
+	if n := c.Len(); n != 1 {
+		t.Errorf("expired entry kept: Len = %d, want 1", n)
+	}
+
+	now = now.Add(365 * 24 * time.Hour)
+	if _, err := c.Get(ctx, "forever"); err != nil {
+		t.Errorf("entry without ttl expired: %v", err)
+	}
+}
+
+func TestLRUDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	_ = c.Set(ctx, "a", []byte("1"), 0)
+	_ = c.Set(ctx, "b", []byte("2"), 0)
+
+	if err := c.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Errorf("a: err = %v, want ErrMiss", err)
+	}
+	if _, err := c.Get(ctx, "b"); err != nil {
+		t.Errorf("b: %v", err)
+	}
+}
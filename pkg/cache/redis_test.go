@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	// No retries, so that TestRedisUnavailable fails fast.
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client, "cache:"), server
+}
+
+func TestRedisGetSet(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("missing key: err = %v, want ErrMiss", err)
+	}
+	if err := c.Set(ctx, "a", []byte("v\r\n1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Get(ctx, "a")
+	if err != nil || string(got) != "v\r\n1" {
+		t.Fatalf("a = %q, %v; want v\\r\\n1", got, err)
+	}
+	if !server.Exists("cache:a") || server.Exists("a") {
+		t.Errorf("keys = %v, want the prefixed key only", server.Keys())
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+
+	_ = c.Set(ctx, "short", []byte("1"), 2*time.Second)
+	_ = c.Set(ctx, "forever", []byte("2"), 0)
+	_ = c.Set(ctx, "negative", []byte("3"), -time.Second)
+	if ttl := server.TTL("cache:short"); ttl != 2*time.Second {
+		t.Errorf("short: ttl = %v, want 2s", ttl)
+	}
+	for _, key := range []string{"cache:forever", "cache:negative"} {
+		if ttl := server.TTL(key); ttl != 0 {
+			t.Errorf("%s: ttl = %v, want none", key, ttl)
+		}
+	}
+
+	server.FastForward(3 * time.Second)
+	if _, err := c.Get(ctx, "short"); !errors.Is(err, ErrMiss) {
+		t.Errorf("short after expiry: err = %v, want ErrMiss", err)
+	}
+	if _, err := c.Get(ctx, "forever"); err != nil {
+		t.Errorf("forever: %v", err)
+	}
+
+	// Overwriting an entry replaces its TTL.
+	_ = c.Set(ctx, "forever", []byte("4"), time.Second)
+	if ttl := server.TTL("cache:forever"); ttl != time.Second {
+		t.Errorf("forever after overwrite: ttl = %v, want 1s", ttl)
+	}
+}
+
+func TestRedisDelete(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = c.Set(ctx, key, []byte(key), 0)
+	}
+	// Keys outside the prefix are left alone.
+	_ = server.Set("a", "other")
+
+	if err := c.Delete(ctx, "a", "b", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx); err != nil {
+		t.Fatalf("Delete without keys: %v", err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, ErrMiss) {
+			t.Errorf("%s after Delete: err = %v, want ErrMiss", key, err)
+		}
+	}
+	if _, err := c.Get(ctx, "c"); err != nil {
+		t.Errorf("c: %v", err)
+	}
+	if !server.Exists("a") {
+		t.Error("unprefixed key a was deleted")
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestRedis(t)
+	server.Close()
+
+	if _, err := c.Get(ctx, "a"); err == nil || errors.Is(err, ErrMiss) {
+		t.Errorf("Get with the server down: err = %v, want a connection error", err)
+	}
+}
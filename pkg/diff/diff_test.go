@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func lines(s ...string) string { return strings.Join(s, "\n") + "\n" }
+
+func TestUnified(t *testing.T) {
+	numbers := lines("1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12")
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{
+			"missing trailing newline", "a\nb\n", "a\nb",
+			lines("--- old", "+++ new", "@@ -1,2 +1,2 @@", " a", "-b", "+b", `\ No newline at end of file`),
+		},
+		{
+			"added trailing newline", "a", "a\n",
+			lines("--- old", "+++ new", "@@ -1 +1 @@", "-a", `\ No newline at end of file`, "+a"),
+		},
+		{
+			// Six unchanged lines between the changes: the context of both
+			// meets, so they form one hunk.
+			"nearby changes merge",
+			numbers,
+			lines("1", "two", "3", "4", "5", "6", "7", "8", "nine", "10", "11", "12"),
+			lines("--- old", "+++ new", "@@ -1,12 +1,12 @@",
+				" 1", "-2", "+two", " 3", " 4", " 5", " 6", " 7", " 8", "-9", "+nine", " 10", " 11", " 12"),
+		},
+		{
+			"distant changes split",
+			numbers,
+			lines("1", "two", "3", "4", "5", "6", "7", "8", "9", "ten", "11", "12"),
+			lines("--- old", "+++ new",
+				"@@ -1,5 +1,5 @@", " 1", "-2", "+two", " 3", " 4", " 5",
+				"@@ -7,6 +7,6 @@", " 7", " 8", " 9", "-10", "+ten", " 11", " 12"),
+		},
+		{
+			"insert into empty", "", "a\n",
+			lines("--- old", "+++ new", "@@ -0,0 +1 @@", "+a"),
+		},
+	}
+	for _, tt := range tests {
+		if got := Unified(tt.a, tt.b, "old", "new", 3); got != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnifiedContextZero(t *testing.T) {
+	got := Unified(lines("a", "b", "c"), lines("a", "x", "c"), "old", "new", 0)
+	want := lines("--- old", "+++ new", "@@ -2 +2 @@", "-b", "+x")
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
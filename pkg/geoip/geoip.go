@@ -81,6 +81,26 @@ func Lookup(ipStr string) string {
 	return formatLocation(&record)
 }
 
+// Country returns the ISO 3166-1 alpha-2 code for the IP, or "" when it is
+// unknown, local, or no database is loaded.
+func Country(ipStr string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if db == nil {
+		return ""
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil || isLocalIP(ip) {
+		return ""
+	}
+	var record CountryRecord
+	if err := db.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
 func formatLocation(record *CityRecord) string {
 	city := getLocalizedName(record.City.Names)
 	country := getLocalizedName(record.Country.Names)
@@ -0,0 +1,133 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+// decodeBlurHash renders hash at w×h as the reference decoder
+// (https://github.com/woltapp/blurhash) does.
+func decodeBlurHash(t *testing.T, hash string, w, h int) *image.NRGBA {
+	t.Helper()
+	base83 := func(s string) int {
+		v := 0
+		for _, c := range s {
+			d := strings.IndexRune(base83Chars, c)
+			if d < 0 {
+				t.Fatalf("invalid base83 character %q in %s", c, hash)
+			}
+			v = v*83 + d
+		}
+		return v
+	}
+	if len(hash) < 6 {
+		t.Fatalf("hash %q is too short", hash)
+	}
+	flag := base83(hash[:1])
+	nx, ny := flag%9+1, flag/9+1
+	if len(hash) != 4+2*nx*ny {
+		t.Fatalf("hash %q has length %d, want %d for %dx%d components", hash, len(hash), 4+2*nx*ny, nx, ny)
+	}
+	maxAC := float64(base83(hash[1:2])+1) / 166
+
+	colors := make([][3]float64, nx*ny)
+	dc := base83(hash[2:6])
+	colors[0] = [3]float64{srgbToLinear(dc >> 16), srgbToLinear(dc >> 8 & 255), srgbToLinear(dc & 255)}
+	for i := 1; i < nx*ny; i++ {
+		v := base83(hash[4+2*i : 6+2*i])
+		for c, q := range []int{v / (19 * 19), v / 19 % 19, v % 19} {
+			colors[i][c] = signPow(float64(q-9)/9, 2) * maxAC
+		}
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var px [3]float64
+			for j := 0; j < ny; j++ {
+				for i := 0; i < nx; i++ {
+					basis := math.Cos(math.Pi*float64(x*i)/float64(w)) * math.Cos(math.Pi*float64(y*j)/float64(h))
+					for c := range px {
+						px[c] += colors[i+j*nx][c] * basis
+					}
+				}
+			}
+			m.SetNRGBA(x, y, color.NRGBA{R: uint8(linearToSRGB(px[0])), G: uint8(linearToSRGB(px[1])), B: uint8(linearToSRGB(px[2])), A: 255})
+		}
+	}
+	return m
+}
+
+// meanError is the mean absolute difference of the colour channels.
+func meanError(a, b *image.NRGBA) float64 {
+	var sum float64
+	for i := 0; i < len(a.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			sum += math.Abs(float64(a.Pix[i+c]) - float64(b.Pix[i+c]))
+		}
+	}
+	return sum / float64(len(a.Pix)/4*3)
+}
+
+func fill(w, h int, fn func(x, y int) color.NRGBA) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, fn(x, y))
+		}
+	}
+	return m
+}
+
+func TestBlurHashRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		img     *image.NRGBA
+		maxDiff float64
+	}{
+		{"white", fill(32, 24, func(x, y int) color.NRGBA { return color.NRGBA{255, 255, 255, 255} }), 4},
+		{"red", fill(32, 24, func(x, y int) color.NRGBA { return color.NRGBA{200, 30, 20, 255} }), 4},
+		{"horizontal ramp", fill(32, 24, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(x * 8), 100, uint8(255 - x*8), 255}
+		}), 12},
+		{"vertical ramp", fill(32, 24, func(x, y int) color.NRGBA {
+			return color.NRGBA{50, uint8(y * 10), 150, 255}
+		}), 12},
+	}
+	for _, tt := range tests {
+		hash := BlurHash(tt.img, 4, 3)
+		got := decodeBlurHash(t, hash, 32, 24)
+		if d := meanError(tt.img, got); d > tt.maxDiff {
+			t.Errorf("%s: %s decodes with a mean error of %.1f, want at most %.0f", tt.name, hash, d, tt.maxDiff)
+		}
+	}
+}
+
+func TestBlurHashTransparentIsWhite(t *testing.T) {
+	transparent := fill(16, 16, func(x, y int) color.NRGBA { return color.NRGBA{R: 255, A: 0} })
+	white := fill(16, 16, func(x, y int) color.NRGBA { return color.NRGBA{255, 255, 255, 255} })
+	if got, want := BlurHash(transparent, 4, 3), BlurHash(white, 4, 3); got != want {
+		t.Errorf("transparent image hashes to %s, want %s as white", got, want)
+	}
+}
+
+func TestBlurHashComponents(t *testing.T) {
+	m := fill(8, 8, func(x, y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} })
+	for _, tt := range []struct{ x, y, wantX, wantY int }{
+		{4, 3, 4, 3},
+		{1, 1, 1, 1},
+		{9, 9, 9, 9},
+		{0, 12, 1, 9}, // Clamped
+	} {
+		hash := BlurHash(m, tt.x, tt.y)
+		if want := 4 + 2*tt.wantX*tt.wantY; len(hash) != want {
+			t.Errorf("%dx%d: length %d, want %d", tt.x, tt.y, len(hash), want)
+		}
+		if flag := strings.IndexByte(base83Chars, hash[0]); flag != (tt.wantX-1)+(tt.wantY-1)*9 {
+			t.Errorf("%dx%d: size flag %d, want %d", tt.x, tt.y, flag, (tt.wantX-1)+(tt.wantY-1)*9)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package imaging prepares uploaded images for the web in pure Go: it strips
+// metadata, generates resized and WebP variants for responsive images and
+// computes a BlurHash placeholder.
+package imaging
+
+import (
+	"blog/pkg/webp"
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+)
+
+const (
+	// maxPixels bounds the images that are decoded; larger ones are only
+	// stripped, so a small file cannot claim gigabytes once decoded.
+	maxPixels = 50_000_000
+	// blurHashWidth is the width the placeholder is computed from.
+	blurHashWidth = 32
+)
+
+// Options configures Process.
+type Options struct {
+	Widths   []int // Widths of the resized variants; only those below the original are made
+	Quality  int   // JPEG and WebP quality, 1-100
+	WebP     bool  // Also make a WebP of the original and of each width, when smaller
+	MaxWidth int   // Downscale wider originals to this width; 0 keeps them as uploaded
+}
+
+// Result is a processed upload.
+type Result struct {
+	Data     []byte // The original to store, without metadata
+	MimeType string
+	Width    int // Display size, after EXIF orientation
+	Height   int
+	BlurHash string
+	Variants []Variant
+}
+
+// Variant is a resized or re-encoded copy of the original.
+type Variant struct {
+	Width    int
+	Height   int
+	MimeType string
+	Data     []byte
+}
+
+// Process strips the metadata of a JPEG, PNG, GIF or WebP upload and makes
+// its variants. Animated GIFs are kept as uploaded, and WebP uploads are not
+// decoded, so neither gets variants; images too large to decode safely are
+// only stripped.
+func Process(data []byte, mimeType string, opts Options) (*Result, error) {
+	stripped, err := StripMetadata(data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Data: stripped, MimeType: mimeType}
+
+	if mimeType == "image/webp" {
+		cfg, err := webp.DecodeConfig(bytes.NewReader(stripped))
+		if err != nil {
+			return nil, err
+		}
+		res.Width, res.Height = cfg.Width, cfg.Height
+		return res, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, err
+	}
+	orientation := 1
+	if mimeType == "image/jpeg" {
+		orientation = Orientation(stripped)
+	}
+	res.Width, res.Height = cfg.Width, cfg.Height
+	if orientation >= 5 {
+		res.Width, res.Height = cfg.Height, cfg.Width
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return res, nil
+	}
+
+	var src image.Image
+	animated := false
+	if mimeType == "image/gif" {
+		g, err := gif.DecodeAll(bytes.NewReader(stripped))
+		if err != nil {
+			return nil, err
+		}
+		if len(g.Image) == 0 {
+			return nil, errors.New("imaging: GIF has no frames")
+		}
+		src, animated = g.Image[0], len(g.Image) > 1
+	} else {
+		if src, _, err = image.Decode(bytes.NewReader(stripped)); err != nil {
+			return nil, err
+		}
+	}
+	src = Orient(src, orientation)
+
+	thumb := src
+	if src.Bounds().Dx() > blurHashWidth {
+		thumb = Resize(src, blurHashWidth)
+	}
+	res.BlurHash = BlurHash(thumb, 4, 3)
+	if animated {
+		return res, nil
+	}
+
+	// Static GIF variants are PNGs: re-encoding as GIF would quantize again.
+	format := mimeType
+	if format == "image/gif" {
+		format = "image/png"
+	}
+
+	if opts.MaxWidth > 0 && res.Width > opts.MaxWidth && mimeType != "image/gif" {
+		resized := Resize(src, opts.MaxWidth)
+		if res.Data, err = encode(resized, format, opts.Quality); err != nil {
+			return nil, err
+		}
+		src = resized
+		res.Width, res.Height = resized.Rect.Dx(), resized.Rect.Dy()
+	}
+
+	widths := slices.Clone(opts.Widths)
+	slices.Sort(widths)
+	widths = slices.Compact(widths)
+	for _, w := range widths {
+		if w <= 0 || w >= res.Width {
+			continue
+		}
+		resized := Resize(src, w)
+		v, err := encode(resized, format, opts.Quality)
+		if err != nil {
+			return nil, err
+		}
+		res.Variants = append(res.Variants, Variant{Width: w, Height: resized.Rect.Dy(), MimeType: format, Data: v})
+		if opts.WebP {
+			if err := res.addWebP(resized, v, opts.Quality); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.WebP {
+		if err := res.addWebP(src, res.Data, opts.Quality); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// addWebP adds a WebP variant of m if it is smaller than the same image in
+// the original format.
+func (r *Result) addWebP(m image.Image, original []byte, quality int) error {
+	data, err := encode(m, "image/webp", quality)
+	if err != nil {
+		return err
+	}
+	if len(data) < len(original) {
+		b := m.Bounds()
+		r.Variants = append(r.Variants, Variant{Width: b.Dx(), Height: b.Dy(), MimeType: "image/webp", Data: data})
+	}
+	return nil
+}
+
+func encode(m image.Image, mimeType string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch mimeType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, m, &jpeg.Options{Quality: quality})
+	case "image/png":
+		err = png.Encode(&buf, m)
+	case "image/webp":
+		err = webp.Encode(&buf, m, &webp.Options{Quality: quality})
+	default:
+		err = errors.New("imaging: cannot encode " + mimeType)
+	}
+	return buf.Bytes(), err
+}
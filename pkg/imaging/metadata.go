@@ -0,0 +1,297 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// exifOrientationTag is the EXIF tag of the orientation, in IFD0.
+const exifOrientationTag = 0x0112
+
+var (
+	errInvalidJPEG = errors.New("imaging: invalid JPEG")
+	errInvalidPNG  = errors.New("imaging: invalid PNG")
+	errInvalidWebP = errors.New("imaging: invalid WebP")
+)
+
+// StripMetadata removes EXIF, XMP and IPTC data, comments and text chunks,
+// which carry camera details and GPS positions, from a JPEG, PNG or WebP
+// file without re-encoding it. Colour profiles are kept, and so is the EXIF
+// orientation of a JPEG, as its only tag, so it still displays upright.
+// Other formats are returned unchanged.
+func StripMetadata(data []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	default:
+		return data, nil
+	}
+}
+
+// Orientation returns the EXIF orientation of a JPEG, from 1 (upright) to 8,
+// or 1 when it has none.
+func Orientation(data []byte) int {
+	orientation := 1
+	walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker == 0xe1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			orientation = exifOrientation(segment[10:])
+			return false
+		}
+		return true
+	})
+	return orientation
+}
+
+// Orient returns m transformed as EXIF orientation o asks for display.
+func Orient(m image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return m
+	}
+	src := toRGBA(m)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case 2: // Mirrored
+				sx, sy = w-1-x, y
+			case 3: // Rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // Transposed
+				sx, sy = y, x
+			case 6: // Rotated 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // Transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // Rotated 90° counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:])
+		}
+	}
+	return dst
+}
+
+// exifOrientation reads the orientation from the TIFF structure of an EXIF
+// block.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// orientationSegment is an APP1 segment holding an EXIF block with nothing
+// but orientation o.
+func orientationSegment(o int) []byte {
+	seg := []byte{0xff, 0xe1, 0, 0}
+	seg = append(seg, "Exif\x00\x00"...)
+	seg = append(seg, "MM\x00\x2a\x00\x00\x00\x08"...) // Big endian, IFD0 at 8
+	seg = append(seg, 0, 1)                            // One entry
+	seg = append(seg, 0x01, 0x12, 0, 3, 0, 0, 0, 1)    // Orientation, SHORT, count 1
+	seg = append(seg, 0, byte(o), 0, 0)
+	seg = append(seg, 0, 0, 0, 0) // No next IFD
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(seg)-2))
+	return seg
+}
+
+// walkJPEG calls fn with each marker segment before the image data, marker
+// and length included, until fn returns false. It returns the offset of the
+// start of scan marker, or an error when the file is malformed.
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 0, errInvalidJPEG
+	}
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xff {
+			return 0, errInvalidJPEG
+		}
+		marker := data[i+1]
+		if marker == 0xff { // Fill byte
+			i++
+			continue
+		}
+		if marker == 0xda {
+			return i, nil
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || end < i+4 {
+			return 0, errInvalidJPEG
+		}
+		if !fn(marker, data[i:end]) {
+			return i, nil
+		}
+		i = end
+	}
+}
+
+// stripJPEG keeps the segments needed to display the image: tables, frame
+// and scan headers, JFIF and Adobe markers and ICC profiles. Data after the
+// end of image, such as the video of a motion photo, is dropped too.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+	keep := func(marker byte, segment []byte) bool {
+		switch {
+		case marker == 0xe1: // EXIF, XMP
+			if bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+				if o := exifOrientation(segment[10:]); o > 1 {
+					out = append(out, orientationSegment(o)...)
+				}
+			}
+		case marker == 0xe2: // ICC profile, multi-picture index
+			if bytes.HasPrefix(segment[4:], []byte("ICC_PROFILE\x00")) {
+				out = append(out, segment...)
+			}
+		case marker == 0xe0, marker == 0xee: // JFIF, Adobe colour transform
+			out = append(out, segment...)
+		case marker >= 0xe3 && marker <= 0xef, marker == 0xfe: // Other APPn (IPTC...), comments
+		default:
+			out = append(out, segment...)
+		}
+		return true
+	}
+
+	sos, err := walkJPEG(data, keep)
+	if err != nil {
+		return nil, err
+	}
+	// Scans, with the tables of progressive images between them. Within
+	// entropy-coded data 0xff is followed by a stuffed zero or a restart
+	// marker, so any other marker is found by scanning.
+	i := sos
+	for {
+		if i+2 > len(data) || data[i] != 0xff {
+			return nil, errInvalidJPEG
+		}
+		marker := data[i+1]
+		if marker == 0xd9 {
+			return append(out, 0xff, 0xd9), nil
+		}
+		if i+4 > len(data) {
+			return nil, errInvalidJPEG
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) || end < i+4 {
+			return nil, errInvalidJPEG
+		}
+		if marker == 0xda || !(marker >= 0xe1 && marker <= 0xef || marker == 0xfe) {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if marker == 0xda {
+			start := i
+			for i+1 < len(data) && (data[i] != 0xff || data[i+1] == 0 || data[i+1] >= 0xd0 && data[i+1] <= 0xd7) {
+				i++
+			}
+			out = append(out, data[start:i]...)
+			if i+1 >= len(data) {
+				// Truncated file: keep what there is, as decoders do.
+				return out, nil
+			}
+		}
+		for i+1 < len(data) && data[i] == 0xff && data[i+1] == 0xff {
+			i++
+		}
+	}
+}
+
+// pngDroppedChunks are the ancillary PNG chunks that hold text or metadata.
+var pngDroppedChunks = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errInvalidPNG
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, errInvalidPNG
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			return nil, errInvalidPNG
+		}
+		typ := string(data[i+4 : i+8])
+		if !pngDroppedChunks[typ] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+		if typ == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errInvalidWebP
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errInvalidWebP
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) || end < i {
+			return nil, errInvalidWebP
+		}
+		switch fourCC := string(data[i : i+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			out[start+8] &^= 0x08 | 0x04 // EXIF and XMP flags
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,359 @@
+package imaging
+
+import (
+	"blog/pkg/webp"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+// Tags written by the fixtures.
+const (
+	tagMake    = 0x010f
+	tagGPSInfo = 0x8825
+	cameraMake = "PhoneCam"
+)
+
+// gpsLatitude is the GPSLatitude value of the fixtures, 48° 51' 29.4",
+// as three RATIONALs.
+var gpsLatitude = []uint32{48, 1, 51, 1, 294, 10}
+
+// testImage returns a w×h image where every pixel differs.
+func testImage(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 40), G: uint8(y * 40), B: uint8(x*7 + y*13), A: 255})
+		}
+	}
+	return m
+}
+
+// exifTIFF builds the TIFF structure of an EXIF block as cameras write it:
+// IFD0 with the make, the orientation and a pointer to a GPS IFD holding
+// the latitude.
+func exifTIFF(order binary.ByteOrder, orientation int) []byte {
+	const (
+		ifd0   = 8
+		ifd0N  = 3
+		gpsIFD = ifd0 + 2 + ifd0N*12 + 4
+		gpsN   = 2
+		data   = gpsIFD + 2 + gpsN*12 + 4
+	)
+	makeOff := data
+	latOff := makeOff + len(cameraMake) + 1
+
+	b := make([]byte, latOff+len(gpsLatitude)*4)
+	if order == binary.ByteOrder(binary.BigEndian) {
+		copy(b, "MM")
+	} else {
+		copy(b, "II")
+	}
+	order.PutUint16(b[2:], 42)
+	order.PutUint32(b[4:], ifd0)
+
+	entry := func(at int, tag, typ uint16, count, value uint32) {
+		order.PutUint16(b[at:], tag)
+		order.PutUint16(b[at+2:], typ)
+		order.PutUint32(b[at+4:], count)
+		order.PutUint32(b[at+8:], value)
+	}
+	order.PutUint16(b[ifd0:], ifd0N)
+	entry(ifd0+2, tagMake, 2, uint32(len(cameraMake)+1), uint32(makeOff))
+	entry(ifd0+14, exifOrientationTag, 3, 1, 0)
+	order.PutUint16(b[ifd0+14+8:], uint16(orientation))
+	entry(ifd0+26, tagGPSInfo, 4, 1, gpsIFD)
+
+	order.PutUint16(b[gpsIFD:], gpsN)
+	entry(gpsIFD+2, 0x0001, 2, 2, 0) // GPSLatitudeRef
+	copy(b[gpsIFD+2+8:], "N")
+	entry(gpsIFD+14, 0x0002, 5, 3, uint32(latOff)) // GPSLatitude
+
+	copy(b[makeOff:], cameraMake)
+	for i, v := range gpsLatitude {
+		order.PutUint32(b[latOff+i*4:], v)
+	}
+	return b
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// jpegFixture encodes img and inserts, after SOI, the metadata a phone
+// writes: EXIF with GPS, XMP, an IPTC block, a comment, and an ICC profile
+// that must be kept.
+func jpegFixture(t *testing.T, img image.Image, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	enc := buf.Bytes()
+
+	out := append([]byte{}, enc[:2]...)
+	out = append(out, jpegSegment(0xe1, append([]byte("Exif\x00\x00"), exifTIFF(order, orientation)...))...)
+	out = append(out, jpegSegment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta><exif:GPSLatitude>48,51.49N</exif:GPSLatitude></x:xmpmeta>"))...)
+	out = append(out, jpegSegment(0xed, []byte("Photoshop 3.0\x008BIM\x04\x04"))...)
+	out = append(out, jpegSegment(0xfe, []byte("shot at home"))...)
+	out = append(out, jpegSegment(0xe2, []byte("ICC_PROFILE\x00\x01\x01fake-profile"))...)
+	return append(out, enc[2:]...)
+}
+
+// exifTags returns the tags of IFD0 in the EXIF block of a JPEG.
+func exifTags(t *testing.T, data []byte) []uint16 {
+	t.Helper()
+	var tags []uint16
+	_, err := walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker != 0xe1 || !bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			return true
+		}
+		tiff := segment[10:]
+		order := binary.ByteOrder(binary.LittleEndian)
+		if string(tiff[:2]) == "MM" {
+			order = binary.BigEndian
+		}
+		ifd := int(order.Uint32(tiff[4:]))
+		for i := 0; i < int(order.Uint16(tiff[ifd:])); i++ {
+			tags = append(tags, order.Uint16(tiff[ifd+2+i*12:]))
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tags
+}
+
+func TestStripJPEG(t *testing.T) {
+	src := testImage(24, 16)
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		data := jpegFixture(t, src, order, 6)
+		if got := Orientation(data); got != 6 {
+			t.Fatalf("%v: fixture orientation = %d, want 6", order, got)
+		}
+
+		stripped, err := StripMetadata(data, "image/jpeg")
+		if err != nil {
+			t.Fatalf("%v: %v", order, err)
+		}
+
+		if tags := exifTags(t, stripped); !reflect.DeepEqual(tags, []uint16{exifOrientationTag}) {
+			t.Errorf("%v: EXIF tags after stripping = %#x, want only the orientation", order, tags)
+		}
+		if got := Orientation(stripped); got != 6 {
+			t.Errorf("%v: orientation after stripping = %d, want 6", order, got)
+		}
+		lat := make([]byte, 4)
+		order.PutUint32(lat, gpsLatitude[4])
+		for _, leak := range [][]byte{[]byte(cameraMake), []byte("GPSLatitude"), lat, []byte("Photoshop"), []byte("shot at home")} {
+			if bytes.Contains(stripped, leak) {
+				t.Errorf("%v: stripped file still contains %q", order, leak)
+			}
+		}
+		if !bytes.Contains(stripped, []byte("ICC_PROFILE\x00\x01\x01fake-profile")) {
+			t.Errorf("%v: ICC profile was dropped", order)
+		}
+
+		// The image data is untouched: both decode to the same pixels.
+		want, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := jpeg.Decode(bytes.NewReader(stripped))
+		if err != nil {
+			t.Fatalf("%v: reference decoder: %v", order, err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("%v: stripped file decodes to different pixels", order)
+		}
+	}
+}
+
+func TestStripJPEGUprightDropsEXIF(t *testing.T) {
+	stripped, err := StripMetadata(jpegFixture(t, testImage(8, 8), binary.BigEndian, 1), "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := exifTags(t, stripped); tags != nil {
+		t.Errorf("EXIF tags = %#x, want no EXIF block for an upright image", tags)
+	}
+}
+
+func TestStripJPEGDropsTrailingData(t *testing.T) {
+	data := append(jpegFixture(t, testImage(8, 8), binary.BigEndian, 1), "ftypmp42 motion photo video"...)
+	stripped, err := StripMetadata(data, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(stripped, []byte{0xff, 0xd9}) {
+		t.Error("stripped file does not end at the end of image marker")
+	}
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	res, err := Process(jpegFixture(t, testImage(24, 16), binary.BigEndian, 6), "image/jpeg", Options{Quality: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Width != 16 || res.Height != 24 {
+		t.Errorf("display size = %dx%d, want 16x24", res.Width, res.Height)
+	}
+	if tags := exifTags(t, res.Data); !reflect.DeepEqual(tags, []uint16{exifOrientationTag}) {
+		t.Errorf("EXIF tags = %#x, want only the orientation", tags)
+	}
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	c := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(c, uint32(len(data)))
+	copy(c[4:], typ)
+	c = append(c, data...)
+	return binary.BigEndian.AppendUint32(c, crc32.ChecksumIEEE(c[4:]))
+}
+
+func TestStripPNG(t *testing.T) {
+	src := testImage(10, 6)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	enc := buf.Bytes()
+	// Insert the metadata chunks after IHDR: signature (8) + IHDR (25).
+	data := append([]byte{}, enc[:33]...)
+	data = append(data, pngChunk("eXIf", exifTIFF(binary.BigEndian, 1))...)
+	data = append(data, pngChunk("tEXt", []byte("Comment\x00shot at home"))...)
+	data = append(data, pngChunk("gAMA", []byte{0, 0, 0xb1, 0x8f})...)
+	data = append(data, enc[33:]...)
+
+	stripped, err := StripMetadata(data, "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{"eXIf", "tEXt", cameraMake, "shot at home"} {
+		if bytes.Contains(stripped, []byte(leak)) {
+			t.Errorf("stripped file still contains %q", leak)
+		}
+	}
+	if !bytes.Contains(stripped, []byte("gAMA")) {
+		t.Error("gAMA chunk was dropped")
+	}
+	got, err := png.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("reference decoder: %v", err)
+	}
+	want, _ := png.Decode(bytes.NewReader(enc))
+	if !reflect.DeepEqual(want, got) {
+		t.Error("stripped file decodes to different pixels")
+	}
+}
+
+func riffChunk(fourCC string, data []byte) []byte {
+	c := make([]byte, 8, 9+len(data))
+	copy(c, fourCC)
+	binary.LittleEndian.PutUint32(c[4:], uint32(len(data)))
+	c = append(c, data...)
+	if len(data)%2 == 1 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+func TestStripWebP(t *testing.T) {
+	// Transparency makes the encoder write the extended format with VP8X.
+	src := testImage(12, 10)
+	src.SetNRGBA(0, 0, color.NRGBA{A: 0})
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	enc := buf.Bytes()
+	if string(enc[12:16]) != "VP8X" {
+		t.Fatalf("first chunk is %q, want VP8X", enc[12:16])
+	}
+
+	// Chunks after VP8X, with the EXIF and XMP flags set. The XMP payload
+	// has an odd length to exercise chunk padding.
+	vp8xEnd := 12 + 8 + 10
+	data := append([]byte{}, enc[:vp8xEnd]...)
+	data[20] |= 0x08 | 0x04
+	data = append(data, enc[vp8xEnd:]...)
+	data = append(data, riffChunk("EXIF", exifTIFF(binary.LittleEndian, 1))...)
+	data = append(data, riffChunk("XMP ", []byte("<x:xmpmeta>GPS</x:xmpmeta>!"))...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	stripped, err := StripMetadata(data, "image/webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, enc) {
+		t.Errorf("stripped file differs from the file without metadata (%d bytes, want %d)", len(stripped), len(enc))
+	}
+	if size := binary.LittleEndian.Uint32(stripped[4:]); int(size) != len(stripped)-8 {
+		t.Errorf("RIFF size = %d, want %d", size, len(stripped)-8)
+	}
+	if _, err := xwebp.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Fatalf("reference decoder: %v", err)
+	}
+}
+
+func TestStripMetadataRejectsMalformed(t *testing.T) {
+	for _, tt := range []struct {
+		mimeType string
+		data     []byte
+	}{
+		{"image/jpeg", []byte("not a jpeg")},
+		{"image/jpeg", []byte{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff}},
+		{"image/png", []byte("\x89PNG\r\n\x1a\n\x00\x00")},
+		{"image/webp", []byte("RIFF\x10\x00\x00\x00WEBPVP8 \xff\xff\x00\x00")},
+	} {
+		if _, err := StripMetadata(tt.data, tt.mimeType); err == nil {
+			t.Errorf("%s %q: no error", tt.mimeType, tt.data)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3×2 image; each case lists the source pixel expected at the
+	// corners of the result: top left, top right, bottom left.
+	src := testImage(3, 2)
+	at := func(x, y int) color.Color { return color.RGBAModel.Convert(src.At(x, y)) }
+	tests := []struct {
+		o       int
+		w, h    int
+		corners [3]image.Point
+	}{
+		{1, 3, 2, [3]image.Point{{0, 0}, {2, 0}, {0, 1}}},
+		{2, 3, 2, [3]image.Point{{2, 0}, {0, 0}, {2, 1}}},
+		{3, 3, 2, [3]image.Point{{2, 1}, {0, 1}, {2, 0}}},
+		{4, 3, 2, [3]image.Point{{0, 1}, {2, 1}, {0, 0}}},
+		{5, 2, 3, [3]image.Point{{0, 0}, {0, 1}, {2, 0}}},
+		{6, 2, 3, [3]image.Point{{0, 1}, {0, 0}, {2, 1}}},
+		{7, 2, 3, [3]image.Point{{2, 1}, {2, 0}, {0, 1}}},
+		{8, 2, 3, [3]image.Point{{2, 0}, {2, 1}, {0, 0}}},
+	}
+	for _, tt := range tests {
+		got := Orient(src, tt.o)
+		b := got.Bounds()
+		if b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: size %dx%d, want %dx%d", tt.o, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		for i, p := range []image.Point{{0, 0}, {b.Dx() - 1, 0}, {0, b.Dy() - 1}} {
+			want := tt.corners[i]
+			if c := color.RGBAModel.Convert(got.At(p.X, p.Y)); c != at(want.X, want.Y) {
+				t.Errorf("orientation %d: pixel %v = %v, want source pixel %v = %v", tt.o, p, c, want, at(want.X, want.Y))
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+	"math"
+)
+
+// weightBits is the fixed-point precision of filter weights.
+const weightBits = 14
+
+// Resize scales m to width pixels wide, keeping its aspect ratio, with a
+// Catmull-Rom filter. It works on premultiplied alpha, so the colour of
+// transparent pixels does not bleed into their neighbours.
+func Resize(m image.Image, width int) *image.RGBA {
+	b := m.Bounds()
+	width = max(width, 1)
+	height := max(int((int64(b.Dy())*int64(width)+int64(b.Dx())/2)/int64(b.Dx())), 1)
+
+	src := toRGBA(m)
+	tmp := image.NewRGBA(image.Rect(0, 0, width, b.Dy()))
+	kx := kernel(b.Dx(), width)
+	for y := 0; y < b.Dy(); y++ {
+		row := src.Pix[y*src.Stride:]
+		for x := range kx {
+			kx[x].apply(row, 4, tmp.Pix[y*tmp.Stride+x*4:])
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	ky := kernel(b.Dy(), height)
+	for y := range ky {
+		for x := 0; x < width; x++ {
+			ky[y].apply(tmp.Pix[x*4:], tmp.Stride, dst.Pix[y*dst.Stride+x*4:])
+		}
+	}
+	return dst
+}
+
+// contribution lists the source pixels an output pixel is made of.
+type contribution struct {
+	index  []int
+	weight []int32
+}
+
+// apply writes to dst the weighted sum of the RGBA pixels of src at
+// index*stride.
+func (c *contribution) apply(src []uint8, stride int, dst []uint8) {
+	var r, g, b, a int32
+	for i, idx := range c.index {
+		p := src[idx*stride:]
+		w := c.weight[i]
+		r += w * int32(p[0])
+		g += w * int32(p[1])
+		b += w * int32(p[2])
+		a += w * int32(p[3])
+	}
+	// Catmull-Rom overshoots; colour must not exceed alpha once premultiplied.
+	alpha := clamp(a, 255)
+	dst[0] = clamp(r, int32(alpha))
+	dst[1] = clamp(g, int32(alpha))
+	dst[2] = clamp(b, int32(alpha))
+	dst[3] = alpha
+}
+
+// kernel returns, for each of dstLen output pixels, the source pixels and
+// weights it is filtered from.
+func kernel(srcLen, dstLen int) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	// Downscaling widens the filter to cover every source pixel.
+	stretch := math.Max(scale, 1)
+	support := 2 * stretch
+
+	out := make([]contribution, dstLen)
+	for i := range out {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := int(math.Ceil(center - support))
+		end := int(math.Floor(center + support))
+
+		var c contribution
+		var weights []float64
+		var sum float64
+		for j := start; j <= end; j++ {
+			w := catmullRom((float64(j) - center) / stretch)
+			if w == 0 {
+				continue
+			}
+			c.index = append(c.index, min(max(j, 0), srcLen-1))
+			weights = append(weights, w)
+			sum += w
+		}
+		var total int32
+		for _, w := range weights {
+			fixed := int32(math.Round(w / sum * (1 << weightBits)))
+			c.weight = append(c.weight, fixed)
+			total += fixed
+		}
+		// Put the rounding error on the largest weight so flat areas stay flat.
+		if len(c.weight) > 0 {
+			largest := 0
+			for k, w := range c.weight {
+				if w > c.weight[largest] {
+					largest = k
+				}
+			}
+			c.weight[largest] += 1<<weightBits - total
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func catmullRom(x float64) float64 {
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return (1.5*x-2.5)*x*x + 1
+	case x < 2:
+		return ((-0.5*x+2.5)*x-4)*x + 2
+	default:
+		return 0
+	}
+}
+
+// clamp rounds a fixed-point sum to a pixel value between 0 and limit.
+func clamp(v, limit int32) uint8 {
+	v = (v + 1<<(weightBits-1)) >> weightBits
+	if v < 0 {
+		return 0
+	}
+	if v > limit {
+		return uint8(limit)
+	}
+	return uint8(v)
+}
+
+// toRGBA returns m as premultiplied RGBA with its origin at (0, 0).
+func toRGBA(m image.Image) *image.RGBA {
+	b := m.Bounds()
+	if img, ok := m.(*image.RGBA); ok && b.Min == (image.Point{}) {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), m, b.Min, draw.Src)
+	return dst
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 const (
@@ -19,8 +20,9 @@ type Claims struct {
 	UserID       int64  `json:"user_id"`
 	Username     string `json:"username"`
 	Role         string `json:"role"`
-	TokenType    string `json:"token_type"` // "access" or "refresh"
-	TokenVersion int    `json:"tv"`         // Token revocation version
+	TokenType    string `json:"token_type"`    // "access" or "refresh"
+	TokenVersion int    `json:"tv"`            // Token revocation version
+	SessionID    string `json:"sid,omitempty"` // Login session (refresh token family)
 	jwt.RegisteredClaims
 }
 
@@ -29,35 +31,44 @@ type TokenPair struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int64  `json:"expires_in"` // Access token expiration in seconds
+
+	RefreshID        string    `json:"-"` // jti of the refresh token
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
-// GenerateTokenPair generates both access and refresh tokens
-func GenerateTokenPair(user *entity.User) (*TokenPair, error) {
-	accessToken, err := generateToken(user, TokenTypeAccess, time.Duration(config.Conf.App.JwtAccessDuration)*time.Minute)
+// GenerateTokenPair generates both access and refresh tokens for a login
+// session. The refresh token gets a unique jti so the server can track and
+// rotate it; both tokens carry the session ID.
+func GenerateTokenPair(user *entity.User, sessionID string) (*TokenPair, error) {
+	accessToken, _, err := generateToken(user, TokenTypeAccess, sessionID, time.Duration(config.Conf.App.JwtAccessDuration)*time.Minute)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := generateToken(user, TokenTypeRefresh, time.Duration(config.Conf.App.JwtRefreshDuration)*24*time.Hour)
+	refreshDuration := time.Duration(config.Conf.App.JwtRefreshDuration) * 24 * time.Hour
+	refreshToken, refreshClaims, err := generateToken(user, TokenTypeRefresh, sessionID, refreshDuration)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
 	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(config.Conf.App.JwtAccessDuration * 60), // Convert to seconds
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int64(config.Conf.App.JwtAccessDuration * 60), // Convert to seconds
+		RefreshID:        refreshClaims.ID,
+		RefreshExpiresAt: refreshClaims.ExpiresAt.Time,
 	}, nil
 }
 
 // GenerateToken generates a JWT token (deprecated, use GenerateTokenPair instead)
 // Kept for backward compatibility
 func GenerateToken(user *entity.User) (string, error) {
-	return generateToken(user, TokenTypeAccess, time.Duration(config.Conf.App.JwtAccessDuration)*time.Minute)
+	token, _, err := generateToken(user, TokenTypeAccess, "", time.Duration(config.Conf.App.JwtAccessDuration)*time.Minute)
+	return token, err
 }
 
 // generateToken is the internal token generation function
-func generateToken(user *entity.User, tokenType string, duration time.Duration) (string, error) {
+func generateToken(user *entity.User, tokenType, sessionID string, duration time.Duration) (string, *Claims, error) {
 	now := time.Now()
 	tv := user.TokenVersion
 	if tv <= 0 {
@@ -69,7 +80,9 @@ func generateToken(user *entity.User, tokenType string, duration time.Duration)
 		Role:         user.Role,
 		TokenType:    tokenType,
 		TokenVersion: tv,
+		SessionID:    sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -77,7 +90,11 @@ func generateToken(user *entity.User, tokenType string, duration time.Duration)
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.Conf.App.JwtSecret))
+	signed, err := token.SignedString([]byte(config.Conf.App.JwtSecret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, &claims, nil
 }
 
 // ParseToken parses and validates a JWT token
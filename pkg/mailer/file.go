@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"blog/pkg/log"
+)
+
+// FileMailer is a development mailer. Every message is logged and, when a
+// directory is configured, also written there as an .eml file that any mail
+// client can open.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	log.Infow("Mail sent",
+		log.Pair("to", msg.To),
+		log.Pair("subject", msg.Subject),
+	)
+	if m.dir == "" {
+		return nil
+	}
+
+	data, err := Build(m.from, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102-150405"), time.Now().UnixNano()%1e6)
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0644)
+}
@@ -0,0 +1,166 @@
+// Package mailer sends email. It ships an SMTP implementation for production
+// and a file/log implementation for development.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is a single email. At least one of Text and HTML should be set;
+// when both are, the message is sent as multipart/alternative.
+type Message struct {
+	From    string // Defaults to the mailer's configured sender
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // Extra headers, e.g. List-Unsubscribe
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a Mailer.
+type Config struct {
+	Driver   string // smtp | file | log
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Dir      string // Output directory for the file driver
+}
+
+// New returns the Mailer selected by cfg.Driver. An empty driver means log.
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.Host == "" {
+			return nil, fmt.Errorf("mailer: smtp host is required")
+		}
+		return NewSMTPMailer(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From), nil
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From), nil
+	case "", "log":
+		return NewFileMailer("", cfg.From), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown driver %q", cfg.Driver)
+	}
+}
+
+// headerBreaks strips line breaks from header values (header injection).
+var headerBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// Build renders msg as an RFC 5322 message.
+func Build(from string, msg Message) ([]byte, error) {
+	if msg.From != "" {
+		from = msg.From
+	}
+
+	var buf bytes.Buffer
+	headers := map[string]string{
+		"From":         encodeAddress(from),
+		"To":           encodeAddress(msg.To),
+		"Subject":      mime.QEncoding.Encode("utf-8", headerBreaks.Replace(msg.Subject)),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   messageID(from),
+		"MIME-Version": "1.0",
+	}
+	for k, v := range msg.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(k)] = v
+	}
+
+	var body bytes.Buffer
+	switch {
+	case msg.Text != "" && msg.HTML != "":
+		w := multipart.NewWriter(&body)
+		headers["Content-Type"] = `multipart/alternative; boundary="` + w.Boundary() + `"`
+		if err := writePart(w, "text/plain; charset=utf-8", msg.Text); err != nil {
+			return nil, err
+		}
+		if err := writePart(w, "text/html; charset=utf-8", msg.HTML); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case msg.HTML != "":
+		headers["Content-Type"] = "text/html; charset=utf-8"
+		headers["Content-Transfer-Encoding"] = "quoted-printable"
+		if err := writeQP(&body, msg.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		headers["Content-Type"] = "text/plain; charset=utf-8"
+		headers["Content-Transfer-Encoding"] = "quoted-printable"
+		if err := writeQP(&body, msg.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := headerBreaks.Replace(headers[k])
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// encodeAddress Q-encodes a non-ASCII display name so that servers without
+// SMTPUTF8 accept the header. Values that do not parse are kept as is.
+func encodeAddress(value string) string {
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return value
+	}
+	return addr.String()
+}
+
+func writePart(w *multipart.Writer, contentType, content string) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	return writeQP(part, content)
+}
+
+func writeQP(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
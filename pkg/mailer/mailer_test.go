@@ -0,0 +1,201 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// parse reads a built message back the way a mail client would.
+func parse(t *testing.T, data []byte) *mail.Message {
+	t.Helper()
+	if !bytes.Contains(data, []byte("\r\n\r\n")) {
+		t.Fatalf("no CRLF between headers and body:\n%s", data)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v\n%s", err, data)
+	}
+	return msg
+}
+
+func decodeQP(t *testing.T, r io.Reader) string {
+	t.Helper()
+	b, err := io.ReadAll(quotedprintable.NewReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestBuildHeaders(t *testing.T) {
+	dec := new(mime.WordDecoder)
+	tests := []struct {
+		name    string
+		from    string
+		msg     Message
+		header  string
+		want    string // decoded value
+		wantRaw string // substring of the raw header line, if set
+	}{
+		{"ascii subject", "a@example.com", Message{To: "b@example.com", Subject: "Hello"}, "Subject", "Hello", "Subject: Hello\r\n"},
+		{"utf-8 subject", "a@example.com", Message{To: "b@example.com", Subject: "新评论：你好"}, "Subject", "新评论：你好", "=?utf-8?q?"},
+		{"utf-8 sender name", "博客 <noreply@example.com>", Message{To: "b@example.com"}, "From", "博客 <noreply@example.com>", "From: =?utf-8?"},
+		{"utf-8 recipient name", "a@example.com", Message{To: "Zoë <zoe@example.com>"}, "To", "Zoë <zoe@example.com>", ""},
+		{"message sender wins", "a@example.com", Message{From: "c@example.com", To: "b@example.com"}, "From", "<c@example.com>", ""},
+		{"extra header canonicalised", "a@example.com", Message{To: "b@example.com", Headers: map[string]string{"list-unsubscribe": "<https://example.com/u>"}}, "List-Unsubscribe", "<https://example.com/u>", ""},
+	}
+	for _, tt := range tests {
+		data, err := Build(tt.from, tt.msg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, line := range strings.Split(string(data[:bytes.Index(data, []byte("\r\n\r\n"))]), "\r\n") {
+			for _, r := range line {
+				if r > 127 {
+					t.Errorf("%s: non-ASCII header line %q", tt.name, line)
+					break
+				}
+			}
+		}
+		if tt.wantRaw != "" && !bytes.Contains(data, []byte(tt.wantRaw)) {
+			t.Errorf("%s: raw message lacks %q:\n%s", tt.name, tt.wantRaw, data)
+		}
+
+		msg := parse(t, data)
+		got, err := dec.DecodeHeader(msg.Header.Get(tt.header))
+		if err != nil {
+			t.Fatalf("%s: decode %s: %v", tt.name, tt.header, err)
+		}
+		if tt.header == "From" || tt.header == "To" {
+			addr, err := mail.ParseAddress(got)
+			if err != nil {
+				t.Fatalf("%s: %s = %q does not parse: %v", tt.name, tt.header, got, err)
+			}
+			got = addr.Name + " <" + addr.Address + ">"
+			got = strings.TrimPrefix(got, " ")
+		}
+		if got != tt.want {
+			t.Errorf("%s: %s = %q, want %q", tt.name, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestBuildStripsHeaderInjection(t *testing.T) {
+	data, err := Build("a@example.com", Message{
+		To:      "b@example.com",
+		Subject: "Hi\r\nBcc: victim@example.com",
+		Headers: map[string]string{"X-Tag": "x\nBcc: other@example.com"},
+		Text:    "body",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := parse(t, data)
+	if bcc := msg.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("injected Bcc header %q", bcc)
+	}
+	if got := msg.Header.Get("Subject"); got != "HiBcc: victim@example.com" {
+		t.Errorf("Subject = %q", got)
+	}
+}
+
+func TestBuildStandardHeaders(t *testing.T) {
+	data, err := Build("Blog <noreply@blog.example.com>", Message{To: "b@example.com", Text: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := parse(t, data)
+	if got := msg.Header.Get("MIME-Version"); got != "1.0" {
+		t.Errorf("MIME-Version = %q", got)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@blog.example.com>") {
+		t.Errorf("Message-ID = %q, want <...@blog.example.com>", id)
+	}
+}
+
+func TestBuildSinglePart(t *testing.T) {
+	long := strings.Repeat("Zwölf Boxkämpfer jagen Viktor quer über den Sylter Deich. ", 4) + "a=b"
+	tests := []struct {
+		name        string
+		msg         Message
+		contentType string
+		want        string
+	}{
+		{"text", Message{To: "b@example.com", Text: long}, "text/plain; charset=utf-8", long},
+		{"html", Message{To: "b@example.com", HTML: "<p>" + long + "</p>"}, "text/html; charset=utf-8", "<p>" + long + "</p>"},
+	}
+	for _, tt := range tests {
+		data, err := Build("a@example.com", tt.msg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		msg := parse(t, data)
+		if got := msg.Header.Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.name, got, tt.contentType)
+		}
+		if got := msg.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Errorf("%s: Content-Transfer-Encoding = %q", tt.name, got)
+		}
+		raw, _ := io.ReadAll(msg.Body)
+		for _, line := range strings.Split(string(raw), "\r\n") {
+			if len(line) > 76 {
+				t.Errorf("%s: body line longer than 76 characters: %q", tt.name, line)
+			}
+		}
+		if got := decodeQP(t, bytes.NewReader(raw)); got != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildAlternative(t *testing.T) {
+	data, err := Build("a@example.com", Message{To: "b@example.com", Text: "Grüße", HTML: "<b>Grüße</b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := parse(t, data)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" || params["boundary"] == "" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	// Plain text first: clients show the last part they can render.
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Grüße"},
+		{"text/html; charset=utf-8", "<b>Grüße</b>"},
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for i := 0; ; i++ {
+		part, err := r.NextRawPart()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Errorf("got %d parts, want %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(want) {
+			t.Fatalf("unexpected part %d", i+1)
+		}
+		if got := part.Header.Get("Content-Type"); got != want[i].contentType {
+			t.Errorf("part %d: Content-Type = %q, want %q", i+1, got, want[i].contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+			t.Errorf("part %d: Content-Transfer-Encoding = %q", i+1, got)
+		}
+		if got := decodeQP(t, part); got != want[i].body {
+			t.Errorf("part %d: body = %q, want %q", i+1, got, want[i].body)
+		}
+	}
+}
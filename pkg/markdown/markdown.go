@@ -2,6 +2,9 @@ package markdown
 
 import (
 	"bytes"
+	"html"
+	"regexp"
+	"strings"
 
 	"github.com/yuin/goldmark"
 )
@@ -16,3 +19,11 @@ func ToHTML(source string) string {
 	}
 	return buf.String()
 }
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ToPlainText renders markdown and strips all markup, leaving readable text.
+func ToPlainText(source string) string {
+	text := tagPattern.ReplaceAllString(ToHTML(source), " ")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
@@ -0,0 +1,290 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// mockProvider is an OAuth2 provider with PKCE. It remembers the challenge
+// sent with the consent URL and only issues a token for the matching
+// verifier. Profile endpoints answer from the fields below.
+type mockProvider struct {
+	*httptest.Server
+	challenge string
+	code      string
+	user      any
+	emails    any // nil: the emails endpoint fails
+}
+
+const mockToken = "token-123"
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	m := &mockProvider{code: "code-abc"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", m.token)
+	mux.HandleFunc("GET /user", m.profile(func() any { return m.user }))
+	mux.HandleFunc("GET /user/emails", m.profile(func() any { return m.emails }))
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockProvider) provider(name string) *Provider {
+	return &Provider{
+		Name:         name,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthURL:      m.URL + "/authorize",
+		TokenURL:     m.URL + "/token",
+		UserInfoURL:  m.URL + "/user",
+		EmailsURL:    m.URL + "/user/emails",
+		RedirectURL:  "https://blog.example.com/callback",
+		Scopes:       []string{"read:user", "user:email"},
+	}
+}
+
+// authorize records the challenge of a consent URL, as the provider would
+// when the user approves.
+func (m *mockProvider) authorize(t *testing.T, authURL string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("consent URL without S256 challenge: %s", authURL)
+	}
+	m.challenge = u.Query().Get("code_challenge")
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Form.Get("client_id") != "client" || r.Form.Get("client_secret") != "secret":
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+	case r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != m.code ||
+		r.Form.Get("redirect_uri") != "https://blog.example.com/callback":
+		// GitHub style: an error in a 200 response.
+		_, _ = w.Write([]byte(`{"error":"bad_verification_code","error_description":"The code is incorrect."}`))
+	case Challenge(r.Form.Get("code_verifier")) != m.challenge:
+		_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"PKCE verification failed"}`))
+	default:
+		_, _ = w.Write([]byte(`{"access_token":"` + mockToken + `","token_type":"bearer"}`))
+	}
+}
+
+func (m *mockProvider) profile(body func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+mockToken {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		v := body()
+		if v == nil {
+			http.Error(w, `{"message":"Forbidden"}`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636, appendix B.
+	got := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Challenge = %q, want %q", got, want)
+	}
+	if a, b := NewVerifier(), NewVerifier(); a == b || len(a) < 43 {
+		t.Errorf("verifiers %q, %q: want distinct and at least 43 characters", a, b)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p := &Provider{
+		ClientID:    "client",
+		AuthURL:     "https://accounts.example.com/o/oauth2/auth?prompt=select_account",
+		RedirectURL: "https://blog.example.com/callback",
+		Scopes:      []string{"openid", "email"},
+	}
+	u, err := url.Parse(p.AuthCodeURL("state-1", "verifier-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{
+		"prompt":                "select_account",
+		"response_type":         "code",
+		"client_id":             "client",
+		"redirect_uri":          "https://blog.example.com/callback",
+		"state":                 "state-1",
+		"code_challenge":        Challenge("verifier-1"),
+		"code_challenge_method": "S256",
+		"scope":                 "openid email",
+	} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if q.Has("code_verifier") {
+		t.Error("consent URL leaks the verifier")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+	m := newMockProvider(t)
+	p := m.provider(ProviderGitHub)
+	verifier := NewVerifier()
+	m.authorize(t, p.AuthCodeURL(NewState(), verifier))
+
+	token, err := p.Exchange(ctx, m.code, verifier)
+	if err != nil || token != mockToken {
+		t.Fatalf("Exchange = %q, %v; want %q", token, err, mockToken)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		verifier string
+		secret   string
+		wantErr  string
+	}{
+		{"wrong verifier", m.code, NewVerifier(), "secret", "PKCE verification failed"},
+		{"wrong code", "other", verifier, "secret", "bad_verification_code"},
+		{"wrong secret", m.code, verifier, "nope", "unexpected status 401"},
+	}
+	for _, tt := range tests {
+		p := m.provider(ProviderGitHub)
+		p.ClientSecret = tt.secret
+		token, err := p.Exchange(ctx, tt.code, tt.verifier)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) || token != "" {
+			t.Errorf("%s: Exchange = %q, %v; want an error containing %q", tt.name, token, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExchangeWithoutToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"token_type":"bearer"}`))
+	}))
+	defer srv.Close()
+
+	p := &Provider{TokenURL: srv.URL}
+	if _, err := p.Exchange(context.Background(), "code", "verifier"); err == nil || !strings.Contains(err.Error(), "no access token") {
+		t.Errorf("err = %v, want no access token", err)
+	}
+}
+
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func TestFetchGitHubProfile(t *testing.T) {
+	user := githubUser{ID: 42, Login: "octocat", Name: "The Octocat", Email: "public@example.com", AvatarURL: "https://avatars.example.com/42"}
+	tests := []struct {
+		name         string
+		emails       any
+		wantEmail    string
+		wantVerified bool
+	}{
+		{
+			name: "primary verified address",
+			emails: []githubEmail{
+				{Email: "old@example.com", Verified: true},
+				{Email: "primary@example.com", Primary: true, Verified: true},
+			},
+			wantEmail:    "primary@example.com",
+			wantVerified: true,
+		},
+		{
+			name:      "primary address unverified",
+			emails:    []githubEmail{{Email: "primary@example.com", Primary: true}},
+			wantEmail: "public@example.com",
+		},
+		{
+			name:      "emails endpoint fails",
+			emails:    nil,
+			wantEmail: "public@example.com",
+		},
+	}
+	for _, tt := range tests {
+		m := newMockProvider(t)
+		m.user, m.emails = user, tt.emails
+
+		profile, err := m.provider(ProviderGitHub).FetchProfile(context.Background(), mockToken)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		want := Profile{ID: "42", Email: tt.wantEmail, EmailVerified: tt.wantVerified, Name: "octocat", AvatarURL: user.AvatarURL}
+		if *profile != want {
+			t.Errorf("%s: profile = %+v, want %+v", tt.name, *profile, want)
+		}
+	}
+}
+
+func TestFetchGitHubProfileErrors(t *testing.T) {
+	m := newMockProvider(t)
+	m.user = githubUser{Login: "ghost"}
+	if _, err := m.provider(ProviderGitHub).FetchProfile(context.Background(), mockToken); err == nil {
+		t.Error("profile without id: want an error")
+	}
+
+	m.user = githubUser{ID: 42}
+	if _, err := m.provider(ProviderGitHub).FetchProfile(context.Background(), "expired"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("bad token: err = %v, want a 401", err)
+	}
+}
+
+func TestFetchGoogleProfile(t *testing.T) {
+	m := newMockProvider(t)
+	m.user = map[string]any{
+		"sub":            "1098",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+		"picture":        "https://lh3.example.com/photo.jpg",
+		"locale":         "en",
+	}
+	profile, err := m.provider(ProviderGoogle).FetchProfile(context.Background(), mockToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Profile{ID: "1098", Email: "user@example.com", EmailVerified: true, Name: "Jane Doe", AvatarURL: "https://lh3.example.com/photo.jpg"}
+	if *profile != want {
+		t.Errorf("profile = %+v, want %+v", *profile, want)
+	}
+
+	m.user = map[string]any{"email": "user@example.com", "email_verified": true}
+	if _, err := m.provider(ProviderGoogle).FetchProfile(context.Background(), mockToken); err == nil {
+		t.Error("profile without subject: want an error")
+	}
+}
+
+func TestFetchProfileUnsupportedProvider(t *testing.T) {
+	p := &Provider{Name: "gitlab"}
+	if _, err := p.FetchProfile(context.Background(), mockToken); err == nil {
+		t.Error("want an error for an unsupported provider")
+	}
+}
@@ -0,0 +1,60 @@
+// Package privacy anonymizes client IP addresses and reads tracking opt-out
+// signals.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+)
+
+// IP storage modes.
+const (
+	ModeFull     = "full"     // Store the address as is
+	ModeTruncate = "truncate" // Zero the host part: IPv4 /24, IPv6 /48
+	ModeHash     = "hash"     // Keyed hash; equal addresses map to equal values under one key
+)
+
+// Truncate zeroes the last octet of an IPv4 address and all but the first 48
+// bits of an IPv6 address. Anything that does not parse becomes "".
+func Truncate(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// Hash returns a hex HMAC-SHA256 of value under key, shortened to 128 bits.
+// It fits the columns that hold IP addresses.
+func Hash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Anonymize applies mode to ip; the key is only used by ModeHash.
+func Anonymize(mode, ip string, key []byte) string {
+	switch mode {
+	case ModeTruncate:
+		return Truncate(ip)
+	case ModeHash:
+		if ip == "" {
+			return ""
+		}
+		return Hash(key, ip)
+	default:
+		return ip
+	}
+}
+
+// DoNotTrack reports whether the request opted out of tracking through the
+// Do Not Track (DNT: 1) or Global Privacy Control (Sec-GPC: 1) header.
+func DoNotTrack(h http.Header) bool {
+	return h.Get("DNT") == "1" || h.Get("Sec-GPC") == "1"
+}
@@ -0,0 +1,83 @@
+package privacy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.0.2.33", "192.0.2.0"},
+		{"10.1.2.255", "10.1.2.0"},
+		{"::ffff:192.0.2.33", "192.0.2.0"},
+		{"2001:db8:85a3:8d3:1319:8a2e:370:7348", "2001:db8:85a3::"},
+		{"2001:db8::1", "2001:db8::"},
+		{"fe80::1%eth0", ""},
+		{"not an ip", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.ip); got != tt.want {
+			t.Errorf("Truncate(%q) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	key := []byte("key")
+	hashed := Anonymize(ModeHash, "192.0.2.33", key)
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"full keeps the address", Anonymize(ModeFull, "192.0.2.33", key), "192.0.2.33"},
+		{"unknown mode keeps the address", Anonymize("", "2001:db8::1", key), "2001:db8::1"},
+		{"truncate v4", Anonymize(ModeTruncate, "192.0.2.33", key), "192.0.2.0"},
+		{"truncate v6", Anonymize(ModeTruncate, "2001:db8:1:2::1", key), "2001:db8:1::"},
+		{"hash is stable", Anonymize(ModeHash, "192.0.2.33", key), hashed},
+		{"hash of nothing", Anonymize(ModeHash, "", key), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if len(hashed) != 32 {
+		t.Errorf("hash length = %d, want 32 hex characters", len(hashed))
+	}
+	if hashed == "192.0.2.33" || hashed == Anonymize(ModeHash, "192.0.2.34", key) {
+		t.Error("hash must hide the address and tell addresses apart")
+	}
+	if hashed == Anonymize(ModeHash, "192.0.2.33", []byte("other")) {
+		t.Error("hash must depend on the key")
+	}
+}
+
+func TestDoNotTrack(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no headers", nil, false},
+		{"dnt", map[string]string{"DNT": "1"}, true},
+		{"dnt off", map[string]string{"DNT": "0"}, false},
+		{"gpc", map[string]string{"Sec-GPC": "1"}, true},
+		{"gpc with other value", map[string]string{"Sec-GPC": "true"}, false},
+		{"both", map[string]string{"DNT": "0", "Sec-GPC": "1"}, true},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		if got := DoNotTrack(h); got != tt.want {
+			t.Errorf("%s: DoNotTrack = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEvictsIdleBuckets(t *testing.T) {
+	clk := newClock()
+	s := NewMemoryStore()
+	s.now = clk.now
+	s.lastSweep = clk.now()
+
+	fast := Limit{Requests: 10, Period: time.Second}
+	slow := Limit{Requests: 1, Period: time.Hour}
+	take(t, s, "fast", fast)
+	take(t, s, "slow", slow)
+
+	// Both buckets survive until the next sweep is due.
+	clk.advance(sweepInterval - time.Second)
+	take(t, s, "other", fast)
+	if n := len(s.buckets); n != 3 {
+		t.Fatalf("buckets before sweep = %d, want 3", n)
+	}
+
+	// The sweep drops buckets that have refilled and keeps the rest.
+	clk.advance(time.Second)
+	take(t, s, "other", fast)
+	if _, ok := s.buckets["fast"]; ok {
+		t.Error("full bucket fast was kept")
+	}
+	if _, ok := s.buckets["slow"]; !ok {
+		t.Error("refilling bucket slow was dropped")
+	}
+
+	// Forgetting a full bucket does not change the outcome.
+	if res := take(t, s, "fast", fast); !res.Allowed || res.Remaining != 9 {
+		t.Errorf("fast after eviction = %+v, want a full bucket", res)
+	}
+	if res := take(t, s, "slow", slow); res.Allowed {
+		t.Errorf("slow after sweep = %+v, want still empty", res)
+	}
+}
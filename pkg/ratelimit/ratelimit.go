@@ -0,0 +1,74 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// storage: in-process for a single instance, or Redis for several.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Period on average, with bursts of up to Burst.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// rate returns the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// Valid reports whether the limit can be enforced.
+func (l Limit) Valid() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Limit      int           // Bucket capacity
+	Remaining  int           // Tokens left after this request
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until a token is available; zero when allowed
+}
+
+// Store keeps bucket state. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes one token from the bucket for key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill advances a bucket holding tokens at time last to now and takes a
+// token if one is available. It is shared by the stores so they agree on
+// the arithmetic.
+func refill(tokens float64, last, now time.Time, limit Limit) (float64, Result) {
+	rate := limit.rate()
+	burst := float64(limit.burst())
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed*rate)
+	}
+
+	res := Result{Limit: limit.burst()}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((burst - tokens) / rate)
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// clock is a manual time source for the stores.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newClock() *clock {
+	return &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// stores returns each store kind reading time from clk.
+func stores(t *testing.T, clk *clock) map[string]Store {
+	memory := NewMemoryStore()
+	memory.now = clk.now
+	memory.lastSweep = clk.now()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	shared := NewRedisStore(client, "ratelimit:")
+	shared.now = clk.now
+
+	return map[string]Store{"memory": memory, "redis": shared}
+}
+
+func take(t *testing.T, s Store, key string, limit Limit) Result {
+	t.Helper()
+	res, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestBurstThenDeny(t *testing.T) {
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 5}
+	clk := newClock()
+	for name, s := range stores(t, clk) {
+		for i := 4; i >= 0; i-- {
+			res := take(t, s, "a", limit)
+			if !res.Allowed || res.Remaining != i || res.Limit != 5 || res.RetryAfter != 0 {
+				t.Fatalf("%s: take %d = %+v, want allowed with %d remaining", name, 5-i, res, i)
+			}
+		}
+		res := take(t, s, "a", limit)
+		if res.Allowed || res.Remaining != 0 {
+			t.Errorf("%s: take past burst = %+v, want denied", name, res)
+		}
+		if res.RetryAfter != 500*time.Millisecond {
+			t.Errorf("%s: RetryAfter = %v, want 500ms", name, res.RetryAfter)
+		}
+		if res.Reset != 2500*time.Millisecond {
+			t.Errorf("%s: Reset = %v, want 2.5s", name, res.Reset)
+		}
+	}
+}
+
+func TestRefillTiming(t *testing.T) {
+	limit := Limit{Requests: 2, Period: time.Second, Burst: 2}
+	clk := newClock()
+	for name, s := range stores(t, clk) {
+		take(t, s, "a", limit)
+		take(t, s, "a", limit)
+
+		// Half a token back: still denied, and told to wait for the rest.
+		clk.advance(250 * time.Millisecond)
+		if res := take(t, s, "a", limit); res.Allowed || res.RetryAfter != 250*time.Millisecond {
+			t.Errorf("%s: after 250ms = %+v, want denied for 250ms", name, res)
+		}
+		clk.advance(250 * time.Millisecond)
+		if res := take(t, s, "a", limit); !res.Allowed || res.Remaining != 0 {
+			t.Errorf("%s: after 500ms = %+v, want one token", name, res)
+		}
+
+		// A long pause refills up to the burst and no further.
+		clk.advance(time.Hour)
+		for i := range 2 {
+			if res := take(t, s, "a", limit); !res.Allowed {
+				t.Errorf("%s: take %d after idle = %+v, want allowed", name, i+1, res)
+			}
+		}
+		if res := take(t, s, "a", limit); res.Allowed {
+			t.Errorf("%s: third take after idle = %+v, want denied", name, res)
+		}
+	}
+}
+
+func TestBurstDefaultsToRequests(t *testing.T) {
+	limit := Limit{Requests: 3, Period: time.Minute}
+	for name, s := range stores(t, newClock()) {
+		res := take(t, s, "a", limit)
+		if res.Limit != 3 || res.Remaining != 2 {
+			t.Errorf("%s: first take = %+v, want limit 3 with 2 remaining", name, res)
+		}
+	}
+}
+
+func TestKeysAreIsolated(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Minute}
+	for name, s := range stores(t, newClock()) {
+		if res := take(t, s, "a", limit); !res.Allowed {
+			t.Fatalf("%s: a = %+v, want allowed", name, res)
+		}
+		if res := take(t, s, "a", limit); res.Allowed {
+			t.Errorf("%s: a again = %+v, want denied", name, res)
+		}
+		if res := take(t, s, "b", limit); !res.Allowed {
+			t.Errorf("%s: b = %+v, want allowed despite a being empty", name, res)
+		}
+	}
+}
+
+func TestLimitValid(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  bool
+	}{
+		{Limit{Requests: 1, Period: time.Second}, true},
+		{Limit{Requests: 0, Period: time.Second}, false},
+		{Limit{Requests: 1}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Valid(); got != tt.want {
+			t.Errorf("%+v: Valid = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package search provides text normalization shared by the post search index
+// and anything else that needs to compare free text, including CJK content.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits text into lowercase search tokens.
+// Latin/numeric words are kept whole; runs of CJK characters, which have no
+// spaces between words, are split into overlapping bigrams so that any
+// two-character substring of a Chinese phrase can be matched.
+func Tokenize(text string) []string {
+	return tokenize(text, false)
+}
+
+// IndexTokens returns the tokens stored for a document: those of Tokenize
+// plus every single CJK character, so that a one-character query such as
+// "猫" (a single unigram token) matches too.
+func IndexTokens(text string) []string {
+	return tokenize(text, true)
+}
+
+func tokenize(text string, unigrams bool) []string {
+	var (
+		tokens []string
+		word   []rune
+		cjk    []rune
+	)
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+			if unigrams {
+				for _, r := range cjk {
+					tokens = append(tokens, string(r))
+				}
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case IsCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// Normalize returns the index tokens of text joined by single spaces. It is
+// the form stored in the database index; queries are tokenized with Tokenize,
+// whose tokens are a subset of IndexTokens.
+func Normalize(text string) string {
+	return strings.Join(IndexTokens(text), " ")
+}
+
+// Terms splits a user query into the phrases that should be highlighted:
+// whitespace-separated words and, so that partial matches inside CJK text
+// are marked as well, the search tokens of each word. Terms are lowercased
+// and deduplicated; Highlight prefers the longest one at each position.
+func Terms(query string) []string {
+	seen := make(map[string]struct{})
+	var terms []string
+	add := func(t string) {
+		if _, ok := seen[t]; ok || t == "" {
+			return
+		}
+		seen[t] = struct{}{}
+		terms = append(terms, t)
+	}
+	for _, f := range strings.Fields(strings.ToLower(query)) {
+		add(strings.TrimFunc(f, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		}))
+		for _, t := range Tokenize(f) {
+			// Single Latin letters left over from e.g. "e-mail" would mark
+			// every occurrence of that letter.
+			if r := []rune(t); len(r) > 1 || IsCJK(r[0]) {
+				add(t)
+			}
+		}
+	}
+	return terms
+}
+
+// IsCJK reports whether r is a Han, Hiragana, Katakana or Hangul character.
+func IsCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// Highlight HTML-escapes text and wraps every case-insensitive occurrence of
+// any term in <mark></mark>.
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Case folding changed the length (rare); fall back to no highlighting.
+		return html.EscapeString(text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(lower); {
+		n := matchAt(lower, i, terms)
+		if n == 0 {
+			i++
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[last:i])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[i : i+n])))
+		b.WriteString("</mark>")
+		i += n
+		last = i
+	}
+	b.WriteString(html.EscapeString(string(runes[last:])))
+	return b.String()
+}
+
+// Snippet extracts a window of about maxRunes runes around the first term
+// occurrence in text and highlights it. If no term occurs, the beginning of
+// the text is returned.
+func Snippet(text string, terms []string, maxRunes int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return Highlight(text, terms)
+	}
+
+	start := 0
+	if len(lower) == len(runes) {
+		for i := range lower {
+			if matchAt(lower, i, terms) > 0 {
+				start = i - maxRunes/3
+				break
+			}
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxRunes
+	if end > len(runes) {
+		end = len(runes)
+		start = max(0, end-maxRunes)
+	}
+
+	out := Highlight(string(runes[start:end]), terms)
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
+
+// matchAt returns the rune length of the longest term matching at position i.
+func matchAt(lower []rune, i int, terms []string) int {
+	best := 0
+	for _, t := range terms {
+		tr := []rune(t)
+		if len(tr) <= best || i+len(tr) > len(lower) {
+			continue
+		}
+		if string(lower[i:i+len(tr)]) == t {
+			best = len(tr)
+		}
+	}
+	return best
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello, World 42", []string{"hello", "world", "42"}},
+		{"猫", []string{"猫"}},
+		{"我的猫", []string{"我的", "的猫"}},
+		{"Go语言", []string{"go", "语言"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIndexTokensMatchSingleCharacterQueries(t *testing.T) {
+	index := map[string]bool{}
+	for _, tok := range IndexTokens("我的猫很可爱") {
+		index[tok] = true
+	}
+	for _, query := range []string{"猫", "可爱", "我的猫"} {
+		for _, tok := range Tokenize(query) {
+			if !index[tok] {
+				t.Errorf("query %q: token %q is not indexed", query, tok)
+			}
+		}
+	}
+}
+
+func TestHighlightCJKInsideSentence(t *testing.T) {
+	got := Highlight("我的猫很可爱", Terms("猫可爱"))
+	if !strings.Contains(got, "<mark>可爱</mark>") {
+		t.Errorf("Highlight = %q, want 可爱 marked", got)
+	}
+	got = Highlight("我的猫很可爱", Terms("猫"))
+	if got != "我的<mark>猫</mark>很可爱" {
+		t.Errorf("Highlight = %q", got)
+	}
+}
+
+func TestHighlightPrefersWholeWord(t *testing.T) {
+	got := Highlight("Send an e-mail", Terms("e-mail"))
+	if got != "Send an <mark>e-mail</mark>" {
+		t.Errorf("Highlight = %q", got)
+	}
+	got = Highlight("<b>Go</b> rocks", Terms("go"))
+	if got != "&lt;b&gt;<mark>Go</mark>&lt;/b&gt; rocks" {
+		t.Errorf("Highlight = %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+// Package spam implements the text features and naive Bayes scoring used to
+// classify user-submitted comments. It holds no state: token counts are
+// loaded and persisted by the caller.
+package spam
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"blog/pkg/search"
+)
+
+const (
+	// maxTokenLen drops absurdly long tokens (base64 blobs, minified junk).
+	maxTokenLen = 64
+	// maxTokens caps how many distinct tokens one document contributes.
+	maxTokens = 200
+	// interestingTokens is how many of the most decisive tokens are combined.
+	interestingTokens = 15
+	// strength and assumed are Robinson's prior for rarely seen tokens:
+	// a token seen n times moves from 0.5 towards its observed ratio as n grows.
+	strength = 1.0
+	assumed  = 0.5
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// Links returns the URLs found in text.
+func Links(text string) []string {
+	return linkPattern.FindAllString(text, -1)
+}
+
+// Tokens returns the distinct classification features of text: its search
+// tokens plus one "host:" token per linked domain, so that repeated links to
+// the same site are learned regardless of the surrounding words.
+func Tokens(text string) []string {
+	seen := make(map[string]struct{})
+	var out []string
+	add := func(t string) {
+		if t == "" || len(t) > maxTokenLen || len(out) >= maxTokens {
+			return
+		}
+		if _, ok := seen[t]; ok {
+			return
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+
+	for _, link := range Links(text) {
+		if host := linkHost(link); host != "" {
+			add("host:" + host)
+		}
+	}
+	for _, t := range search.Tokenize(text) {
+		add(t)
+	}
+	return out
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Counts is how many spam and ham training documents contained a token.
+type Counts struct {
+	Spam int64
+	Ham  int64
+}
+
+// Score returns the probability in [0,1] that a document with the given
+// tokens is spam, given per-token counts and the number of spam and ham
+// documents trained. It returns 0.5 when either class has no documents.
+func Score(tokens []string, counts map[string]Counts, spamDocs, hamDocs int64) float64 {
+	if spamDocs <= 0 || hamDocs <= 0 {
+		return 0.5
+	}
+
+	probs := make([]float64, 0, len(tokens))
+	for _, t := range tokens {
+		c, ok := counts[t]
+		if !ok || c.Spam+c.Ham == 0 {
+			continue
+		}
+		spamFreq := math.Min(1, float64(c.Spam)/float64(spamDocs))
+		hamFreq := math.Min(1, float64(c.Ham)/float64(hamDocs))
+		p := spamFreq / (spamFreq + hamFreq)
+		n := float64(c.Spam + c.Ham)
+		f := (strength*assumed + n*p) / (strength + n)
+		// Keep away from 0 and 1 so a single token can never decide alone.
+		probs = append(probs, math.Min(0.99, math.Max(0.01, f)))
+	}
+	if len(probs) == 0 {
+		return 0.5
+	}
+
+	sort.Slice(probs, func(i, j int) bool {
+		return math.Abs(probs[i]-0.5) > math.Abs(probs[j]-0.5)
+	})
+	if len(probs) > interestingTokens {
+		probs = probs[:interestingTokens]
+	}
+
+	// Combine in log-odds space to avoid underflow.
+	var logOdds float64
+	for _, p := range probs {
+		logOdds += math.Log(p) - math.Log(1-p)
+	}
+	return 1 / (1 + math.Exp(-logOdds))
+}
@@ -0,0 +1,83 @@
+package spam
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLinkHost(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://Example.com/path?q=1", "example.com"},
+		{"http://www.example.com", "example.com"},
+		{"www.Shop.example.org/deal", "shop.example.org"},
+		{"https://example.com:8443/x", "example.com"},
+		{"http://%zz", ""},
+	}
+	for _, tt := range tests {
+		if got := linkHost(tt.in); got != tt.want {
+			t.Errorf("linkHost(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTokensIncludeLinkHosts(t *testing.T) {
+	got := Tokens("Cheap pills at https://www.pills.example/buy and www.pills.example/now")
+	want := []string{"host:pills.example", "cheap", "pills", "at"}
+	if len(got) < len(want) || !reflect.DeepEqual(got[:len(want)], want) {
+		t.Errorf("Tokens = %q, want prefix %q", got, want)
+	}
+	hosts := 0
+	for _, tok := range got {
+		if tok == "host:pills.example" {
+			hosts++
+		}
+	}
+	if hosts != 1 {
+		t.Errorf("host token appears %d times, want 1", hosts)
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name              string
+		counts            map[string]Counts
+		spamDocs, hamDocs int64
+		want              float64
+	}{
+		{"no spam documents", map[string]Counts{"a": {Ham: 3}}, 0, 10, 0.5},
+		{"no ham documents", map[string]Counts{"a": {Spam: 3}}, 10, 0, 0.5},
+		{"unknown token", map[string]Counts{}, 10, 10, 0.5},
+		// Seen once, only in spam: (1*0.5 + 1*1) / (1 + 1).
+		{"prior pulls rare token towards 0.5", map[string]Counts{"a": {Spam: 1}}, 10, 10, 0.75},
+		// Seen 9 times, only in spam: (0.5 + 9) / 10.
+		{"frequent token approaches its ratio", map[string]Counts{"a": {Spam: 9}}, 10, 10, 0.95},
+		{"clamped below 1", map[string]Counts{"a": {Spam: 1000}}, 1000, 1000, 0.99},
+		{"clamped above 0", map[string]Counts{"a": {Ham: 1000}}, 1000, 1000, 0.01},
+		{"balanced token", map[string]Counts{"a": {Spam: 5, Ham: 5}}, 10, 10, 0.5},
+	}
+	for _, tt := range tests {
+		got := Score([]string{"a"}, tt.counts, tt.spamDocs, tt.hamDocs)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Score = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScoreCombinesTokens(t *testing.T) {
+	counts := map[string]Counts{
+		"spam1": {Spam: 9},
+		"spam2": {Spam: 9},
+		"ham":   {Ham: 9},
+	}
+	both := Score([]string{"spam1", "spam2"}, counts, 10, 10)
+	if both <= 0.95 {
+		t.Errorf("two spam tokens = %v, want above a single token's 0.95", both)
+	}
+	mixed := Score([]string{"spam1", "ham"}, counts, 10, 10)
+	if math.Abs(mixed-0.5) > 1e-9 {
+		t.Errorf("opposing tokens = %v, want 0.5", mixed)
+	}
+}
@@ -0,0 +1,74 @@
+// Package storage stores media files. It ships a local filesystem
+// implementation and one for S3-compatible object stores (AWS S3, MinIO,
+// Cloudflare R2 and the like), with no dependency beyond the standard library.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get and Stat when no object has the key.
+var ErrNotFound = errors.New("storage: object not found")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage is a flat store of objects addressed by slash-separated keys such
+// as "uploads/1f0c....jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens an object; the caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Walk calls fn for every object whose key starts with prefix, a
+	// directory such as "uploads/". Errors from fn stop the walk.
+	Walk(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+	// URL is the permanent public URL of an object. It may be relative to
+	// the site, starting with a slash.
+	URL(key string) string
+	// SignedURL is a URL that grants read access until it expires, even to
+	// objects that are not public. Stores whose objects are always public
+	// return URL.
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Config selects and configures a Storage.
+type Config struct {
+	Driver string // local | s3
+	Local  LocalConfig
+	S3     S3Config
+}
+
+// New returns the Storage selected by cfg.Driver. An empty driver means local.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.Local), nil
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.Driver)
+	}
+}
+
+// cleanKey rejects keys that could escape the store's root.
+func cleanKey(key string) (string, error) {
+	clean := path.Clean(key)
+	if key == "" || clean != key || strings.HasPrefix(clean, "/") || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return clean, nil
+}
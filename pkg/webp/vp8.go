@@ -0,0 +1,667 @@
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Intra prediction modes, numbered as the decoder does.
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+	numPredModes
+)
+
+// maxLevel bounds a quantized coefficient to what DCT_CAT6 can code.
+const maxLevel = 2048
+
+// maxPartitionSize is the largest first partition the frame tag can describe.
+const maxPartitionSize = 1<<19 - 1
+
+// nzContext records which 4x4 blocks along a macroblock edge had non-zero
+// coefficients; token probabilities of the neighbouring blocks depend on it.
+type nzContext struct {
+	y  [4]uint8
+	u  [2]uint8
+	v  [2]uint8
+	y2 uint8
+}
+
+// macroblockInfo is what the first partition records of a macroblock.
+type macroblockInfo struct {
+	ymode, cmode int
+	skip         bool // No non-zero coefficients, no tokens
+}
+
+// vp8Encoder encodes one key frame. Every macroblock predicts luma as a
+// whole (DC, TM, V or H, whichever is closest to the source) rather than
+// per 4x4 block, and tokens use the default probabilities, which keeps the
+// encoder small at the cost of some compression.
+type vp8Encoder struct {
+	width, height int
+	mbw, mbh      int
+
+	// Source planes, padded to whole macroblocks by repeating the last row
+	// and column, and the reconstruction the decoder will arrive at, which
+	// is what later macroblocks predict from.
+	y, u, v           []uint8
+	ry, ru, rv        []uint8
+	yStride, uvStride int
+
+	qi         int
+	y1, y2, uv [2]int32 // DC and AC step sizes
+
+	hdr, tok *boolEncoder
+	mbs      []macroblockInfo
+	top      []nzContext
+	left     nzContext
+}
+
+func newVP8Encoder(width, height, qi int) *vp8Encoder {
+	mbw, mbh := (width+15)/16, (height+15)/16
+	e := &vp8Encoder{
+		width:    width,
+		height:   height,
+		mbw:      mbw,
+		mbh:      mbh,
+		yStride:  mbw * 16,
+		uvStride: mbw * 8,
+		qi:       qi,
+		hdr:      newBoolEncoder(),
+		tok:      newBoolEncoder(),
+		mbs:      make([]macroblockInfo, 0, mbw*mbh),
+		top:      make([]nzContext, mbw),
+	}
+	e.y = make([]uint8, e.yStride*mbh*16)
+	e.u = make([]uint8, e.uvStride*mbh*8)
+	e.v = make([]uint8, e.uvStride*mbh*8)
+	e.ry = make([]uint8, len(e.y))
+	e.ru = make([]uint8, len(e.u))
+	e.rv = make([]uint8, len(e.v))
+
+	// Section 9.6 and 14.1.
+	e.y1 = [2]int32{int32(dcTable[qi]), int32(acTable[qi])}
+	e.y2 = [2]int32{int32(dcTable[qi]) * 2, max(int32(acTable[qi])*155/100, 8)}
+	e.uv = [2]int32{int32(dcTable[min(qi, 117)]), int32(acTable[qi])}
+	return e
+}
+
+// encode returns the frame as the payload of a "VP8 " chunk.
+func (e *vp8Encoder) encode() ([]byte, error) {
+	// Tokens first: the header needs the share of skipped macroblocks.
+	for mby := 0; mby < e.mbh; mby++ {
+		e.left = nzContext{}
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+	e.writeFirstPartition()
+	first, tokens := e.hdr.finish(), e.tok.finish()
+	if len(first) > maxPartitionSize {
+		return nil, errors.New("webp: image too large")
+	}
+
+	// Frame tag, start code and dimensions (section 9.1).
+	out := make([]byte, 10, 10+len(first)+len(tokens))
+	tag := uint32(len(first))<<5 | 1<<4 // Key frame, version 0, shown
+	out[0], out[1], out[2] = byte(tag), byte(tag>>8), byte(tag>>16)
+	out[3], out[4], out[5] = 0x9d, 0x01, 0x2a
+	binary.LittleEndian.PutUint16(out[6:], uint16(e.width))
+	binary.LittleEndian.PutUint16(out[8:], uint16(e.height))
+	out = append(out, first...)
+	return append(out, tokens...), nil
+}
+
+// writeFirstPartition writes the frame header (section 9.2 to 9.11), then
+// the modes of every macroblock (section 11.2).
+func (e *vp8Encoder) writeFirstPartition() {
+	h := e.hdr
+	h.putLiteral(0, 1) // Color space
+	h.putLiteral(0, 1) // Clamping required
+	h.putLiteral(0, 1) // No segmentation
+
+	// Normal loop filter, stronger as quantization gets coarser.
+	h.putLiteral(0, 1)
+	h.putLiteral(uint32(e.qi*3/8), 6)
+	h.putLiteral(0, 3) // Sharpness
+	h.putLiteral(0, 1) // No per-mode adjustments
+
+	h.putLiteral(0, 2) // One token partition
+
+	h.putLiteral(uint32(e.qi), 7)
+	for i := 0; i < 5; i++ {
+		h.putLiteral(0, 1) // No quantizer deltas
+	}
+
+	h.putLiteral(0, 1) // Refresh entropy probabilities
+	for i := range coeffUpdateProbs {
+		for j := range coeffUpdateProbs[i] {
+			for k := range coeffUpdateProbs[i][j] {
+				for l := range coeffUpdateProbs[i][j][k] {
+					h.put(false, coeffUpdateProbs[i][j][k][l])
+				}
+			}
+		}
+	}
+
+	// Probability that a macroblock has coefficients.
+	skipped := 0
+	for _, mb := range e.mbs {
+		if mb.skip {
+			skipped++
+		}
+	}
+	skipProb := uint8(min(max(255-skipped*255/len(e.mbs), 1), 254))
+	h.putLiteral(1, 1)
+	h.putLiteral(uint32(skipProb), 8)
+
+	for _, mb := range e.mbs {
+		h.put(mb.skip, skipProb)
+		e.putModes(mb.ymode, mb.cmode)
+	}
+}
+
+func (e *vp8Encoder) putModes(ymode, cmode int) {
+	h := e.hdr
+	h.put(true, 145) // 16x16 prediction
+	switch ymode {
+	case predDC:
+		h.put(false, 156)
+		h.put(false, 163)
+	case predVE:
+		h.put(false, 156)
+		h.put(true, 163)
+	case predHE:
+		h.put(true, 156)
+		h.put(false, 128)
+	case predTM:
+		h.put(true, 156)
+		h.put(true, 128)
+	}
+	switch cmode {
+	case predDC:
+		h.put(false, 142)
+	case predVE:
+		h.put(true, 142)
+		h.put(false, 114)
+	case predHE:
+		h.put(true, 142)
+		h.put(true, 114)
+		h.put(false, 183)
+	case predTM:
+		h.put(true, 142)
+		h.put(true, 114)
+		h.put(true, 183)
+	}
+}
+
+func (e *vp8Encoder) encodeMacroblock(mbx, mby int) {
+	var levelsY [16][16]int32
+	var levelsY2 [16]int32
+	var levelsU, levelsV [4][16]int32
+
+	mb := macroblockInfo{
+		ymode: e.predictLuma(mbx, mby, &levelsY, &levelsY2),
+		cmode: e.predictChroma(mbx, mby, &levelsU, &levelsV),
+		skip:  allZero(levelsY2) && allZero(levelsY[:]...) && allZero(levelsU[:]...) && allZero(levelsV[:]...),
+	}
+	e.mbs = append(e.mbs, mb)
+
+	// Residuals (section 13), in the order the decoder reads them. A skipped
+	// macroblock has none, and leaves no non-zero context behind.
+	top := &e.top[mbx]
+	if mb.skip {
+		*top, e.left = nzContext{}, nzContext{}
+		return
+	}
+	nz := e.putBlock(planeY2, top.y2+e.left.y2, &levelsY2, 0)
+	top.y2, e.left.y2 = nz, nz
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			nz := e.putBlock(planeY1WithY2, top.y[x]+e.left.y[y], &levelsY[y*4+x], 1)
+			top.y[x], e.left.y[y] = nz, nz
+		}
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			nz := e.putBlock(planeUV, top.u[x]+e.left.u[y], &levelsU[y*2+x], 0)
+			top.u[x], e.left.u[y] = nz, nz
+		}
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			nz := e.putBlock(planeUV, top.v[x]+e.left.v[y], &levelsV[y*2+x], 0)
+			top.v[x], e.left.v[y] = nz, nz
+		}
+	}
+}
+
+// predictLuma picks the luma mode, quantizes the residual into levels and
+// levelsY2 and reconstructs the macroblock.
+func (e *vp8Encoder) predictLuma(mbx, mby int, levels *[16][16]int32, levelsY2 *[16]int32) int {
+	const n = 16
+	off := mby*n*e.yStride + mbx*n
+	src := e.y[off:]
+	top, left, corner := edges(e.ry, e.yStride, mbx, mby, n)
+
+	var pred, best [n * n]uint8
+	mode, bestSSE := 0, -1
+	for m := 0; m < numPredModes; m++ {
+		predict(pred[:], n, m, top, left, corner, mbx, mby)
+		if s := sse(src, e.yStride, pred[:], n); bestSSE < 0 || s < bestSSE {
+			mode, bestSSE, best = m, s, pred
+		}
+	}
+
+	var coeffs [16][16]int32
+	var dc [16]int32
+	for b := 0; b < 16; b++ {
+		bx, by := b%4*4, b/4*4
+		fdct(src[by*e.yStride+bx:], e.yStride, best[by*n+bx:], n, &coeffs[b])
+		dc[b] = coeffs[b][0]
+	}
+
+	var y2 [16]int32
+	fwht(&dc, &y2)
+	for i := range y2 {
+		levelsY2[i] = quantize(y2[i], e.y2[min(i, 1)], i == 0)
+		y2[i] = levelsY2[i] * e.y2[min(i, 1)]
+	}
+	iwht(&y2, &dc)
+
+	recon := e.ry[off:]
+	for b := 0; b < 16; b++ {
+		levels[b][0] = 0
+		coeffs[b][0] = dc[b]
+		for i := 1; i < 16; i++ {
+			levels[b][i] = quantize(coeffs[b][i], e.y1[1], false)
+			coeffs[b][i] = levels[b][i] * e.y1[1]
+		}
+		bx, by := b%4*4, b/4*4
+		idct(&coeffs[b], best[by*n+bx:], n, recon[by*e.yStride+bx:], e.yStride)
+	}
+	return mode
+}
+
+// predictChroma does for both chroma planes what predictLuma does for luma;
+// they share one mode.
+func (e *vp8Encoder) predictChroma(mbx, mby int, levelsU, levelsV *[4][16]int32) int {
+	const n = 8
+	off := mby*n*e.uvStride + mbx*n
+	topU, leftU, cornerU := edges(e.ru, e.uvStride, mbx, mby, n)
+	topV, leftV, cornerV := edges(e.rv, e.uvStride, mbx, mby, n)
+
+	var predU, predV, bestU, bestV [n * n]uint8
+	mode, bestSSE := 0, -1
+	for m := 0; m < numPredModes; m++ {
+		predict(predU[:], n, m, topU, leftU, cornerU, mbx, mby)
+		predict(predV[:], n, m, topV, leftV, cornerV, mbx, mby)
+		s := sse(e.u[off:], e.uvStride, predU[:], n) + sse(e.v[off:], e.uvStride, predV[:], n)
+		if bestSSE < 0 || s < bestSSE {
+			mode, bestSSE, bestU, bestV = m, s, predU, predV
+		}
+	}
+
+	e.chromaResidual(e.u[off:], bestU[:], e.ru[off:], levelsU)
+	e.chromaResidual(e.v[off:], bestV[:], e.rv[off:], levelsV)
+	return mode
+}
+
+func (e *vp8Encoder) chromaResidual(src, pred, recon []uint8, levels *[4][16]int32) {
+	const n = 8
+	for b := 0; b < 4; b++ {
+		bx, by := b%2*4, b/2*4
+		var coeffs [16]int32
+		fdct(src[by*e.uvStride+bx:], e.uvStride, pred[by*n+bx:], n, &coeffs)
+		for i := range coeffs {
+			levels[b][i] = quantize(coeffs[i], e.uv[min(i, 1)], i == 0)
+			coeffs[i] = levels[b][i] * e.uv[min(i, 1)]
+		}
+		idct(&coeffs, pred[by*n+bx:], n, recon[by*e.uvStride+bx:], e.uvStride)
+	}
+}
+
+// putBlock writes the tokens of one 4x4 block from position first on, and
+// reports whether any coefficient was non-zero (section 13.2 and 13.3).
+func (e *vp8Encoder) putBlock(plane int, ctx uint8, levels *[16]int32, first int) uint8 {
+	probs := &defaultCoeffProbs[plane]
+	t := e.tok
+
+	last := -1
+	for i := 15; i >= first; i-- {
+		if levels[zigzag[i]] != 0 {
+			last = i
+			break
+		}
+	}
+
+	p := &probs[bands[first]][ctx]
+	if last < 0 {
+		t.put(false, p[0]) // EOB
+		return 0
+	}
+	t.put(true, p[0])
+
+	for n := first; n < 16; {
+		v := levels[zigzag[n]]
+		n++
+		if v == 0 {
+			t.put(false, p[1])
+			p = &probs[bands[n]][0]
+			continue
+		}
+		t.put(true, p[1])
+
+		a := v
+		if a < 0 {
+			a = -a
+		}
+		switch {
+		case a == 1:
+			t.put(false, p[2])
+		case a <= 4:
+			t.put(true, p[2])
+			t.put(false, p[3])
+			if a == 2 {
+				t.put(false, p[4])
+			} else {
+				t.put(true, p[4])
+				t.put(a == 4, p[5])
+			}
+		case a <= 10:
+			t.put(true, p[2])
+			t.put(true, p[3])
+			t.put(false, p[6])
+			if a <= 6 {
+				t.put(false, p[7])
+				t.put(a == 6, 159)
+			} else {
+				t.put(true, p[7])
+				t.put((a-7)&2 != 0, 165)
+				t.put((a-7)&1 != 0, 145)
+			}
+		default:
+			t.put(true, p[2])
+			t.put(true, p[3])
+			t.put(true, p[6])
+			cat := 3
+			switch {
+			case a < 19:
+				cat = 0
+			case a < 35:
+				cat = 1
+			case a < 67:
+				cat = 2
+			}
+			t.put(cat&2 != 0, p[8])
+			t.put(cat&1 != 0, p[9+cat>>1])
+			extra := a - 3 - 8<<cat
+			bits := catProbs[cat]
+			for i, prob := range bits {
+				t.put(extra>>(len(bits)-1-i)&1 != 0, prob)
+			}
+		}
+		t.put(v < 0, 128)
+
+		if a == 1 {
+			p = &probs[bands[n]][1]
+		} else {
+			p = &probs[bands[n]][2]
+		}
+		if n == 16 {
+			break
+		}
+		t.put(n <= last, p[0])
+		if n > last {
+			break
+		}
+	}
+	return 1
+}
+
+func allZero(blocks ...[16]int32) bool {
+	for _, b := range blocks {
+		if b != ([16]int32{}) {
+			return false
+		}
+	}
+	return true
+}
+
+// edges returns the pixels a macroblock is predicted from, or the constants
+// the decoder substitutes at the frame border.
+func edges(plane []uint8, stride, mbx, mby, n int) (top, left []uint8, corner uint8) {
+	top, left = make([]uint8, n), make([]uint8, n)
+	x0, y0 := mbx*n, mby*n
+	for i := 0; i < n; i++ {
+		if mby == 0 {
+			top[i] = 0x7f
+		} else {
+			top[i] = plane[(y0-1)*stride+x0+i]
+		}
+		if mbx == 0 {
+			left[i] = 0x81
+		} else {
+			left[i] = plane[(y0+i)*stride+x0-1]
+		}
+	}
+	switch {
+	case mby == 0:
+		corner = 0x7f
+	case mbx == 0:
+		corner = 0x81
+	default:
+		corner = plane[(y0-1)*stride+x0-1]
+	}
+	return top, left, corner
+}
+
+// predict fills the n×n block dst with the prediction of mode (section 12.2).
+func predict(dst []uint8, n, mode int, top, left []uint8, corner uint8, mbx, mby int) {
+	switch mode {
+	case predDC:
+		var sum, count int
+		if mby > 0 {
+			for _, v := range top {
+				sum += int(v)
+			}
+			count += n
+		}
+		if mbx > 0 {
+			for _, v := range left {
+				sum += int(v)
+			}
+			count += n
+		}
+		avg := uint8(0x80)
+		if count > 0 {
+			avg = uint8((sum + count/2) / count)
+		}
+		for i := range dst[:n*n] {
+			dst[i] = avg
+		}
+	case predTM:
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				dst[y*n+x] = clip8(int32(left[y]) + int32(top[x]) - int32(corner))
+			}
+		}
+	case predVE:
+		for y := 0; y < n; y++ {
+			copy(dst[y*n:y*n+n], top)
+		}
+	case predHE:
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				dst[y*n+x] = left[y]
+			}
+		}
+	}
+}
+
+// sse is the squared error between the n×n block at src and pred.
+func sse(src []uint8, stride int, pred []uint8, n int) int {
+	s := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			d := int(src[y*stride+x]) - int(pred[y*n+x])
+			s += d * d
+		}
+	}
+	return s
+}
+
+// quantize maps a coefficient to a level. AC coefficients are rounded
+// towards zero slightly more, which drops noise cheaply.
+func quantize(c, q int32, dc bool) int32 {
+	bias := q * 3 / 8
+	if dc {
+		bias = q / 2
+	}
+	neg := c < 0
+	if neg {
+		c = -c
+	}
+	level := min((c+bias)/q, maxLevel)
+	if neg {
+		return -level
+	}
+	return level
+}
+
+// fdct is the forward transform of the difference between a 4x4 block of
+// src and pred, the inverse of idct (the reference encoder's fdct).
+func fdct(src []uint8, srcStride int, pred []uint8, predStride int, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		var d [4]int32
+		for j := range d {
+			d[j] = int32(src[i*srcStride+j]) - int32(pred[i*predStride+j])
+		}
+		a1 := (d[0] + d[3]) * 8
+		b1 := (d[1] + d[2]) * 8
+		c1 := (d[1] - d[2]) * 8
+		d1 := (d[0] - d[3]) * 8
+		tmp[i*4+0] = a1 + b1
+		tmp[i*4+2] = a1 - b1
+		tmp[i*4+1] = (c1*2217 + d1*5352 + 14500) >> 12
+		tmp[i*4+3] = (d1*2217 - c1*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a1 := tmp[i] + tmp[12+i]
+		b1 := tmp[4+i] + tmp[8+i]
+		c1 := tmp[4+i] - tmp[8+i]
+		d1 := tmp[i] - tmp[12+i]
+		out[i] = (a1 + b1 + 7) >> 4
+		out[8+i] = (a1 - b1 + 7) >> 4
+		out[4+i] = (c1*2217+d1*5352+12000)>>16 + btoi(d1 != 0)
+		out[12+i] = (d1*2217 - c1*5352 + 51000) >> 16
+	}
+}
+
+// idct adds the inverse transform of coeffs to the 4x4 block at pred and
+// stores the result at dst, exactly as the decoder does (section 14.3).
+func idct(coeffs *[16]int32, pred []uint8, predStride int, dst []uint8, dstStride int) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coeffs[i] + coeffs[8+i]
+		b := coeffs[i] - coeffs[8+i]
+		c := (coeffs[4+i]*c2)>>16 - (coeffs[12+i]*c1)>>16
+		d := (coeffs[4+i]*c1)>>16 + (coeffs[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		res := [4]int32{(a + d) >> 3, (b + c) >> 3, (b - c) >> 3, (a - d) >> 3}
+		for i, r := range res {
+			dst[j*dstStride+i] = clip8(int32(pred[j*predStride+i]) + r)
+		}
+	}
+}
+
+// fwht is the forward Walsh-Hadamard transform of the 16 luma DC
+// coefficients, in block raster order, the inverse of iwht.
+func fwht(in, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		a := in[i*4+0] + in[i*4+3]
+		b := in[i*4+1] + in[i*4+2]
+		c := in[i*4+1] - in[i*4+2]
+		d := in[i*4+0] - in[i*4+3]
+		tmp[i*4+0] = a + b
+		tmp[i*4+1] = d + c
+		tmp[i*4+2] = a - b
+		tmp[i*4+3] = d - c
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[12+i]
+		b := tmp[4+i] + tmp[8+i]
+		c := tmp[4+i] - tmp[8+i]
+		d := tmp[i] - tmp[12+i]
+		out[i] = half(a + b)
+		out[4+i] = half(d + c)
+		out[8+i] = half(a - b)
+		out[12+i] = half(d - c)
+	}
+}
+
+// iwht is the decoder's inverse Walsh-Hadamard transform (section 14.3).
+func iwht(in, out *[16]int32) {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := in[i] + in[12+i]
+		a1 := in[4+i] + in[8+i]
+		a2 := in[4+i] - in[8+i]
+		a3 := in[i] - in[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[i*4+3]
+		a1 := m[i*4+1] + m[i*4+2]
+		a2 := m[i*4+1] - m[i*4+2]
+		a3 := dc - m[i*4+3]
+		out[i*4+0] = (a0 + a1) >> 3
+		out[i*4+1] = (a3 + a2) >> 3
+		out[i*4+2] = (a0 - a1) >> 3
+		out[i*4+3] = (a3 - a2) >> 3
+	}
+}
+
+// half divides by two, rounding half away from zero.
+func half(v int32) int32 {
+	if v < 0 {
+		return -((-v + 1) >> 1)
+	}
+	return (v + 1) >> 1
+}
+
+func clip8(v int32) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+func btoi(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
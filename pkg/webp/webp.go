@@ -0,0 +1,210 @@
+// Package webp writes images in the WebP format, lossy VP8 with an
+// uncompressed alpha channel when the image has transparency, in pure Go.
+//
+// The encoder favours simplicity over compression: it is meant for
+// generating resized variants of uploads, which are kept only when smaller
+// than the original format. Decoding is limited to reading the dimensions.
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+// DefaultQuality is the quality used when Options is nil.
+const DefaultQuality = 75
+
+// maxDimension is the largest width or height VP8 can describe.
+const maxDimension = 16383
+
+// Options are the encoding parameters. Quality ranges from 1 to 100
+// inclusive, higher is better.
+type Options struct {
+	Quality int
+}
+
+// Encode writes the image m to w in lossy WebP format.
+func Encode(w io.Writer, m image.Image, o *Options) error {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 || width > maxDimension || height > maxDimension {
+		return errors.New("webp: invalid image size")
+	}
+	quality := DefaultQuality
+	if o != nil {
+		quality = min(max(o.Quality, 1), 100)
+	}
+
+	img := toNRGBA(m)
+	enc := newVP8Encoder(width, height, qualityToIndex(quality))
+	enc.loadYUV(img)
+	frame, err := enc.encode()
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	if alpha := alphaPlane(img); alpha != nil {
+		// Extended format: canvas size and flags, then the alpha channel,
+		// stored without compression or filtering.
+		var vp8x [10]byte
+		vp8x[0] = 0x10 // Alpha
+		putUint24(vp8x[4:], uint32(width-1))
+		putUint24(vp8x[7:], uint32(height-1))
+		writeChunk(&body, "VP8X", vp8x[:])
+		writeChunk(&body, "ALPH", append([]byte{0}, alpha...))
+	}
+	writeChunk(&body, "VP8 ", frame)
+
+	var hdr [8]byte
+	copy(hdr[:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(body.Len()))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(body.Bytes())
+	return err
+}
+
+// DecodeConfig returns the dimensions of a WebP image without decoding it.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	var buf [30]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return image.Config{}, errors.New("webp: invalid format")
+	}
+	if string(buf[:4]) != "RIFF" || string(buf[8:12]) != "WEBP" {
+		return image.Config{}, errors.New("webp: invalid format")
+	}
+
+	var width, height int
+	chunk := buf[20:]
+	switch string(buf[12:16]) {
+	case "VP8 ":
+		if chunk[3] != 0x9d || chunk[4] != 0x01 || chunk[5] != 0x2a {
+			return image.Config{}, errors.New("webp: invalid VP8 header")
+		}
+		width = int(binary.LittleEndian.Uint16(chunk[6:]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(chunk[8:]) & 0x3fff)
+	case "VP8L":
+		if chunk[0] != 0x2f {
+			return image.Config{}, errors.New("webp: invalid VP8L header")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		width = int(uint24(chunk[4:])) + 1
+		height = int(uint24(chunk[7:])) + 1
+	default:
+		return image.Config{}, errors.New("webp: unsupported chunk")
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: width, Height: height}, nil
+}
+
+// qualityToIndex maps quality to a quantizer index, 0 being the finest.
+func qualityToIndex(quality int) int {
+	return min((100-quality)*127/80, 127)
+}
+
+// toNRGBA returns m as non-premultiplied RGBA with its origin at (0, 0).
+func toNRGBA(m image.Image) *image.NRGBA {
+	b := m.Bounds()
+	if img, ok := m.(*image.NRGBA); ok && b.Min == (image.Point{}) {
+		return img
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if src, ok := m.(*image.RGBA); ok {
+		for y := 0; y < b.Dy(); y++ {
+			s := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			d := dst.Pix[y*dst.Stride:]
+			for i := 0; i < b.Dx()*4; i += 4 {
+				a := uint32(s[i+3])
+				d[i+3] = s[i+3]
+				if a == 0 {
+					continue
+				}
+				d[i+0] = uint8((uint32(s[i+0])*255 + a/2) / a)
+				d[i+1] = uint8((uint32(s[i+1])*255 + a/2) / a)
+				d[i+2] = uint8((uint32(s[i+2])*255 + a/2) / a)
+			}
+		}
+		return dst
+	}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetNRGBA(x, y, color.NRGBAModel.Convert(m.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA))
+		}
+	}
+	return dst
+}
+
+// loadYUV converts img to limited range BT.601 Y'CbCr with 4:2:0 chroma.
+func (e *vp8Encoder) loadYUV(img *image.NRGBA) {
+	rgb := func(x, y int) (int32, int32, int32) {
+		x, y = min(x, e.width-1), min(y, e.height-1)
+		p := img.Pix[y*img.Stride+x*4:]
+		return int32(p[0]), int32(p[1]), int32(p[2])
+	}
+	for y := 0; y < e.mbh*16; y++ {
+		for x := 0; x < e.mbw*16; x++ {
+			r, g, b := rgb(x, y)
+			e.y[y*e.yStride+x] = clip8((16839*r + 33059*g + 6420*b + 16<<16 + 1<<15) >> 16)
+		}
+	}
+	for y := 0; y < e.mbh*8; y++ {
+		for x := 0; x < e.mbw*8; x++ {
+			var r, g, b int32
+			for i := 0; i < 4; i++ {
+				pr, pg, pb := rgb(2*x+i%2, 2*y+i/2)
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			// Sums of four pixels: the constants are scaled by four.
+			e.u[y*e.uvStride+x] = clip8((-9719*r - 19081*g + 28800*b + 128<<18 + 1<<17) >> 18)
+			e.v[y*e.uvStride+x] = clip8((28800*r - 24116*g - 4684*b + 128<<18 + 1<<17) >> 18)
+		}
+	}
+}
+
+// alphaPlane returns the alpha values of img, or nil when it is opaque.
+func alphaPlane(img *image.NRGBA) []byte {
+	b := img.Bounds()
+	opaque := true
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < b.Dx(); x++ {
+			a := row[x*4+3]
+			opaque = opaque && a == 0xff
+			alpha = append(alpha, a)
+		}
+	}
+	if opaque {
+		return nil
+	}
+	return alpha
+}
+
+// writeChunk writes a RIFF chunk, padded to an even length.
+func writeChunk(buf *bytes.Buffer, fourCC string, data []byte) {
+	var hdr [8]byte
+	copy(hdr[:], fourCC)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(data)))
+	buf.Write(hdr[:])
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
@@ -0,0 +1,191 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+// gradient returns a w×h test image with smooth colour ramps and a sharp
+// edge, and alpha a where the ramps meet when a < 255.
+func gradient(w, h int, a uint8) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 128, A: 255}
+			if x > w/2 {
+				c.B = 32
+			}
+			if x >= w/4 && x < w*3/4 && y >= h/4 && y < h*3/4 {
+				c.A = a
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+// decoded returns the pixels the reference decoder produced. The decoder
+// hands back the Y'CbCr planes, which image.YCbCr would convert as full
+// range JPEG data; VP8 uses the limited range, so they are converted the
+// way libwebp does.
+func decoded(t *testing.T, m image.Image) *image.NRGBA {
+	t.Helper()
+	var ycc *image.YCbCr
+	var alpha *image.NYCbCrA
+	switch m := m.(type) {
+	case *image.YCbCr:
+		ycc = m
+	case *image.NYCbCrA:
+		ycc, alpha = &m.YCbCr, m
+	default:
+		t.Fatalf("decoded image is %T, want Y'CbCr", m)
+	}
+	b := ycc.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			yy := 1.164 * (float64(ycc.Y[ycc.YOffset(x, y)]) - 16)
+			cb := float64(ycc.Cb[ycc.COffset(x, y)]) - 128
+			cr := float64(ycc.Cr[ycc.COffset(x, y)]) - 128
+			c := color.NRGBA{
+				R: clamp(yy + 1.596*cr),
+				G: clamp(yy - 0.813*cr - 0.391*cb),
+				B: clamp(yy + 2.018*cb),
+				A: 255,
+			}
+			if alpha != nil {
+				c.A = alpha.A[alpha.AOffset(x, y)]
+			}
+			dst.SetNRGBA(x-b.Min.X, y-b.Min.Y, c)
+		}
+	}
+	return dst
+}
+
+func clamp(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// psnr compares the colour channels of two images of the same size.
+func psnr(t *testing.T, want, got *image.NRGBA) float64 {
+	t.Helper()
+	if got.Bounds().Size() != want.Bounds().Size() {
+		t.Fatalf("decoded size %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	var sum float64
+	b := want.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			w, g := want.NRGBAAt(x, y), got.NRGBAAt(x, y)
+			for _, d := range []float64{float64(w.R) - float64(g.R), float64(w.G) - float64(g.G), float64(w.B) - float64(g.B)} {
+				sum += d * d
+			}
+		}
+	}
+	mse := sum / float64(3*b.Dx()*b.Dy())
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
+
+// TestEncodeDecodes checks the output with the reference decoder, at sizes
+// that are and are not multiples of the 16×16 macroblock.
+func TestEncodeDecodes(t *testing.T) {
+	for _, size := range []image.Point{{16, 16}, {37, 23}, {1, 1}, {200, 120}} {
+		src := gradient(size.X, size.Y, 255)
+		var buf bytes.Buffer
+		if err := Encode(&buf, src, &Options{Quality: 90}); err != nil {
+			t.Fatalf("%v: Encode: %v", size, err)
+		}
+
+		m, err := xwebp.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%v: reference decoder: %v", size, err)
+		}
+		// Small images with steep ramps lose most to 4:2:0 chroma.
+		if p := psnr(t, src, decoded(t, m)); p < 25 {
+			t.Errorf("%v: PSNR %.1f dB, want at least 25", size, p)
+		}
+
+		conf, err := DecodeConfig(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%v: DecodeConfig: %v", size, err)
+		}
+		if conf.Width != size.X || conf.Height != size.Y {
+			t.Errorf("%v: DecodeConfig = %dx%d", size, conf.Width, conf.Height)
+		}
+	}
+}
+
+func TestEncodeQuality(t *testing.T) {
+	src := gradient(64, 64, 255)
+	var low, high bytes.Buffer
+	if err := Encode(&low, src, &Options{Quality: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Encode(&high, src, &Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("quality 10 is %d bytes, not smaller than quality 95 at %d", low.Len(), high.Len())
+	}
+	lowImg, err := xwebp.Decode(&low)
+	if err != nil {
+		t.Fatal(err)
+	}
+	highImg, err := xwebp.Decode(&high)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pl, ph := psnr(t, src, decoded(t, lowImg)), psnr(t, src, decoded(t, highImg)); pl >= ph {
+		t.Errorf("quality 10 PSNR %.1f dB is not below quality 95 PSNR %.1f dB", pl, ph)
+	}
+}
+
+func TestEncodeAlpha(t *testing.T) {
+	src := gradient(40, 30, 64)
+	var buf bytes.Buffer
+	if err := Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes()[:40], []byte("VP8X")) {
+		t.Fatal("image with transparency was not written in the extended format")
+	}
+
+	m, err := xwebp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("reference decoder: %v", err)
+	}
+	got := decoded(t, m)
+	b := src.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if want, a := src.NRGBAAt(x, y).A, got.NRGBAAt(x, y).A; a != want {
+				t.Fatalf("alpha at (%d,%d) = %d, want %d", x, y, a, want)
+			}
+		}
+	}
+	if p := psnr(t, src, got); p < 25 {
+		t.Errorf("PSNR %.1f dB, want at least 25", p)
+	}
+
+	conf, err := DecodeConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Width != 40 || conf.Height != 30 {
+		t.Errorf("DecodeConfig = %dx%d, want 40x30", conf.Width, conf.Height)
+	}
+}
+
+func TestEncodeRejectsEmptyImage(t *testing.T) {
+	if err := Encode(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 5)), nil); err == nil {
+		t.Fatal("empty image was encoded")
+	}
+}
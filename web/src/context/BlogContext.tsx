@@ -67,7 +67,8 @@ export const BlogProvider: React.FC<{ children: ReactNode }> = ({ children }) =>
   const refreshPosts = async () => {
     try {
       let fetchedPosts: BlogPost[] = [];
-      if (user?.role === 'admin') {
+      // Staff (admin, editor, author) see drafts through the admin API
+      if (user?.role && user.role !== 'visitor') {
         fetchedPosts = await postService.getAdminPosts();
       } else {
         const response = await postService.getPosts();
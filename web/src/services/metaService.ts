@@ -30,8 +30,8 @@ export const metaService = {
 
     // Files (Media)
     getFiles: async (): Promise<MediaFile[]> => {
-        const response = await apiClient.get('/admin/files');
-        return response.data;
+        const response = await apiClient.get('/admin/files', { params: { limit: 200 } });
+        return response.data.data;
     },
     addFile: async (file: MediaFile): Promise<MediaFile> => {
         // The file is already uploaded via uploadService (POST /upload).
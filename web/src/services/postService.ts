@@ -28,6 +28,17 @@ export const postService = {
         }
     },
 
+    getRelatedPosts: async (slug: string, limit = 5): Promise<BlogPost[]> => {
+        try {
+            // Public API: /posts/:slug/related (precomputed "read next" suggestions)
+            const response = await apiClient.get(`/posts/${slug}/related`, { params: { limit } });
+            return Array.isArray(response.data) ? response.data : [];
+        } catch (error) {
+            console.error(`Failed to get related posts for ${slug}`, error);
+            return [];
+        }
+    },
+
     // Admin Endpoints
     getAdminPosts: async (params?: { category?: string; status?: string; search?: string; page?: number; limit?: number }): Promise<BlogPost[]> => {
         // Admin API: /admin/posts (all statuses)
@@ -6,8 +6,10 @@ export interface BlogPost {
   excerpt: string;
   content: string;
   author: string;
+  authorId?: number;
   // Scheduled publish time in RFC3339 (e.g. 2025-12-14T16:30:00+08:00)
   publishAt: string;
+  unpublishAt?: string;
   categoryId: number;
   category: string;
   readTime: string;
@@ -15,6 +17,23 @@ export interface BlogPost {
   tags: string[]; // Tag names from API response
   views: number;
   status: 'published' | 'draft';
+  series?: PostSeries;
+}
+
+export interface SeriesLink {
+  slug: string;
+  title: string;
+}
+
+// Where a post sits in its series ("part N of M"), counting live posts only
+export interface PostSeries {
+  id: number;
+  title: string;
+  slug: string;
+  part: number;
+  total: number;
+  prev?: SeriesLink;
+  next?: SeriesLink;
 }
 
 // Editing state: tags stored as IDs for API submission
@@ -38,6 +57,10 @@ export interface MediaFile {
   name: string;
   type: 'image' | 'video' | 'document';
   date: string;
+  alt?: string;
+  caption?: string;
+  folderId?: number | null;
+  usageCount?: number;
 }
 
 export interface ChatMessage {
@@ -67,12 +90,20 @@ export interface User {
   id: number;
   username: string;
   email: string;
-  role: 'admin' | 'visitor';
+  role: 'admin' | 'editor' | 'author' | 'visitor';
   status?: 'active' | 'banned';
   provider?: 'email' | 'google' | 'github';
   avatar?: string;
 }
 
+export interface Author {
+  id: number;
+  username: string;
+  bio?: string;
+  avatar?: string;
+  postCount: number;
+}
+
 export const Theme = {
   LIGHT: 'light',
   DARK: 'dark',
@@ -103,6 +134,8 @@ export interface DashboardOverview {
   recentPosts: BlogPost[];
   systemStatus: {
     storageUsage: number;
+    storageUsedBytes?: number;
+    storageQuotaBytes?: number;
     aiQuota: number;
   };
 }
@@ -113,3 +146,40 @@ export interface AuthResponse {
   expires_in: number;
   user: User;
 }
+
+export type WebhookEvent =
+  | 'post.created'
+  | 'post.updated'
+  | 'post.published'
+  | 'post.deleted'
+  | 'comment.created'
+  | 'user.registered';
+
+export interface Webhook {
+  id: number;
+  name: string;
+  url: string;
+  events: WebhookEvent[];
+  active: boolean;
+  // Only returned when the secret was set or generated
+  secret?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface WebhookDelivery {
+  id: number;
+  webhookId: number;
+  eventId: string;
+  event: WebhookEvent | 'ping';
+  payload: string;
+  status: 'pending' | 'success' | 'failed';
+  attempts: number;
+  nextAttemptAt: string;
+  responseStatus: number;
+  responseBody?: string;
+  lastError?: string;
+  durationMs: number;
+  deliveredAt?: string;
+  createdAt: string;
+}